@@ -4,6 +4,7 @@ import (
 	_ "github.com/KryukovO/gophermart/docs"
 	"github.com/KryukovO/gophermart/internal/gophermart"
 	"github.com/KryukovO/gophermart/internal/gophermart/config"
+	"github.com/KryukovO/gophermart/internal/gophermart/logging"
 	"github.com/spf13/pflag"
 
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -19,17 +20,57 @@ func main() {
 	pflag.BoolVarP(&helpFlag, "help", "h", false, "Shows gophermart usage")
 
 	pflag.StringVarP(&cfg.Address, "address", "a", cfg.Address, "Address to run HTTP server")
+	pflag.StringVar(&cfg.MetricsAddress, "metrics", cfg.MetricsAddress, "Address to serve Prometheus metrics (disabled if empty)")
 	pflag.StringVarP(&cfg.DSN, "dsn", "d", cfg.DSN, "URI to database")
 	pflag.StringVarP(&cfg.AccrualAddress, "accrual", "r", cfg.AccrualAddress, "Accrual system address")
+	pflag.StringVar(&cfg.Storage, "storage", cfg.Storage, "Storage type (postgres, memory)")
+	pflag.StringVar(&cfg.LogLevel, "loglevel", cfg.LogLevel, "Log level (panic, fatal, error, warn, info, debug, trace)")
+	pflag.StringVar(&cfg.LogFormat, "logformat", cfg.LogFormat, "Log format (text, json)")
 
 	pflag.StringVar(&cfg.SecretKey, "secret", cfg.SecretKey, "Authorization token encryption key")
 	pflag.DurationVar(&cfg.UserTokenTTL, "userttl", cfg.UserTokenTTL, "User token lifetime")
+	pflag.DurationVar(&cfg.RefreshTokenTTL, "refreshttl", cfg.RefreshTokenTTL, "Refresh token lifetime")
+	pflag.StringSliceVar(&cfg.JWTKeys, "jwtkeys", cfg.JWTKeys, "PEM files of token signing keys (path[@RFC3339 activation time])")
+	pflag.DurationVar(&cfg.JWTKeyGrace, "jwtgrace", cfg.JWTKeyGrace, "Time a replaced signing key is still accepted")
+	pflag.StringVar(&cfg.TokenSource, "tokensource", cfg.TokenSource, "Preferred source of the access token (header, cookie)")
+	pflag.StringVar(&cfg.PasswordHasher, "hasher", cfg.PasswordHasher, "Password hashing algorithm (argon2id, bcrypt)")
+	pflag.IntVar(&cfg.BcryptCost, "bcryptcost", cfg.BcryptCost, "Bcrypt hashing cost")
+	pflag.UintVar(&cfg.Argon2Memory, "argon2memory", cfg.Argon2Memory, "Argon2id memory in KiB")
+	pflag.UintVar(&cfg.Argon2Iterations, "argon2iterations", cfg.Argon2Iterations, "Argon2id number of iterations")
+	pflag.UintVar(&cfg.Argon2Parallelism, "argon2parallelism", cfg.Argon2Parallelism, "Argon2id degree of parallelism")
+	pflag.DurationVar(&cfg.PasswordResetTTL, "resetttl", cfg.PasswordResetTTL, "Password reset token lifetime")
+	pflag.IntVar(&cfg.LoginMaxAttempts, "loginattempts", cfg.LoginMaxAttempts, "Failed login attempts per login before lockout")
+	pflag.IntVar(&cfg.LoginMaxAttemptsIP, "loginattemptsip", cfg.LoginMaxAttemptsIP, "Failed login attempts per IP before lockout")
+	pflag.DurationVar(&cfg.LoginDelay, "logindelay", cfg.LoginDelay, "Delay after the first failed login attempt")
+	pflag.DurationVar(&cfg.LoginLockout, "loginlockout", cfg.LoginLockout, "Login lockout duration")
+	pflag.DurationVar(&cfg.IdempotencyKeyTTL, "idempotencyttl", cfg.IdempotencyKeyTTL, "Idempotency key retention period")
+	pflag.DurationVar(&cfg.IdempotencyLease, "idempotencylease", cfg.IdempotencyLease, "Time an idempotency key is locked while the request is processed")
 	pflag.DurationVar(&cfg.ShutdownTimeout, "shutdown", cfg.ShutdownTimeout, "Server shutdown timeout")
+	pflag.DurationVar(&cfg.DrainTimeout, "drain", cfg.DrainTimeout, "Time the server reports not ready before shutdown")
+	pflag.DurationVar(&cfg.ReadinessMaxAge, "readyage", cfg.ReadinessMaxAge, "Max time since the last successful Accrual poll")
 	pflag.DurationVar(&cfg.RepositioryTimeout, "timeout", cfg.RepositioryTimeout, "Repository connection timeout")
+	pflag.DurationVar(&cfg.CleanupInterval, "cleanup", cfg.CleanupInterval, "Interval for deleting expired data")
 	pflag.StringVar(&cfg.Migrations, "migrations", cfg.Migrations, "Directory of database migration files")
 	pflag.UintVar(&cfg.AccrualWorkers, "workers", cfg.AccrualWorkers, "Number of concurrent requests to Accrual")
 	pflag.DurationVar(&cfg.AccrualInterval, "interval", cfg.AccrualInterval, "Interval for generating requests to Accrual")
 	pflag.DurationVar(&cfg.AccrualShutdown, "accshutdown", cfg.AccrualShutdown, "Accrual connector shutdown timeout")
+	pflag.UintVar(&cfg.AccrualBatchSize, "batch", cfg.AccrualBatchSize, "Maximum number of orders in a batch of requests to Accrual")
+	pflag.DurationVar(&cfg.AccrualBackoff, "backoff", cfg.AccrualBackoff, "Maximum delay before the next poll of an order")
+	pflag.DurationVar(&cfg.AccrualMaxAge, "maxage", cfg.AccrualMaxAge, "Maximum age of an order processed by Accrual")
+	pflag.DurationVar(&cfg.AccrualLease, "lease", cfg.AccrualLease, "Lease time of a batch of orders claimed by the service instance")
+
+	pflag.UintVar(&cfg.WebhookWorkers, "webhookworkers", cfg.WebhookWorkers, "Number of concurrent webhook deliveries")
+	pflag.DurationVar(&cfg.WebhookInterval, "webhookinterval", cfg.WebhookInterval, "Interval for polling the webhook delivery queue")
+	pflag.DurationVar(&cfg.WebhookShutdown, "webhookshutdown", cfg.WebhookShutdown, "Webhook sender shutdown timeout")
+	pflag.UintVar(&cfg.WebhookBatchSize, "webhookbatch", cfg.WebhookBatchSize, "Maximum number of webhook deliveries in a batch")
+	pflag.DurationVar(&cfg.WebhookLease, "webhooklease", cfg.WebhookLease, "Lease time of a batch of webhook deliveries")
+	pflag.DurationVar(&cfg.WebhookTimeout, "webhooktimeout", cfg.WebhookTimeout, "Webhook delivery request timeout")
+	pflag.UintVar(&cfg.WebhookAttempts, "webhookattempts", cfg.WebhookAttempts, "Maximum number of webhook delivery attempts")
+	pflag.DurationVar(&cfg.WebhookBackoff, "webhookbackoff", cfg.WebhookBackoff, "Maximum delay before the next webhook delivery attempt")
+
+	pflag.StringVar(&cfg.TracingEndpoint, "otlp", cfg.TracingEndpoint, "OpenTelemetry collector OTLP/HTTP endpoint")
+	pflag.StringVar(&cfg.TracingOutput, "traceout", cfg.TracingOutput, "File to write spans to if no collector is set (stdout)")
+	pflag.Float64Var(&cfg.TracingSampleRatio, "traceratio", cfg.TracingSampleRatio, "Ratio of traced requests")
 
 	pflag.Parse()
 
@@ -39,12 +80,10 @@ func main() {
 		return
 	}
 
-	logger := log.New()
-	logger.SetLevel(log.DebugLevel)
-	logger.SetFormatter(&log.TextFormatter{
-		FullTimestamp:   true,
-		TimestampFormat: "2006-01-02 15:04:05 Z07:00",
-	})
+	logger, err := logging.NewLogger(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatalf("Invalid logger settings: %s. Exit(1)", err)
+	}
 
 	if err := gophermart.Run(cfg, logger); err != nil {
 		logger.Fatalf("Gophermart service error: %s. Exit(1)", err)
@@ -16,353 +16,2082 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/user/balance": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Get the public keys used to verify access tokens, selected by the kid token header.\nKeys scheduled for future use and replaced keys within the grace period are included.\nThe set is empty if tokens are signed with a shared secret.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Token signing keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/JWKS"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/orders/pending": {
             "get": {
                 "security": [
                     {
                         "JWT": []
+                    },
+                    {
+                        "Bearer": []
                     }
                 ],
-                "description": "Get the current balance of the user's loyalty points account.",
+                "description": "Get the number of orders of all users whose accrual calculation is not completed.\nAvailable to administrators only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Gophermart HTTP API"
                 ],
-                "summary": "Get user balance",
+                "summary": "Get pending orders count",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/Balance"
+                            "$ref": "#/definitions/PendingOrders"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     }
                 }
             }
         },
-        "/api/user/balance/withdraw": {
-            "post": {
+        "/api/admin/users": {
+            "get": {
                 "security": [
                     {
                         "JWT": []
+                    },
+                    {
+                        "Bearer": []
                     }
                 ],
-                "description": "Withdraw points from the loyalty points account to pay for a new order.",
-                "consumes": [
+                "description": "Get a list of users whose login contains the given string (case-insensitive).\nUsers are ordered by ID and returned page by page, the cursor of the next page\nis passed in the X-Next-Cursor response header. Available to administrators only.",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Gophermart HTTP API"
                 ],
-                "summary": "Withdrawal request",
+                "summary": "Search users",
                 "parameters": [
                     {
-                        "description": "Order number and withdrawal sum.",
-                        "name": "withdrawal",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/BalanceChange"
-                        }
+                        "type": "string",
+                        "description": "Part of the user login.",
+                        "name": "login",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 100, maximum 1000).",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page from the X-Next-Cursor header.",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort direction by user ID.",
+                        "name": "sort",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/UserProfile"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor of the next page."
+                            }
+                        }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     },
-                    "402": {
-                        "description": "Payment Required",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     },
-                    "422": {
-                        "description": "Unprocessable Entity",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     }
                 }
             }
         },
-        "/api/user/login": {
-            "post": {
-                "description": "User authorization by login and password.",
-                "consumes": [
+        "/api/admin/users/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the account data of any user. Available to administrators only.",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Gophermart HTTP API"
                 ],
-                "summary": "User authorization",
+                "summary": "Get user",
                 "parameters": [
                     {
-                        "description": "User login and password.",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/User"
-                        }
+                        "type": "integer",
+                        "description": "User ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/UserProfile"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     }
                 }
             }
         },
-        "/api/user/orders": {
+        "/api/admin/users/{id}/balance": {
             "get": {
                 "security": [
                     {
                         "JWT": []
+                    },
+                    {
+                        "Bearer": []
                     }
                 ],
-                "description": "Get a list of order numbers uploaded by the user,\ntheir processing statuses and information about accruals.",
+                "description": "Get the current balance of any user's loyalty points account. Available to administrators only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Gophermart HTTP API"
                 ],
-                "summary": "Get uploaded orders",
+                "summary": "Get user balance",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/Order"
-                            }
+                            "$ref": "#/definitions/Balance"
                         }
                     },
-                    "204": {
-                        "description": "No Content"
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/api/admin/users/{id}/balance/adjustments": {
             "post": {
                 "security": [
                     {
                         "JWT": []
+                    },
+                    {
+                        "Bearer": []
                     }
                 ],
-                "description": "Loading by the user of the order number.",
+                "description": "Credit (positive sum) or debit (negative sum) any user's loyalty points account.\nThe adjustment is recorded in the user's statement together with the acting administrator,\nthe reason and the reference. Available to administrators only.",
                 "consumes": [
-                    "text/plain"
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
                 ],
                 "tags": [
                     "Gophermart HTTP API"
                 ],
-                "summary": "Add new order",
+                "summary": "Adjust user balance",
                 "parameters": [
                     {
-                        "description": "Order number.",
-                        "name": "order",
+                        "type": "integer",
+                        "description": "User ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Sum, reason and reference.",
+                        "name": "adjustment",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/BalanceAdjustment"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
-                    },
-                    "202": {
-                        "description": "Accepted"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/Balance"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "402": {
+                        "description": "Payment Required",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     },
-                    "422": {
-                        "description": "Unprocessable Entity",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     }
                 }
             }
         },
-        "/api/user/register": {
-            "post": {
-                "description": "User registration by login and password.",
-                "consumes": [
+        "/api/admin/users/{id}/balance/history": {
+            "get": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get all refills and withdrawals of any user's loyalty points account\nwith the account balance after each operation.\nEntries are returned page by page, the cursor of the next page\nis passed in the X-Next-Cursor response header. Available to administrators only.",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Gophermart HTTP API"
                 ],
-                "summary": "User registration",
+                "summary": "Get user account statement",
                 "parameters": [
                     {
-                        "description": "User login and password.",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/User"
-                        }
+                        "type": "integer",
+                        "description": "User ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 100, maximum 1000).",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page from the X-Next-Cursor header.",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort direction by processing time.",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum processing time (RFC 3339).",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum processing time (RFC 3339).",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/StatementEntry"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor of the next page."
+                            }
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     }
                 }
             }
         },
-        "/api/user/withdrawals": {
+        "/api/admin/users/{id}/orders": {
             "get": {
                 "security": [
                     {
                         "JWT": []
+                    },
+                    {
+                        "Bearer": []
                     }
                 ],
-                "description": "Get a list of withdrawals from a user's loyalty points account.",
+                "description": "Get a list of orders uploaded by any user.\nOrders are returned page by page, the cursor of the next page\nis passed in the X-Next-Cursor response header. Available to administrators only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Gophermart HTTP API"
                 ],
-                "summary": "Get withdrawals list",
+                "summary": "Get user orders",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 100, maximum 1000).",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page from the X-Next-Cursor header.",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort direction by upload time.",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Order statuses.",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum upload time (RFC 3339).",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum upload time (RFC 3339).",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/BalanceChange"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/Order"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor of the next page."
+                            }
                         }
                     },
                     "204": {
                         "description": "No Content"
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "Balance": {
-            "description": "User's loyalty points account balance.",
-            "type": "object",
-            "properties": {
-                "current": {
-                    "type": "number"
-                },
-                "withdrawn": {
+        },
+        "/api/admin/withdrawals/{order}/reversal": {
+            "post": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Return the points withdrawn to pay for the order to the user's loyalty points account,\ne.g. when the shop cancels the order. The last withdrawal for the order is reversed\nfor the exact amount; a withdrawal can be reversed only once. Available to administrators only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Reverse withdrawal",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order number.",
+                        "name": "order",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/BalanceChange"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/balance": {
+            "get": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the current balance of the user's loyalty points account.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Get user balance",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/Balance"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/balance/history": {
+            "get": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get all refills and withdrawals of a user's loyalty points account\nwith the account balance after each operation.\nEntries are returned page by page, the cursor of the next page\nis passed in the X-Next-Cursor response header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Get account statement",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 100, maximum 1000).",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page from the X-Next-Cursor header.",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort direction by processing time.",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum processing time (RFC 3339).",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum processing time (RFC 3339).",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/StatementEntry"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor of the next page."
+                            }
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/balance/history/export": {
+            "get": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Export all refills and withdrawals of a user's loyalty points account\nwith the account balance after each operation as CSV or NDJSON.\nThe format is selected by the format query parameter or the Accept header, CSV is used by default.\nEntries are streamed to the client without loading them all into memory.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Export account statement",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Export format.",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort direction by processing time.",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum processing time (RFC 3339).",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum processing time (RFC 3339).",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/StatementEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/balance/withdraw": {
+            "post": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Withdraw points from the loyalty points account to pay for a new order.\nA retry with the same Idempotency-Key header and body returns the stored response\ninstead of withdrawing the points again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Withdrawal request",
+                "parameters": [
+                    {
+                        "description": "Order number and withdrawal sum.",
+                        "name": "withdrawal",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/BalanceChange"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique request key for safe retries.",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/login": {
+            "post": {
+                "description": "User authorization by login and password.\nAfter repeated failed attempts for the same login or from the same address\nthe login is temporarily locked.\nIssued tokens are returned in the response body, the Authorization header and cookies.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "User authorization",
+                "parameters": [
+                    {
+                        "description": "User login and password.",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/Tokens"
+                        },
+                        "headers": {
+                            "Authorization": {
+                                "type": "string",
+                                "description": "Bearer access token"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds until the login is unlocked"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/logout": {
+            "post": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Revoke the current access token and all refresh tokens issued with it.",
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "User logout",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/orders": {
+            "get": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get a list of order numbers uploaded by the user,\ntheir processing statuses and information about accruals.\nOrders are returned page by page, the cursor of the next page\nis passed in the X-Next-Cursor response header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Get uploaded orders",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 100, maximum 1000).",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page from the X-Next-Cursor header.",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort direction by upload time.",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Order statuses.",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum upload time (RFC 3339).",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum upload time (RFC 3339).",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/Order"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor of the next page."
+                            }
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Loading by the user of the order number.\nA retry with the same Idempotency-Key header and body returns the stored response.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Add new order",
+                "parameters": [
+                    {
+                        "description": "Order number.",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique request key for safe retries.",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "202": {
+                        "description": "Accepted"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/orders/export": {
+            "get": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Export all orders uploaded by the user as CSV or NDJSON.\nThe format is selected by the format query parameter or the Accept header, CSV is used by default.\nOrders are streamed to the client without loading them all into memory.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Export orders",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Export format.",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort direction by upload time.",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Order statuses.",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum upload time (RFC 3339).",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum upload time (RFC 3339).",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/Order"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/password": {
+            "post": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Change the password of the authorized user after checking the current password.\nAll user sessions are terminated, and a new pair of tokens is issued for the current one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Password change",
+                "parameters": [
+                    {
+                        "description": "Current and new passwords.",
+                        "name": "password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/PasswordChange"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/Tokens"
+                        },
+                        "headers": {
+                            "Authorization": {
+                                "type": "string",
+                                "description": "Bearer access token"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/password/reset": {
+            "post": {
+                "description": "Send a single-use password reset token to the user.\nThe response does not depend on whether the user exists.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Password reset request",
+                "parameters": [
+                    {
+                        "description": "User login.",
+                        "name": "login",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/PasswordResetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/password/reset/confirm": {
+            "post": {
+                "description": "Set a new password by the password reset token.\nAll user sessions are terminated.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Password reset",
+                "parameters": [
+                    {
+                        "description": "Password reset token and new password.",
+                        "name": "reset",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/PasswordReset"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/refresh": {
+            "post": {
+                "description": "Exchange the refresh token from the refresh_token cookie or the request body\nfor a new pair of tokens.\nEach refresh token can be used only once. Reusing a refresh token revokes\nall tokens issued since the user logged in.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Token refresh",
+                "parameters": [
+                    {
+                        "description": "Refresh token, if not passed in the cookie.",
+                        "name": "token",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/Tokens"
+                        },
+                        "headers": {
+                            "Authorization": {
+                                "type": "string",
+                                "description": "Bearer access token"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/register": {
+            "post": {
+                "description": "User registration by login and password.\nIssued tokens are returned in the response body, the Authorization header and cookies.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "User registration",
+                "parameters": [
+                    {
+                        "description": "User login and password.",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/Tokens"
+                        },
+                        "headers": {
+                            "Authorization": {
+                                "type": "string",
+                                "description": "Bearer access token"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the notification subscriptions of the user.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Get webhooks list",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/Webhook"
+                            }
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Subscribe to notifications about order status and balance changes.\nEach notification is sent as a POST request signed with HMAC-SHA256 using the secret.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Register webhook",
+                "parameters": [
+                    {
+                        "description": "Notification URL and signing secret.",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/WebhookRegistration"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/webhooks/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Delete the notification subscription together with its delivery log.",
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Delete webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the delivery attempts of notifications sent to the subscription.\nDeliveries are returned page by page, the cursor of the next page\nis passed in the X-Next-Cursor response header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Get webhook delivery log",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID.",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 100, maximum 1000).",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page from the X-Next-Cursor header.",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort direction by event time.",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum event time (RFC 3339).",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum event time (RFC 3339).",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/WebhookDelivery"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor of the next page."
+                            }
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/withdrawals": {
+            "get": {
+                "security": [
+                    {
+                        "JWT": []
+                    },
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get a list of withdrawals from a user's loyalty points account.\nWithdrawals are returned page by page, the cursor of the next page\nis passed in the X-Next-Cursor response header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Get withdrawals list",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 100, maximum 1000).",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page from the X-Next-Cursor header.",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort direction by processing time.",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum processing time (RFC 3339).",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum processing time (RFC 3339).",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/BalanceChange"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor of the next page."
+                            }
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Check that the service is running. Dependencies are not checked.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/HealthReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Check that the service is ready to handle requests:\nthe database is reachable, migrations are applied and accrual polling succeeds.\nThe service is not ready during graceful shutdown.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Gophermart HTTP API"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/HealthReport"
+                        }
+                    },
+                    "503": {
+                        "description": "Service is not ready",
+                        "schema": {
+                            "$ref": "#/definitions/HealthReport"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "Balance": {
+            "description": "User's loyalty points account balance.",
+            "type": "object",
+            "properties": {
+                "current": {
+                    "type": "number"
+                },
+                "withdrawn": {
                     "type": "number"
                 }
             }
         },
+        "BalanceAdjustment": {
+            "description": "Manual adjustment of the user's loyalty points account balance.",
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "example": "Fraudulent accrual"
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "SUP-1234"
+                },
+                "sum": {
+                    "type": "number",
+                    "example": -150.5
+                }
+            }
+        },
         "BalanceChange": {
             "description": "Change of the user's loyalty points account balance.",
             "type": "object",
@@ -373,11 +2102,105 @@ const docTemplate = `{
                 "processed_at": {
                     "type": "string"
                 },
+                "reversed_at": {
+                    "type": "string"
+                },
                 "sum": {
                     "type": "number"
                 }
             }
         },
+        "ComponentStatus": {
+            "description": "Component readiness status.",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "up",
+                        "down"
+                    ]
+                }
+            }
+        },
+        "HealthReport": {
+            "description": "Service readiness status with the status of each component.",
+            "type": "object",
+            "properties": {
+                "components": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/ComponentStatus"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "up",
+                        "down"
+                    ]
+                }
+            }
+        },
+        "JWK": {
+            "description": "Public key in the JWK format (RFC 7517).",
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string",
+                    "enum": [
+                        "RS256",
+                        "EdDSA"
+                    ]
+                },
+                "crv": {
+                    "type": "string",
+                    "enum": [
+                        "Ed25519"
+                    ]
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string",
+                    "enum": [
+                        "RSA",
+                        "OKP"
+                    ]
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string",
+                    "enum": [
+                        "sig"
+                    ]
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "JWKS": {
+            "description": "Set of public keys used to verify access tokens.",
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/JWK"
+                    }
+                }
+            }
+        },
         "Order": {
             "description": "Order data.",
             "type": "object",
@@ -396,6 +2219,154 @@ const docTemplate = `{
                 }
             }
         },
+        "PasswordChange": {
+            "description": "Password change request.",
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "type": "string"
+                },
+                "old_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "PasswordReset": {
+            "description": "New password set by the password reset token.",
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "PasswordResetRequest": {
+            "description": "Password reset request.",
+            "type": "object",
+            "properties": {
+                "login": {
+                    "type": "string"
+                }
+            }
+        },
+        "PendingOrders": {
+            "description": "Number of orders whose accrual calculation is not completed.",
+            "type": "object",
+            "properties": {
+                "new": {
+                    "type": "integer"
+                },
+                "processing": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "Problem": {
+            "description": "Error description in the RFC 7807 problem details format.",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "not_enough_funds"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "not enough funds"
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/api/user/balance/withdraw"
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "5d1c5b9e-8f0e-4a55-9a5c-3f5f2a1d7b61"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 402
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Payment Required"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "urn:gophermart:problem:not_enough_funds"
+                }
+            }
+        },
+        "RefreshRequest": {
+            "description": "Refresh token exchanged for a new pair of tokens.",
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "StatementEntry": {
+            "description": "Entry of the user's loyalty points account statement.",
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "number"
+                },
+                "operation": {
+                    "type": "string",
+                    "enum": [
+                        "refill",
+                        "withdrawal",
+                        "adjustment",
+                        "reversal"
+                    ]
+                },
+                "order": {
+                    "type": "string"
+                },
+                "processed_at": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "reference": {
+                    "type": "string"
+                },
+                "sum": {
+                    "type": "number"
+                }
+            }
+        },
+        "Tokens": {
+            "description": "Authorization tokens issued to the user.",
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "refresh_expires_at": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string",
+                    "enum": [
+                        "Bearer"
+                    ]
+                }
+            }
+        },
         "User": {
             "description": "User account data.",
             "type": "object",
@@ -408,14 +2379,107 @@ const docTemplate = `{
                 }
             }
         },
-        "echo.HTTPError": {
+        "UserProfile": {
+            "description": "User account data available to administrators.",
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "user",
+                        "admin"
+                    ]
+                }
+            }
+        },
+        "Webhook": {
+            "description": "Webhook subscription of the user.",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "WebhookDelivery": {
+            "description": "Delivery of a webhook event.",
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string",
+                    "enum": [
+                        "order.status_changed",
+                        "balance.changed"
+                    ]
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "response_status": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "delivered",
+                        "failed"
+                    ]
+                }
+            }
+        },
+        "WebhookRegistration": {
+            "description": "Webhook subscription registration.",
             "type": "object",
             "properties": {
-                "message": {}
+                "secret": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://shop.example.com/hooks/gophermart"
+                }
             }
         }
     },
     "securityDefinitions": {
+        "Bearer": {
+            "description": "JSON Web Token with the Bearer prefix: \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        },
         "JWT": {
             "description": "JSON Web Token",
             "type": "apiKey",
@@ -7,11 +7,22 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/logging"
+	"github.com/KryukovO/gophermart/internal/gophermart/metrics"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
 	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/KryukovO/gophermart/internal/utils"
+	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/semconv/v1.17.0/httpconv"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -19,22 +30,36 @@ var (
 	ErrUnexpectedStatus          = errors.New("unexpected response status")
 	ErrAccrualServiceUnavailable = errors.New("accrual service is unavailable")
 	ErrAccrualOrderNotFound      = errors.New("accrual order not found")
+	ErrOrderExpired              = errors.New("order exceeded maximum processing age")
+	ErrPollStale                 = errors.New("no successful accrual poll recently")
+	ErrLeaseExpired              = errors.New("order lease expires before accrual rate limit allows a request")
 )
 
+var tracer = otel.Tracer("github.com/KryukovO/gophermart/internal/gophermart/accrualconnector")
+
 type AccrualConnector struct {
 	accrualAddr string
 	workers     uint
 	interval    time.Duration
+	batchSize   uint
+	lease       time.Duration
+	maxBackoff  time.Duration
+	maxAge      time.Duration
+	instance    string
 	order       usecases.Order
-	balance     usecases.Balance
+	metrics     *metrics.Metrics
 	logger      *log.Logger
+	limiter     *rateLimiter
 	close       chan struct{}
+	lastPoll    atomic.Int64 // Время завершения последнего успешного опроса в наносекундах Unix
+	reached     atomic.Bool  // Признак ответа сервиса Accrual хотя бы на один запрос текущего опроса
 }
 
 func NewAccrualConnector(
 	accrualAddr string, workers uint, interval time.Duration,
-	order usecases.Order, balance usecases.Balance,
-	logger *log.Logger,
+	batchSize uint, lease time.Duration,
+	maxBackoff time.Duration, maxAge time.Duration,
+	order usecases.Order, metrics *metrics.Metrics, logger *log.Logger,
 ) *AccrualConnector {
 	connectorLogger := log.StandardLogger()
 	if logger != nil {
@@ -45,9 +70,15 @@ func NewAccrualConnector(
 		accrualAddr: accrualAddr,
 		workers:     workers,
 		interval:    interval,
+		batchSize:   batchSize,
+		lease:       lease,
+		maxBackoff:  maxBackoff,
+		maxAge:      maxAge,
+		instance:    uuid.NewString(),
 		order:       order,
-		balance:     balance,
+		metrics:     metrics,
 		logger:      connectorLogger,
+		limiter:     newRateLimiter(),
 		close:       make(chan struct{}),
 	}
 }
@@ -65,9 +96,20 @@ func (connector *AccrualConnector) Run(ctx context.Context) {
 		case <-time.After(connector.interval):
 		}
 
-		orders, err := connector.order.ProcessableOrders(ctx)
+		connector.reached.Store(false)
+
+		// Повторы запросов после 429 Too Many Requests не должны выходить за срок аренды заказов,
+		// иначе заказ может быть одновременно обработан другим экземпляром.
+		deadline := time.Now().Add(connector.lease)
+
+		orders, err := connector.order.ProcessableOrders(
+			ctx, connector.instance, connector.batchSize, connector.lease,
+		)
 		if err != nil {
 			connector.logger.Errorf("AccrualConnector error: %s", err)
+			connector.metrics.IncAccrualErrors(metrics.AccrualStageStorage)
+		} else {
+			connector.metrics.ObserveAccrualBatch(len(orders))
 		}
 
 		tasks := connector.generateOrderTasks(ctx, orders)
@@ -76,17 +118,52 @@ func (connector *AccrualConnector) Run(ctx context.Context) {
 
 		for w := 0; w < int(connector.workers); w++ {
 			group.Go(func() error {
-				return connector.orderTaskWorker(gCtx, tasks)
+				return connector.orderTaskWorker(gCtx, tasks, deadline)
 			})
 		}
 
-		err = group.Wait()
-		if err != nil {
-			connector.logger.Errorf("AccrualConnector error: %s", err)
+		waitErr := group.Wait()
+		if waitErr != nil {
+			connector.logger.Errorf("AccrualConnector error: %s", waitErr)
+			connector.metrics.IncAccrualErrors(metrics.AccrualStageStorage)
+		}
+
+		// Ошибки отдельных запросов к сервису Accrual не делают опрос неуспешным,
+		// иначе единичный сбой сети приводил бы к неготовности всех экземпляров сервиса
+		reached := len(orders) == 0 || connector.reached.Load()
+		if err == nil && waitErr == nil && reached && ctx.Err() == nil {
+			connector.lastPoll.Store(time.Now().UnixNano())
 		}
 	}
 }
 
+// Возвращает время завершения последнего успешного опроса сервиса Accrual.
+// Опрос считается успешным, если заказы получены и сохранены в хранилище
+// и сервис Accrual ответил хотя бы на один из запросов опроса.
+// Возвращает нулевое время, если успешных опросов ещё не было.
+func (connector *AccrualConnector) LastPoll() time.Time {
+	lastPoll := connector.lastPoll.Load()
+	if lastPoll == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, lastPoll)
+}
+
+// Проверяет, что последний успешный опрос сервиса Accrual завершился не ранее maxAge назад.
+func (connector *AccrualConnector) CheckPoll(maxAge time.Duration) error {
+	lastPoll := connector.LastPoll()
+	if lastPoll.IsZero() {
+		return fmt.Errorf("%w: no successful poll yet", ErrPollStale)
+	}
+
+	if since := time.Since(lastPoll); since > maxAge {
+		return fmt.Errorf("%w: last successful poll %s ago", ErrPollStale, since.Truncate(time.Second))
+	}
+
+	return nil
+}
+
 func (connector *AccrualConnector) Shutdown(ctx context.Context) {
 	select {
 	case <-ctx.Done():
@@ -118,7 +195,9 @@ func (connector *AccrualConnector) generateOrderTasks(
 	return outCh
 }
 
-func (connector *AccrualConnector) orderTaskWorker(ctx context.Context, tasks <-chan entities.Order) error {
+func (connector *AccrualConnector) orderTaskWorker(
+	ctx context.Context, tasks <-chan entities.Order, deadline time.Time,
+) error {
 	client := http.Client{}
 
 	for order := range tasks {
@@ -126,52 +205,141 @@ func (connector *AccrualConnector) orderTaskWorker(ctx context.Context, tasks <-
 		case <-ctx.Done():
 			return nil
 		default:
-			accrualOrder, err := connector.doRequest(ctx, &client, order.Number)
+			err := connector.processOrder(ctx, &client, &order, deadline)
+			if errors.Is(err, entities.ErrOrderLeaseLost) {
+				// Результат опроса отбрасывается: заказ обрабатывается другим экземпляром
+				logging.FromContext(ctx, connector.logger).
+					WithField(logging.FieldOrder, order.Number).
+					Warnf("AccrualConnector: %s", err)
+
+				continue
+			}
+
 			if err != nil {
-				if errors.Is(err, ErrAccrualOrderNotFound) {
-					continue
+				if ctx.Err() != nil {
+					return nil
 				}
 
 				return err
 			}
+		}
+	}
 
-			order.Status = entities.AccrualToOrderStatus(accrualOrder.Status)
-			order.Accrual = accrualOrder.Accrual
+	return nil
+}
 
-			err = connector.order.UpdateOrder(ctx, &order)
-			if err != nil {
-				return err
-			}
+// Запрашивает у сервиса Accrual статус заказа и сохраняет результат.
+// Если расчёт начисления не завершён либо запрос не удался,
+// следующий опрос заказа откладывается.
+// Запрос не повторяется после deadline — окончания аренды заказа.
+func (connector *AccrualConnector) processOrder(
+	ctx context.Context, client *http.Client, order *entities.Order, deadline time.Time,
+) (err error) {
+	ctx, span := tracer.Start(
+		ctx, "AccrualConnector.processOrder",
+		trace.WithAttributes(attribute.String("order.number", order.Number)),
+	)
+	defer tracing.End(span, &err)
+
+	logger := logging.FromContext(ctx, connector.logger).WithField(logging.FieldOrder, order.Number)
+	ctx = logging.WithLogger(ctx, logger)
+
+	accrualOrder, err := connector.requestOrder(ctx, client, order.Number, deadline)
+	if err == nil || errors.Is(err, ErrAccrualOrderNotFound) || errors.Is(err, ErrLeaseExpired) {
+		connector.reached.Store(true)
+	}
 
-			if order.Status == entities.OrderStatusProcessed {
-				balanceChange := entities.BalanceChange{
-					UserID:    order.UserID,
-					Operation: entities.BalanceOperationRefill,
-					Order:     order.Number,
-					Sum:       order.Accrual,
-				}
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
 
-				err := connector.balance.ChangeBalance(ctx, &balanceChange)
-				if err != nil {
-					return err
-				}
-			}
+		if !errors.Is(err, ErrAccrualOrderNotFound) {
+			connector.metrics.IncAccrualErrors(metrics.AccrualStageRequest)
 		}
+
+		return connector.postponeOrder(ctx, order, err.Error())
 	}
 
-	return nil
+	order.Status = entities.AccrualToOrderStatus(accrualOrder.Status)
+	order.Accrual = accrualOrder.Accrual
+
+	switch order.Status {
+	case entities.OrderStatusProcessed:
+		return connector.order.ProcessOrder(ctx, connector.instance, order)
+	case entities.OrderStatusInvalid:
+		return connector.order.UpdateOrder(ctx, connector.instance, order)
+	default:
+		return connector.postponeOrder(ctx, order, fmt.Sprintf("accrual status: %s", accrualOrder.Status))
+	}
+}
+
+// Откладывает следующий опрос заказа с экспоненциально растущей задержкой.
+// Заказ, не обработанный сервисом Accrual за maxAge, переводится в статус INVALID.
+func (connector *AccrualConnector) postponeOrder(
+	ctx context.Context, order *entities.Order, reason string,
+) error {
+	order.Attempts++
+	order.LastError = reason
+	order.NextPollAt = time.Now().Add(connector.backoff(order.Attempts))
+
+	if time.Since(order.UploadedAt) > connector.maxAge {
+		order.Status = entities.OrderStatusInvalid
+		order.LastError = fmt.Sprintf("%s: %s", ErrOrderExpired, reason)
+		order.NextPollAt = time.Time{}
+
+		logging.FromContext(ctx, connector.logger).Warnf("AccrualConnector: %s", order.LastError)
+	}
+
+	return connector.order.UpdateOrder(ctx, connector.instance, order)
+}
+
+// Возвращает задержку перед очередным опросом заказа после attempts неудачных попыток.
+func (connector *AccrualConnector) backoff(attempts uint) time.Duration {
+	return utils.Backoff(connector.interval, connector.maxBackoff, attempts)
+}
+
+// Запрашивает данные заказа у сервиса Accrual с учётом ограничения частоты запросов.
+// При получении 429 Too Many Requests запрос повторяется после паузы, общей для всех воркеров,
+// пока пауза не выходит за deadline; в противном случае возвращается ErrLeaseExpired.
+func (connector *AccrualConnector) requestOrder(
+	ctx context.Context, client *http.Client, order string, deadline time.Time,
+) (entities.AccrualOrder, error) {
+	for {
+		err := connector.limiter.Wait(ctx, deadline)
+		if err != nil {
+			return entities.AccrualOrder{}, err
+		}
+
+		accrualOrder, err := connector.doRequest(ctx, client, order)
+		if errors.Is(err, ErrAccrualServiceUnavailable) {
+			continue
+		}
+
+		return accrualOrder, err
+	}
 }
 
+// Выполняет запрос данных заказа к сервису Accrual.
+// Контекст трассировки передаётся сервису Accrual в заголовках запроса.
 func (connector *AccrualConnector) doRequest(
 	ctx context.Context, client *http.Client, order string,
-) (entities.AccrualOrder, error) {
+) (_ entities.AccrualOrder, err error) {
 	url := fmt.Sprintf("%s/api/orders/%s", connector.accrualAddr, order)
 
+	ctx, span := tracer.Start(ctx, "AccrualConnector.doRequest", trace.WithSpanKind(trace.SpanKindClient))
+	defer tracing.End(span, &err)
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return entities.AccrualOrder{}, err
 	}
 
+	span.SetAttributes(httpconv.ClientRequest(req)...)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	start := time.Now()
+
 	resp, err := client.Do(req)
 	if err != nil {
 		return entities.AccrualOrder{}, err
@@ -179,12 +347,23 @@ func (connector *AccrualConnector) doRequest(
 
 	defer resp.Body.Close()
 
+	connector.metrics.ObserveAccrualResponse(resp.StatusCode, time.Since(start))
+	span.SetAttributes(httpconv.ClientResponse(resp)...)
+
 	if resp.StatusCode != http.StatusOK {
 		if resp.StatusCode == http.StatusNoContent {
 			return entities.AccrualOrder{}, ErrAccrualOrderNotFound
 		}
 
 		if resp.StatusCode == http.StatusTooManyRequests {
+			body, _ := io.ReadAll(resp.Body)
+			retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+
+			connector.limiter.Pause(retryAfter, parseQuota(body))
+			logging.FromContext(ctx, connector.logger).Warnf(
+				"AccrualConnector: too many requests, retry after %s", retryAfter,
+			)
+
 			return entities.AccrualOrder{}, ErrAccrualServiceUnavailable
 		}
 
@@ -2,6 +2,11 @@ package accrualconnector
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -13,6 +18,11 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestNewAccrualConnector(t *testing.T) {
@@ -20,8 +30,11 @@ func TestNewAccrualConnector(t *testing.T) {
 		accrualAddr string
 		workers     uint
 		interval    time.Duration
+		batchSize   uint
+		lease       time.Duration
+		maxBackoff  time.Duration
+		maxAge      time.Duration
 		order       usecases.Order
-		balance     usecases.Balance
 		logger      *log.Logger
 	}
 
@@ -35,8 +48,11 @@ func TestNewAccrualConnector(t *testing.T) {
 				accrualAddr: "http://localhost:8080",
 				workers:     3,
 				interval:    time.Second,
+				batchSize:   100,
+				lease:       time.Minute,
+				maxBackoff:  time.Minute,
+				maxAge:      time.Hour,
 				order:       usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
-				balance:     usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
 				logger:      log.New(),
 			},
 		},
@@ -46,9 +62,11 @@ func TestNewAccrualConnector(t *testing.T) {
 				accrualAddr: "http://localhost:8080",
 				workers:     3,
 				interval:    time.Second,
-				order:       mocks.NewMockOrderRepo(gomock.NewController(t)),
-				balance:     mocks.NewMockBalanceRepo(gomock.NewController(t)),
-				logger:      log.New(),
+				batchSize:   100,
+				lease:       time.Minute,
+				maxBackoff:  time.Minute,
+				maxAge:      time.Hour,
+				order:       usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
 			},
 		},
 	}
@@ -56,7 +74,9 @@ func TestNewAccrualConnector(t *testing.T) {
 	for _, test := range tests {
 		con := NewAccrualConnector(
 			test.args.accrualAddr, test.args.workers, test.args.interval,
-			test.args.order, test.args.balance, test.args.logger,
+			test.args.batchSize, test.args.lease,
+			test.args.maxBackoff, test.args.maxAge,
+			test.args.order, nil, test.args.logger,
 		)
 
 		require.NotNil(t, con)
@@ -64,8 +84,12 @@ func TestNewAccrualConnector(t *testing.T) {
 		assert.Equal(t, test.args.accrualAddr, con.accrualAddr)
 		assert.Equal(t, test.args.workers, con.workers)
 		assert.Equal(t, test.args.interval, con.interval)
+		assert.Equal(t, test.args.batchSize, con.batchSize)
+		assert.Equal(t, test.args.lease, con.lease)
+		assert.Equal(t, test.args.maxBackoff, con.maxBackoff)
+		assert.Equal(t, test.args.maxAge, con.maxAge)
+		assert.NotEmpty(t, con.instance)
 		assert.Equal(t, test.args.order, con.order)
-		assert.Equal(t, test.args.balance, con.balance)
 
 		if test.args.logger != nil {
 			assert.Equal(t, test.args.logger, con.logger)
@@ -87,15 +111,9 @@ func TestRun(t *testing.T) {
 		UserID:     1,
 		Number:     "4561261212345467",
 		Status:     "PROCESSED",
-		Accrual:    500,
+		Accrual:    entities.NewMoney(500, 0),
 		UploadedAt: ts,
 	}
-	balance := entities.BalanceChange{
-		UserID:    1,
-		Operation: "refill",
-		Order:     "4561261212345467",
-		Sum:       500,
-	}
 
 	type args struct {
 		timeout  time.Duration
@@ -126,17 +144,14 @@ func TestRun(t *testing.T) {
 	for _, test := range tests {
 		ctr := gomock.NewController(t)
 		orderRepo := mocks.NewMockOrderRepo(ctr)
-		balanceRepo := mocks.NewMockBalanceRepo(ctr)
 
-		orderRepo.EXPECT().ProcessableOrders(gomock.Any()).AnyTimes().Return([]entities.Order{order}, nil)
-		orderRepo.EXPECT().UpdateOrder(gomock.Any(), mocks.OrderMatcher(&orderExpected)).AnyTimes().Return(nil)
-		balanceRepo.EXPECT().ChangeBalance(gomock.Any(), mocks.BalanceChangeMatcher(&balance)).AnyTimes().Return(nil)
+		orderRepo.EXPECT().ProcessableOrders(gomock.Any(), gomock.Any(), uint(10), time.Minute).AnyTimes().Return([]entities.Order{order}, nil)
+		orderRepo.EXPECT().ProcessOrder(gomock.Any(), gomock.Any(), mocks.OrderMatcher(&orderExpected)).AnyTimes().Return(nil)
 
 		con := NewAccrualConnector(
-			accrual.URL, 1, time.Second,
+			accrual.URL, 1, time.Second, 10, time.Minute, time.Minute, time.Hour,
 			usecases.NewOrderUseCase(orderRepo, time.Second),
-			usecases.NewBalanceUseCase(balanceRepo, time.Second),
-			nil,
+			nil, nil,
 		)
 
 		ctx, cancel := context.WithCancel(context.Background())
@@ -150,12 +165,77 @@ func TestRun(t *testing.T) {
 			con.Shutdown(context.Background())
 		} else {
 			<-ctx.Done()
+
+			assert.NoError(t, con.CheckPoll(test.args.timeout))
 		}
 
 		cancel()
 	}
 }
 
+func TestRunPartialFailure(t *testing.T) {
+	orders := []entities.Order{
+		{UserID: 1, Number: "4561261212345467", Status: "NEW", UploadedAt: time.Now()},
+		{UserID: 1, Number: "12345678903", Status: "NEW", UploadedAt: time.Now()},
+	}
+
+	tests := []struct {
+		name    string
+		failed  map[string]bool
+		wantErr bool
+	}{
+		{
+			name:   "Single request failed",
+			failed: map[string]bool{"12345678903": true},
+		},
+		{
+			name:    "All requests failed",
+			failed:  map[string]bool{"4561261212345467": true, "12345678903": true},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		failed := test.failed
+
+		accrual := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			number := strings.TrimPrefix(r.URL.Path, "/api/orders/")
+			if failed[number] {
+				w.WriteHeader(http.StatusInternalServerError)
+
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(fmt.Sprintf(`{"order":"%s","status":"PROCESSED","accrual":500}`, number)))
+		}))
+
+		orderRepo := mocks.NewMockOrderRepo(gomock.NewController(t))
+		orderRepo.EXPECT().ProcessableOrders(gomock.Any(), gomock.Any(), uint(10), time.Minute).
+			AnyTimes().Return(orders, nil)
+		orderRepo.EXPECT().ProcessOrder(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+		orderRepo.EXPECT().UpdateOrder(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+
+		con := NewAccrualConnector(
+			accrual.URL, 2, 100*time.Millisecond, 10, time.Minute, time.Minute, time.Hour,
+			usecases.NewOrderUseCase(orderRepo, time.Second),
+			nil, log.New(),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+
+		con.Run(ctx)
+		cancel()
+		accrual.Close()
+
+		if test.wantErr {
+			assert.ErrorIs(t, con.CheckPoll(time.Minute), ErrPollStale, test.name)
+		} else {
+			assert.NoError(t, con.CheckPoll(time.Minute), test.name)
+		}
+	}
+}
+
 func TestGenerateOrderTasks(t *testing.T) {
 	orders := []entities.Order{
 		{
@@ -195,37 +275,66 @@ func TestOrderTaskWorker(t *testing.T) {
 		UserID:     1,
 		Number:     "4561261212345467",
 		Status:     "PROCESSED",
-		Accrual:    500,
+		Accrual:    entities.NewMoney(500, 0),
 		UploadedAt: ts,
 	}
-	balance := entities.BalanceChange{
-		UserID:    1,
-		Operation: "refill",
-		Order:     "4561261212345467",
-		Sum:       500,
-	}
 
 	accrual := accmock.NewMockAccrual()
 	defer accrual.Close()
 
 	ctr := gomock.NewController(t)
 	orderRepo := mocks.NewMockOrderRepo(ctr)
-	balanceRepo := mocks.NewMockBalanceRepo(ctr)
 
-	orderRepo.EXPECT().UpdateOrder(gomock.Any(), mocks.OrderMatcher(&orderExpected)).Return(nil)
-	balanceRepo.EXPECT().ChangeBalance(gomock.Any(), mocks.BalanceChangeMatcher(&balance)).Return(nil)
+	orderRepo.EXPECT().ProcessOrder(gomock.Any(), "instance", mocks.OrderMatcher(&orderExpected)).Return(nil)
 
 	con := AccrualConnector{
 		accrualAddr: accrual.URL,
-		order:       orderRepo,
-		balance:     balanceRepo,
+		instance:    "instance",
+		order:       usecases.NewOrderUseCase(orderRepo, time.Second),
+		limiter:     newRateLimiter(),
 	}
 
 	ch := make(chan entities.Order, 1)
 	ch <- order
 	close(ch)
 
-	err := con.orderTaskWorker(context.Background(), ch)
+	err := con.orderTaskWorker(context.Background(), ch, time.Now().Add(time.Minute))
+
+	assert.NoError(t, err)
+}
+
+func TestOrderTaskWorkerLeaseLost(t *testing.T) {
+	orders := []entities.Order{
+		{UserID: 1, Number: "4561261212345467", Status: "NEW", UploadedAt: time.Now()},
+		{UserID: 1, Number: "12345678903", Status: "NEW", UploadedAt: time.Now()},
+	}
+
+	accrual := accmock.NewMockAccrual()
+	defer accrual.Close()
+
+	ctr := gomock.NewController(t)
+	orderRepo := mocks.NewMockOrderRepo(ctr)
+
+	gomock.InOrder(
+		orderRepo.EXPECT().ProcessOrder(gomock.Any(), "instance", gomock.Any()).Return(entities.ErrOrderLeaseLost),
+		orderRepo.EXPECT().ProcessOrder(gomock.Any(), "instance", gomock.Any()).Return(nil),
+	)
+
+	con := AccrualConnector{
+		accrualAddr: accrual.URL,
+		instance:    "instance",
+		order:       usecases.NewOrderUseCase(orderRepo, time.Second),
+		logger:      log.New(),
+		limiter:     newRateLimiter(),
+	}
+
+	ch := make(chan entities.Order, len(orders))
+	for _, order := range orders {
+		ch <- order
+	}
+	close(ch)
+
+	err := con.orderTaskWorker(context.Background(), ch, time.Now().Add(time.Minute))
 
 	assert.NoError(t, err)
 }
@@ -234,7 +343,7 @@ func TestDoRequest(t *testing.T) {
 	order := entities.AccrualOrder{
 		Order:   "4561261212345467",
 		Status:  "PROCESSED",
-		Accrual: 500,
+		Accrual: entities.NewMoney(500, 0),
 	}
 
 	accrual := accmock.NewMockAccrual()
@@ -246,3 +355,213 @@ func TestDoRequest(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, order, res)
 }
+
+func TestDoRequestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var traceparent string
+
+	accrual := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer accrual.Close()
+
+	con := AccrualConnector{accrualAddr: accrual.URL}
+	_, err := con.doRequest(context.Background(), accrual.Client(), "4561261212345467")
+
+	assert.ErrorIs(t, err, ErrAccrualOrderNotFound)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+
+	assert.Equal(t, "AccrualConnector.doRequest", spans[0].Name())
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	assert.Contains(t, traceparent, spans[0].SpanContext().TraceID().String())
+	assert.Contains(t, traceparent, spans[0].SpanContext().SpanID().String())
+}
+
+func TestRequestOrderTooManyRequests(t *testing.T) {
+	order := entities.AccrualOrder{
+		Order:   "4561261212345467",
+		Status:  "PROCESSED",
+		Accrual: entities.NewMoney(500, 0),
+	}
+
+	var requests int32
+
+	accrual := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte("No more than 60 requests per minute allowed"))
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(fmt.Sprintf(`{"order":"%s","status":"PROCESSED","accrual":500}`, order.Order)))
+	}))
+	defer accrual.Close()
+
+	con := AccrualConnector{
+		accrualAddr: accrual.URL,
+		logger:      log.New(),
+		limiter:     newRateLimiter(),
+	}
+
+	start := time.Now()
+	res, err := con.requestOrder(context.Background(), accrual.Client(), order.Order, time.Now().Add(time.Minute))
+
+	require.NoError(t, err)
+	assert.Equal(t, order, res)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Equal(t, time.Second, con.limiter.interval)
+}
+
+func TestRequestOrderLeaseExpired(t *testing.T) {
+	var requests int32
+
+	accrual := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer accrual.Close()
+
+	con := AccrualConnector{
+		accrualAddr: accrual.URL,
+		logger:      log.New(),
+		limiter:     newRateLimiter(),
+	}
+
+	start := time.Now()
+	_, err := con.requestOrder(context.Background(), accrual.Client(), "4561261212345467", time.Now().Add(time.Second))
+
+	assert.ErrorIs(t, err, ErrLeaseExpired)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestProcessOrderPostpone(t *testing.T) {
+	accrual := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer accrual.Close()
+
+	type args struct {
+		uploadedAt time.Time
+		attempts   uint
+	}
+
+	type wants struct {
+		status   string
+		attempts uint
+		delay    time.Duration
+	}
+
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name: "Order is not registered",
+			args: args{
+				uploadedAt: time.Now(),
+				attempts:   2,
+			},
+			wants: wants{
+				status:   entities.OrderStatusNew,
+				attempts: 3,
+				delay:    4 * time.Second,
+			},
+		},
+		{
+			name: "Order expired",
+			args: args{
+				uploadedAt: time.Now().Add(-2 * time.Hour),
+				attempts:   2,
+			},
+			wants: wants{
+				status:   entities.OrderStatusInvalid,
+				attempts: 3,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var updated entities.Order
+
+		orderRepo := mocks.NewMockOrderRepo(gomock.NewController(t))
+		orderRepo.EXPECT().UpdateOrder(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, order *entities.Order) error {
+				updated = *order
+
+				return nil
+			},
+		)
+
+		con := AccrualConnector{
+			accrualAddr: accrual.URL,
+			interval:    time.Second,
+			maxBackoff:  time.Minute,
+			maxAge:      time.Hour,
+			order:       usecases.NewOrderUseCase(orderRepo, time.Second),
+			logger:      log.New(),
+			limiter:     newRateLimiter(),
+		}
+
+		order := entities.Order{
+			UserID:     1,
+			Number:     "4561261212345467",
+			Status:     entities.OrderStatusNew,
+			UploadedAt: test.args.uploadedAt,
+			Attempts:   test.args.attempts,
+		}
+
+		err := con.processOrder(context.Background(), accrual.Client(), &order, time.Now().Add(time.Minute))
+		require.NoError(t, err, test.name)
+
+		assert.Equal(t, test.wants.status, updated.Status, test.name)
+		assert.Equal(t, test.wants.attempts, updated.Attempts, test.name)
+		assert.NotEmpty(t, updated.LastError, test.name)
+
+		if test.wants.delay != 0 {
+			assert.WithinDuration(t, time.Now().Add(test.wants.delay), updated.NextPollAt, time.Second, test.name)
+		} else {
+			assert.True(t, updated.NextPollAt.IsZero(), test.name)
+		}
+	}
+}
+
+func TestCheckPoll(t *testing.T) {
+	con := AccrualConnector{}
+
+	assert.ErrorIs(t, con.CheckPoll(time.Minute), ErrPollStale)
+
+	con.lastPoll.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	assert.ErrorIs(t, con.CheckPoll(time.Minute), ErrPollStale)
+
+	con.lastPoll.Store(time.Now().UnixNano())
+	assert.NoError(t, con.CheckPoll(time.Minute))
+}
+
+func TestBackoff(t *testing.T) {
+	con := AccrualConnector{
+		interval:   time.Second,
+		maxBackoff: 10 * time.Second,
+	}
+
+	assert.Equal(t, time.Second, con.backoff(1))
+	assert.Equal(t, 2*time.Second, con.backoff(2))
+	assert.Equal(t, 8*time.Second, con.backoff(4))
+	assert.Equal(t, 10*time.Second, con.backoff(5))
+	assert.Equal(t, 10*time.Second, con.backoff(100))
+}
@@ -0,0 +1,138 @@
+package accrualconnector
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRetryAfter = 60 * time.Second
+	// Минимальная пауза после 429 Too Many Requests: нулевое или прошедшее время в Retry-After
+	// не должно приводить к повтору запросов без паузы.
+	minRetryAfter = time.Second
+)
+
+var quotaRegexp = regexp.MustCompile(`(\d+)\s+requests\s+per\s+minute`)
+
+// Общее для всех воркеров AccrualConnector состояние ограничения частоты запросов.
+// После получения 429 Too Many Requests все воркеры приостанавливаются до истечения
+// Retry-After, а последующие запросы распределяются в соответствии с квотой,
+// объявленной сервисом Accrual.
+type rateLimiter struct {
+	mtx         sync.Mutex
+	pausedUntil time.Time
+	next        time.Time
+	interval    time.Duration
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{}
+}
+
+// Блокирует выполнение до момента, когда разрешено отправить очередной запрос.
+// Если deadline задан и запрос не может быть отправлен до его наступления,
+// сразу возвращает ErrLeaseExpired.
+func (l *rateLimiter) Wait(ctx context.Context, deadline time.Time) error {
+	for {
+		l.mtx.Lock()
+
+		now := time.Now()
+
+		at := now
+		if l.pausedUntil.After(at) {
+			at = l.pausedUntil
+		}
+
+		if l.next.After(at) {
+			at = l.next
+		}
+
+		if !deadline.IsZero() && at.After(deadline) {
+			l.mtx.Unlock()
+
+			return ErrLeaseExpired
+		}
+
+		delay := at.Sub(now)
+		if delay <= 0 {
+			l.next = now.Add(l.interval)
+			l.mtx.Unlock()
+
+			return nil
+		}
+
+		l.mtx.Unlock()
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Приостанавливает отправку запросов на время retryAfter.
+// Если limit больше нуля, запросы далее распределяются не чаще limit в минуту.
+func (l *rateLimiter) Pause(retryAfter time.Duration, limit uint64) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	until := time.Now().Add(retryAfter)
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+
+	if limit > 0 {
+		l.interval = time.Minute / time.Duration(limit)
+	}
+}
+
+// Возвращает время ожидания из заголовка Retry-After,
+// заданного в секундах либо в формате HTTP-даты, но не меньше minRetryAfter.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return defaultRetryAfter
+	}
+
+	if seconds, err := strconv.ParseUint(header, 10, 32); err == nil {
+		return clampRetryAfter(time.Duration(seconds) * time.Second)
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		return clampRetryAfter(time.Until(date))
+	}
+
+	return defaultRetryAfter
+}
+
+func clampRetryAfter(delay time.Duration) time.Duration {
+	if delay < minRetryAfter {
+		return minRetryAfter
+	}
+
+	return delay
+}
+
+// Возвращает квоту запросов в минуту из тела ответа вида
+// "No more than N requests per minute allowed" или 0, если квота не указана.
+func parseQuota(body []byte) uint64 {
+	match := quotaRegexp.FindSubmatch(body)
+	if match == nil {
+		return 0
+	}
+
+	limit, err := strconv.ParseUint(string(match[1]), 10, 32)
+	if err != nil {
+		return 0
+	}
+
+	return limit
+}
@@ -0,0 +1,115 @@
+package accrualconnector
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	limiter := newRateLimiter()
+
+	require.NoError(t, limiter.Wait(context.Background(), time.Time{}))
+
+	limiter.Pause(time.Second, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	assert.Error(t, limiter.Wait(ctx, time.Time{}))
+
+	start := time.Now()
+
+	require.NoError(t, limiter.Wait(context.Background(), time.Time{}))
+	assert.GreaterOrEqual(t, time.Since(start), 800*time.Millisecond)
+}
+
+func TestRateLimiterWaitDeadline(t *testing.T) {
+	limiter := newRateLimiter()
+
+	limiter.Pause(time.Minute, 0)
+
+	start := time.Now()
+
+	assert.ErrorIs(t, limiter.Wait(context.Background(), time.Now().Add(time.Second)), ErrLeaseExpired)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestRateLimiterPause(t *testing.T) {
+	limiter := newRateLimiter()
+
+	limiter.Pause(time.Minute, 120)
+
+	assert.Equal(t, 500*time.Millisecond, limiter.interval)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), limiter.pausedUntil, time.Second)
+
+	limiter.Pause(time.Second, 0)
+
+	assert.Equal(t, 500*time.Millisecond, limiter.interval)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), limiter.pausedUntil, time.Second)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected time.Duration
+	}{
+		{
+			name:     "Seconds",
+			header:   "60",
+			expected: time.Minute,
+		},
+		{
+			name:     "Empty header",
+			header:   "",
+			expected: defaultRetryAfter,
+		},
+		{
+			name:     "Invalid header",
+			header:   "soon",
+			expected: defaultRetryAfter,
+		},
+		{
+			name:     "Zero seconds",
+			header:   "0",
+			expected: minRetryAfter,
+		},
+		{
+			name:     "Date in the past",
+			header:   time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat),
+			expected: minRetryAfter,
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, parseRetryAfter(test.header), test.name)
+	}
+}
+
+func TestParseQuota(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected uint64
+	}{
+		{
+			name:     "Correct body",
+			body:     "No more than 42 requests per minute allowed",
+			expected: 42,
+		},
+		{
+			name:     "Empty body",
+			body:     "",
+			expected: 0,
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, parseQuota([]byte(test.body)), test.name)
+	}
+}
@@ -0,0 +1,64 @@
+package cleaner
+
+import (
+	"context"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	log "github.com/sirupsen/logrus"
+)
+
+// Периодически удаляет устаревшие данные сервиса, например истёкшие ключи идемпотентности.
+// Очистка выполняется в фоне, чтобы не нагружать ею обработку запросов пользователей.
+type Cleaner struct {
+	interval time.Duration
+	tasks    []usecases.Cleanup
+	logger   *log.Logger
+	close    chan struct{}
+}
+
+func NewCleaner(interval time.Duration, tasks []usecases.Cleanup, logger *log.Logger) *Cleaner {
+	cleanerLogger := log.StandardLogger()
+	if logger != nil {
+		cleanerLogger = logger
+	}
+
+	return &Cleaner{
+		interval: interval,
+		tasks:    tasks,
+		logger:   cleanerLogger,
+		close:    make(chan struct{}),
+	}
+}
+
+func (cleaner *Cleaner) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-cleaner.close:
+			return
+		case <-time.After(cleaner.interval):
+		}
+
+		cleaner.clean(ctx)
+	}
+}
+
+func (cleaner *Cleaner) Shutdown(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		return
+	case cleaner.close <- struct{}{}:
+		return
+	}
+}
+
+// Выполняет все задачи очистки. Ошибка одной задачи не мешает выполнению остальных.
+func (cleaner *Cleaner) clean(ctx context.Context) {
+	for _, task := range cleaner.tasks {
+		if err := task.Cleanup(ctx); err != nil && ctx.Err() == nil {
+			cleaner.logger.Errorf("Cleaner error: %s", err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+type cleanupFunc func(ctx context.Context) error
+
+func (f cleanupFunc) Cleanup(ctx context.Context) error {
+	return f(ctx)
+}
+
+func TestClean(t *testing.T) {
+	var calls int
+
+	tasks := []usecases.Cleanup{
+		cleanupFunc(func(context.Context) error {
+			calls++
+
+			return errors.New("cleanup failed")
+		}),
+		cleanupFunc(func(context.Context) error {
+			calls++
+
+			return nil
+		}),
+	}
+
+	logger, hook := logtest.NewNullLogger()
+
+	NewCleaner(time.Minute, tasks, logger).clean(context.Background())
+
+	assert.Equal(t, 2, calls)
+	assert.Len(t, hook.AllEntries(), 1)
+}
+
+func TestRun(t *testing.T) {
+	done := make(chan struct{})
+
+	tasks := []usecases.Cleanup{
+		cleanupFunc(func(context.Context) error {
+			select {
+			case done <- struct{}{}:
+			default:
+			}
+
+			return nil
+		}),
+	}
+
+	cleaner := NewCleaner(time.Millisecond, tasks, nil)
+
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		cleaner.Run(context.Background())
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cleanup task was not run")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	cleaner.Shutdown(ctx)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("cleaner was not stopped")
+	}
+}
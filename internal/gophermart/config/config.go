@@ -1,39 +1,125 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
+var ErrInvalidBackoff = errors.New("maximum backoff must not be less than the interval")
+
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
 const (
 	address        = ":8081"
+	metricsAddress = ":9090"
 	dsn            = ""
 	accrualAddress = ""
+	storage        = StoragePostgres
+	logLevel       = "info"
+	logFormat      = "text"
 
-	secretKey         = ""
-	userTokenTTL      = 30 * time.Minute
-	shutdownTimeout   = 10 * time.Second
-	repositoryTimeout = 3 * time.Second
-	migrations        = "sql/migrations"
-	accrualWorkers    = 3
-	accrualInterval   = 3 * time.Second
-	accrualShutdown   = 3 * time.Second
+	secretKey          = ""
+	userTokenTTL       = 30 * time.Minute
+	refreshTokenTTL    = 30 * 24 * time.Hour
+	tokenSource        = "header"
+	jwtKeys            = ""
+	jwtKeyGrace        = 30 * time.Minute
+	passwordHasher     = "argon2id"
+	bcryptCost         = 12
+	argon2Memory       = 64 * 1024
+	argon2Iterations   = 3
+	argon2Parallelism  = 4
+	passwordResetTTL   = time.Hour
+	loginMaxAttempts   = 5
+	loginMaxAttemptsIP = 20
+	loginDelay         = time.Second
+	loginLockout       = 15 * time.Minute
+	idempotencyKeyTTL  = 24 * time.Hour
+	idempotencyLease   = time.Minute
+	shutdownTimeout    = 10 * time.Second
+	drainTimeout       = 5 * time.Second
+	readinessMaxAge    = time.Minute
+	repositoryTimeout  = 3 * time.Second
+	cleanupInterval    = 10 * time.Minute
+	migrations         = "sql/migrations"
+	accrualWorkers     = 3
+	accrualInterval    = 3 * time.Second
+	accrualShutdown    = 3 * time.Second
+	accrualBatchSize   = 100
+	accrualLease       = time.Minute
+	accrualBackoff     = 10 * time.Minute
+	accrualMaxAge      = 7 * 24 * time.Hour
+	webhookWorkers     = 3
+	webhookInterval    = time.Second
+	webhookShutdown    = 3 * time.Second
+	webhookBatchSize   = 100
+	webhookLease       = time.Minute
+	webhookTimeout     = 5 * time.Second
+	webhookAttempts    = 10
+	webhookBackoff     = time.Hour
+	tracingEndpoint    = ""
+	tracingOutput      = ""
+	tracingSampleRatio = 1.0
 )
 
 type Config struct {
 	Address        string // Адрес эндпоинта сервера (host:port)
+	MetricsAddress string // Адрес эндпоинта метрик (host:port), пустое значение флага отключает эндпоинт
 	DSN            string // Адрес подключения к БД
 	AccrualAddress string // Адрес системы расчёта начислений
+	Storage        string // Тип хранилища данных (postgres, memory)
+	LogLevel       string // Уровень журналирования
+	LogFormat      string // Формат вывода журнала (text, json)
 
 	SecretKey          string        // Ключ шифрования токена авторизации
 	UserTokenTTL       time.Duration // Время жизни токена пользователя
+	RefreshTokenTTL    time.Duration // Время жизни токена обновления
+	TokenSource        string        // Источник токена доступа, проверяемый первым (header, cookie)
+	JWTKeys            []string      // PEM-файлы ключей подписи токенов вида path[@RFC3339]
+	JWTKeyGrace        time.Duration // Время, в течение которого заменённый ключ подписи принимается при проверке
+	PasswordHasher     string        // Алгоритм хеширования паролей (argon2id, bcrypt)
+	BcryptCost         int           // Стоимость хеширования bcrypt
+	Argon2Memory       uint          // Объём памяти argon2id в КиБ
+	Argon2Iterations   uint          // Количество проходов argon2id
+	Argon2Parallelism  uint          // Количество потоков argon2id
+	PasswordResetTTL   time.Duration // Время жизни токена сброса пароля
+	LoginMaxAttempts   int           // Порог неудачных попыток входа по логину
+	LoginMaxAttemptsIP int           // Порог неудачных попыток входа с одного IP-адреса
+	LoginDelay         time.Duration // Задержка после первой неудачной попытки входа
+	LoginLockout       time.Duration // Время блокировки входа после достижения порога неудачных попыток
+	IdempotencyKeyTTL  time.Duration // Время хранения ключей идемпотентности и ответов на запросы с ними
+	IdempotencyLease   time.Duration // Время, на которое ключ идемпотентности захватывается для обработки запроса
 	ShutdownTimeout    time.Duration // Таймаут для graceful shutdown сервера
+	DrainTimeout       time.Duration // Время между снятием готовности и остановкой сервера
+	ReadinessMaxAge    time.Duration // Максимальное время с последнего успешного опроса Accrual для готовности
 	RepositioryTimeout time.Duration // Таймаут соединения с хранилищем
+	CleanupInterval    time.Duration // Интервал удаления устаревших данных
 	Migrations         string        // Путь до директории с файлами миграции
 	AccrualWorkers     uint          // Количество одновременно исходящих запросов к сервису Accrual
 	AccrualInterval    time.Duration // Интервал генерации новой партии запросов к сервису Accrual
 	AccrualShutdown    time.Duration // Таймаут для завершения соединения с Accrual
+	AccrualBatchSize   uint          // Максимальное количество заказов в партии запросов к сервису Accrual
+	AccrualLease       time.Duration // Время, на которое экземпляр сервиса захватывает партию заказов
+	AccrualBackoff     time.Duration // Максимальная задержка перед повторным опросом заказа
+	AccrualMaxAge      time.Duration // Максимальное время обработки заказа сервисом Accrual
+	WebhookWorkers     uint          // Количество одновременно отправляемых уведомлений подписчикам
+	WebhookInterval    time.Duration // Интервал выборки новой партии уведомлений из очереди доставки
+	WebhookShutdown    time.Duration // Таймаут для завершения отправки уведомлений
+	WebhookBatchSize   uint          // Максимальное количество уведомлений в партии
+	WebhookLease       time.Duration // Время, на которое экземпляр сервиса захватывает партию уведомлений
+	WebhookTimeout     time.Duration // Таймаут запроса доставки уведомления
+	WebhookAttempts    uint          // Максимальное количество попыток доставки уведомления
+	WebhookBackoff     time.Duration // Максимальная задержка перед повторной попыткой доставки уведомления
+	TracingEndpoint    string        // Адрес коллектора OpenTelemetry (OTLP/HTTP)
+	TracingOutput      string        // Файл вывода спанов при отсутствии коллектора (stdout - стандартный вывод)
+	TracingSampleRatio float64       // Доля трассируемых запросов
 }
 
 func NewConfig() *Config {
@@ -42,40 +128,184 @@ func NewConfig() *Config {
 	vpr.AllowEmptyEnv(false)
 
 	vpr.BindEnv("run_address")
+	vpr.BindEnv("metrics_address")
 	vpr.BindEnv("database_uri")
 	vpr.BindEnv("accrual_system_address")
+	vpr.BindEnv("storage_type")
+	vpr.BindEnv("log_level")
+	vpr.BindEnv("log_format")
 	vpr.BindEnv("jwt_secret")
 	vpr.BindEnv("jwt_ttl")
+	vpr.BindEnv("jwt_refresh_ttl")
+	vpr.BindEnv("jwt_source")
+	vpr.BindEnv("jwt_keys")
+	vpr.BindEnv("jwt_key_grace")
+	vpr.BindEnv("password_hasher")
+	vpr.BindEnv("password_bcrypt_cost")
+	vpr.BindEnv("password_argon2_memory")
+	vpr.BindEnv("password_argon2_iterations")
+	vpr.BindEnv("password_argon2_parallelism")
+	vpr.BindEnv("password_reset_ttl")
+	vpr.BindEnv("login_max_attempts")
+	vpr.BindEnv("login_max_attempts_ip")
+	vpr.BindEnv("login_delay")
+	vpr.BindEnv("login_lockout")
+	vpr.BindEnv("idempotency_key_ttl")
+	vpr.BindEnv("idempotency_key_lease")
 	vpr.BindEnv("server_shutdown")
+	vpr.BindEnv("server_drain")
+	vpr.BindEnv("readiness_accrual_max_age")
 	vpr.BindEnv("repository_timeout")
+	vpr.BindEnv("cleanup_interval")
 	vpr.BindEnv("database_migrations")
 	vpr.BindEnv("accrual_connector_workers")
 	vpr.BindEnv("accrual_connector_interval")
 	vpr.BindEnv("accrual_connector_shutdown")
+	vpr.BindEnv("accrual_connector_batch")
+	vpr.BindEnv("accrual_connector_lease")
+	vpr.BindEnv("accrual_connector_backoff")
+	vpr.BindEnv("accrual_connector_max_age")
+	vpr.BindEnv("webhook_sender_workers")
+	vpr.BindEnv("webhook_sender_interval")
+	vpr.BindEnv("webhook_sender_shutdown")
+	vpr.BindEnv("webhook_sender_batch")
+	vpr.BindEnv("webhook_sender_lease")
+	vpr.BindEnv("webhook_sender_timeout")
+	vpr.BindEnv("webhook_sender_max_attempts")
+	vpr.BindEnv("webhook_sender_backoff")
+	vpr.BindEnv("tracing_otlp_endpoint")
+	vpr.BindEnv("tracing_output")
+	vpr.BindEnv("tracing_sample_ratio")
 
 	vpr.SetDefault("run_address", address)
+	vpr.SetDefault("metrics_address", metricsAddress)
 	vpr.SetDefault("database_uri", dsn)
 	vpr.SetDefault("accrual_system_address", accrualAddress)
+	vpr.SetDefault("storage_type", storage)
+	vpr.SetDefault("log_level", logLevel)
+	vpr.SetDefault("log_format", logFormat)
 	vpr.SetDefault("jwt_secret", secretKey)
 	vpr.SetDefault("jwt_ttl", userTokenTTL)
+	vpr.SetDefault("jwt_refresh_ttl", refreshTokenTTL)
+	vpr.SetDefault("jwt_source", tokenSource)
+	vpr.SetDefault("jwt_keys", jwtKeys)
+	vpr.SetDefault("jwt_key_grace", jwtKeyGrace)
+	vpr.SetDefault("password_hasher", passwordHasher)
+	vpr.SetDefault("password_bcrypt_cost", bcryptCost)
+	vpr.SetDefault("password_argon2_memory", argon2Memory)
+	vpr.SetDefault("password_argon2_iterations", argon2Iterations)
+	vpr.SetDefault("password_argon2_parallelism", argon2Parallelism)
+	vpr.SetDefault("password_reset_ttl", passwordResetTTL)
+	vpr.SetDefault("login_max_attempts", loginMaxAttempts)
+	vpr.SetDefault("login_max_attempts_ip", loginMaxAttemptsIP)
+	vpr.SetDefault("login_delay", loginDelay)
+	vpr.SetDefault("login_lockout", loginLockout)
+	vpr.SetDefault("idempotency_key_ttl", idempotencyKeyTTL)
+	vpr.SetDefault("idempotency_key_lease", idempotencyLease)
 	vpr.SetDefault("server_shutdown", shutdownTimeout)
+	vpr.SetDefault("server_drain", drainTimeout)
+	vpr.SetDefault("readiness_accrual_max_age", readinessMaxAge)
 	vpr.SetDefault("repository_timeout", repositoryTimeout)
+	vpr.SetDefault("cleanup_interval", cleanupInterval)
 	vpr.SetDefault("database_migrations", migrations)
 	vpr.SetDefault("accrual_connector_workers", accrualWorkers)
 	vpr.SetDefault("accrual_connector_interval", accrualInterval)
 	vpr.SetDefault("accrual_connector_shutdown", accrualShutdown)
+	vpr.SetDefault("accrual_connector_batch", accrualBatchSize)
+	vpr.SetDefault("accrual_connector_lease", accrualLease)
+	vpr.SetDefault("accrual_connector_backoff", accrualBackoff)
+	vpr.SetDefault("accrual_connector_max_age", accrualMaxAge)
+	vpr.SetDefault("webhook_sender_workers", webhookWorkers)
+	vpr.SetDefault("webhook_sender_interval", webhookInterval)
+	vpr.SetDefault("webhook_sender_shutdown", webhookShutdown)
+	vpr.SetDefault("webhook_sender_batch", webhookBatchSize)
+	vpr.SetDefault("webhook_sender_lease", webhookLease)
+	vpr.SetDefault("webhook_sender_timeout", webhookTimeout)
+	vpr.SetDefault("webhook_sender_max_attempts", webhookAttempts)
+	vpr.SetDefault("webhook_sender_backoff", webhookBackoff)
+	vpr.SetDefault("tracing_otlp_endpoint", tracingEndpoint)
+	vpr.SetDefault("tracing_output", tracingOutput)
+	vpr.SetDefault("tracing_sample_ratio", tracingSampleRatio)
 
 	return &Config{
 		Address:            vpr.GetString("run_address"),
+		MetricsAddress:     vpr.GetString("metrics_address"),
 		DSN:                vpr.GetString("database_uri"),
 		AccrualAddress:     vpr.GetString("accrual_system_address"),
+		Storage:            vpr.GetString("storage_type"),
+		LogLevel:           vpr.GetString("log_level"),
+		LogFormat:          vpr.GetString("log_format"),
 		SecretKey:          vpr.GetString("jwt_secret"),
 		UserTokenTTL:       vpr.GetDuration("jwt_ttl"),
+		RefreshTokenTTL:    vpr.GetDuration("jwt_refresh_ttl"),
+		TokenSource:        vpr.GetString("jwt_source"),
+		JWTKeys:            splitList(vpr.GetString("jwt_keys")),
+		JWTKeyGrace:        vpr.GetDuration("jwt_key_grace"),
+		PasswordHasher:     vpr.GetString("password_hasher"),
+		BcryptCost:         vpr.GetInt("password_bcrypt_cost"),
+		Argon2Memory:       vpr.GetUint("password_argon2_memory"),
+		Argon2Iterations:   vpr.GetUint("password_argon2_iterations"),
+		Argon2Parallelism:  vpr.GetUint("password_argon2_parallelism"),
+		PasswordResetTTL:   vpr.GetDuration("password_reset_ttl"),
+		LoginMaxAttempts:   vpr.GetInt("login_max_attempts"),
+		LoginMaxAttemptsIP: vpr.GetInt("login_max_attempts_ip"),
+		LoginDelay:         vpr.GetDuration("login_delay"),
+		LoginLockout:       vpr.GetDuration("login_lockout"),
+		IdempotencyKeyTTL:  vpr.GetDuration("idempotency_key_ttl"),
+		IdempotencyLease:   vpr.GetDuration("idempotency_key_lease"),
 		ShutdownTimeout:    vpr.GetDuration("server_shutdown"),
+		DrainTimeout:       vpr.GetDuration("server_drain"),
+		ReadinessMaxAge:    vpr.GetDuration("readiness_accrual_max_age"),
 		RepositioryTimeout: vpr.GetDuration("repository_timeout"),
+		CleanupInterval:    vpr.GetDuration("cleanup_interval"),
 		Migrations:         vpr.GetString("database_migrations"),
 		AccrualWorkers:     vpr.GetUint("accrual_connector_workers"),
 		AccrualInterval:    vpr.GetDuration("accrual_connector_interval"),
 		AccrualShutdown:    vpr.GetDuration("accrual_connector_shutdown"),
+		AccrualBatchSize:   vpr.GetUint("accrual_connector_batch"),
+		AccrualLease:       vpr.GetDuration("accrual_connector_lease"),
+		AccrualBackoff:     vpr.GetDuration("accrual_connector_backoff"),
+		AccrualMaxAge:      vpr.GetDuration("accrual_connector_max_age"),
+		WebhookWorkers:     vpr.GetUint("webhook_sender_workers"),
+		WebhookInterval:    vpr.GetDuration("webhook_sender_interval"),
+		WebhookShutdown:    vpr.GetDuration("webhook_sender_shutdown"),
+		WebhookBatchSize:   vpr.GetUint("webhook_sender_batch"),
+		WebhookLease:       vpr.GetDuration("webhook_sender_lease"),
+		WebhookTimeout:     vpr.GetDuration("webhook_sender_timeout"),
+		WebhookAttempts:    vpr.GetUint("webhook_sender_max_attempts"),
+		WebhookBackoff:     vpr.GetDuration("webhook_sender_backoff"),
+		TracingEndpoint:    vpr.GetString("tracing_otlp_endpoint"),
+		TracingOutput:      vpr.GetString("tracing_output"),
+		TracingSampleRatio: vpr.GetFloat64("tracing_sample_ratio"),
 	}
 }
+
+// Проверяет согласованность параметров конфигурации.
+func (cfg *Config) Validate() error {
+	// Иначе задержка перед повторной попыткой оказывается меньше интервала опроса
+	// и неудачные попытки повторяются на каждом интервале без нарастающей задержки
+	if cfg.AccrualBackoff < cfg.AccrualInterval {
+		return fmt.Errorf("%w: accrual connector backoff %s, interval %s",
+			ErrInvalidBackoff, cfg.AccrualBackoff, cfg.AccrualInterval)
+	}
+
+	if cfg.WebhookBackoff < cfg.WebhookInterval {
+		return fmt.Errorf("%w: webhook sender backoff %s, interval %s",
+			ErrInvalidBackoff, cfg.WebhookBackoff, cfg.WebhookInterval)
+	}
+
+	return nil
+}
+
+// Разбирает список значений, разделённых запятыми, пропуская пустые значения.
+func splitList(value string) []string {
+	var list []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
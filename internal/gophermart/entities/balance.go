@@ -2,38 +2,102 @@ package entities
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/KryukovO/gophermart/internal/utils"
 )
 
-var ErrNotEnoughFunds = errors.New("not enough funds")
+var (
+	ErrNotEnoughFunds       = errors.New("not enough funds")
+	ErrOrderAlreadyCredited = errors.New("accrual for the order has already been credited")
+	ErrInvalidAdjustment    = errors.New("invalid balance adjustment")
+
+	ErrWithdrawalNotFound        = errors.New("withdrawal not found")
+	ErrWithdrawalAlreadyReversed = errors.New("withdrawal has already been reversed")
+)
 
 const (
 	BalanceOperationRefill     string = "refill"
 	BalanceOperationWithdrawal string = "withdrawal"
+	BalanceOperationAdjustment string = "adjustment"
+	BalanceOperationReversal   string = "reversal"
 )
 
 // @Description User's loyalty points account balance.
 type Balance struct {
-	UserID    int64   `json:"-"         swaggerignore:"true"`
-	Current   float64 `json:"current"   swaggerignore:"false"`
-	Withdrawn float64 `json:"withdrawn" swaggerignore:"false"`
+	UserID    int64 `json:"-"         swaggerignore:"true"`
+	Current   Money `json:"current"   swaggerignore:"false" swaggertype:"number"`
+	Withdrawn Money `json:"withdrawn" swaggerignore:"false" swaggertype:"number"`
 } // @name Balance
 
 // @Description Change of the user's loyalty points account balance.
 type BalanceChange struct {
+	ID          int64     `json:"-"                      swaggerignore:"true"`
 	UserID      int64     `json:"-"                      swaggerignore:"true"`
 	Operation   string    `json:"-"                      swaggerignore:"true"`
 	Order       string    `json:"order"                  swaggerignore:"false"`
-	Sum         float64   `json:"sum"                    swaggerignore:"false"`
+	Sum         Money     `json:"sum"                    swaggerignore:"false" swaggertype:"number"`
 	ProcessedAt time.Time `json:"processed_at,omitempty" swaggerignore:"false"`
+	// Корректировка баланса выполняется администратором AdminID по причине Reason
+	// на основании Reference (например, номера обращения в поддержку).
+	// Сумма корректировки положительна при начислении и отрицательна при списании.
+	AdminID   int64  `json:"-" swaggerignore:"true"`
+	Reason    string `json:"-" swaggerignore:"true"`
+	Reference string `json:"-" swaggerignore:"true"`
+	// Отмена списания (BalanceOperationReversal) ссылается на отменённое списание ReversalOf,
+	// а у отменённого списания заполняется время отмены ReversedAt.
+	ReversalOf int64      `json:"-"                     swaggerignore:"true"`
+	ReversedAt *time.Time `json:"reversed_at,omitempty" swaggerignore:"false"`
 } // @name BalanceChange
 
+// @Description Manual adjustment of the user's loyalty points account balance.
+type BalanceAdjustment struct {
+	Sum       Money  `json:"sum"       swaggerignore:"false" swaggertype:"number" example:"-150.5"`
+	Reason    string `json:"reason"    swaggerignore:"false" example:"Fraudulent accrual"`
+	Reference string `json:"reference" swaggerignore:"false" example:"SUP-1234"`
+} // @name BalanceAdjustment
+
+// @Description Entry of the user's loyalty points account statement.
+type StatementEntry struct {
+	ID          int64     `json:"-"                   swaggerignore:"true"`
+	Operation   string    `json:"operation"           enums:"refill,withdrawal,adjustment,reversal"`
+	Order       string    `json:"order"               swaggerignore:"false"`
+	Sum         Money     `json:"sum"                 swaggerignore:"false" swaggertype:"number"`
+	Balance     Money     `json:"balance"             swaggerignore:"false" swaggertype:"number"`
+	ProcessedAt time.Time `json:"processed_at"        swaggerignore:"false"`
+	Reason      string    `json:"reason,omitempty"    swaggerignore:"false"`
+	Reference   string    `json:"reference,omitempty" swaggerignore:"false"`
+} // @name StatementEntry
+
 func (operation *BalanceChange) Validate() error {
+	if operation.Operation == BalanceOperationAdjustment {
+		return operation.validateAdjustment()
+	}
+
 	if ok := utils.LuhnCheck(operation.Order); !ok {
 		return ErrInvalidOrderNumber
 	}
 
 	return nil
 }
+
+// Проверяет, что для корректировки баланса указаны ненулевая сумма, администратор, причина и основание.
+func (operation *BalanceChange) validateAdjustment() error {
+	operation.Reason = strings.TrimSpace(operation.Reason)
+	operation.Reference = strings.TrimSpace(operation.Reference)
+
+	switch {
+	case operation.Sum == 0:
+		return fmt.Errorf("%w: non-zero sum is required", ErrInvalidAdjustment)
+	case operation.AdminID == 0:
+		return fmt.Errorf("%w: administrator is required", ErrInvalidAdjustment)
+	case operation.Reason == "":
+		return fmt.Errorf("%w: reason is required", ErrInvalidAdjustment)
+	case operation.Reference == "":
+		return fmt.Errorf("%w: reference is required", ErrInvalidAdjustment)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package entities
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key has already been used for a different request")
+	ErrIdempotencyKeyInUse   = errors.New("request with the idempotency key is still being processed")
+)
+
+// Максимальная длина ключа идемпотентности.
+const maxIdempotencyKeyLength = 255
+
+// Ключ идемпотентности запроса пользователя UserID и сохранённый ответ на запрос.
+// Запрос, для которого выдан ключ, определяется отпечатком RequestHash метода, маршрута и тела запроса.
+// Пока запрос обрабатывается, статус ответа Status равен нулю, а ключ захвачен до LockedUntil:
+// после этого ключ незавершённого запроса может захватить повторный запрос с тем же отпечатком.
+type IdempotencyKey struct {
+	Key         string
+	UserID      int64
+	RequestHash string
+	Status      int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	LockedUntil time.Time
+}
+
+// Проверяет, что ключ не пустой, не длиннее maxIdempotencyKeyLength
+// и состоит из печатных символов ASCII.
+func (key *IdempotencyKey) Validate() error {
+	if key.Key == "" || len(key.Key) > maxIdempotencyKeyLength {
+		return ErrInvalidIdempotencyKey
+	}
+
+	for _, r := range key.Key {
+		if r < ' ' || r > '~' {
+			return ErrInvalidIdempotencyKey
+		}
+	}
+
+	return nil
+}
+
+// Возвращает true, если ответ на запрос с ключом сохранён.
+func (key *IdempotencyKey) Completed() bool {
+	return key.Status != 0
+}
+
+// Возвращает true, если ключ незавершённого запроса может захватить запрос с отпечатком requestHash:
+// захват ключа истёк к моменту now, например из-за аварийной остановки экземпляра сервиса.
+func (key *IdempotencyKey) Reclaimable(requestHash string, now time.Time) bool {
+	return !key.Completed() && key.RequestHash == requestHash && key.LockedUntil.Before(now)
+}
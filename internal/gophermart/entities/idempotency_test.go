@@ -0,0 +1,45 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKeyReclaimable(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		key         IdempotencyKey
+		requestHash string
+		expected    bool
+	}{
+		{
+			name:        "Expired lease",
+			key:         IdempotencyKey{RequestHash: "hash", LockedUntil: now.Add(-time.Second)},
+			requestHash: "hash",
+			expected:    true,
+		},
+		{
+			name:        "Active lease",
+			key:         IdempotencyKey{RequestHash: "hash", LockedUntil: now.Add(time.Second)},
+			requestHash: "hash",
+		},
+		{
+			name:        "Different request",
+			key:         IdempotencyKey{RequestHash: "hash", LockedUntil: now.Add(-time.Second)},
+			requestHash: "other",
+		},
+		{
+			name:        "Completed request",
+			key:         IdempotencyKey{RequestHash: "hash", Status: 200, LockedUntil: now.Add(-time.Second)},
+			requestHash: "hash",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.key.Reclaimable(test.requestHash, now), test.name)
+	}
+}
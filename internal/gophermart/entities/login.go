@@ -0,0 +1,115 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrLoginLocked = errors.New("too many failed login attempts")
+
+// Ошибка временной блокировки входа после неудачных попыток.
+type LoginLockedError struct {
+	RetryAfter time.Duration // Время до снятия блокировки
+}
+
+func (err *LoginLockedError) Error() string {
+	return fmt.Sprintf("%s: retry after %s", ErrLoginLocked, err.RetryAfter)
+}
+
+func (err *LoginLockedError) Unwrap() error {
+	return ErrLoginLocked
+}
+
+// Правила ограничения неудачных попыток входа.
+// Неудачные попытки учитываются отдельно по логину и по IP-адресу клиента.
+// После каждой неудачной попытки вход блокируется на Delay, удваиваемую с каждой следующей попыткой,
+// а после достижения порога попыток - на Lockout.
+// Счётчик неудачных попыток сбрасывается, если в течение Lockout новых неудачных попыток не было.
+type LoginPolicy struct {
+	MaxAttempts      int           // Порог неудачных попыток по логину; 0 - без блокировки
+	MaxAttemptsPerIP int           // Порог неудачных попыток с одного IP-адреса; 0 - без блокировки
+	Delay            time.Duration // Задержка после первой неудачной попытки
+	Lockout          time.Duration // Время блокировки после достижения порога
+}
+
+// Возвращает время, на которое блокируется вход после failures неудачных попыток
+// при пороге maxAttempts.
+func (policy LoginPolicy) LockDuration(failures, maxAttempts int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+
+	if maxAttempts > 0 && failures >= maxAttempts {
+		return policy.Lockout
+	}
+
+	if policy.Delay <= 0 {
+		return 0
+	}
+
+	delay := policy.Delay
+	for i := 1; i < failures && delay < policy.Lockout; i++ {
+		delay *= 2
+	}
+
+	if policy.Lockout > 0 && delay > policy.Lockout {
+		return policy.Lockout
+	}
+
+	return delay
+}
+
+// Попытка входа, учитываемая по ключу Key с порогом неудачных попыток MaxAttempts.
+// Попытка резервируется до проверки пароля и заранее считается неудачной,
+// чтобы параллельные попытки не обходили задержку и блокировку.
+// LockedUntil - время снятия блокировки, установленной при резервировании попытки.
+type LoginAttempt struct {
+	Key         string
+	MaxAttempts int
+	LockedUntil time.Time
+}
+
+// Неудачные попытки входа по ключу: количество неудачных попыток подряд,
+// время последней из них и время снятия блокировки входа.
+type LoginFailures struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// Резервирует попытку входа с порогом maxAttempts в момент now: учитывает её как неудачную
+// и блокирует вход на время, соответствующее количеству неудачных попыток.
+// Счётчик начинается заново, если предыдущая неудачная попытка была раньше, чем Lockout назад.
+// Возвращает LoginLockedError, если вход заблокирован.
+func (policy LoginPolicy) Reserve(failures *LoginFailures, maxAttempts int, now time.Time) error {
+	if failures.LockedUntil.After(now) {
+		return &LoginLockedError{RetryAfter: failures.LockedUntil.Sub(now)}
+	}
+
+	if failures.LastFailure.Before(now.Add(-policy.Lockout)) {
+		failures.Failures = 0
+	}
+
+	failures.Failures++
+	failures.LastFailure = now
+	failures.LockedUntil = time.Time{}
+
+	if lock := policy.LockDuration(failures.Failures, maxAttempts); lock > 0 {
+		failures.LockedUntil = now.Add(lock)
+	}
+
+	return nil
+}
+
+// Отменяет зарезервированную попытку входа, которая заблокировала вход до lockedUntil.
+// Блокировка снимается, только если после резервирования её не изменили другие попытки.
+func (failures *LoginFailures) Release(lockedUntil time.Time) {
+	if failures.Failures > 0 {
+		failures.Failures--
+	}
+
+	if !lockedUntil.IsZero() && failures.LockedUntil.Equal(lockedUntil) {
+		failures.LockedUntil = time.Time{}
+	}
+}
@@ -0,0 +1,106 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockDuration(t *testing.T) {
+	policy := LoginPolicy{
+		Delay:   time.Second,
+		Lockout: 10 * time.Second,
+	}
+
+	tests := []struct {
+		name        string
+		failures    int
+		maxAttempts int
+		want        time.Duration
+	}{
+		{name: "No failures", failures: 0, maxAttempts: 5, want: 0},
+		{name: "First failure", failures: 1, maxAttempts: 5, want: time.Second},
+		{name: "Second failure", failures: 2, maxAttempts: 5, want: 2 * time.Second},
+		{name: "Fourth failure", failures: 4, maxAttempts: 5, want: 8 * time.Second},
+		{name: "Threshold reached", failures: 5, maxAttempts: 5, want: 10 * time.Second},
+		{name: "Delay capped by lockout", failures: 10, maxAttempts: 0, want: 10 * time.Second},
+		{name: "Many failures", failures: 100, maxAttempts: 0, want: 10 * time.Second},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, policy.LockDuration(test.failures, test.maxAttempts), test.name)
+	}
+
+	assert.Equal(t, time.Duration(0), LoginPolicy{Lockout: time.Minute}.LockDuration(1, 5))
+	assert.Equal(t, time.Minute, LoginPolicy{Lockout: time.Minute}.LockDuration(5, 5))
+}
+
+func TestLoginPolicyReserve(t *testing.T) {
+	policy := LoginPolicy{
+		MaxAttempts: 3,
+		Delay:       time.Second,
+		Lockout:     time.Minute,
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		failures LoginFailures
+		wants    LoginFailures
+		wantErr  error
+	}{
+		{
+			name:  "First attempt",
+			wants: LoginFailures{Failures: 1, LastFailure: now, LockedUntil: now.Add(time.Second)},
+		},
+		{
+			name:     "Progressive delay",
+			failures: LoginFailures{Failures: 1, LastFailure: now.Add(-2 * time.Second)},
+			wants:    LoginFailures{Failures: 2, LastFailure: now, LockedUntil: now.Add(2 * time.Second)},
+		},
+		{
+			name:     "Threshold reached",
+			failures: LoginFailures{Failures: 2, LastFailure: now.Add(-5 * time.Second)},
+			wants:    LoginFailures{Failures: 3, LastFailure: now, LockedUntil: now.Add(time.Minute)},
+		},
+		{
+			name:     "Counter restarts after the lockout window",
+			failures: LoginFailures{Failures: 2, LastFailure: now.Add(-2 * time.Minute)},
+			wants:    LoginFailures{Failures: 1, LastFailure: now, LockedUntil: now.Add(time.Second)},
+		},
+		{
+			name:     "Locked",
+			failures: LoginFailures{Failures: 1, LastFailure: now, LockedUntil: now.Add(time.Second)},
+			wantErr:  ErrLoginLocked,
+		},
+	}
+
+	for _, test := range tests {
+		failures := test.failures
+
+		err := policy.Reserve(&failures, policy.MaxAttempts, now)
+		if test.wantErr != nil {
+			assert.ErrorIs(t, err, test.wantErr, test.name)
+			assert.Equal(t, test.failures, failures, test.name)
+
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.wants, failures, test.name)
+	}
+}
+
+func TestLoginFailuresRelease(t *testing.T) {
+	now := time.Now()
+
+	failures := LoginFailures{Failures: 2, LastFailure: now, LockedUntil: now.Add(time.Second)}
+	failures.Release(now.Add(time.Second))
+	assert.Equal(t, LoginFailures{Failures: 1, LastFailure: now}, failures)
+
+	// Блокировка, изменённая другой попыткой, сохраняется
+	failures = LoginFailures{Failures: 2, LastFailure: now, LockedUntil: now.Add(time.Minute)}
+	failures.Release(now.Add(time.Second))
+	assert.Equal(t, LoginFailures{Failures: 1, LastFailure: now, LockedUntil: now.Add(time.Minute)}, failures)
+}
@@ -0,0 +1,138 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+const moneyScale = 100
+
+var (
+	ErrInvalidMoney  = errors.New("invalid money value")
+	ErrMoneyOverflow = errors.New("money value is out of range")
+)
+
+// Денежная сумма с фиксированной точностью до сотых (копеек).
+// Хранится как целое число сотых долей, что исключает накопление
+// ошибок округления при пополнениях и списаниях.
+// В JSON и SQL представляется десятичным числом.
+type Money int64
+
+// Возвращает сумму, составленную из целой части units и сотых долей cents.
+func NewMoney(units, cents int64) Money {
+	return Money(units*moneyScale + cents)
+}
+
+// Разбирает десятичное представление суммы.
+// Значения с точностью выше сотых округляются до ближайшей сотой.
+func ParseMoney(value string) (Money, error) {
+	rat, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, value)
+	}
+
+	rat.Mul(rat, big.NewRat(moneyScale, 1))
+
+	num := new(big.Int).Abs(rat.Num())
+	quo, rem := new(big.Int).QuoRem(num, rat.Denom(), new(big.Int))
+
+	if rem.Lsh(rem, 1).Cmp(rat.Denom()) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+
+	if rat.Sign() < 0 {
+		quo.Neg(quo)
+	}
+
+	if !quo.IsInt64() {
+		return 0, fmt.Errorf("%w: %q", ErrMoneyOverflow, value)
+	}
+
+	return Money(quo.Int64()), nil
+}
+
+func (m Money) String() string {
+	sign := ""
+	abs := int64(m)
+
+	if abs < 0 {
+		sign = "-"
+		abs = -abs
+	}
+
+	units, cents := abs/moneyScale, abs%moneyScale
+
+	switch {
+	case cents == 0:
+		return fmt.Sprintf("%s%d", sign, units)
+	case cents%10 == 0:
+		return fmt.Sprintf("%s%d.%d", sign, units, cents/10)
+	default:
+		return fmt.Sprintf("%s%d.%02d", sign, units, cents)
+	}
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	value := string(data)
+
+	if value == "null" {
+		return nil
+	}
+
+	money, err := ParseMoney(value)
+	if err != nil {
+		return err
+	}
+
+	*m = money
+
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Считывает значение NUMERIC-столбца. NULL считывается как нулевая сумма.
+func (m *Money) Scan(src interface{}) error {
+	var (
+		money Money
+		err   error
+	)
+
+	switch value := src.(type) {
+	case nil:
+		money = 0
+	case int64:
+		money = Money(value * moneyScale)
+	case float64:
+		money, err = ParseMoney(strconv.FormatFloat(value, 'f', -1, 64))
+	case string:
+		money, err = ParseMoney(value)
+	case []byte:
+		money, err = ParseMoney(string(value))
+	default:
+		return fmt.Errorf("%w: unsupported type %T", ErrInvalidMoney, src)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	*m = money
+
+	return nil
+}
+
+// Возвращает сумму в виде числа с плавающей точкой. Используется только для отчётности,
+// где допустима потеря точности.
+func (m Money) Float64() float64 {
+	return float64(m) / moneyScale
+}
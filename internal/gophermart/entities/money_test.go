@@ -0,0 +1,119 @@
+package entities
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected Money
+		wantErr  bool
+	}{
+		{
+			name:     "Integer",
+			value:    "500",
+			expected: NewMoney(500, 0),
+		},
+		{
+			name:     "Kopecks",
+			value:    "729.98",
+			expected: NewMoney(729, 98),
+		},
+		{
+			name:     "Exponent",
+			value:    "1.5e2",
+			expected: NewMoney(150, 0),
+		},
+		{
+			name:     "Rounding half up",
+			value:    "0.125",
+			expected: NewMoney(0, 13),
+		},
+		{
+			name:     "Negative",
+			value:    "-0.125",
+			expected: -NewMoney(0, 13),
+		},
+		{
+			name:    "Invalid value",
+			value:   "abc",
+			wantErr: true,
+		},
+		{
+			name:    "Overflow",
+			value:   "1e30",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		money, err := ParseMoney(test.value)
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+			assert.Equal(t, test.expected, money, test.name)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	assert.Equal(t, "500", NewMoney(500, 0).String())
+	assert.Equal(t, "0.1", NewMoney(0, 10).String())
+	assert.Equal(t, "42.05", NewMoney(42, 5).String())
+	assert.Equal(t, "-3.5", (-NewMoney(3, 50)).String())
+}
+
+func TestMoneyJSON(t *testing.T) {
+	balance := Balance{
+		Current:   NewMoney(500, 50),
+		Withdrawn: NewMoney(42, 0),
+	}
+
+	data, err := json.Marshal(balance)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"current":500.5,"withdrawn":42}`, string(data))
+
+	var change BalanceChange
+
+	err = json.Unmarshal([]byte(`{"order":"2377225624","sum":0.1}`), &change)
+	require.NoError(t, err)
+	assert.Equal(t, NewMoney(0, 10), change.Sum)
+
+	for i := 0; i < 2; i++ {
+		change.Sum += NewMoney(0, 20)
+	}
+
+	assert.Equal(t, NewMoney(0, 50), change.Sum)
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      interface{}
+		expected Money
+	}{
+		{name: "Null", src: nil, expected: 0},
+		{name: "String", src: "10.01", expected: NewMoney(10, 1)},
+		{name: "Bytes", src: []byte("0.3"), expected: NewMoney(0, 30)},
+		{name: "Float", src: 0.1 + 0.2, expected: NewMoney(0, 30)},
+		{name: "Integer", src: int64(7), expected: NewMoney(7, 0)},
+	}
+
+	for _, test := range tests {
+		var money Money
+
+		require.NoError(t, money.Scan(test.src), test.name)
+		assert.Equal(t, test.expected, money, test.name)
+	}
+
+	var money Money
+
+	assert.Error(t, money.Scan(true))
+}
@@ -11,6 +11,7 @@ var (
 	ErrInvalidOrderNumber = errors.New("invalid order number")
 	ErrOrderAlreadyAdded  = errors.New("order has already been added")
 	ErrOrderAddedByOther  = errors.New("order has already been added by another user")
+	ErrOrderLeaseLost     = errors.New("order is no longer claimed by the instance")
 )
 
 const (
@@ -38,13 +39,24 @@ func AccrualToOrderStatus(status string) string {
 
 // @Description Order data.
 type Order struct {
+	ID         int64     `json:"-"                 swaggerignore:"true"`
 	UserID     int64     `json:"-"                 swaggerignore:"true"`
 	Number     string    `json:"number"            swaggerignore:"false"`
 	Status     string    `json:"status"            swaggerignore:"false"`
-	Accrual    float64   `json:"accrual,omitempty" swaggerignore:"false"`
+	Accrual    Money     `json:"accrual,omitempty" swaggerignore:"false" swaggertype:"number"`
 	UploadedAt time.Time `json:"uploaded_at"       swaggerignore:"false"`
+	Attempts   uint      `json:"-"                 swaggerignore:"true"`
+	LastError  string    `json:"-"                 swaggerignore:"true"`
+	NextPollAt time.Time `json:"-"                 swaggerignore:"true"`
 } // @name Order
 
+// @Description Number of orders whose accrual calculation is not completed.
+type PendingOrders struct {
+	New        int64 `json:"new"        swaggerignore:"false"`
+	Processing int64 `json:"processing" swaggerignore:"false"`
+	Total      int64 `json:"total"      swaggerignore:"false"`
+} // @name PendingOrders
+
 func NewOrder(number string, userID int64) *Order {
 	return &Order{
 		UserID: userID,
@@ -63,7 +75,7 @@ func (order *Order) Validate() error {
 
 // @Description Order data from the Accrual service.
 type AccrualOrder struct {
-	Order   string  `json:"order"   swaggerignore:"false"`
-	Status  string  `json:"status"  swaggerignore:"false"`
-	Accrual float64 `json:"accrual" swaggerignore:"false"`
+	Order   string `json:"order"   swaggerignore:"false"`
+	Status  string `json:"status"  swaggerignore:"false"`
+	Accrual Money  `json:"accrual" swaggerignore:"false" swaggertype:"number"`
 } // @name AccrualOrder
@@ -0,0 +1,180 @@
+package entities
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageLimit uint = 100
+	MaxPageLimit     uint = 1000
+
+	cursorParts = 2
+)
+
+var (
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrInvalidPageLimit = errors.New("invalid page limit")
+	ErrInvalidDateRange = errors.New("invalid date range")
+	ErrInvalidStatus    = errors.New("invalid status")
+)
+
+// Позиция последней записи страницы, после которой продолжается выборка.
+type Cursor struct {
+	Time time.Time
+	ID   int64
+}
+
+// Возвращает непрозрачное строковое представление курсора.
+func (c Cursor) String() string {
+	raw := fmt.Sprintf("%d:%d", c.Time.UnixNano(), c.ID)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func ParseCursor(value string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != cursorParts {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	nsec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor{Time: time.Unix(0, nsec), ID: id}, nil
+}
+
+// Параметры постраничной выборки.
+type Page struct {
+	Limit      uint
+	Cursor     *Cursor
+	Descending bool
+}
+
+func (page *Page) Validate() error {
+	if page.Limit == 0 {
+		page.Limit = DefaultPageLimit
+	}
+
+	if page.Limit > MaxPageLimit {
+		return ErrInvalidPageLimit
+	}
+
+	return nil
+}
+
+// Диапазон дат выборки. Нулевые границы не ограничивают выборку.
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+func (dr DateRange) Validate() error {
+	if !dr.From.IsZero() && !dr.To.IsZero() && dr.To.Before(dr.From) {
+		return ErrInvalidDateRange
+	}
+
+	return nil
+}
+
+// Параметры выборки заказов пользователя.
+type OrderFilter struct {
+	UserID   int64
+	Statuses []string
+	Uploaded DateRange
+	Page
+}
+
+func (filter *OrderFilter) Validate() error {
+	for _, status := range filter.Statuses {
+		switch status {
+		case OrderStatusNew, OrderStatusProcessing, OrderStatusInvalid, OrderStatusProcessed:
+		default:
+			return fmt.Errorf("%w: %s", ErrInvalidStatus, status)
+		}
+	}
+
+	if err := filter.Uploaded.Validate(); err != nil {
+		return err
+	}
+
+	return filter.Page.Validate()
+}
+
+// Параметры выборки списаний пользователя.
+type WithdrawalFilter struct {
+	UserID    int64
+	Processed DateRange
+	Page
+}
+
+func (filter *WithdrawalFilter) Validate() error {
+	if err := filter.Processed.Validate(); err != nil {
+		return err
+	}
+
+	return filter.Page.Validate()
+}
+
+// Параметры выборки выписки по счёту пользователя.
+type StatementFilter struct {
+	UserID    int64
+	Processed DateRange
+	Page
+}
+
+func (filter *StatementFilter) Validate() error {
+	if err := filter.Processed.Validate(); err != nil {
+		return err
+	}
+
+	return filter.Page.Validate()
+}
+
+// Параметры выборки журнала доставки уведомлений подписки WebhookID пользователя UserID.
+type WebhookDeliveryFilter struct {
+	UserID    int64
+	WebhookID int64
+	Created   DateRange
+	Page
+}
+
+func (filter *WebhookDeliveryFilter) Validate() error {
+	if err := filter.Created.Validate(); err != nil {
+		return err
+	}
+
+	return filter.Page.Validate()
+}
+
+// Параметры поиска пользователей. Login - часть логина без учёта регистра;
+// пустое значение не ограничивает выборку. Пользователи упорядочиваются по идентификатору,
+// поэтому курсор страницы содержит только идентификатор.
+type UserFilter struct {
+	Login string
+	Page
+}
+
+func (filter *UserFilter) Validate() error {
+	if filter.Cursor != nil {
+		filter.Cursor = &Cursor{ID: filter.Cursor.ID}
+	}
+
+	return filter.Page.Validate()
+}
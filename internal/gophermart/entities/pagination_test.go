@@ -0,0 +1,65 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	cursor := Cursor{Time: time.Now(), ID: 42}
+
+	parsed, err := ParseCursor(cursor.String())
+	require.NoError(t, err)
+	assert.True(t, cursor.Time.Equal(parsed.Time))
+	assert.Equal(t, cursor.ID, parsed.ID)
+
+	for _, value := range []string{"abc", "", "MQ", "YTpi"} {
+		_, err = ParseCursor(value)
+		assert.ErrorIs(t, err, ErrInvalidCursor, value)
+	}
+}
+
+func TestOrderFilterValidate(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		filter  OrderFilter
+		limit   uint
+		wantErr error
+	}{
+		{
+			name:   "Default limit",
+			filter: OrderFilter{Statuses: []string{OrderStatusNew, OrderStatusProcessed}},
+			limit:  DefaultPageLimit,
+		},
+		{
+			name:    "Limit too large",
+			filter:  OrderFilter{Page: Page{Limit: MaxPageLimit + 1}},
+			wantErr: ErrInvalidPageLimit,
+		},
+		{
+			name:    "Unknown status",
+			filter:  OrderFilter{Statuses: []string{"DONE"}},
+			wantErr: ErrInvalidStatus,
+		},
+		{
+			name:    "Inverted date range",
+			filter:  OrderFilter{Uploaded: DateRange{From: now, To: now.Add(-time.Hour)}},
+			wantErr: ErrInvalidDateRange,
+		},
+	}
+
+	for _, test := range tests {
+		err := test.filter.Validate()
+		if test.wantErr != nil {
+			assert.ErrorIs(t, err, test.wantErr, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+			assert.Equal(t, test.limit, test.filter.Limit, test.name)
+		}
+	}
+}
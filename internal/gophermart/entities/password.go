@@ -0,0 +1,37 @@
+package entities
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrInvalidResetToken = errors.New("password reset token is invalid or expired")
+)
+
+// @Description Password change request.
+type PasswordChange struct {
+	OldPassword string `json:"old_password" swaggerignore:"false"`
+	NewPassword string `json:"new_password" swaggerignore:"false"`
+} // @name PasswordChange
+
+// @Description Password reset request.
+type PasswordResetRequest struct {
+	Login string `json:"login" swaggerignore:"false"`
+} // @name PasswordResetRequest
+
+// @Description New password set by the password reset token.
+type PasswordReset struct {
+	Token       string `json:"token"        swaggerignore:"false"`
+	NewPassword string `json:"new_password" swaggerignore:"false"`
+} // @name PasswordReset
+
+// Токен сброса пароля, хранимый в репозитории.
+// Токен действует до ExpiresAt и может быть использован только один раз.
+type ResetToken struct {
+	Hash      string
+	UserID    int64
+	ExpiresAt time.Time
+	UsedAt    time.Time
+}
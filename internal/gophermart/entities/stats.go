@@ -0,0 +1,8 @@
+package entities
+
+// Сводные показатели сервиса.
+type Stats struct {
+	PendingOrders int64 // Количество заказов, расчёт начисления по которым не завершён
+	Accrued       Money // Сумма начисленных пользователям баллов
+	Withdrawn     Money // Сумма списанных пользователями баллов за вычетом отменённых списаний
+}
@@ -0,0 +1,61 @@
+package entities
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidToken       = errors.New("token is invalid")
+	ErrTokenRevoked       = errors.New("token has been revoked")
+	ErrRefreshTokenReused = errors.New("refresh token has already been used")
+)
+
+// Данные проверенного токена доступа.
+type AccessToken struct {
+	ID        string
+	UserID    int64
+	Role      string
+	ExpiresAt time.Time
+}
+
+// Токен обновления, хранимый в репозитории.
+// Токены, выпущенные в рамках одного входа пользователя, образуют семейство Family:
+// при обновлении использованный токен заменяется новым токеном того же семейства,
+// а повторное использование токена приводит к отзыву всего семейства.
+// Роль Role не хранится вместе с токеном: она считывается у пользователя при использовании токена,
+// поэтому изменение роли вступает в силу при следующем обновлении токенов.
+type RefreshToken struct {
+	Hash            string
+	UserID          int64
+	Role            string
+	Family          string
+	AccessTokenID   string
+	AccessExpiresAt time.Time
+	IssuedAt        time.Time
+	ExpiresAt       time.Time
+	UsedAt          time.Time
+	RevokedAt       time.Time
+}
+
+// Пара токенов, выдаваемая пользователю при входе и обновлении.
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// @Description Authorization tokens issued to the user.
+type Tokens struct {
+	AccessToken      string    `json:"access_token"       swaggerignore:"false"`
+	TokenType        string    `json:"token_type"         swaggerignore:"false" enums:"Bearer"`
+	ExpiresAt        time.Time `json:"expires_at"         swaggerignore:"false"`
+	RefreshToken     string    `json:"refresh_token"      swaggerignore:"false"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at" swaggerignore:"false"`
+} // @name Tokens
+
+// @Description Refresh token exchanged for a new pair of tokens.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" swaggerignore:"false"`
+} // @name RefreshRequest
@@ -5,10 +5,8 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
-	"math/rand"
-	"time"
 
-	"github.com/KryukovO/gophermart/internal/utils"
+	"github.com/KryukovO/gophermart/internal/password"
 )
 
 var (
@@ -16,6 +14,12 @@ var (
 	ErrInvalidLoginPassword = errors.New("invalid login/password")
 )
 
+// Роли пользователей.
+const (
+	RoleUser  string = "user"
+	RoleAdmin string = "admin"
+)
+
 // @Description User account data.
 type User struct {
 	ID                int64  `json:"-"        swaggerignore:"true"`
@@ -23,39 +27,67 @@ type User struct {
 	Password          string `json:"password" swaggerignore:"false"`
 	EncryptedPassword string `json:"-"        swaggerignore:"true"`
 	Salt              string `json:"-"        swaggerignore:"true"`
+	Role              string `json:"-"        swaggerignore:"true"`
 } // @name User
 
-// Выполняет шифрование SHA-256 поля Password с добавлением соли.
-func (user *User) Encrypt(secret []byte) error {
-	if user.Salt == "" {
-		salt, err := utils.GenerateRandomSalt(rand.NewSource(time.Now().UnixNano()))
-		if err != nil {
-			return err
-		}
-
-		user.Salt = salt
-	}
-
-	enc := hmac.New(sha256.New, secret)
-
-	_, err := enc.Write([]byte(user.Password + user.Salt))
+// @Description User account data available to administrators.
+type UserProfile struct {
+	ID    int64  `json:"id"    swaggerignore:"false"`
+	Login string `json:"login" swaggerignore:"false"`
+	Role  string `json:"role"  swaggerignore:"false" enums:"user,admin"`
+} // @name UserProfile
+
+// Выполняет хеширование поля Password алгоритмом hasher.
+// Соль и параметры алгоритма сохраняются в самом хеше, поэтому поле Salt очищается.
+func (user *User) Encrypt(hasher password.Hasher) error {
+	hash, err := hasher.Hash(user.Password)
 	if err != nil {
 		return err
 	}
 
-	user.EncryptedPassword = hex.EncodeToString(enc.Sum(nil))
+	user.EncryptedPassword = hash
+	user.Salt = ""
 
 	return nil
 }
 
-// Возвращает ErrInvalidLoginPassword, если результат SHA-256 шифрования Password
-// не соответствует EncryptedPassword с учетом Salt и secret.
-// Всегда nil, если EncryptedPassword не установлен.
-func (user *User) Validate(secret []byte) error {
+// Возвращает ErrInvalidLoginPassword, если Password не соответствует EncryptedPassword.
+// Хеши в устаревшем формате HMAC-SHA256 проверяются с учетом Salt и secret.
+// Возвращает true, если хеш получен устаревшим алгоритмом или с устаревшими параметрами
+// и должен быть пересчитан.
+func (user *User) Validate(hasher password.Hasher, secret []byte) (bool, error) {
 	if user.EncryptedPassword == "" {
-		return nil
+		return false, ErrInvalidLoginPassword
 	}
 
+	if user.HasLegacyPassword() {
+		err := user.validateLegacy(secret)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	err := hasher.Verify(user.Password, user.EncryptedPassword)
+	if err != nil {
+		if errors.Is(err, password.ErrMismatchedPassword) {
+			return false, ErrInvalidLoginPassword
+		}
+
+		return false, err
+	}
+
+	return hasher.NeedsRehash(user.EncryptedPassword), nil
+}
+
+// Возвращает true, если пароль хранится в устаревшем формате HMAC-SHA256,
+// не содержащем идентификатора алгоритма.
+func (user *User) HasLegacyPassword() bool {
+	return password.Algorithm(user.EncryptedPassword) == ""
+}
+
+func (user *User) validateLegacy(secret []byte) error {
 	enc := hmac.New(sha256.New, secret)
 
 	_, err := enc.Write([]byte(user.Password + user.Salt))
@@ -63,9 +95,8 @@ func (user *User) Validate(secret []byte) error {
 		return err
 	}
 
-	hash := hex.EncodeToString(enc.Sum(nil))
-
-	if user.EncryptedPassword != hash {
+	hash, err := hex.DecodeString(user.EncryptedPassword)
+	if err != nil || !hmac.Equal(hash, enc.Sum(nil)) {
 		return ErrInvalidLoginPassword
 	}
 
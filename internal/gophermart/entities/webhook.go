@@ -0,0 +1,128 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidWebhook  = errors.New("invalid webhook")
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// Минимальная длина секрета подписи уведомлений.
+const minWebhookSecretLength = 16
+
+const (
+	WebhookEventOrderStatusChanged string = "order.status_changed"
+	WebhookEventBalanceChanged     string = "balance.changed"
+)
+
+const (
+	WebhookDeliveryPending   string = "pending"
+	WebhookDeliveryDelivered string = "delivered"
+	WebhookDeliveryFailed    string = "failed"
+)
+
+// @Description Webhook subscription of the user.
+type Webhook struct {
+	ID        int64     `json:"id"         swaggerignore:"false"`
+	UserID    int64     `json:"-"          swaggerignore:"true"`
+	URL       string    `json:"url"        swaggerignore:"false"`
+	Secret    string    `json:"-"          swaggerignore:"true"`
+	CreatedAt time.Time `json:"created_at" swaggerignore:"false"`
+} // @name Webhook
+
+// @Description Webhook subscription registration.
+type WebhookRegistration struct {
+	URL    string `json:"url"    swaggerignore:"false" example:"https://shop.example.com/hooks/gophermart"`
+	Secret string `json:"secret" swaggerignore:"false" example:"9f86d081884c7d659a2feaa0c55ad015"`
+} // @name WebhookRegistration
+
+// Проверяет, что адрес подписки - абсолютный URL со схемой http или https,
+// который не указывает на локальный, частный или link-local адрес,
+// а секрет подписи уведомлений не короче minWebhookSecretLength.
+// Адреса, которые разрешаются в такие IP через DNS, отклоняются при доставке уведомлений.
+func (webhook *Webhook) Validate() error {
+	webhook.URL = strings.TrimSpace(webhook.URL)
+
+	addr, err := url.Parse(webhook.URL)
+	if err != nil || (addr.Scheme != "http" && addr.Scheme != "https") || addr.Host == "" {
+		return fmt.Errorf("%w: absolute http or https url is required", ErrInvalidWebhook)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(addr.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: url must not point to a local address", ErrInvalidWebhook)
+	}
+
+	if ip := net.ParseIP(host); ip != nil && !utils.IsPublicIP(ip) {
+		return fmt.Errorf("%w: url must not point to a private or local address", ErrInvalidWebhook)
+	}
+
+	if len(webhook.Secret) < minWebhookSecretLength {
+		return fmt.Errorf("%w: secret must be at least %d characters long", ErrInvalidWebhook, minWebhookSecretLength)
+	}
+
+	return nil
+}
+
+// Событие, о котором уведомляются подписки пользователя.
+// Data содержит OrderStatusEvent или BalanceEvent в зависимости от типа события Type.
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+func NewWebhookEvent(eventType string, data interface{}) WebhookEvent {
+	return WebhookEvent{
+		ID:        uuid.NewString(),
+		Type:      eventType,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+}
+
+// Изменение статуса заказа сервисом расчёта начислений.
+type OrderStatusEvent struct {
+	Number         string `json:"number"`
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previous_status"`
+	Accrual        Money  `json:"accrual,omitempty"`
+}
+
+// Зафиксированное изменение баланса пользователя.
+type BalanceEvent struct {
+	Operation   string    `json:"operation"`
+	Order       string    `json:"order,omitempty"`
+	Sum         Money     `json:"sum"`
+	Current     Money     `json:"current"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// @Description Delivery of a webhook event.
+type WebhookDelivery struct {
+	ID             int64      `json:"id"                        swaggerignore:"false"`
+	WebhookID      int64      `json:"-"                         swaggerignore:"true"`
+	URL            string     `json:"-"                         swaggerignore:"true"`
+	Secret         string     `json:"-"                         swaggerignore:"true"`
+	EventID        string     `json:"event_id"                  swaggerignore:"false"`
+	EventType      string     `json:"event_type"                enums:"order.status_changed,balance.changed"`
+	Payload        []byte     `json:"-"                         swaggerignore:"true"`
+	Status         string     `json:"status"                    enums:"pending,delivered,failed"`
+	Attempts       uint       `json:"attempts"                  swaggerignore:"false"`
+	ResponseStatus int        `json:"response_status,omitempty" swaggerignore:"false"`
+	LastError      string     `json:"last_error,omitempty"      swaggerignore:"false"`
+	CreatedAt      time.Time  `json:"created_at"                swaggerignore:"false"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty" swaggerignore:"false"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"    swaggerignore:"false"`
+} // @name WebhookDelivery
@@ -0,0 +1,88 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookValidate(t *testing.T) {
+	const secret = "9f86d081884c7d659a2feaa0c55ad015"
+
+	tests := []struct {
+		name    string
+		url     string
+		secret  string
+		wantErr error
+	}{
+		{
+			name:   "Public host",
+			url:    " https://shop.example.com/hooks/gophermart ",
+			secret: secret,
+		},
+		{
+			name:   "Public IP",
+			url:    "http://93.184.216.34:8080/hooks",
+			secret: secret,
+		},
+		{
+			name:    "Relative url",
+			url:     "/hooks/gophermart",
+			secret:  secret,
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "Unsupported scheme",
+			url:     "ftp://shop.example.com/hooks",
+			secret:  secret,
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "Loopback",
+			url:     "http://127.0.0.1:8080/hooks",
+			secret:  secret,
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "IPv6 loopback",
+			url:     "http://[::1]:8080/hooks",
+			secret:  secret,
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "Localhost",
+			url:     "http://LocalHost./hooks",
+			secret:  secret,
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "Private network",
+			url:     "http://10.0.0.5/hooks",
+			secret:  secret,
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "Link-local metadata",
+			url:     "http://169.254.169.254/latest/meta-data",
+			secret:  secret,
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "Short secret",
+			url:     "https://shop.example.com/hooks",
+			secret:  "secret",
+			wantErr: ErrInvalidWebhook,
+		},
+	}
+
+	for _, test := range tests {
+		webhook := Webhook{URL: test.url, Secret: test.secret}
+
+		err := webhook.Validate()
+		if test.wantErr != nil {
+			assert.ErrorIs(t, err, test.wantErr, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+		}
+	}
+}
@@ -3,22 +3,38 @@ package gophermart
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/KryukovO/gophermart/internal/gophermart/accrualconnector"
+	"github.com/KryukovO/gophermart/internal/gophermart/cleaner"
 	"github.com/KryukovO/gophermart/internal/gophermart/config"
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/health"
+	"github.com/KryukovO/gophermart/internal/gophermart/metrics"
+	"github.com/KryukovO/gophermart/internal/gophermart/notifier"
+	"github.com/KryukovO/gophermart/internal/gophermart/repository"
+	"github.com/KryukovO/gophermart/internal/gophermart/repository/memrepo"
 	"github.com/KryukovO/gophermart/internal/gophermart/repository/pgrepo"
 	server "github.com/KryukovO/gophermart/internal/gophermart/server/http"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
 	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/KryukovO/gophermart/internal/gophermart/webhooksender"
+	"github.com/KryukovO/gophermart/internal/jwtkeys"
+	"github.com/KryukovO/gophermart/internal/password"
 	"github.com/KryukovO/gophermart/internal/postgres"
 
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
 )
 
+var ErrUnknownStorage = errors.New("unknown storage type")
+
 // @title           Loyalty points service
 // @version         1.0
 // @description     Service for maintaining a user's accumulative bonus account.
@@ -31,34 +47,185 @@ import (
 // @name                        token
 // @description					JSON Web Token
 
+// @securityDefinitions.apikey  Bearer
+// @in                          header
+// @name                        Authorization
+// @description					JSON Web Token with the Bearer prefix: "Bearer <token>"
+
 func Run(cfg *config.Config, logger *log.Logger) error {
-	logger.Infof("Connect to the database: %s", cfg.DSN)
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
 
-	repoCtx, cancel := context.WithTimeout(context.Background(), cfg.RepositioryTimeout)
-	defer cancel()
+	var (
+		userRepo         repository.UserRepo
+		orderRepo        repository.OrderRepo
+		balanceRepo      repository.BalanceRepo
+		tokenRepo        repository.TokenRepo
+		loginAttemptRepo repository.LoginAttemptRepo
+		statsRepo        repository.StatsRepo
+		idempotencyRepo  repository.IdempotencyRepo
+		webhookRepo      repository.WebhookRepo
+	)
+
+	serviceMetrics := metrics.NewMetrics(logger)
+	serviceHealth := health.NewHealth(cfg.RepositioryTimeout)
 
-	pg, err := postgres.NewPostgres(repoCtx, cfg.DSN, cfg.Migrations)
+	serviceTracing, err := tracing.NewTracing(
+		context.Background(),
+		cfg.TracingEndpoint, cfg.TracingOutput, cfg.TracingSampleRatio,
+		logger,
+	)
 	if err != nil {
 		return err
 	}
 
-	logger.Info("Database connection established")
+	if serviceTracing.Enabled() {
+		logger.Info("Tracing enabled")
 
-	defer func() {
-		pg.Close()
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+			defer cancel()
 
-		logger.Info("Database connection closed")
-	}()
+			if err := serviceTracing.Shutdown(ctx); err != nil {
+				logger.Errorf("Unable to flush traces: %s", err)
+			}
+		}()
+	}
+
+	switch cfg.Storage {
+	case config.StorageMemory:
+		logger.Info("Use in-memory storage")
+
+		storage := memrepo.NewStorage()
+
+		userRepo = memrepo.NewUserRepo(storage)
+		orderRepo = memrepo.NewOrderRepo(storage)
+		balanceRepo = memrepo.NewBalanceRepo(storage)
+		tokenRepo = memrepo.NewTokenRepo(storage)
+		loginAttemptRepo = memrepo.NewLoginAttemptRepo(storage)
+		statsRepo = memrepo.NewStatsRepo(storage)
+		idempotencyRepo = memrepo.NewIdempotencyRepo(storage)
+		webhookRepo = memrepo.NewWebhookRepo(storage)
+	case config.StoragePostgres:
+		logger.Infof("Connect to the database: %s", cfg.DSN)
 
-	user := usecases.NewUserUseCase(pgrepo.NewUserRepo(pg), cfg.RepositioryTimeout)
-	order := usecases.NewOrderUseCase(pgrepo.NewOrderRepo(pg), cfg.RepositioryTimeout)
-	balance := usecases.NewBalanceUseCase(pgrepo.NewBalanceRepo(pg), cfg.RepositioryTimeout)
+		repoCtx, cancel := context.WithTimeout(context.Background(), cfg.RepositioryTimeout)
+		defer cancel()
+
+		pg, err := postgres.NewPostgres(repoCtx, cfg.DSN, cfg.Migrations)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Database connection established")
+
+		defer func() {
+			pg.Close()
+
+			logger.Info("Database connection closed")
+		}()
+
+		userRepo = pgrepo.NewUserRepo(pg)
+		orderRepo = pgrepo.NewOrderRepo(pg)
+		balanceRepo = pgrepo.NewBalanceRepo(pg)
+		tokenRepo = pgrepo.NewTokenRepo(pg)
+		loginAttemptRepo = pgrepo.NewLoginAttemptRepo(pg)
+		statsRepo = pgrepo.NewStatsRepo(pg)
+		idempotencyRepo = pgrepo.NewIdempotencyRepo(pg)
+		webhookRepo = pgrepo.NewWebhookRepo(pg)
+
+		err = serviceMetrics.RegisterDB(pg.DB)
+		if err != nil {
+			return err
+		}
+
+		serviceHealth.AddCheck("database", pg.PingContext)
+		serviceHealth.AddCheck("migrations", pg.CheckMigrations)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownStorage, cfg.Storage)
+	}
+
+	hasher, err := password.NewHasher(
+		cfg.PasswordHasher, cfg.BcryptCost,
+		password.Argon2Params{
+			Memory:      uint32(cfg.Argon2Memory),
+			Iterations:  uint32(cfg.Argon2Iterations),
+			Parallelism: uint8(cfg.Argon2Parallelism),
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	keys := jwtkeys.NewHMACKeySet([]byte(cfg.SecretKey))
+
+	if len(cfg.JWTKeys) > 0 {
+		signingKeys, err := jwtkeys.LoadKeys(cfg.JWTKeys)
+		if err != nil {
+			return err
+		}
+
+		// Заменённый ключ должен приниматься как минимум до истечения выданных им токенов
+		grace := cfg.JWTKeyGrace
+		if grace < cfg.UserTokenTTL {
+			grace = cfg.UserTokenTTL
+		}
+
+		keys, err = jwtkeys.NewKeySet(signingKeys, grace)
+		if err != nil {
+			return err
+		}
+
+		logger.Infof("Loaded %d token signing keys", len(signingKeys))
+	}
+
+	user := usecases.NewUserUseCase(
+		userRepo, loginAttemptRepo, hasher,
+		entities.LoginPolicy{
+			MaxAttempts:      cfg.LoginMaxAttempts,
+			MaxAttemptsPerIP: cfg.LoginMaxAttemptsIP,
+			Delay:            cfg.LoginDelay,
+			Lockout:          cfg.LoginLockout,
+		},
+		notifier.NewLogNotifier(logger), cfg.PasswordResetTTL,
+		cfg.RepositioryTimeout,
+	)
+	order := usecases.NewOrderUseCase(orderRepo, cfg.RepositioryTimeout)
+	balance := usecases.NewBalanceUseCase(balanceRepo, cfg.RepositioryTimeout)
+	stats := usecases.NewStatsUseCase(statsRepo, cfg.RepositioryTimeout)
+	token := usecases.NewTokenUseCase(
+		tokenRepo, keys,
+		cfg.UserTokenTTL, cfg.RefreshTokenTTL,
+		cfg.RepositioryTimeout,
+	)
+	idempotency := usecases.NewIdempotencyUseCase(
+		idempotencyRepo,
+		cfg.IdempotencyKeyTTL, cfg.IdempotencyLease,
+		cfg.RepositioryTimeout,
+	)
+	webhook := usecases.NewWebhookUseCase(webhookRepo, cfg.RepositioryTimeout)
+
+	err = serviceMetrics.RegisterStats(stats)
+	if err != nil {
+		return err
+	}
+
+	var metricsServer *server.MetricsServer
+
+	if cfg.MetricsAddress != "" {
+		metricsServer, err = server.NewMetricsServer(cfg.MetricsAddress, serviceMetrics, logger)
+		if err != nil {
+			return err
+		}
+	} else {
+		logger.Warn("Metrics endpoint is disabled: metrics address is empty")
+	}
 
 	server, err := server.NewServer(
-		cfg.Address, []byte(cfg.SecretKey),
-		cfg.UserTokenTTL,
-		user, order, balance,
-		logger,
+		cfg.Address, []byte(cfg.SecretKey), middleware.TokenSource(cfg.TokenSource), keys,
+		user, order, balance, token, webhook, idempotency,
+		serviceHealth, serviceMetrics, logger,
 	)
 	if err != nil {
 		return err
@@ -66,10 +233,24 @@ func Run(cfg *config.Config, logger *log.Logger) error {
 
 	accrualConnector := accrualconnector.NewAccrualConnector(
 		cfg.AccrualAddress, cfg.AccrualWorkers, cfg.AccrualInterval,
-		order, balance,
-		logger,
+		cfg.AccrualBatchSize, cfg.AccrualLease,
+		cfg.AccrualBackoff, cfg.AccrualMaxAge,
+		order, serviceMetrics, logger,
+	)
+
+	webhookSender := webhooksender.NewWebhookSender(
+		cfg.WebhookWorkers, cfg.WebhookInterval,
+		cfg.WebhookBatchSize, cfg.WebhookLease, cfg.WebhookTimeout,
+		cfg.WebhookAttempts, cfg.WebhookBackoff,
+		webhook, logger,
 	)
 
+	dataCleaner := cleaner.NewCleaner(cfg.CleanupInterval, []usecases.Cleanup{user, idempotency}, logger)
+
+	serviceHealth.AddCheck("accrual", func(ctx context.Context) error {
+		return accrualConnector.CheckPoll(cfg.ReadinessMaxAge)
+	})
+
 	sigCtx, sigCancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer sigCancel()
 
@@ -85,8 +266,23 @@ func Run(cfg *config.Config, logger *log.Logger) error {
 		return nil
 	})
 
+	if metricsServer != nil {
+		group.Go(func() error {
+			logger.Infof("Run metrics server at %s", cfg.MetricsAddress)
+
+			if err := metricsServer.Run(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+
+			return nil
+		})
+	}
+
 	group.Go(func() error {
-		logger.Infof("Run accrual connector: workers: %d, interval: %s", cfg.AccrualWorkers, cfg.AccrualInterval)
+		logger.Infof(
+			"Run accrual connector: workers: %d, interval: %s, batch: %d, lease: %s",
+			cfg.AccrualWorkers, cfg.AccrualInterval, cfg.AccrualBatchSize, cfg.AccrualLease,
+		)
 
 		accrualConnector.Run(groupCtx)
 
@@ -96,13 +292,29 @@ func Run(cfg *config.Config, logger *log.Logger) error {
 	})
 
 	group.Go(func() error {
-		select {
-		case <-groupCtx.Done():
-			return nil
-		case <-sigCtx.Done():
-			logger.Info("Shutdown signal received")
-		}
+		logger.Infof(
+			"Run webhook sender: workers: %d, interval: %s, batch: %d, lease: %s",
+			cfg.WebhookWorkers, cfg.WebhookInterval, cfg.WebhookBatchSize, cfg.WebhookLease,
+		)
+
+		webhookSender.Run(groupCtx)
+
+		logger.Info("Webhook sender stopped")
+
+		return nil
+	})
+
+	group.Go(func() error {
+		logger.Infof("Run cleaner: interval: %s", cfg.CleanupInterval)
+
+		dataCleaner.Run(groupCtx)
+
+		logger.Info("Cleaner stopped")
+
+		return nil
+	})
 
+	stopServers := func() {
 		logger.Info("Stopping server...")
 
 		shutdownCtx, shutdownCancel := context.WithTimeout(
@@ -117,6 +329,38 @@ func Run(cfg *config.Config, logger *log.Logger) error {
 			logger.Info("Server stopped gracefully")
 		}
 
+		if metricsServer != nil {
+			if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+				logger.Errorf("Unable to gracefully stop the metrics server: %s", err)
+			}
+		}
+	}
+
+	group.Go(func() error {
+		select {
+		case <-groupCtx.Done():
+			// Один из компонентов завершился с ошибкой. Остальные компоненты
+			// останавливаются по отмене groupCtx, а серверы нужно остановить явно
+			stopServers()
+
+			return nil
+		case <-sigCtx.Done():
+			logger.Info("Shutdown signal received")
+		}
+
+		// Сервис перестаёт быть готовым заранее, чтобы балансировщик нагрузки
+		// успел перестать направлять ему запросы до остановки сервера
+		serviceHealth.Drain()
+
+		logger.Infof("Draining server for %s...", cfg.DrainTimeout)
+
+		select {
+		case <-groupCtx.Done():
+		case <-time.After(cfg.DrainTimeout):
+		}
+
+		stopServers()
+
 		accrualCtx, accrualCancel := context.WithTimeout(
 			context.Background(),
 			cfg.AccrualShutdown,
@@ -125,6 +369,22 @@ func Run(cfg *config.Config, logger *log.Logger) error {
 
 		accrualConnector.Shutdown(accrualCtx)
 
+		webhookCtx, webhookCancel := context.WithTimeout(
+			context.Background(),
+			cfg.WebhookShutdown,
+		)
+		defer webhookCancel()
+
+		webhookSender.Shutdown(webhookCtx)
+
+		cleanerCtx, cleanerCancel := context.WithTimeout(
+			context.Background(),
+			cfg.ShutdownTimeout,
+		)
+		defer cleanerCancel()
+
+		dataCleaner.Shutdown(cleanerCtx)
+
 		return nil
 	})
 
@@ -0,0 +1,114 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Состояние сервиса и его компонентов.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Компонент, отражающий завершение работы сервера.
+const serverComponent = "server"
+
+var ErrShuttingDown = errors.New("service is shutting down")
+
+// Проверка готовности компонента сервиса. Возвращает ошибку, если компонент не готов.
+type Check func(ctx context.Context) error
+
+// @Description Component readiness status.
+type ComponentStatus struct {
+	Status string `json:"status"          enums:"up,down"`
+	Error  string `json:"error,omitempty"`
+} // @name ComponentStatus
+
+// @Description Service readiness status with the status of each component.
+type Report struct {
+	Status     string                     `json:"status"     enums:"up,down"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+} // @name HealthReport
+
+// Проверка готовности сервиса к обработке запросов.
+// Сервис готов, если готовы все его компоненты и не начато завершение работы.
+type Health struct {
+	checks   map[string]Check
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+func NewHealth(timeout time.Duration) *Health {
+	return &Health{
+		checks:  make(map[string]Check),
+		timeout: timeout,
+	}
+}
+
+// Добавляет проверку готовности компонента name.
+func (h *Health) AddCheck(name string, check Check) {
+	h.checks[name] = check
+}
+
+// Переводит сервис в состояние завершения работы, в котором он не готов к обработке запросов.
+// Позволяет балансировщику нагрузки перестать направлять запросы до остановки сервера.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Выполняет проверки готовности всех компонентов одновременно и возвращает их результат.
+// Проверка, не завершившаяся за отведённое время, считается неуспешной.
+func (h *Health) Ready(ctx context.Context) (Report, bool) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	report := Report{
+		Status:     StatusUp,
+		Components: make(map[string]ComponentStatus, len(h.checks)+1),
+	}
+
+	var (
+		mtx sync.Mutex
+		wg  sync.WaitGroup
+	)
+
+	setStatus := func(name string, err error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if err != nil {
+			report.Status = StatusDown
+			report.Components[name] = ComponentStatus{Status: StatusDown, Error: err.Error()}
+
+			return
+		}
+
+		report.Components[name] = ComponentStatus{Status: StatusUp}
+	}
+
+	if h.draining.Load() {
+		setStatus(serverComponent, ErrShuttingDown)
+	} else {
+		setStatus(serverComponent, nil)
+	}
+
+	for name, check := range h.checks {
+		name, check := name, check
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			setStatus(name, check(ctx))
+		}()
+	}
+
+	wg.Wait()
+
+	return report, report.Status == StatusUp
+}
@@ -0,0 +1,105 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReady(t *testing.T) {
+	errCheck := errors.New("check failed")
+
+	tests := []struct {
+		name       string
+		checks     map[string]Check
+		drain      bool
+		ready      bool
+		components map[string]ComponentStatus
+	}{
+		{
+			name:  "No checks",
+			ready: true,
+			components: map[string]ComponentStatus{
+				"server": {Status: StatusUp},
+			},
+		},
+		{
+			name: "All components ready",
+			checks: map[string]Check{
+				"database": func(ctx context.Context) error { return nil },
+				"accrual":  func(ctx context.Context) error { return nil },
+			},
+			ready: true,
+			components: map[string]ComponentStatus{
+				"server":   {Status: StatusUp},
+				"database": {Status: StatusUp},
+				"accrual":  {Status: StatusUp},
+			},
+		},
+		{
+			name: "Component not ready",
+			checks: map[string]Check{
+				"database": func(ctx context.Context) error { return nil },
+				"accrual":  func(ctx context.Context) error { return errCheck },
+			},
+			components: map[string]ComponentStatus{
+				"server":   {Status: StatusUp},
+				"database": {Status: StatusUp},
+				"accrual":  {Status: StatusDown, Error: errCheck.Error()},
+			},
+		},
+		{
+			name: "Check timeout",
+			checks: map[string]Check{
+				"database": func(ctx context.Context) error {
+					<-ctx.Done()
+
+					return ctx.Err()
+				},
+			},
+			components: map[string]ComponentStatus{
+				"server":   {Status: StatusUp},
+				"database": {Status: StatusDown, Error: context.DeadlineExceeded.Error()},
+			},
+		},
+		{
+			name: "Draining",
+			checks: map[string]Check{
+				"database": func(ctx context.Context) error { return nil },
+			},
+			drain: true,
+			components: map[string]ComponentStatus{
+				"server":   {Status: StatusDown, Error: ErrShuttingDown.Error()},
+				"database": {Status: StatusUp},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			health := NewHealth(50 * time.Millisecond)
+
+			for name, check := range test.checks {
+				health.AddCheck(name, check)
+			}
+
+			if test.drain {
+				health.Drain()
+			}
+
+			report, ready := health.Ready(context.Background())
+
+			assert.Equal(t, test.ready, ready)
+			assert.Equal(t, test.components, report.Components)
+
+			if test.ready {
+				assert.Equal(t, StatusUp, report.Status)
+			} else {
+				assert.Equal(t, StatusDown, report.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Формат вывода журнала.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Поля записей журнала.
+const (
+	FieldRequestID = "request_id"
+	FieldUserID    = "user_id"
+	FieldMethod    = "method"
+	FieldRoute     = "route"
+	FieldStatus    = "status"
+	FieldLatency   = "latency"
+	FieldTraceID   = "trace_id"
+	FieldOrder     = "order"
+)
+
+const timestampFormat = "2006-01-02 15:04:05 Z07:00"
+
+var ErrUnknownFormat = errors.New("unknown log format")
+
+type loggerKey struct{}
+
+// Создаёт журнал с уровнем level (panic, fatal, error, warn, info, debug, trace)
+// и форматом вывода format (text, json).
+func NewLogger(level, format string) (*log.Logger, error) {
+	logLevel, err := log.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := log.New()
+	logger.SetLevel(logLevel)
+
+	switch format {
+	case FormatText:
+		logger.SetFormatter(&log.TextFormatter{
+			FullTimestamp:   true,
+			TimestampFormat: timestampFormat,
+		})
+	case FormatJSON:
+		logger.SetFormatter(&log.JSONFormatter{})
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+
+	return logger, nil
+}
+
+// Возвращает копию ctx, содержащую журнал entry.
+func WithLogger(ctx context.Context, entry *log.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey{}, entry)
+}
+
+// Возвращает журнал, сохранённый в ctx, а при его отсутствии - журнал fallback.
+// Если ctx содержит спан трассировки, в записи журнала добавляется идентификатор трассировки.
+func FromContext(ctx context.Context, fallback *log.Logger) *log.Entry {
+	entry, ok := ctx.Value(loggerKey{}).(*log.Entry)
+	if !ok {
+		if fallback == nil {
+			fallback = log.StandardLogger()
+		}
+
+		entry = log.NewEntry(fallback)
+	}
+
+	if _, ok := entry.Data[FieldTraceID]; !ok {
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+			entry = entry.WithField(FieldTraceID, spanContext.TraceID().String())
+		}
+	}
+
+	return entry
+}
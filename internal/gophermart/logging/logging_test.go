@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   string
+		format  string
+		wantErr bool
+	}{
+		{name: "Text", level: "info", format: FormatText},
+		{name: "JSON", level: "debug", format: FormatJSON},
+		{name: "Unknown level", level: "verbose", format: FormatText, wantErr: true},
+		{name: "Unknown format", level: "info", format: "xml", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logger, err := NewLogger(test.level, test.format)
+			if test.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.level, logger.GetLevel().String())
+
+			if test.format == FormatJSON {
+				assert.IsType(t, &log.JSONFormatter{}, logger.Formatter)
+			} else {
+				assert.IsType(t, &log.TextFormatter{}, logger.Formatter)
+			}
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	FromContext(context.Background(), logger).Info("fallback")
+	assert.Empty(t, hook.LastEntry().Data)
+
+	ctx := WithLogger(context.Background(), logger.WithField(FieldRequestID, "request"))
+
+	FromContext(ctx, nil).Info("stored")
+	assert.Equal(t, log.Fields{FieldRequestID: "request"}, hook.LastEntry().Data)
+
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	FromContext(ctx, nil).Info("traced")
+	assert.Equal(t, log.Fields{
+		FieldRequestID: "request",
+		FieldTraceID:   traceID.String(),
+	}, hook.LastEntry().Data)
+}
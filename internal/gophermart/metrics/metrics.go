@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const namespace = "gophermart"
+
+// Этапы опроса сервиса Accrual, на которых учитываются ошибки.
+const (
+	AccrualStageRequest = "request" // Запрос к сервису Accrual
+	AccrualStageStorage = "storage" // Получение и сохранение заказов в хранилище
+)
+
+// Метрики сервиса в формате Prometheus.
+// Методы регистрации событий допускают вызов на nil, что позволяет не собирать метрики,
+// например, в тестах.
+type Metrics struct {
+	registry *prometheus.Registry
+	logger   *log.Logger
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	accrualBatchSize prometheus.Histogram
+	accrualResponses *prometheus.CounterVec
+	accrualThrottled prometheus.Counter
+	accrualErrors    *prometheus.CounterVec
+	accrualDuration  prometheus.Histogram
+}
+
+func NewMetrics(logger *log.Logger) *Metrics {
+	metricsLogger := log.StandardLogger()
+	if logger != nil {
+		metricsLogger = logger
+	}
+
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		logger:   metricsLogger,
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of handled HTTP requests.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request handling latency.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+
+		accrualBatchSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "accrual",
+			Name:      "batch_size",
+			Help:      "Number of orders claimed for polling the accrual system per interval.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 11),
+		}),
+		accrualResponses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "accrual",
+			Name:      "responses_total",
+			Help:      "Number of accrual system responses by HTTP status.",
+		}, []string{"status"}),
+		accrualThrottled: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "accrual",
+			Name:      "throttled_total",
+			Help:      "Number of 429 Too Many Requests responses of the accrual system.",
+		}),
+		accrualErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "accrual",
+			Name:      "errors_total",
+			Help:      "Number of accrual system polling errors by stage.",
+		}, []string{"stage"}),
+		accrualDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "accrual",
+			Name:      "request_duration_seconds",
+			Help:      "Accrual system polling latency.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration,
+		m.accrualBatchSize, m.accrualResponses, m.accrualThrottled, m.accrualErrors, m.accrualDuration,
+	)
+
+	return m
+}
+
+// Регистрирует метрики пула соединений с БД.
+func (m *Metrics) RegisterDB(db *sql.DB) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// Регистрирует сводные показатели сервиса, запрашиваемые у stats при каждом сборе метрик.
+func (m *Metrics) RegisterStats(stats usecases.Stats) error {
+	return m.registry.Register(newStatsCollector(stats))
+}
+
+// Возвращает обработчик, отдающий метрики в текстовом формате Prometheus.
+// Ошибка сбора отдельных метрик не препятствует отдаче остальных.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		ErrorLog:      m.logger,
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// Регистрирует обработанный HTTP-запрос к маршруту route.
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	code := strconv.Itoa(status)
+
+	m.httpRequests.WithLabelValues(method, route, code).Inc()
+	m.httpDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+// Регистрирует количество заказов в очередной партии опроса сервиса Accrual.
+func (m *Metrics) ObserveAccrualBatch(size int) {
+	if m == nil {
+		return
+	}
+
+	m.accrualBatchSize.Observe(float64(size))
+}
+
+// Регистрирует ответ сервиса Accrual со статусом status, полученный за duration.
+func (m *Metrics) ObserveAccrualResponse(status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.accrualResponses.WithLabelValues(strconv.Itoa(status)).Inc()
+	m.accrualDuration.Observe(duration.Seconds())
+
+	if status == http.StatusTooManyRequests {
+		m.accrualThrottled.Inc()
+	}
+}
+
+// Регистрирует ошибку опроса сервиса Accrual на этапе stage.
+func (m *Metrics) IncAccrualErrors(stage string) {
+	if m == nil {
+		return
+	}
+
+	m.accrualErrors.WithLabelValues(stage).Inc()
+}
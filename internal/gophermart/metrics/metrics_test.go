@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type statsFunc func(ctx context.Context) (entities.Stats, error)
+
+func (f statsFunc) Stats(ctx context.Context) (entities.Stats, error) {
+	return f(ctx)
+}
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics(nil)
+
+	m.ObserveHTTPRequest(http.MethodGet, "/api/user/orders", http.StatusOK, 10*time.Millisecond)
+	m.ObserveAccrualBatch(5)
+	m.ObserveAccrualResponse(http.StatusOK, time.Millisecond)
+	m.ObserveAccrualResponse(http.StatusTooManyRequests, time.Millisecond)
+	m.IncAccrualErrors(AccrualStageRequest)
+
+	err := m.RegisterStats(statsFunc(func(context.Context) (entities.Stats, error) {
+		return entities.Stats{
+			PendingOrders: 3,
+			Accrued:       entities.NewMoney(500, 50),
+			Withdrawn:     entities.NewMoney(200, 0),
+		}, nil
+	}))
+	require.NoError(t, err)
+
+	body := scrape(t, m)
+
+	for _, line := range []string{
+		`gophermart_http_requests_total{method="GET",route="/api/user/orders",status="200"} 1`,
+		`gophermart_http_request_duration_seconds_count{method="GET",route="/api/user/orders",status="200"} 1`,
+		`gophermart_accrual_batch_size_sum 5`,
+		`gophermart_accrual_responses_total{status="200"} 1`,
+		`gophermart_accrual_responses_total{status="429"} 1`,
+		`gophermart_accrual_throttled_total 1`,
+		`gophermart_accrual_errors_total{stage="request"} 1`,
+		`gophermart_accrual_request_duration_seconds_count 2`,
+		`gophermart_orders_pending 3`,
+		`gophermart_points_issued 500.5`,
+		`gophermart_points_withdrawn 200`,
+	} {
+		assert.Contains(t, body, line)
+	}
+}
+
+func TestStatsError(t *testing.T) {
+	m := NewMetrics(nil)
+
+	err := m.RegisterStats(statsFunc(func(context.Context) (entities.Stats, error) {
+		return entities.Stats{}, errors.New("storage is unavailable")
+	}))
+	require.NoError(t, err)
+
+	// Ошибка получения показателей не мешает отдаче остальных метрик
+	m.ObserveAccrualBatch(1)
+
+	body := scrape(t, m)
+
+	assert.Contains(t, body, "gophermart_accrual_batch_size_count 1")
+	assert.NotContains(t, body, "gophermart_orders_pending")
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+
+	assert.NotPanics(t, func() {
+		m.ObserveHTTPRequest(http.MethodGet, "/", http.StatusOK, time.Millisecond)
+		m.ObserveAccrualBatch(1)
+		m.ObserveAccrualResponse(http.StatusOK, time.Millisecond)
+		m.IncAccrualErrors(AccrualStageStorage)
+	})
+}
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	res := rec.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
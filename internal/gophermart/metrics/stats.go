@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Коллектор сводных показателей сервиса.
+// Показатели запрашиваются из хранилища при каждом сборе метрик,
+// поэтому совпадают для всех экземпляров сервиса, работающих с одной БД.
+type statsCollector struct {
+	stats usecases.Stats
+
+	pendingOrders *prometheus.Desc
+	accrued       *prometheus.Desc
+	withdrawn     *prometheus.Desc
+}
+
+func newStatsCollector(stats usecases.Stats) *statsCollector {
+	return &statsCollector{
+		stats: stats,
+		pendingOrders: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "orders_pending"),
+			"Number of orders awaiting accrual calculation.",
+			nil, nil,
+		),
+		accrued: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "points_issued"),
+			"Total loyalty points accrued to users.",
+			nil, nil,
+		),
+		withdrawn: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "points_withdrawn"),
+			"Total loyalty points withdrawn by users.",
+			nil, nil,
+		),
+	}
+}
+
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.pendingOrders
+	ch <- c.accrued
+	ch <- c.withdrawn
+}
+
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.stats.Stats(context.Background())
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.pendingOrders, err)
+
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.pendingOrders, prometheus.GaugeValue, float64(stats.PendingOrders))
+	ch <- prometheus.MustNewConstMetric(c.accrued, prometheus.GaugeValue, stats.Accrued.Float64())
+	ch <- prometheus.MustNewConstMetric(c.withdrawn, prometheus.GaugeValue, stats.Withdrawn.Float64())
+}
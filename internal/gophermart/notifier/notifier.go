@@ -0,0 +1,41 @@
+package notifier
+
+import (
+	"context"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/logging"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Доставляет пользователям уведомления сервиса.
+type Notifier interface {
+	// Передаёт пользователю токен сброса пароля, действующий до expiresAt.
+	PasswordReset(ctx context.Context, user entities.User, token string, expiresAt time.Time) error
+}
+
+// Уведомитель, записывающий уведомления в журнал.
+// Используется по умолчанию, пока не настроен способ доставки уведомлений пользователям.
+type LogNotifier struct {
+	logger *log.Logger
+}
+
+func NewLogNotifier(logger *log.Logger) *LogNotifier {
+	notifierLogger := log.StandardLogger()
+	if logger != nil {
+		notifierLogger = logger
+	}
+
+	return &LogNotifier{logger: notifierLogger}
+}
+
+func (n *LogNotifier) PasswordReset(ctx context.Context, user entities.User, token string, expiresAt time.Time) error {
+	logging.FromContext(ctx, n.logger).Infof(
+		"Password reset token for user %q: %s (valid until %s)",
+		user.Login, token, expiresAt.Format(time.RFC3339),
+	)
+
+	return nil
+}
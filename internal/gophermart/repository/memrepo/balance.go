@@ -0,0 +1,213 @@
+package memrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+)
+
+type BalanceRepo struct {
+	storage *Storage
+}
+
+func NewBalanceRepo(storage *Storage) *BalanceRepo {
+	return &BalanceRepo{storage: storage}
+}
+
+func (repo *BalanceRepo) Balance(_ context.Context, userID int64) (entities.Balance, error) {
+	repo.storage.mtx.RLock()
+	defer repo.storage.mtx.RUnlock()
+
+	current, ok := repo.storage.balances[userID]
+	if !ok {
+		return entities.Balance{}, entities.ErrUserNotFound
+	}
+
+	balance := entities.Balance{
+		UserID:  userID,
+		Current: current,
+	}
+
+	for _, change := range repo.storage.balanceLog {
+		if change.UserID != userID {
+			continue
+		}
+
+		switch change.Operation {
+		case entities.BalanceOperationWithdrawal:
+			balance.Withdrawn += change.Sum
+		case entities.BalanceOperationReversal:
+			balance.Withdrawn -= change.Sum
+		}
+	}
+
+	return balance, nil
+}
+
+func (repo *BalanceRepo) ChangeBalance(_ context.Context, change *entities.BalanceChange) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	current, ok := repo.storage.balances[change.UserID]
+	if !ok {
+		return entities.ErrUserNotFound
+	}
+
+	if change.Operation == entities.BalanceOperationWithdrawal {
+		current -= change.Sum
+	} else {
+		current += change.Sum
+	}
+
+	if current < 0 {
+		return entities.ErrNotEnoughFunds
+	}
+
+	if change.Operation == entities.BalanceOperationRefill {
+		if _, ok := repo.storage.refills[change.Order]; ok {
+			return entities.ErrOrderAlreadyCredited
+		}
+
+		repo.storage.refills[change.Order] = struct{}{}
+	}
+
+	repo.storage.balances[change.UserID] = current
+	repo.storage.appendBalanceLog(entities.BalanceChange{
+		UserID:    change.UserID,
+		Operation: change.Operation,
+		Order:     change.Order,
+		Sum:       change.Sum,
+		AdminID:   change.AdminID,
+		Reason:    change.Reason,
+		Reference: change.Reference,
+	})
+
+	return nil
+}
+
+// Отменяет последнее списание по заказу reversal.Order и заполняет остальные поля reversal.
+func (repo *BalanceRepo) ReverseWithdrawal(_ context.Context, reversal *entities.BalanceChange) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	var withdrawal *entities.BalanceChange
+
+	for i := len(repo.storage.balanceLog) - 1; i >= 0; i-- {
+		change := repo.storage.balanceLog[i]
+		if change.Operation == entities.BalanceOperationWithdrawal && change.Order == reversal.Order {
+			withdrawal = &change
+
+			break
+		}
+	}
+
+	if withdrawal == nil {
+		return entities.ErrWithdrawalNotFound
+	}
+
+	for _, change := range repo.storage.balanceLog {
+		if change.Operation == entities.BalanceOperationReversal && change.ReversalOf == withdrawal.ID {
+			return entities.ErrWithdrawalAlreadyReversed
+		}
+	}
+
+	reversal.UserID = withdrawal.UserID
+	reversal.Operation = entities.BalanceOperationReversal
+	reversal.Sum = withdrawal.Sum
+	reversal.ReversalOf = withdrawal.ID
+
+	repo.storage.balances[reversal.UserID] += reversal.Sum
+	repo.storage.appendBalanceLog(entities.BalanceChange{
+		UserID:     reversal.UserID,
+		Operation:  reversal.Operation,
+		Order:      reversal.Order,
+		Sum:        reversal.Sum,
+		AdminID:    reversal.AdminID,
+		ReversalOf: reversal.ReversalOf,
+	})
+
+	last := repo.storage.balanceLog[len(repo.storage.balanceLog)-1]
+	reversal.ID = last.ID
+	reversal.ProcessedAt = last.ProcessedAt
+
+	return nil
+}
+
+func (repo *BalanceRepo) Withdrawals(
+	_ context.Context, filter *entities.WithdrawalFilter,
+) ([]entities.BalanceChange, error) {
+	repo.storage.mtx.RLock()
+	defer repo.storage.mtx.RUnlock()
+
+	withdrawals := make([]entities.BalanceChange, 0)
+	reversed := make(map[int64]time.Time)
+
+	for _, change := range repo.storage.balanceLog {
+		if change.Operation == entities.BalanceOperationReversal {
+			reversed[change.ReversalOf] = change.ProcessedAt
+		}
+	}
+
+	for _, change := range repo.storage.balanceLog {
+		if change.UserID == filter.UserID && change.Operation == entities.BalanceOperationWithdrawal &&
+			inDateRange(change.ProcessedAt, filter.Processed) {
+			if reversedAt, ok := reversed[change.ID]; ok {
+				change.ReversedAt = &reversedAt
+			}
+
+			withdrawals = append(withdrawals, change)
+		}
+	}
+
+	return paginate(withdrawals, balanceChangeCursor, filter.Page), nil
+}
+
+// Возвращает записи журнала операций со счётом пользователя с остатком после каждой операции.
+func (repo *BalanceRepo) Statement(
+	_ context.Context, filter *entities.StatementFilter,
+) ([]entities.StatementEntry, error) {
+	repo.storage.mtx.RLock()
+	defer repo.storage.mtx.RUnlock()
+
+	var balance entities.Money
+
+	entries := make([]entities.StatementEntry, 0)
+
+	for _, change := range repo.storage.balanceLog {
+		if change.UserID != filter.UserID {
+			continue
+		}
+
+		if change.Operation == entities.BalanceOperationWithdrawal {
+			balance -= change.Sum
+		} else {
+			balance += change.Sum
+		}
+
+		if !inDateRange(change.ProcessedAt, filter.Processed) {
+			continue
+		}
+
+		entries = append(entries, entities.StatementEntry{
+			ID:          change.ID,
+			Operation:   change.Operation,
+			Order:       change.Order,
+			Sum:         change.Sum,
+			Balance:     balance,
+			ProcessedAt: change.ProcessedAt,
+			Reason:      change.Reason,
+			Reference:   change.Reference,
+		})
+	}
+
+	return paginate(entries, statementEntryCursor, filter.Page), nil
+}
+
+func statementEntryCursor(entry entities.StatementEntry) entities.Cursor {
+	return entities.Cursor{Time: entry.ProcessedAt, ID: entry.ID}
+}
+
+func balanceChangeCursor(change entities.BalanceChange) entities.Cursor {
+	return entities.Cursor{Time: change.ProcessedAt, ID: change.ID}
+}
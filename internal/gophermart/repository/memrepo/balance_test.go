@@ -0,0 +1,238 @@
+package memrepo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeBalance(t *testing.T) {
+	storage := NewStorage()
+	user := entities.User{Login: "user1"}
+
+	require.NoError(t, NewUserRepo(storage).AddUser(context.Background(), &user))
+
+	repo := NewBalanceRepo(storage)
+
+	err := repo.ChangeBalance(context.Background(), &entities.BalanceChange{
+		UserID:    user.ID,
+		Operation: entities.BalanceOperationRefill,
+		Order:     "4561261212345467",
+		Sum:       entities.NewMoney(500, 0),
+	})
+	require.NoError(t, err)
+
+	err = repo.ChangeBalance(context.Background(), &entities.BalanceChange{
+		UserID:    user.ID,
+		Operation: entities.BalanceOperationWithdrawal,
+		Order:     "12345678903",
+		Sum:       entities.NewMoney(200, 0),
+	})
+	require.NoError(t, err)
+
+	err = repo.ChangeBalance(context.Background(), &entities.BalanceChange{
+		UserID:    user.ID,
+		Operation: entities.BalanceOperationWithdrawal,
+		Order:     "12345678903",
+		Sum:       entities.NewMoney(1000, 0),
+	})
+	assert.ErrorIs(t, err, entities.ErrNotEnoughFunds)
+
+	balance, err := repo.Balance(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entities.NewMoney(300, 0), balance.Current)
+	assert.Equal(t, entities.NewMoney(200, 0), balance.Withdrawn)
+
+	withdrawals, err := repo.Withdrawals(
+		context.Background(), &entities.WithdrawalFilter{UserID: user.ID, Page: entities.Page{Limit: 10}},
+	)
+	require.NoError(t, err)
+	require.Len(t, withdrawals, 1)
+	assert.Equal(t, "12345678903", withdrawals[0].Order)
+}
+
+func TestBalanceAdjustment(t *testing.T) {
+	storage := NewStorage()
+	user := entities.User{Login: "user1"}
+
+	require.NoError(t, NewUserRepo(storage).AddUser(context.Background(), &user))
+
+	repo := NewBalanceRepo(storage)
+
+	err := repo.ChangeBalance(context.Background(), &entities.BalanceChange{
+		UserID:    user.ID,
+		Operation: entities.BalanceOperationAdjustment,
+		Sum:       entities.NewMoney(100, 0),
+		AdminID:   2,
+		Reason:    "Goodwill",
+		Reference: "SUP-1",
+	})
+	require.NoError(t, err)
+
+	err = repo.ChangeBalance(context.Background(), &entities.BalanceChange{
+		UserID:    user.ID,
+		Operation: entities.BalanceOperationAdjustment,
+		Sum:       -entities.NewMoney(150, 0),
+		AdminID:   2,
+		Reason:    "Fraudulent accrual",
+		Reference: "SUP-2",
+	})
+	assert.ErrorIs(t, err, entities.ErrNotEnoughFunds)
+
+	err = repo.ChangeBalance(context.Background(), &entities.BalanceChange{
+		UserID:    user.ID,
+		Operation: entities.BalanceOperationAdjustment,
+		Sum:       -entities.NewMoney(40, 0),
+		AdminID:   2,
+		Reason:    "Fraudulent accrual",
+		Reference: "SUP-3",
+	})
+	require.NoError(t, err)
+
+	balance, err := repo.Balance(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entities.NewMoney(60, 0), balance.Current)
+	assert.Zero(t, balance.Withdrawn)
+
+	entries, err := repo.Statement(
+		context.Background(), &entities.StatementFilter{UserID: user.ID, Page: entities.Page{Limit: 10}},
+	)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, entities.BalanceOperationAdjustment, entries[1].Operation)
+	assert.Equal(t, -entities.NewMoney(40, 0), entries[1].Sum)
+	assert.Equal(t, entities.NewMoney(60, 0), entries[1].Balance)
+	assert.Equal(t, "Fraudulent accrual", entries[1].Reason)
+	assert.Equal(t, "SUP-3", entries[1].Reference)
+}
+
+func TestReverseWithdrawal(t *testing.T) {
+	storage := NewStorage()
+	user := entities.User{Login: "user1"}
+
+	require.NoError(t, NewUserRepo(storage).AddUser(context.Background(), &user))
+
+	repo := NewBalanceRepo(storage)
+
+	for _, change := range []entities.BalanceChange{
+		{UserID: user.ID, Operation: entities.BalanceOperationRefill, Order: "2377225624", Sum: entities.NewMoney(100, 0)},
+		{UserID: user.ID, Operation: entities.BalanceOperationWithdrawal, Order: "4561261212345467", Sum: 3050},
+	} {
+		require.NoError(t, repo.ChangeBalance(context.Background(), &change))
+	}
+
+	err := repo.ReverseWithdrawal(context.Background(), &entities.BalanceChange{Order: "2377225624", AdminID: 2})
+	assert.ErrorIs(t, err, entities.ErrWithdrawalNotFound)
+
+	reversal := entities.BalanceChange{Order: "4561261212345467", AdminID: 2}
+
+	require.NoError(t, repo.ReverseWithdrawal(context.Background(), &reversal))
+	assert.Equal(t, user.ID, reversal.UserID)
+	assert.Equal(t, entities.BalanceOperationReversal, reversal.Operation)
+	assert.Equal(t, entities.Money(3050), reversal.Sum)
+	assert.Equal(t, int64(2), reversal.ReversalOf)
+
+	err = repo.ReverseWithdrawal(context.Background(), &entities.BalanceChange{Order: "4561261212345467", AdminID: 2})
+	assert.ErrorIs(t, err, entities.ErrWithdrawalAlreadyReversed)
+
+	balance, err := repo.Balance(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entities.NewMoney(100, 0), balance.Current)
+	assert.Zero(t, balance.Withdrawn)
+
+	withdrawals, err := repo.Withdrawals(
+		context.Background(), &entities.WithdrawalFilter{UserID: user.ID, Page: entities.Page{Limit: 10}},
+	)
+	require.NoError(t, err)
+	require.Len(t, withdrawals, 1)
+	require.NotNil(t, withdrawals[0].ReversedAt)
+	assert.Equal(t, reversal.ProcessedAt, *withdrawals[0].ReversedAt)
+
+	entries, err := repo.Statement(
+		context.Background(), &entities.StatementFilter{UserID: user.ID, Page: entities.Page{Limit: 10}},
+	)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, entities.BalanceOperationReversal, entries[2].Operation)
+	assert.Equal(t, entities.NewMoney(100, 0), entries[2].Balance)
+}
+
+func TestBalanceUnknownUser(t *testing.T) {
+	repo := NewBalanceRepo(NewStorage())
+
+	_, err := repo.Balance(context.Background(), 1)
+	assert.ErrorIs(t, err, entities.ErrUserNotFound)
+
+	err = repo.ChangeBalance(context.Background(), &entities.BalanceChange{
+		UserID:    1,
+		Operation: entities.BalanceOperationRefill,
+		Sum:       entities.NewMoney(1, 0),
+	})
+	assert.ErrorIs(t, err, entities.ErrUserNotFound)
+}
+
+func TestStatement(t *testing.T) {
+	storage := NewStorage()
+	user := entities.User{Login: "user1"}
+	other := entities.User{Login: "user2"}
+
+	require.NoError(t, NewUserRepo(storage).AddUser(context.Background(), &user))
+	require.NoError(t, NewUserRepo(storage).AddUser(context.Background(), &other))
+
+	repo := NewBalanceRepo(storage)
+	changes := []entities.BalanceChange{
+		{
+			UserID:    user.ID,
+			Operation: entities.BalanceOperationRefill,
+			Order:     "4561261212345467",
+			Sum:       entities.NewMoney(500, 0),
+		},
+		{
+			UserID:    other.ID,
+			Operation: entities.BalanceOperationRefill,
+			Order:     "2377225624",
+			Sum:       entities.NewMoney(100, 0),
+		},
+		{
+			UserID:    user.ID,
+			Operation: entities.BalanceOperationWithdrawal,
+			Order:     "12345678903",
+			Sum:       entities.NewMoney(200, 50),
+		},
+		{
+			UserID:    user.ID,
+			Operation: entities.BalanceOperationRefill,
+			Order:     "4861261212345464",
+			Sum:       entities.NewMoney(50, 0),
+		},
+	}
+
+	for i := range changes {
+		require.NoError(t, repo.ChangeBalance(context.Background(), &changes[i]))
+	}
+
+	entries, err := repo.Statement(
+		context.Background(), &entities.StatementFilter{UserID: user.ID, Page: entities.Page{Limit: 10}},
+	)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, entities.NewMoney(500, 0), entries[0].Balance)
+	assert.Equal(t, entities.NewMoney(299, 50), entries[1].Balance)
+	assert.Equal(t, entities.NewMoney(349, 50), entries[2].Balance)
+
+	entries, err = repo.Statement(context.Background(), &entities.StatementFilter{
+		UserID: user.ID,
+		Page:   entities.Page{Limit: 1, Descending: true},
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "4861261212345464", entries[0].Order)
+	assert.Equal(t, entities.NewMoney(349, 50), entries[0].Balance)
+
+	balance, err := repo.Balance(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, balance.Current, entries[0].Balance)
+}
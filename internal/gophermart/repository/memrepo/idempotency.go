@@ -0,0 +1,82 @@
+package memrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+)
+
+type IdempotencyRepo struct {
+	storage *Storage
+}
+
+func NewIdempotencyRepo(storage *Storage) *IdempotencyRepo {
+	return &IdempotencyRepo{storage: storage}
+}
+
+func (repo *IdempotencyRepo) AddIdempotencyKey(
+	_ context.Context, key *entities.IdempotencyKey,
+) (entities.IdempotencyKey, bool, error) {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	now := time.Now()
+	id := idempotencyKeyID{userID: key.UserID, key: key.Key}
+
+	stored, ok := repo.storage.idempotencyKeys[id]
+	if ok && !stored.ExpiresAt.Before(now) && !stored.Reclaimable(key.RequestHash, now) {
+		return stored, true, nil
+	}
+
+	repo.storage.idempotencyKeys[id] = *key
+
+	return entities.IdempotencyKey{}, false, nil
+}
+
+func (repo *IdempotencyRepo) SaveIdempotentResponse(_ context.Context, key *entities.IdempotencyKey) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	id := idempotencyKeyID{userID: key.UserID, key: key.Key}
+
+	stored, ok := repo.storage.idempotencyKeys[id]
+	if !ok || stored.Completed() {
+		return nil
+	}
+
+	stored.Status = key.Status
+	stored.ContentType = key.ContentType
+	stored.Body = key.Body
+	repo.storage.idempotencyKeys[id] = stored
+
+	return nil
+}
+
+func (repo *IdempotencyRepo) DeleteIdempotencyKey(_ context.Context, key *entities.IdempotencyKey) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	id := idempotencyKeyID{userID: key.UserID, key: key.Key}
+
+	if stored, ok := repo.storage.idempotencyKeys[id]; ok && !stored.Completed() {
+		delete(repo.storage.idempotencyKeys, id)
+	}
+
+	return nil
+}
+
+func (repo *IdempotencyRepo) DeleteExpiredIdempotencyKeys(_ context.Context) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	now := time.Now()
+
+	for id, stored := range repo.storage.idempotencyKeys {
+		if stored.ExpiresAt.Before(now) {
+			delete(repo.storage.idempotencyKeys, id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package memrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+)
+
+type LoginAttemptRepo struct {
+	storage *Storage
+}
+
+func NewLoginAttemptRepo(storage *Storage) *LoginAttemptRepo {
+	return &LoginAttemptRepo{storage: storage}
+}
+
+func (repo *LoginAttemptRepo) ReserveLoginAttempt(
+	_ context.Context, attempt *entities.LoginAttempt, policy entities.LoginPolicy,
+) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	failures := repo.storage.loginAttempts[attempt.Key]
+
+	err := policy.Reserve(&failures, attempt.MaxAttempts, time.Now())
+	if err != nil {
+		return err
+	}
+
+	repo.storage.loginAttempts[attempt.Key] = failures
+	attempt.LockedUntil = failures.LockedUntil
+
+	return nil
+}
+
+func (repo *LoginAttemptRepo) ReleaseLoginAttempt(_ context.Context, attempt *entities.LoginAttempt) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	failures, ok := repo.storage.loginAttempts[attempt.Key]
+	if !ok {
+		return nil
+	}
+
+	failures.Release(attempt.LockedUntil)
+	repo.storage.loginAttempts[attempt.Key] = failures
+
+	return nil
+}
+
+func (repo *LoginAttemptRepo) ResetLoginFailures(_ context.Context, key string) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	delete(repo.storage.loginAttempts, key)
+
+	return nil
+}
+
+func (repo *LoginAttemptRepo) DeleteStaleLoginAttempts(_ context.Context, window time.Duration) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	now := time.Now()
+	expired := now.Add(-window)
+
+	for key, failures := range repo.storage.loginAttempts {
+		if failures.LastFailure.Before(expired) && failures.LockedUntil.Before(now) {
+			delete(repo.storage.loginAttempts, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package memrepo
+
+import (
+	"sync"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+)
+
+// Хранилище данных в оперативной памяти, разделяемое репозиториями пакета.
+// Соблюдает те же ограничения, что и схема БД: уникальность логинов
+// и номеров заказов, неотрицательность баланса пользователя.
+type Storage struct {
+	mtx sync.RWMutex
+
+	lastUserID int64
+	users      map[string]entities.User
+	balances   map[int64]entities.Money
+	balanceLog []entities.BalanceChange
+	refills    map[string]struct{}
+	orders     []entities.Order
+	orderIdx   map[string]int
+	claims     map[string]orderClaim
+
+	refreshTokens map[string]entities.RefreshToken
+	revokedTokens map[string]time.Time
+
+	loginAttempts map[string]entities.LoginFailures
+
+	resetTokens map[string]entities.ResetToken
+
+	idempotencyKeys map[idempotencyKeyID]entities.IdempotencyKey
+
+	lastWebhookID  int64
+	webhooks       []entities.Webhook
+	deliveries     []webhookDelivery
+	deliveryClaims map[int64]orderClaim
+	lastDeliveryID int64
+}
+
+type orderClaim struct {
+	instance string
+	until    time.Time
+}
+
+// Ключи идемпотентности уникальны в пределах пользователя.
+type idempotencyKeyID struct {
+	userID int64
+	key    string
+}
+
+func NewStorage() *Storage {
+	return &Storage{
+		users:      make(map[string]entities.User),
+		balances:   make(map[int64]entities.Money),
+		balanceLog: make([]entities.BalanceChange, 0),
+		refills:    make(map[string]struct{}),
+		orders:     make([]entities.Order, 0),
+		orderIdx:   make(map[string]int),
+		claims:     make(map[string]orderClaim),
+
+		refreshTokens: make(map[string]entities.RefreshToken),
+		revokedTokens: make(map[string]time.Time),
+
+		loginAttempts: make(map[string]entities.LoginFailures),
+
+		resetTokens: make(map[string]entities.ResetToken),
+
+		idempotencyKeys: make(map[idempotencyKeyID]entities.IdempotencyKey),
+
+		webhooks:       make([]entities.Webhook, 0),
+		deliveries:     make([]webhookDelivery, 0),
+		deliveryClaims: make(map[int64]orderClaim),
+	}
+}
+
+// Добавляет запись в журнал операций со счётом и уведомление об изменении баланса
+// в очередь доставки. Вызывается под блокировкой на запись после изменения баланса.
+func (s *Storage) appendBalanceLog(change entities.BalanceChange) {
+	change.ID = int64(len(s.balanceLog) + 1)
+	change.ProcessedAt = time.Now()
+
+	s.balanceLog = append(s.balanceLog, change)
+
+	s.enqueueWebhookEvent(change.UserID, entities.NewWebhookEvent(
+		entities.WebhookEventBalanceChanged,
+		entities.BalanceEvent{
+			Operation:   change.Operation,
+			Order:       change.Order,
+			Sum:         change.Sum,
+			Current:     s.balances[change.UserID],
+			ProcessedAt: change.ProcessedAt,
+		},
+	))
+}
+
+// Возвращает страницу записей items, упорядоченных по возрастанию ключа key.
+func paginate[T any](items []T, key func(T) entities.Cursor, page entities.Page) []T {
+	result := make([]T, 0)
+
+	for i := range items {
+		item := items[i]
+		if page.Descending {
+			item = items[len(items)-1-i]
+		}
+
+		if page.Cursor != nil {
+			cursor := key(item)
+			if !page.Descending && !cursorLess(*page.Cursor, cursor) ||
+				page.Descending && !cursorLess(cursor, *page.Cursor) {
+				continue
+			}
+		}
+
+		if uint(len(result)) >= page.Limit {
+			break
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
+
+func cursorLess(a, b entities.Cursor) bool {
+	if a.Time.Equal(b.Time) {
+		return a.ID < b.ID
+	}
+
+	return a.Time.Before(b.Time)
+}
+
+func inDateRange(ts time.Time, dateRange entities.DateRange) bool {
+	if !dateRange.From.IsZero() && ts.Before(dateRange.From) {
+		return false
+	}
+
+	if !dateRange.To.IsZero() && ts.After(dateRange.To) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,223 @@
+package memrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+)
+
+type OrderRepo struct {
+	storage *Storage
+}
+
+func NewOrderRepo(storage *Storage) *OrderRepo {
+	return &OrderRepo{storage: storage}
+}
+
+func (repo *OrderRepo) AddOrder(_ context.Context, order *entities.Order) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	if idx, ok := repo.storage.orderIdx[order.Number]; ok {
+		if repo.storage.orders[idx].UserID == order.UserID {
+			return entities.ErrOrderAlreadyAdded
+		}
+
+		return entities.ErrOrderAddedByOther
+	}
+
+	repo.storage.orders = append(repo.storage.orders, entities.Order{
+		ID:         int64(len(repo.storage.orders) + 1),
+		UserID:     order.UserID,
+		Number:     order.Number,
+		Status:     order.Status,
+		UploadedAt: time.Now(),
+	})
+	repo.storage.orderIdx[order.Number] = len(repo.storage.orders) - 1
+
+	return nil
+}
+
+func (repo *OrderRepo) Orders(_ context.Context, filter *entities.OrderFilter) ([]entities.Order, error) {
+	repo.storage.mtx.RLock()
+	defer repo.storage.mtx.RUnlock()
+
+	orders := make([]entities.Order, 0)
+
+	for _, order := range repo.storage.orders {
+		if order.UserID != filter.UserID || !inDateRange(order.UploadedAt, filter.Uploaded) {
+			continue
+		}
+
+		if len(filter.Statuses) != 0 && !containsStatus(filter.Statuses, order.Status) {
+			continue
+		}
+
+		orders = append(orders, order)
+	}
+
+	return paginate(orders, orderCursor, filter.Page), nil
+}
+
+func (repo *OrderRepo) ProcessableOrders(
+	_ context.Context, instance string, limit uint, lease time.Duration,
+) ([]entities.Order, error) {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	now := time.Now()
+	orders := make([]entities.Order, 0)
+
+	for _, order := range repo.storage.orders {
+		if uint(len(orders)) >= limit {
+			break
+		}
+
+		if !isProcessable(order.Status) || order.NextPollAt.After(now) {
+			continue
+		}
+
+		claim, ok := repo.storage.claims[order.Number]
+		if ok && claim.instance != instance && claim.until.After(now) {
+			continue
+		}
+
+		repo.storage.claims[order.Number] = orderClaim{
+			instance: instance,
+			until:    now.Add(lease),
+		}
+
+		orders = append(orders, entities.Order{
+			UserID:     order.UserID,
+			Number:     order.Number,
+			Status:     order.Status,
+			UploadedAt: order.UploadedAt,
+			Attempts:   order.Attempts,
+		})
+	}
+
+	return orders, nil
+}
+
+func (repo *OrderRepo) UpdateOrder(_ context.Context, instance string, order *entities.Order) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	idx, ok := repo.storage.orderIdx[order.Number]
+	if !ok || !isProcessable(repo.storage.orders[idx].Status) || !repo.claimedBy(order.Number, instance) {
+		// Заказ уже обработан либо захвачен другим экземпляром
+		return entities.ErrOrderLeaseLost
+	}
+
+	stored := &repo.storage.orders[idx]
+	if stored.Status != order.Status {
+		repo.storage.enqueueWebhookEvent(stored.UserID, entities.NewWebhookEvent(
+			entities.WebhookEventOrderStatusChanged,
+			entities.OrderStatusEvent{
+				Number:         order.Number,
+				Status:         order.Status,
+				PreviousStatus: stored.Status,
+				Accrual:        order.Accrual,
+			},
+		))
+	}
+
+	repo.storage.orders[idx].Status = order.Status
+	repo.storage.orders[idx].Accrual = order.Accrual
+	repo.storage.orders[idx].Attempts = order.Attempts
+	repo.storage.orders[idx].LastError = order.LastError
+	repo.storage.orders[idx].NextPollAt = order.NextPollAt
+
+	delete(repo.storage.claims, order.Number)
+
+	return nil
+}
+
+func (repo *OrderRepo) ProcessOrder(_ context.Context, instance string, order *entities.Order) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	idx, ok := repo.storage.orderIdx[order.Number]
+	if !ok || !isProcessable(repo.storage.orders[idx].Status) || !repo.claimedBy(order.Number, instance) {
+		// Заказ уже обработан либо захвачен другим экземпляром
+		return entities.ErrOrderLeaseLost
+	}
+
+	stored := &repo.storage.orders[idx]
+	repo.storage.enqueueWebhookEvent(stored.UserID, entities.NewWebhookEvent(
+		entities.WebhookEventOrderStatusChanged,
+		entities.OrderStatusEvent{
+			Number:         order.Number,
+			Status:         entities.OrderStatusProcessed,
+			PreviousStatus: stored.Status,
+			Accrual:        order.Accrual,
+		},
+	))
+
+	stored.Status = entities.OrderStatusProcessed
+	stored.Accrual = order.Accrual
+	stored.LastError = ""
+	stored.NextPollAt = time.Time{}
+
+	delete(repo.storage.claims, order.Number)
+
+	if _, ok := repo.storage.refills[order.Number]; ok {
+		return nil
+	}
+
+	repo.storage.refills[order.Number] = struct{}{}
+	repo.storage.balances[stored.UserID] += order.Accrual
+	repo.storage.appendBalanceLog(entities.BalanceChange{
+		UserID:    stored.UserID,
+		Operation: entities.BalanceOperationRefill,
+		Order:     order.Number,
+		Sum:       order.Accrual,
+	})
+
+	return nil
+}
+
+func (repo *OrderRepo) PendingOrders(_ context.Context) (entities.PendingOrders, error) {
+	repo.storage.mtx.RLock()
+	defer repo.storage.mtx.RUnlock()
+
+	var pending entities.PendingOrders
+
+	for _, order := range repo.storage.orders {
+		switch order.Status {
+		case entities.OrderStatusNew:
+			pending.New++
+		case entities.OrderStatusProcessing:
+			pending.Processing++
+		}
+	}
+
+	pending.Total = pending.New + pending.Processing
+
+	return pending, nil
+}
+
+func (repo *OrderRepo) claimedBy(number string, instance string) bool {
+	claim, ok := repo.storage.claims[number]
+
+	return ok && claim.instance == instance
+}
+
+func isProcessable(status string) bool {
+	return status == entities.OrderStatusNew || status == entities.OrderStatusProcessing
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, st := range statuses {
+		if st == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+func orderCursor(order entities.Order) entities.Cursor {
+	return entities.Cursor{Time: order.UploadedAt, ID: order.ID}
+}
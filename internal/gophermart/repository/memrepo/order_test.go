@@ -0,0 +1,257 @@
+package memrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddOrder(t *testing.T) {
+	repo := NewOrderRepo(NewStorage())
+
+	require.NoError(t, repo.AddOrder(context.Background(), entities.NewOrder("4561261212345467", 1)))
+
+	assert.ErrorIs(
+		t, repo.AddOrder(context.Background(), entities.NewOrder("4561261212345467", 1)),
+		entities.ErrOrderAlreadyAdded,
+	)
+	assert.ErrorIs(
+		t, repo.AddOrder(context.Background(), entities.NewOrder("4561261212345467", 2)),
+		entities.ErrOrderAddedByOther,
+	)
+}
+
+func TestOrders(t *testing.T) {
+	repo := NewOrderRepo(NewStorage())
+
+	require.NoError(t, repo.AddOrder(context.Background(), entities.NewOrder("4561261212345467", 1)))
+	require.NoError(t, repo.AddOrder(context.Background(), entities.NewOrder("12345678903", 2)))
+
+	orders, err := repo.Orders(context.Background(), &entities.OrderFilter{UserID: 1, Page: entities.Page{Limit: 10}})
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "4561261212345467", orders[0].Number)
+
+	orders, err = repo.Orders(context.Background(), &entities.OrderFilter{UserID: 3, Page: entities.Page{Limit: 10}})
+	require.NoError(t, err)
+	assert.Empty(t, orders)
+}
+
+func TestPendingOrders(t *testing.T) {
+	repo := NewOrderRepo(NewStorage())
+
+	require.NoError(t, repo.AddOrder(context.Background(), entities.NewOrder("4561261212345467", 1)))
+	require.NoError(t, repo.AddOrder(context.Background(), entities.NewOrder("12345678903", 2)))
+	require.NoError(t, repo.AddOrder(context.Background(), entities.NewOrder("2377225624", 2)))
+
+	_, err := repo.ProcessableOrders(context.Background(), "instance", 10, time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.UpdateOrder(context.Background(), "instance", &entities.Order{
+		Number: "12345678903",
+		Status: entities.OrderStatusProcessing,
+	}))
+	require.NoError(t, repo.UpdateOrder(context.Background(), "instance", &entities.Order{
+		Number:  "2377225624",
+		Status:  entities.OrderStatusProcessed,
+		Accrual: entities.NewMoney(10, 0),
+	}))
+
+	pending, err := repo.PendingOrders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, entities.PendingOrders{New: 1, Processing: 1, Total: 2}, pending)
+}
+
+func TestProcessableOrdersAndUpdateOrder(t *testing.T) {
+	repo := NewOrderRepo(NewStorage())
+
+	require.NoError(t, repo.AddOrder(context.Background(), entities.NewOrder("4561261212345467", 1)))
+	require.NoError(t, repo.AddOrder(context.Background(), entities.NewOrder("12345678903", 1)))
+
+	orders, err := repo.ProcessableOrders(context.Background(), "instance", 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+
+	err = repo.UpdateOrder(context.Background(), "instance", &entities.Order{
+		Number:  "12345678903",
+		Status:  entities.OrderStatusProcessed,
+		Accrual: entities.NewMoney(500, 0),
+	})
+	require.NoError(t, err)
+
+	orders, err = repo.ProcessableOrders(context.Background(), "instance", 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "4561261212345467", orders[0].Number)
+
+	orders, err = repo.Orders(context.Background(), &entities.OrderFilter{UserID: 1, Page: entities.Page{Limit: 10}})
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.Equal(t, entities.OrderStatusProcessed, orders[1].Status)
+	assert.Equal(t, entities.NewMoney(500, 0), orders[1].Accrual)
+}
+
+func TestProcessOrder(t *testing.T) {
+	storage := NewStorage()
+	user := entities.User{Login: "user1"}
+
+	require.NoError(t, NewUserRepo(storage).AddUser(context.Background(), &user))
+
+	repo := NewOrderRepo(storage)
+	require.NoError(t, repo.AddOrder(context.Background(), entities.NewOrder("4561261212345467", user.ID)))
+
+	order := entities.Order{
+		Number:  "4561261212345467",
+		Status:  entities.OrderStatusProcessed,
+		Accrual: entities.NewMoney(500, 0),
+	}
+
+	_, err := repo.ProcessableOrders(context.Background(), "instance", 10, time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.ProcessOrder(context.Background(), "instance", &order))
+	assert.ErrorIs(t, repo.ProcessOrder(context.Background(), "instance", &order), entities.ErrOrderLeaseLost)
+
+	balance, err := NewBalanceRepo(storage).Balance(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entities.NewMoney(500, 0), balance.Current)
+
+	err = NewBalanceRepo(storage).ChangeBalance(context.Background(), &entities.BalanceChange{
+		UserID:    user.ID,
+		Operation: entities.BalanceOperationRefill,
+		Order:     "4561261212345467",
+		Sum:       entities.NewMoney(500, 0),
+	})
+	assert.ErrorIs(t, err, entities.ErrOrderAlreadyCredited)
+}
+
+func TestProcessableOrdersClaim(t *testing.T) {
+	repo := NewOrderRepo(NewStorage())
+
+	for _, number := range []string{"4561261212345467", "12345678903", "2377225624"} {
+		require.NoError(t, repo.AddOrder(context.Background(), entities.NewOrder(number, 1)))
+	}
+
+	first, err := repo.ProcessableOrders(context.Background(), "first", 2, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+
+	second, err := repo.ProcessableOrders(context.Background(), "second", 2, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	assert.Equal(t, "2377225624", second[0].Number)
+
+	third, err := repo.ProcessableOrders(context.Background(), "third", 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, third)
+}
+
+func TestProcessableOrdersLeaseExpiration(t *testing.T) {
+	repo := NewOrderRepo(NewStorage())
+
+	require.NoError(t, repo.AddOrder(context.Background(), entities.NewOrder("4561261212345467", 1)))
+
+	crashed, err := repo.ProcessableOrders(context.Background(), "crashed", 10, time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, crashed, 1)
+
+	time.Sleep(2 * time.Millisecond)
+
+	alive, err := repo.ProcessableOrders(context.Background(), "alive", 10, time.Minute)
+	require.NoError(t, err)
+	assert.Len(t, alive, 1)
+
+	// Экземпляр, потерявший аренду, не может перезаписать результат нового владельца
+	err = repo.UpdateOrder(context.Background(), "crashed", &entities.Order{
+		Number: "4561261212345467",
+		Status: entities.OrderStatusInvalid,
+	})
+	assert.ErrorIs(t, err, entities.ErrOrderLeaseLost)
+
+	err = repo.ProcessOrder(context.Background(), "crashed", &entities.Order{
+		Number:  "4561261212345467",
+		Accrual: entities.NewMoney(500, 0),
+	})
+	assert.ErrorIs(t, err, entities.ErrOrderLeaseLost)
+
+	require.NoError(t, repo.ProcessOrder(context.Background(), "alive", &entities.Order{
+		Number:  "4561261212345467",
+		Accrual: entities.NewMoney(100, 0),
+	}))
+}
+
+func TestProcessableOrdersNextPoll(t *testing.T) {
+	repo := NewOrderRepo(NewStorage())
+
+	require.NoError(t, repo.AddOrder(context.Background(), entities.NewOrder("4561261212345467", 1)))
+
+	_, err := repo.ProcessableOrders(context.Background(), "instance", 10, time.Minute)
+	require.NoError(t, err)
+
+	err = repo.UpdateOrder(context.Background(), "instance", &entities.Order{
+		Number:     "4561261212345467",
+		Status:     entities.OrderStatusProcessing,
+		Attempts:   1,
+		LastError:  "accrual status: PROCESSING",
+		NextPollAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	orders, err := repo.ProcessableOrders(context.Background(), "instance", 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, orders)
+}
+
+func TestOrdersPagination(t *testing.T) {
+	repo := NewOrderRepo(NewStorage())
+	numbers := []string{"4561261212345467", "12345678903", "2377225624"}
+
+	for _, number := range numbers {
+		require.NoError(t, repo.AddOrder(context.Background(), entities.NewOrder(number, 1)))
+	}
+
+	filter := entities.OrderFilter{UserID: 1, Page: entities.Page{Limit: 2}}
+
+	page, err := repo.Orders(context.Background(), &filter)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, numbers[:2], []string{page[0].Number, page[1].Number})
+
+	filter.Cursor = &entities.Cursor{Time: page[1].UploadedAt, ID: page[1].ID}
+
+	page, err = repo.Orders(context.Background(), &filter)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, numbers[2], page[0].Number)
+
+	filter = entities.OrderFilter{UserID: 1, Page: entities.Page{Limit: 10, Descending: true}}
+
+	page, err = repo.Orders(context.Background(), &filter)
+	require.NoError(t, err)
+	require.Len(t, page, 3)
+	assert.Equal(t, numbers[2], page[0].Number)
+
+	filter = entities.OrderFilter{
+		UserID:   1,
+		Statuses: []string{entities.OrderStatusProcessed},
+		Page:     entities.Page{Limit: 10},
+	}
+
+	page, err = repo.Orders(context.Background(), &filter)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+
+	filter = entities.OrderFilter{
+		UserID:   1,
+		Uploaded: entities.DateRange{From: time.Now().Add(time.Hour)},
+		Page:     entities.Page{Limit: 10},
+	}
+
+	page, err = repo.Orders(context.Background(), &filter)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+}
@@ -0,0 +1,41 @@
+package memrepo
+
+import (
+	"context"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+)
+
+type StatsRepo struct {
+	storage *Storage
+}
+
+func NewStatsRepo(storage *Storage) *StatsRepo {
+	return &StatsRepo{storage: storage}
+}
+
+func (repo *StatsRepo) Stats(_ context.Context) (entities.Stats, error) {
+	repo.storage.mtx.RLock()
+	defer repo.storage.mtx.RUnlock()
+
+	var stats entities.Stats
+
+	for _, order := range repo.storage.orders {
+		if order.Status == entities.OrderStatusNew || order.Status == entities.OrderStatusProcessing {
+			stats.PendingOrders++
+		}
+	}
+
+	for _, change := range repo.storage.balanceLog {
+		switch change.Operation {
+		case entities.BalanceOperationRefill:
+			stats.Accrued += change.Sum
+		case entities.BalanceOperationWithdrawal:
+			stats.Withdrawn += change.Sum
+		case entities.BalanceOperationReversal:
+			stats.Withdrawn -= change.Sum
+		}
+	}
+
+	return stats, nil
+}
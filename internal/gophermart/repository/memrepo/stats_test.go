@@ -0,0 +1,40 @@
+package memrepo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	storage := NewStorage()
+	user := entities.User{Login: "user1"}
+
+	require.NoError(t, NewUserRepo(storage).AddUser(context.Background(), &user))
+
+	orders := NewOrderRepo(storage)
+	require.NoError(t, orders.AddOrder(context.Background(), entities.NewOrder("4561261212345467", user.ID)))
+	require.NoError(t, orders.AddOrder(context.Background(), entities.NewOrder("12345678903", user.ID)))
+
+	balance := NewBalanceRepo(storage)
+
+	for _, change := range []entities.BalanceChange{
+		{Operation: entities.BalanceOperationRefill, Order: "4561261212345467", Sum: entities.NewMoney(500, 50)},
+		{Operation: entities.BalanceOperationWithdrawal, Order: "2377225624", Sum: entities.NewMoney(200, 0)},
+	} {
+		change := change
+		change.UserID = user.ID
+
+		require.NoError(t, balance.ChangeBalance(context.Background(), &change))
+	}
+
+	stats, err := NewStatsRepo(storage).Stats(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), stats.PendingOrders)
+	assert.Equal(t, entities.NewMoney(500, 50), stats.Accrued)
+	assert.Equal(t, entities.NewMoney(200, 0), stats.Withdrawn)
+}
@@ -0,0 +1,155 @@
+package memrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+)
+
+type TokenRepo struct {
+	storage *Storage
+}
+
+func NewTokenRepo(storage *Storage) *TokenRepo {
+	return &TokenRepo{storage: storage}
+}
+
+func (repo *TokenRepo) AddRefreshToken(_ context.Context, token *entities.RefreshToken) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	now := time.Now()
+
+	for hash, stored := range repo.storage.refreshTokens {
+		if stored.UserID == token.UserID && stored.ExpiresAt.Before(now) {
+			delete(repo.storage.refreshTokens, hash)
+		}
+	}
+
+	repo.storage.refreshTokens[token.Hash] = *token
+
+	return nil
+}
+
+func (repo *TokenRepo) UseRefreshToken(_ context.Context, token *entities.RefreshToken) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	stored, ok := repo.storage.refreshTokens[token.Hash]
+	if !ok || !stored.RevokedAt.IsZero() {
+		return entities.ErrInvalidToken
+	}
+
+	if !stored.UsedAt.IsZero() {
+		token.UserID = stored.UserID
+		token.Family = stored.Family
+
+		return entities.ErrRefreshTokenReused
+	}
+
+	if !stored.ExpiresAt.After(time.Now()) {
+		return entities.ErrInvalidToken
+	}
+
+	stored.UsedAt = time.Now()
+	repo.storage.refreshTokens[token.Hash] = stored
+	*token = stored
+
+	for _, user := range repo.storage.users {
+		if user.ID == stored.UserID {
+			token.Role = user.Role
+
+			break
+		}
+	}
+
+	return nil
+}
+
+func (repo *TokenRepo) RevokeFamily(_ context.Context, family string) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	repo.storage.revokeFamily(family)
+
+	return nil
+}
+
+func (repo *TokenRepo) RevokeSession(_ context.Context, token *entities.AccessToken) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	repo.storage.revokedTokens[token.ID] = token.ExpiresAt
+
+	for _, stored := range repo.storage.refreshTokens {
+		if stored.AccessTokenID == token.ID {
+			repo.storage.revokeFamily(stored.Family)
+
+			break
+		}
+	}
+
+	return nil
+}
+
+func (repo *TokenRepo) RevokeUser(_ context.Context, userID int64) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	repo.storage.revokeUser(userID)
+
+	return nil
+}
+
+func (repo *TokenRepo) AccessTokenRevoked(_ context.Context, tokenID string) (bool, error) {
+	repo.storage.mtx.RLock()
+	defer repo.storage.mtx.RUnlock()
+
+	_, ok := repo.storage.revokedTokens[tokenID]
+
+	return ok, nil
+}
+
+// Отзывает семейство токенов и удаляет записи об истёкших отозванных токенах доступа.
+// Вызывается под блокировкой на запись.
+func (s *Storage) revokeFamily(family string) {
+	now := time.Now()
+
+	for hash, stored := range s.refreshTokens {
+		if stored.Family != family {
+			continue
+		}
+
+		if stored.RevokedAt.IsZero() {
+			stored.RevokedAt = now
+			s.refreshTokens[hash] = stored
+		}
+
+		if stored.AccessExpiresAt.After(now) {
+			s.revokedTokens[stored.AccessTokenID] = stored.AccessExpiresAt
+		}
+	}
+
+	for tokenID, expires := range s.revokedTokens {
+		if expires.Before(now) {
+			delete(s.revokedTokens, tokenID)
+		}
+	}
+}
+
+// Отзывает все семейства токенов пользователя userID.
+// Вызывается под блокировкой на запись.
+func (s *Storage) revokeUser(userID int64) {
+	families := make(map[string]struct{})
+
+	for _, stored := range s.refreshTokens {
+		if stored.UserID == userID {
+			families[stored.Family] = struct{}{}
+		}
+	}
+
+	for family := range families {
+		s.revokeFamily(family)
+	}
+}
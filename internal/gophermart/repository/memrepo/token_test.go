@@ -0,0 +1,130 @@
+package memrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshTokenRotation(t *testing.T) {
+	storage := NewStorage()
+	repo := NewTokenRepo(storage)
+	now := time.Now()
+
+	user := entities.User{Login: "user1", Role: entities.RoleAdmin}
+	require.NoError(t, NewUserRepo(storage).AddUser(context.Background(), &user))
+
+	first := entities.RefreshToken{
+		Hash:            "hash1",
+		UserID:          1,
+		Family:          "family",
+		AccessTokenID:   "access1",
+		AccessExpiresAt: now.Add(time.Minute),
+		IssuedAt:        now,
+		ExpiresAt:       now.Add(time.Hour),
+	}
+	second := first
+	second.Hash = "hash2"
+	second.AccessTokenID = "access2"
+
+	require.NoError(t, repo.AddRefreshToken(context.Background(), &first))
+
+	used := entities.RefreshToken{Hash: "hash1"}
+	require.NoError(t, repo.UseRefreshToken(context.Background(), &used))
+	assert.Equal(t, int64(1), used.UserID)
+	assert.Equal(t, "family", used.Family)
+	assert.Equal(t, entities.RoleAdmin, used.Role)
+
+	require.NoError(t, repo.AddRefreshToken(context.Background(), &second))
+
+	reused := entities.RefreshToken{Hash: "hash1"}
+	err := repo.UseRefreshToken(context.Background(), &reused)
+	require.ErrorIs(t, err, entities.ErrRefreshTokenReused)
+	assert.Equal(t, "family", reused.Family)
+
+	require.NoError(t, repo.RevokeFamily(context.Background(), reused.Family))
+
+	err = repo.UseRefreshToken(context.Background(), &entities.RefreshToken{Hash: "hash2"})
+	assert.ErrorIs(t, err, entities.ErrInvalidToken)
+
+	for _, tokenID := range []string{"access1", "access2"} {
+		revoked, err := repo.AccessTokenRevoked(context.Background(), tokenID)
+		require.NoError(t, err)
+		assert.True(t, revoked, tokenID)
+	}
+
+	err = repo.UseRefreshToken(context.Background(), &entities.RefreshToken{Hash: "unknown"})
+	assert.ErrorIs(t, err, entities.ErrInvalidToken)
+}
+
+func TestRevokeSession(t *testing.T) {
+	repo := NewTokenRepo(NewStorage())
+	now := time.Now()
+
+	token := entities.RefreshToken{
+		Hash:            "hash1",
+		UserID:          1,
+		Family:          "family",
+		AccessTokenID:   "access1",
+		AccessExpiresAt: now.Add(time.Minute),
+		IssuedAt:        now,
+		ExpiresAt:       now.Add(time.Hour),
+	}
+
+	require.NoError(t, repo.AddRefreshToken(context.Background(), &token))
+
+	err := repo.RevokeSession(context.Background(), &entities.AccessToken{
+		ID:        "access1",
+		UserID:    1,
+		ExpiresAt: token.AccessExpiresAt,
+	})
+	require.NoError(t, err)
+
+	revoked, err := repo.AccessTokenRevoked(context.Background(), "access1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	err = repo.UseRefreshToken(context.Background(), &entities.RefreshToken{Hash: "hash1"})
+	assert.ErrorIs(t, err, entities.ErrInvalidToken)
+}
+
+func TestRevokeUser(t *testing.T) {
+	repo := NewTokenRepo(NewStorage())
+	now := time.Now()
+
+	tokens := []entities.RefreshToken{
+		{Hash: "hash1", UserID: 1, Family: "family1", AccessTokenID: "access1"},
+		{Hash: "hash2", UserID: 1, Family: "family2", AccessTokenID: "access2"},
+		{Hash: "hash3", UserID: 2, Family: "family3", AccessTokenID: "access3"},
+	}
+
+	for i := range tokens {
+		tokens[i].AccessExpiresAt = now.Add(time.Minute)
+		tokens[i].IssuedAt = now
+		tokens[i].ExpiresAt = now.Add(time.Hour)
+
+		require.NoError(t, repo.AddRefreshToken(context.Background(), &tokens[i]))
+	}
+
+	require.NoError(t, repo.RevokeUser(context.Background(), 1))
+
+	for _, tokenID := range []string{"access1", "access2"} {
+		revoked, err := repo.AccessTokenRevoked(context.Background(), tokenID)
+		require.NoError(t, err)
+		assert.True(t, revoked, tokenID)
+	}
+
+	revoked, err := repo.AccessTokenRevoked(context.Background(), "access3")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	err = repo.UseRefreshToken(context.Background(), &entities.RefreshToken{Hash: "hash1"})
+	assert.ErrorIs(t, err, entities.ErrInvalidToken)
+
+	err = repo.UseRefreshToken(context.Background(), &entities.RefreshToken{Hash: "hash3"})
+	assert.NoError(t, err)
+}
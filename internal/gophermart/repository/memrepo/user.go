@@ -0,0 +1,193 @@
+package memrepo
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+)
+
+type UserRepo struct {
+	storage *Storage
+}
+
+func NewUserRepo(storage *Storage) *UserRepo {
+	return &UserRepo{storage: storage}
+}
+
+func (repo *UserRepo) AddUser(_ context.Context, user *entities.User) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	if _, ok := repo.storage.users[user.Login]; ok {
+		return entities.ErrUserAlreadyExists
+	}
+
+	repo.storage.lastUserID++
+	user.ID = repo.storage.lastUserID
+
+	repo.storage.users[user.Login] = entities.User{
+		ID:                user.ID,
+		Login:             user.Login,
+		EncryptedPassword: user.EncryptedPassword,
+		Salt:              user.Salt,
+		Role:              user.Role,
+	}
+	repo.storage.balances[user.ID] = 0
+
+	return nil
+}
+
+func (repo *UserRepo) User(_ context.Context, user *entities.User) error {
+	repo.storage.mtx.RLock()
+	defer repo.storage.mtx.RUnlock()
+
+	stored, ok := repo.storage.users[user.Login]
+	if !ok {
+		return entities.ErrInvalidLoginPassword
+	}
+
+	user.ID = stored.ID
+	user.EncryptedPassword = stored.EncryptedPassword
+	user.Salt = stored.Salt
+	user.Role = stored.Role
+
+	return nil
+}
+
+func (repo *UserRepo) UserByID(_ context.Context, user *entities.User) error {
+	repo.storage.mtx.RLock()
+	defer repo.storage.mtx.RUnlock()
+
+	for _, stored := range repo.storage.users {
+		if stored.ID == user.ID {
+			user.Login = stored.Login
+			user.EncryptedPassword = stored.EncryptedPassword
+			user.Salt = stored.Salt
+			user.Role = stored.Role
+
+			return nil
+		}
+	}
+
+	return entities.ErrUserNotFound
+}
+
+func (repo *UserRepo) Users(_ context.Context, filter *entities.UserFilter) ([]entities.UserProfile, error) {
+	repo.storage.mtx.RLock()
+	defer repo.storage.mtx.RUnlock()
+
+	login := strings.ToLower(filter.Login)
+	users := make([]entities.UserProfile, 0)
+
+	for _, user := range repo.storage.users {
+		if !strings.Contains(strings.ToLower(user.Login), login) {
+			continue
+		}
+
+		users = append(users, entities.UserProfile{ID: user.ID, Login: user.Login, Role: user.Role})
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	return paginate(users, userCursor, filter.Page), nil
+}
+
+func (repo *UserRepo) UpdatePassword(_ context.Context, user *entities.User) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	return repo.storage.updatePassword(user)
+}
+
+func (repo *UserRepo) ChangePassword(_ context.Context, user *entities.User) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	return repo.storage.changePassword(user)
+}
+
+func (repo *UserRepo) AddResetToken(_ context.Context, token *entities.ResetToken) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	now := time.Now()
+
+	for hash, stored := range repo.storage.resetTokens {
+		if stored.UserID == token.UserID && stored.ExpiresAt.Before(now) {
+			delete(repo.storage.resetTokens, hash)
+		}
+	}
+
+	repo.storage.resetTokens[token.Hash] = *token
+
+	return nil
+}
+
+func (repo *UserRepo) ResetPassword(_ context.Context, token *entities.ResetToken, user *entities.User) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	stored, ok := repo.storage.resetTokens[token.Hash]
+	if !ok || !stored.UsedAt.IsZero() || !stored.ExpiresAt.After(time.Now()) {
+		return entities.ErrInvalidResetToken
+	}
+
+	user.ID = stored.UserID
+	user.Login = ""
+
+	for _, owner := range repo.storage.users {
+		if owner.ID == user.ID {
+			user.Login = owner.Login
+		}
+	}
+
+	err := repo.storage.changePassword(user)
+	if err != nil {
+		return err
+	}
+
+	stored.UsedAt = time.Now()
+	*token = stored
+
+	return nil
+}
+
+// Сохраняет новый пароль пользователя, удаляет его токены сброса пароля
+// и отзывает все его сессии. Вызывается под блокировкой на запись.
+func (s *Storage) changePassword(user *entities.User) error {
+	err := s.updatePassword(user)
+	if err != nil {
+		return err
+	}
+
+	for hash, token := range s.resetTokens {
+		if token.UserID == user.ID {
+			delete(s.resetTokens, hash)
+		}
+	}
+
+	s.revokeUser(user.ID)
+
+	return nil
+}
+
+// Сохраняет хеш пароля пользователя. Вызывается под блокировкой на запись.
+func (s *Storage) updatePassword(user *entities.User) error {
+	stored, ok := s.users[user.Login]
+	if !ok || stored.ID != user.ID {
+		return entities.ErrUserNotFound
+	}
+
+	stored.EncryptedPassword = user.EncryptedPassword
+	stored.Salt = user.Salt
+	s.users[user.Login] = stored
+
+	return nil
+}
+
+func userCursor(user entities.UserProfile) entities.Cursor {
+	return entities.Cursor{ID: user.ID}
+}
@@ -0,0 +1,259 @@
+package memrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddUser(t *testing.T) {
+	repo := NewUserRepo(NewStorage())
+
+	user1 := entities.User{Login: "user1", EncryptedPassword: "hash", Salt: "salt"}
+	user2 := entities.User{Login: "user2", EncryptedPassword: "hash", Salt: "salt"}
+
+	require.NoError(t, repo.AddUser(context.Background(), &user1))
+	require.NoError(t, repo.AddUser(context.Background(), &user2))
+
+	assert.Equal(t, int64(1), user1.ID)
+	assert.Equal(t, int64(2), user2.ID)
+
+	duplicate := entities.User{Login: "user1"}
+	assert.ErrorIs(t, repo.AddUser(context.Background(), &duplicate), entities.ErrUserAlreadyExists)
+}
+
+func TestUser(t *testing.T) {
+	repo := NewUserRepo(NewStorage())
+
+	stored := entities.User{Login: "user1", EncryptedPassword: "hash", Salt: "salt"}
+	require.NoError(t, repo.AddUser(context.Background(), &stored))
+
+	user := entities.User{Login: "user1", Password: "1234"}
+	require.NoError(t, repo.User(context.Background(), &user))
+
+	assert.Equal(t, stored.ID, user.ID)
+	assert.Equal(t, stored.EncryptedPassword, user.EncryptedPassword)
+	assert.Equal(t, stored.Salt, user.Salt)
+
+	unknown := entities.User{Login: "user2"}
+	assert.ErrorIs(t, repo.User(context.Background(), &unknown), entities.ErrInvalidLoginPassword)
+}
+
+func TestUpdatePassword(t *testing.T) {
+	repo := NewUserRepo(NewStorage())
+
+	stored := entities.User{Login: "user1", EncryptedPassword: "hash", Salt: "salt"}
+	require.NoError(t, repo.AddUser(context.Background(), &stored))
+
+	stored.EncryptedPassword = "newhash"
+	stored.Salt = ""
+	require.NoError(t, repo.UpdatePassword(context.Background(), &stored))
+
+	user := entities.User{Login: "user1"}
+	require.NoError(t, repo.User(context.Background(), &user))
+
+	assert.Equal(t, "newhash", user.EncryptedPassword)
+	assert.Empty(t, user.Salt)
+
+	unknown := entities.User{ID: 2, Login: "user2"}
+	assert.ErrorIs(t, repo.UpdatePassword(context.Background(), &unknown), entities.ErrUserNotFound)
+}
+
+func TestUserByID(t *testing.T) {
+	repo := NewUserRepo(NewStorage())
+
+	stored := entities.User{Login: "user1", EncryptedPassword: "hash", Salt: "salt", Role: entities.RoleAdmin}
+	require.NoError(t, repo.AddUser(context.Background(), &stored))
+
+	user := entities.User{ID: stored.ID}
+	require.NoError(t, repo.UserByID(context.Background(), &user))
+
+	assert.Equal(t, "user1", user.Login)
+	assert.Equal(t, stored.EncryptedPassword, user.EncryptedPassword)
+	assert.Equal(t, stored.Salt, user.Salt)
+	assert.Equal(t, entities.RoleAdmin, user.Role)
+
+	unknown := entities.User{ID: 2}
+	assert.ErrorIs(t, repo.UserByID(context.Background(), &unknown), entities.ErrUserNotFound)
+}
+
+func TestUsers(t *testing.T) {
+	repo := NewUserRepo(NewStorage())
+
+	for _, login := range []string{"Alice", "bob", "alina", "carol"} {
+		user := entities.User{Login: login, Role: entities.RoleUser}
+		require.NoError(t, repo.AddUser(context.Background(), &user))
+	}
+
+	users, err := repo.Users(context.Background(), &entities.UserFilter{Login: "AL", Page: entities.Page{Limit: 10}})
+	require.NoError(t, err)
+	assert.Equal(t, []entities.UserProfile{
+		{ID: 1, Login: "Alice", Role: entities.RoleUser},
+		{ID: 3, Login: "alina", Role: entities.RoleUser},
+	}, users)
+
+	filter := entities.UserFilter{Page: entities.Page{Limit: 2, Descending: true}}
+
+	users, err = repo.Users(context.Background(), &filter)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, int64(4), users[0].ID)
+	assert.Equal(t, int64(3), users[1].ID)
+
+	filter.Cursor = &entities.Cursor{ID: users[1].ID}
+
+	users, err = repo.Users(context.Background(), &filter)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, int64(2), users[0].ID)
+	assert.Equal(t, int64(1), users[1].ID)
+}
+
+func TestResetTokens(t *testing.T) {
+	repo := NewUserRepo(NewStorage())
+
+	user := entities.User{Login: "user1", EncryptedPassword: "hash"}
+	require.NoError(t, repo.AddUser(context.Background(), &user))
+
+	now := time.Now()
+
+	for _, token := range []entities.ResetToken{
+		{Hash: "hash1", UserID: user.ID, ExpiresAt: now.Add(time.Hour)},
+		{Hash: "hash2", UserID: user.ID, ExpiresAt: now.Add(time.Hour)},
+		{Hash: "expired", UserID: user.ID, ExpiresAt: now.Add(-time.Minute)},
+	} {
+		token := token
+		require.NoError(t, repo.AddResetToken(context.Background(), &token))
+	}
+
+	err := repo.ResetPassword(
+		context.Background(), &entities.ResetToken{Hash: "expired"}, &entities.User{EncryptedPassword: "expired"},
+	)
+	assert.ErrorIs(t, err, entities.ErrInvalidResetToken)
+
+	err = repo.ResetPassword(
+		context.Background(), &entities.ResetToken{Hash: "unknown"}, &entities.User{EncryptedPassword: "unknown"},
+	)
+	assert.ErrorIs(t, err, entities.ErrInvalidResetToken)
+
+	// Пересчёт хеша пароля при входе не влияет на токены сброса пароля
+	user.EncryptedPassword = "rehashed"
+	require.NoError(t, repo.UpdatePassword(context.Background(), &user))
+	assert.Contains(t, repo.storage.resetTokens, "hash2")
+
+	token := entities.ResetToken{Hash: "hash1"}
+	reset := entities.User{EncryptedPassword: "newhash"}
+	require.NoError(t, repo.ResetPassword(context.Background(), &token, &reset))
+	assert.Equal(t, user.ID, token.UserID)
+	assert.False(t, token.UsedAt.IsZero())
+	assert.Equal(t, user.ID, reset.ID)
+	assert.Equal(t, "user1", reset.Login)
+
+	stored := entities.User{ID: user.ID}
+	require.NoError(t, repo.UserByID(context.Background(), &stored))
+	assert.Equal(t, "newhash", stored.EncryptedPassword)
+
+	// Токен сброса пароля одноразовый, а смена пароля делает недействительными остальные токены пользователя
+	for _, hash := range []string{"hash1", "hash2"} {
+		err = repo.ResetPassword(
+			context.Background(), &entities.ResetToken{Hash: hash}, &entities.User{EncryptedPassword: "other"},
+		)
+		assert.ErrorIs(t, err, entities.ErrInvalidResetToken)
+	}
+
+	require.NoError(t, repo.UserByID(context.Background(), &stored))
+	assert.Equal(t, "newhash", stored.EncryptedPassword)
+}
+
+func TestChangePassword(t *testing.T) {
+	storage := NewStorage()
+	repo := NewUserRepo(storage)
+	tokenRepo := NewTokenRepo(storage)
+	now := time.Now()
+
+	user := entities.User{Login: "user1", EncryptedPassword: "hash"}
+	require.NoError(t, repo.AddUser(context.Background(), &user))
+
+	token := entities.RefreshToken{
+		Hash:            "hash1",
+		UserID:          user.ID,
+		Family:          "family1",
+		AccessTokenID:   "access1",
+		AccessExpiresAt: now.Add(time.Minute),
+		IssuedAt:        now,
+		ExpiresAt:       now.Add(time.Hour),
+	}
+	require.NoError(t, tokenRepo.AddRefreshToken(context.Background(), &token))
+
+	// Пересчёт хеша пароля не завершает сессии пользователя
+	user.EncryptedPassword = "rehashed"
+	require.NoError(t, repo.UpdatePassword(context.Background(), &user))
+
+	revoked, err := tokenRepo.AccessTokenRevoked(context.Background(), "access1")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	user.EncryptedPassword = "newhash"
+	require.NoError(t, repo.ChangePassword(context.Background(), &user))
+
+	stored := entities.User{Login: "user1"}
+	require.NoError(t, repo.User(context.Background(), &stored))
+	assert.Equal(t, "newhash", stored.EncryptedPassword)
+
+	revoked, err = tokenRepo.AccessTokenRevoked(context.Background(), "access1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	err = tokenRepo.UseRefreshToken(context.Background(), &entities.RefreshToken{Hash: "hash1"})
+	assert.ErrorIs(t, err, entities.ErrInvalidToken)
+
+	unknown := entities.User{ID: 2, Login: "user2"}
+	assert.ErrorIs(t, repo.ChangePassword(context.Background(), &unknown), entities.ErrUserNotFound)
+}
+
+func TestLoginAttempts(t *testing.T) {
+	repo := NewLoginAttemptRepo(NewStorage())
+	ctx := context.Background()
+	policy := entities.LoginPolicy{MaxAttempts: 2, Lockout: time.Hour}
+
+	attempt := entities.LoginAttempt{Key: "login:user1", MaxAttempts: policy.MaxAttempts}
+
+	require.NoError(t, repo.ReserveLoginAttempt(ctx, &attempt, policy))
+	assert.True(t, attempt.LockedUntil.IsZero())
+
+	// Порог достигнут, вход заблокирован уже при резервировании попытки
+	require.NoError(t, repo.ReserveLoginAttempt(ctx, &attempt, policy))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), attempt.LockedUntil, time.Second)
+
+	parallel := entities.LoginAttempt{Key: "login:user1", MaxAttempts: policy.MaxAttempts}
+	assert.ErrorIs(t, repo.ReserveLoginAttempt(ctx, &parallel, policy), entities.ErrLoginLocked)
+
+	// Отменённая попытка снимает установленную ею блокировку и не учитывается
+	require.NoError(t, repo.ReleaseLoginAttempt(ctx, &attempt))
+	require.NoError(t, repo.ReserveLoginAttempt(ctx, &parallel, policy))
+	assert.False(t, parallel.LockedUntil.IsZero())
+
+	// Другие ключи учитываются отдельно
+	other := entities.LoginAttempt{Key: "ip:192.0.2.1", MaxAttempts: policy.MaxAttempts}
+	require.NoError(t, repo.ReserveLoginAttempt(ctx, &other, policy))
+
+	require.NoError(t, repo.ResetLoginFailures(ctx, "login:user1"))
+	require.NoError(t, repo.ReserveLoginAttempt(ctx, &attempt, policy))
+	assert.True(t, attempt.LockedUntil.IsZero())
+
+	locked := entities.LoginAttempt{Key: "login:user2", MaxAttempts: policy.MaxAttempts}
+
+	for i := 0; i < policy.MaxAttempts; i++ {
+		require.NoError(t, repo.ReserveLoginAttempt(ctx, &locked, policy))
+	}
+
+	// Устаревшие записи удаляются, а заблокированные сохраняются,
+	// даже если последняя неудачная попытка вышла за пределы окна
+	require.NoError(t, repo.DeleteStaleLoginAttempts(ctx, 0))
+	assert.Len(t, repo.storage.loginAttempts, 1)
+	assert.ErrorIs(t, repo.ReserveLoginAttempt(ctx, &locked, policy), entities.ErrLoginLocked)
+}
@@ -0,0 +1,230 @@
+package memrepo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+)
+
+// Уведомление в очереди доставки. Событие сериализуется при захвате уведомления для доставки.
+type webhookDelivery struct {
+	entities.WebhookDelivery
+	event entities.WebhookEvent
+}
+
+type WebhookRepo struct {
+	storage *Storage
+}
+
+func NewWebhookRepo(storage *Storage) *WebhookRepo {
+	return &WebhookRepo{storage: storage}
+}
+
+func (repo *WebhookRepo) AddWebhook(_ context.Context, webhook *entities.Webhook) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	repo.storage.lastWebhookID++
+	webhook.ID = repo.storage.lastWebhookID
+	webhook.CreatedAt = time.Now()
+
+	repo.storage.webhooks = append(repo.storage.webhooks, *webhook)
+
+	return nil
+}
+
+func (repo *WebhookRepo) Webhooks(_ context.Context, userID int64) ([]entities.Webhook, error) {
+	repo.storage.mtx.RLock()
+	defer repo.storage.mtx.RUnlock()
+
+	webhooks := make([]entities.Webhook, 0)
+
+	for _, webhook := range repo.storage.webhooks {
+		if webhook.UserID == userID {
+			webhook.Secret = ""
+			webhooks = append(webhooks, webhook)
+		}
+	}
+
+	return webhooks, nil
+}
+
+func (repo *WebhookRepo) Webhook(_ context.Context, webhook *entities.Webhook) error {
+	repo.storage.mtx.RLock()
+	defer repo.storage.mtx.RUnlock()
+
+	idx := repo.storage.webhookIdx(webhook)
+	if idx < 0 {
+		return entities.ErrWebhookNotFound
+	}
+
+	webhook.URL = repo.storage.webhooks[idx].URL
+	webhook.CreatedAt = repo.storage.webhooks[idx].CreatedAt
+
+	return nil
+}
+
+func (repo *WebhookRepo) DeleteWebhook(_ context.Context, webhook *entities.Webhook) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	idx := repo.storage.webhookIdx(webhook)
+	if idx < 0 {
+		return entities.ErrWebhookNotFound
+	}
+
+	repo.storage.webhooks = append(repo.storage.webhooks[:idx], repo.storage.webhooks[idx+1:]...)
+
+	deliveries := make([]webhookDelivery, 0, len(repo.storage.deliveries))
+
+	for _, delivery := range repo.storage.deliveries {
+		if delivery.WebhookID == webhook.ID {
+			delete(repo.storage.deliveryClaims, delivery.ID)
+
+			continue
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	repo.storage.deliveries = deliveries
+
+	return nil
+}
+
+func (repo *WebhookRepo) WebhookDeliveries(
+	_ context.Context, filter *entities.WebhookDeliveryFilter,
+) ([]entities.WebhookDelivery, error) {
+	repo.storage.mtx.RLock()
+	defer repo.storage.mtx.RUnlock()
+
+	if repo.storage.webhookIdx(&entities.Webhook{ID: filter.WebhookID, UserID: filter.UserID}) < 0 {
+		return make([]entities.WebhookDelivery, 0), nil
+	}
+
+	deliveries := make([]entities.WebhookDelivery, 0)
+
+	for _, delivery := range repo.storage.deliveries {
+		if delivery.WebhookID != filter.WebhookID || !inDateRange(delivery.CreatedAt, filter.Created) {
+			continue
+		}
+
+		deliveries = append(deliveries, delivery.WebhookDelivery)
+	}
+
+	return paginate(deliveries, deliveryCursor, filter.Page), nil
+}
+
+func (repo *WebhookRepo) PendingDeliveries(
+	_ context.Context, instance string, limit uint, lease time.Duration,
+) ([]entities.WebhookDelivery, error) {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	now := time.Now()
+	deliveries := make([]entities.WebhookDelivery, 0)
+
+	for _, delivery := range repo.storage.deliveries {
+		if uint(len(deliveries)) >= limit {
+			break
+		}
+
+		if delivery.Status != entities.WebhookDeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+
+		claim, ok := repo.storage.deliveryClaims[delivery.ID]
+		if ok && claim.instance != instance && claim.until.After(now) {
+			continue
+		}
+
+		payload, err := json.Marshal(delivery.event)
+		if err != nil {
+			return nil, err
+		}
+
+		repo.storage.deliveryClaims[delivery.ID] = orderClaim{
+			instance: instance,
+			until:    now.Add(lease),
+		}
+
+		webhook := repo.storage.webhooks[repo.storage.webhookIdx(&entities.Webhook{ID: delivery.WebhookID})]
+
+		claimed := delivery.WebhookDelivery
+		claimed.URL = webhook.URL
+		claimed.Secret = webhook.Secret
+		claimed.Payload = payload
+
+		deliveries = append(deliveries, claimed)
+	}
+
+	return deliveries, nil
+}
+
+func (repo *WebhookRepo) UpdateDelivery(_ context.Context, delivery *entities.WebhookDelivery) error {
+	repo.storage.mtx.Lock()
+	defer repo.storage.mtx.Unlock()
+
+	for i := range repo.storage.deliveries {
+		stored := &repo.storage.deliveries[i]
+		if stored.ID != delivery.ID {
+			continue
+		}
+
+		stored.Status = delivery.Status
+		stored.Attempts = delivery.Attempts
+		stored.ResponseStatus = delivery.ResponseStatus
+		stored.LastError = delivery.LastError
+		stored.NextAttemptAt = delivery.NextAttemptAt
+		stored.DeliveredAt = delivery.DeliveredAt
+
+		delete(repo.storage.deliveryClaims, delivery.ID)
+
+		break
+	}
+
+	return nil
+}
+
+// Возвращает индекс подписки webhook.ID или -1, если подписки нет.
+// Ненулевой webhook.UserID дополнительно проверяет владельца подписки.
+func (s *Storage) webhookIdx(webhook *entities.Webhook) int {
+	for i, stored := range s.webhooks {
+		if stored.ID == webhook.ID && (webhook.UserID == 0 || stored.UserID == webhook.UserID) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Добавляет событие event в очередь доставки каждой подписки пользователя userID.
+// Вызывается под блокировкой на запись вместе с изменением, о котором уведомляет событие.
+func (s *Storage) enqueueWebhookEvent(userID int64, event entities.WebhookEvent) {
+	for _, webhook := range s.webhooks {
+		if webhook.UserID != userID {
+			continue
+		}
+
+		s.lastDeliveryID++
+
+		s.deliveries = append(s.deliveries, webhookDelivery{
+			WebhookDelivery: entities.WebhookDelivery{
+				ID:            s.lastDeliveryID,
+				WebhookID:     webhook.ID,
+				EventID:       event.ID,
+				EventType:     event.Type,
+				Status:        entities.WebhookDeliveryPending,
+				CreatedAt:     event.CreatedAt,
+				NextAttemptAt: &event.CreatedAt,
+			},
+			event: event,
+		})
+	}
+}
+
+func deliveryCursor(delivery entities.WebhookDelivery) entities.Cursor {
+	return entities.Cursor{Time: delivery.CreatedAt, ID: delivery.ID}
+}
@@ -0,0 +1,169 @@
+package memrepo
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooks(t *testing.T) {
+	repo := NewWebhookRepo(NewStorage())
+
+	webhook := entities.Webhook{UserID: 1, URL: "https://shop.example.com/hooks", Secret: "secret"}
+	require.NoError(t, repo.AddWebhook(context.Background(), &webhook))
+	assert.NotZero(t, webhook.ID)
+
+	require.NoError(t, repo.AddWebhook(context.Background(), &entities.Webhook{UserID: 2, URL: "https://example.com"}))
+
+	webhooks, err := repo.Webhooks(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, webhook.URL, webhooks[0].URL)
+	assert.Empty(t, webhooks[0].Secret)
+
+	assert.ErrorIs(
+		t, repo.Webhook(context.Background(), &entities.Webhook{ID: webhook.ID, UserID: 2}),
+		entities.ErrWebhookNotFound,
+	)
+	assert.ErrorIs(
+		t, repo.DeleteWebhook(context.Background(), &entities.Webhook{ID: webhook.ID, UserID: 2}),
+		entities.ErrWebhookNotFound,
+	)
+
+	require.NoError(t, repo.DeleteWebhook(context.Background(), &entities.Webhook{ID: webhook.ID, UserID: 1}))
+
+	webhooks, err = repo.Webhooks(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, webhooks)
+}
+
+func TestWebhookEvents(t *testing.T) {
+	storage := NewStorage()
+	user := entities.User{Login: "user1"}
+
+	require.NoError(t, NewUserRepo(storage).AddUser(context.Background(), &user))
+
+	webhookRepo := NewWebhookRepo(storage)
+	orderRepo := NewOrderRepo(storage)
+	balanceRepo := NewBalanceRepo(storage)
+
+	webhook := entities.Webhook{UserID: user.ID, URL: "https://shop.example.com/hooks", Secret: "secret"}
+	require.NoError(t, webhookRepo.AddWebhook(context.Background(), &webhook))
+
+	require.NoError(t, orderRepo.AddOrder(context.Background(), entities.NewOrder("4561261212345467", user.ID)))
+
+	processing := entities.Order{Number: "4561261212345467", Status: entities.OrderStatusProcessing}
+
+	for i := 0; i < 2; i++ {
+		// Повторный опрос без смены статуса не порождает события
+		_, err := orderRepo.ProcessableOrders(context.Background(), "instance", 10, time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, orderRepo.UpdateOrder(context.Background(), "instance", &processing))
+	}
+
+	_, err := orderRepo.ProcessableOrders(context.Background(), "instance", 10, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, orderRepo.ProcessOrder(context.Background(), "instance", &entities.Order{
+		Number:  "4561261212345467",
+		Accrual: entities.NewMoney(500, 0),
+	}))
+	require.NoError(t, balanceRepo.ChangeBalance(context.Background(), &entities.BalanceChange{
+		UserID:    user.ID,
+		Operation: entities.BalanceOperationWithdrawal,
+		Order:     "12345678903",
+		Sum:       entities.NewMoney(200, 0),
+	}))
+
+	deliveries, err := webhookRepo.PendingDeliveries(context.Background(), "instance1", 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 4)
+
+	expected := []struct {
+		eventType string
+		data      map[string]interface{}
+	}{
+		{
+			eventType: entities.WebhookEventOrderStatusChanged,
+			data:      map[string]interface{}{"status": "PROCESSING", "previous_status": "NEW"},
+		},
+		{
+			eventType: entities.WebhookEventOrderStatusChanged,
+			data:      map[string]interface{}{"status": "PROCESSED", "previous_status": "PROCESSING", "accrual": 500.0},
+		},
+		{
+			eventType: entities.WebhookEventBalanceChanged,
+			data:      map[string]interface{}{"operation": "refill", "sum": 500.0, "current": 500.0},
+		},
+		{
+			eventType: entities.WebhookEventBalanceChanged,
+			data:      map[string]interface{}{"operation": "withdrawal", "sum": 200.0, "current": 300.0},
+		},
+	}
+
+	for i, delivery := range deliveries {
+		assert.Equal(t, expected[i].eventType, delivery.EventType)
+		assert.Equal(t, webhook.URL, delivery.URL)
+		assert.Equal(t, webhook.Secret, delivery.Secret)
+
+		var event struct {
+			ID   string                 `json:"id"`
+			Type string                 `json:"type"`
+			Data map[string]interface{} `json:"data"`
+		}
+
+		require.NoError(t, json.Unmarshal(delivery.Payload, &event))
+		assert.Equal(t, delivery.EventID, event.ID)
+		assert.Equal(t, delivery.EventType, event.Type)
+
+		for key, value := range expected[i].data {
+			assert.Equal(t, value, event.Data[key], key)
+		}
+	}
+
+	// Захваченные уведомления не выдаются другому экземпляру до истечения аренды
+	claimed, err := webhookRepo.PendingDeliveries(context.Background(), "instance2", 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	deliveredAt := time.Now()
+	delivered := deliveries[0]
+	delivered.Status = entities.WebhookDeliveryDelivered
+	delivered.Attempts = 1
+	delivered.ResponseStatus = 200
+	delivered.NextAttemptAt = nil
+	delivered.DeliveredAt = &deliveredAt
+	require.NoError(t, webhookRepo.UpdateDelivery(context.Background(), &delivered))
+
+	history, err := webhookRepo.WebhookDeliveries(context.Background(), &entities.WebhookDeliveryFilter{
+		UserID:    user.ID,
+		WebhookID: webhook.ID,
+		Page:      entities.Page{Limit: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+	assert.Equal(t, entities.WebhookDeliveryDelivered, history[0].Status)
+	assert.Equal(t, 200, history[0].ResponseStatus)
+	assert.Equal(t, entities.WebhookDeliveryPending, history[1].Status)
+}
+
+func TestWebhookEventsWithoutSubscription(t *testing.T) {
+	storage := NewStorage()
+	user := entities.User{Login: "user1"}
+
+	require.NoError(t, NewUserRepo(storage).AddUser(context.Background(), &user))
+	require.NoError(t, NewBalanceRepo(storage).ChangeBalance(context.Background(), &entities.BalanceChange{
+		UserID:    user.ID,
+		Operation: entities.BalanceOperationRefill,
+		Order:     "4561261212345467",
+		Sum:       entities.NewMoney(500, 0),
+	}))
+
+	deliveries, err := NewWebhookRepo(storage).PendingDeliveries(context.Background(), "instance", 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+}
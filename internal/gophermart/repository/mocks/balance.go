@@ -64,8 +64,37 @@ func (mr *MockBalanceRepoMockRecorder) ChangeBalance(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeBalance", reflect.TypeOf((*MockBalanceRepo)(nil).ChangeBalance), arg0, arg1)
 }
 
+// ReverseWithdrawal mocks base method.
+func (m *MockBalanceRepo) ReverseWithdrawal(arg0 context.Context, arg1 *entities.BalanceChange) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReverseWithdrawal", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReverseWithdrawal indicates an expected call of ReverseWithdrawal.
+func (mr *MockBalanceRepoMockRecorder) ReverseWithdrawal(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReverseWithdrawal", reflect.TypeOf((*MockBalanceRepo)(nil).ReverseWithdrawal), arg0, arg1)
+}
+
+// Statement mocks base method.
+func (m *MockBalanceRepo) Statement(arg0 context.Context, arg1 *entities.StatementFilter) ([]entities.StatementEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Statement", arg0, arg1)
+	ret0, _ := ret[0].([]entities.StatementEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Statement indicates an expected call of Statement.
+func (mr *MockBalanceRepoMockRecorder) Statement(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Statement", reflect.TypeOf((*MockBalanceRepo)(nil).Statement), arg0, arg1)
+}
+
 // Withdrawals mocks base method.
-func (m *MockBalanceRepo) Withdrawals(arg0 context.Context, arg1 int64) ([]entities.BalanceChange, error) {
+func (m *MockBalanceRepo) Withdrawals(arg0 context.Context, arg1 *entities.WithdrawalFilter) ([]entities.BalanceChange, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Withdrawals", arg0, arg1)
 	ret0, _ := ret[0].([]entities.BalanceChange)
@@ -49,3 +49,25 @@ func (m balanceChangeMatcher) Matches(x interface{}) bool {
 func (m balanceChangeMatcher) String() string {
 	return fmt.Sprintf("is equal to %v", m.balanceChange)
 }
+
+type loginAttemptMatcher struct {
+	key string
+}
+
+// Сравнивает попытку входа только по ключу: время блокировки задаётся хранилищем.
+func LoginAttemptMatcher(key string) gomock.Matcher {
+	return &loginAttemptMatcher{key: key}
+}
+
+func (m loginAttemptMatcher) Matches(x interface{}) bool {
+	attempt, ok := x.(*entities.LoginAttempt)
+	if !ok {
+		return false
+	}
+
+	return attempt.Key == m.key
+}
+
+func (m loginAttemptMatcher) String() string {
+	return fmt.Sprintf("is login attempt with key %s", m.key)
+}
@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	entities "github.com/KryukovO/gophermart/internal/gophermart/entities"
 	gomock "github.com/golang/mock/gomock"
@@ -50,7 +51,7 @@ func (mr *MockOrderRepoMockRecorder) AddOrder(arg0, arg1 interface{}) *gomock.Ca
 }
 
 // Orders mocks base method.
-func (m *MockOrderRepo) Orders(arg0 context.Context, arg1 int64) ([]entities.Order, error) {
+func (m *MockOrderRepo) Orders(arg0 context.Context, arg1 *entities.OrderFilter) ([]entities.Order, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Orders", arg0, arg1)
 	ret0, _ := ret[0].([]entities.Order)
@@ -64,31 +65,60 @@ func (mr *MockOrderRepoMockRecorder) Orders(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Orders", reflect.TypeOf((*MockOrderRepo)(nil).Orders), arg0, arg1)
 }
 
+// PendingOrders mocks base method.
+func (m *MockOrderRepo) PendingOrders(arg0 context.Context) (entities.PendingOrders, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingOrders", arg0)
+	ret0, _ := ret[0].(entities.PendingOrders)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PendingOrders indicates an expected call of PendingOrders.
+func (mr *MockOrderRepoMockRecorder) PendingOrders(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingOrders", reflect.TypeOf((*MockOrderRepo)(nil).PendingOrders), arg0)
+}
+
+// ProcessOrder mocks base method.
+func (m *MockOrderRepo) ProcessOrder(arg0 context.Context, arg1 string, arg2 *entities.Order) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessOrder", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProcessOrder indicates an expected call of ProcessOrder.
+func (mr *MockOrderRepoMockRecorder) ProcessOrder(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessOrder", reflect.TypeOf((*MockOrderRepo)(nil).ProcessOrder), arg0, arg1, arg2)
+}
+
 // ProcessableOrders mocks base method.
-func (m *MockOrderRepo) ProcessableOrders(arg0 context.Context) ([]entities.Order, error) {
+func (m *MockOrderRepo) ProcessableOrders(arg0 context.Context, arg1 string, arg2 uint, arg3 time.Duration) ([]entities.Order, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ProcessableOrders", arg0)
+	ret := m.ctrl.Call(m, "ProcessableOrders", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].([]entities.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ProcessableOrders indicates an expected call of ProcessableOrders.
-func (mr *MockOrderRepoMockRecorder) ProcessableOrders(arg0 interface{}) *gomock.Call {
+func (mr *MockOrderRepoMockRecorder) ProcessableOrders(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessableOrders", reflect.TypeOf((*MockOrderRepo)(nil).ProcessableOrders), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessableOrders", reflect.TypeOf((*MockOrderRepo)(nil).ProcessableOrders), arg0, arg1, arg2, arg3)
 }
 
 // UpdateOrder mocks base method.
-func (m *MockOrderRepo) UpdateOrder(arg0 context.Context, arg1 *entities.Order) error {
+func (m *MockOrderRepo) UpdateOrder(arg0 context.Context, arg1 string, arg2 *entities.Order) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateOrder", arg0, arg1)
+	ret := m.ctrl.Call(m, "UpdateOrder", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateOrder indicates an expected call of UpdateOrder.
-func (mr *MockOrderRepoMockRecorder) UpdateOrder(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockOrderRepoMockRecorder) UpdateOrder(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrder", reflect.TypeOf((*MockOrderRepo)(nil).UpdateOrder), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrder", reflect.TypeOf((*MockOrderRepo)(nil).UpdateOrder), arg0, arg1, arg2)
 }
@@ -35,6 +35,20 @@ func (m *MockUserRepo) EXPECT() *MockUserRepoMockRecorder {
 	return m.recorder
 }
 
+// AddResetToken mocks base method.
+func (m *MockUserRepo) AddResetToken(arg0 context.Context, arg1 *entities.ResetToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddResetToken", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddResetToken indicates an expected call of AddResetToken.
+func (mr *MockUserRepoMockRecorder) AddResetToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddResetToken", reflect.TypeOf((*MockUserRepo)(nil).AddResetToken), arg0, arg1)
+}
+
 // AddUser mocks base method.
 func (m *MockUserRepo) AddUser(arg0 context.Context, arg1 *entities.User) error {
 	m.ctrl.T.Helper()
@@ -49,6 +63,48 @@ func (mr *MockUserRepoMockRecorder) AddUser(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUser", reflect.TypeOf((*MockUserRepo)(nil).AddUser), arg0, arg1)
 }
 
+// ChangePassword mocks base method.
+func (m *MockUserRepo) ChangePassword(arg0 context.Context, arg1 *entities.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockUserRepoMockRecorder) ChangePassword(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockUserRepo)(nil).ChangePassword), arg0, arg1)
+}
+
+// ResetPassword mocks base method.
+func (m *MockUserRepo) ResetPassword(arg0 context.Context, arg1 *entities.ResetToken, arg2 *entities.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockUserRepoMockRecorder) ResetPassword(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockUserRepo)(nil).ResetPassword), arg0, arg1, arg2)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockUserRepo) UpdatePassword(arg0 context.Context, arg1 *entities.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockUserRepoMockRecorder) UpdatePassword(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockUserRepo)(nil).UpdatePassword), arg0, arg1)
+}
+
 // User mocks base method.
 func (m *MockUserRepo) User(arg0 context.Context, arg1 *entities.User) error {
 	m.ctrl.T.Helper()
@@ -62,3 +118,32 @@ func (mr *MockUserRepoMockRecorder) User(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "User", reflect.TypeOf((*MockUserRepo)(nil).User), arg0, arg1)
 }
+
+// UserByID mocks base method.
+func (m *MockUserRepo) UserByID(arg0 context.Context, arg1 *entities.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserByID", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UserByID indicates an expected call of UserByID.
+func (mr *MockUserRepoMockRecorder) UserByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserByID", reflect.TypeOf((*MockUserRepo)(nil).UserByID), arg0, arg1)
+}
+
+// Users mocks base method.
+func (m *MockUserRepo) Users(arg0 context.Context, arg1 *entities.UserFilter) ([]entities.UserProfile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Users", arg0, arg1)
+	ret0, _ := ret[0].([]entities.UserProfile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Users indicates an expected call of Users.
+func (mr *MockUserRepoMockRecorder) Users(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Users", reflect.TypeOf((*MockUserRepo)(nil).Users), arg0, arg1)
+}
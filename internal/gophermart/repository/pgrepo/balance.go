@@ -2,10 +2,13 @@ package pgrepo
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
 	"github.com/KryukovO/gophermart/internal/postgres"
 
 	"github.com/jackc/pgerrcode"
@@ -20,22 +23,25 @@ func NewBalanceRepo(db *postgres.Postgres) *BalanceRepo {
 	return &BalanceRepo{db: db}
 }
 
-func (repo *BalanceRepo) Balance(ctx context.Context, userID int64) (entities.Balance, error) {
+func (repo *BalanceRepo) Balance(ctx context.Context, userID int64) (_ entities.Balance, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.Balance")
+	defer tracing.End(span, &err)
+
 	query := `
 		SELECT ub.balance, COALESCE(ubl.withdrawals, 0)
 		FROM user_balance ub
 		LEFT JOIN (
-			SELECT user_id, operation, sum(sum) AS withdrawals
+			SELECT user_id, sum(CASE WHEN operation = 'withdrawal' THEN sum ELSE -sum END) AS withdrawals
 			FROM user_balance_log
-			WHERE operation = 'withdrawal'
-			GROUP BY user_id, operation
+			WHERE operation IN ('withdrawal', 'reversal')
+			GROUP BY user_id
 		) ubl ON ub.user_id = ubl.user_id
 		WHERE ub.user_id = $1
 	`
 
 	balance := entities.Balance{UserID: userID}
 
-	err := repo.db.QueryRowContext(ctx, query, userID).Scan(&balance.Current, &balance.Withdrawn)
+	err = repo.db.QueryRowContext(ctx, query, userID).Scan(&balance.Current, &balance.Withdrawn)
 	if err != nil {
 		return entities.Balance{}, err
 	}
@@ -43,11 +49,18 @@ func (repo *BalanceRepo) Balance(ctx context.Context, userID int64) (entities.Ba
 	return balance, nil
 }
 
-func (repo *BalanceRepo) ChangeBalance(ctx context.Context, change *entities.BalanceChange) error {
+// Изменяет баланс пользователя и добавляет запись в журнал операций со счётом.
+// Сумма корректировки баланса прибавляется к балансу с учётом знака.
+// Возвращает ErrNotEnoughFunds, если баланс после операции стал бы отрицательным.
+func (repo *BalanceRepo) ChangeBalance(ctx context.Context, change *entities.BalanceChange) (err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.ChangeBalance")
+	defer tracing.End(span, &err)
+
 	query1 := `
 		UPDATE user_balance
 		SET balance = balance %s $1
 		WHERE user_id = $2
+		RETURNING balance
 	`
 
 	if change.Operation == entities.BalanceOperationWithdrawal {
@@ -57,8 +70,9 @@ func (repo *BalanceRepo) ChangeBalance(ctx context.Context, change *entities.Bal
 	}
 
 	query2 := `
-		INSERT INTO user_balance_log(user_id, processed, operation, order_num, sum)
-		VALUES ($1, now(), $2, $3, $4)
+		INSERT INTO user_balance_log(user_id, processed, operation, order_num, sum, admin_id, reason, reference)
+		VALUES ($1, now(), $2, $3, $4, NULLIF($5::BIGINT, 0), NULLIF($6, ''), NULLIF($7, ''))
+		RETURNING processed
 	`
 
 	tx, err := repo.db.BeginTx(ctx, nil)
@@ -68,15 +82,43 @@ func (repo *BalanceRepo) ChangeBalance(ctx context.Context, change *entities.Bal
 
 	defer tx.Rollback()
 
-	_, err = tx.ExecContext(ctx, query1, change.Sum, change.UserID)
+	var current entities.Money
+
+	err = tx.QueryRowContext(ctx, query1, change.Sum, change.UserID).Scan(&current)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.CheckViolation {
 			return entities.ErrNotEnoughFunds
 		}
+
+		return err
 	}
 
-	_, err = tx.ExecContext(ctx, query2, change.UserID, change.Operation, change.Order, change.Sum)
+	var processedAt time.Time
+
+	err = tx.QueryRowContext(
+		ctx, query2,
+		change.UserID, change.Operation, change.Order, change.Sum, change.AdminID, change.Reason, change.Reference,
+	).Scan(&processedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return entities.ErrOrderAlreadyCredited
+		}
+
+		return err
+	}
+
+	err = enqueueWebhookEvent(ctx, tx, change.UserID, entities.NewWebhookEvent(
+		entities.WebhookEventBalanceChanged,
+		entities.BalanceEvent{
+			Operation:   change.Operation,
+			Order:       change.Order,
+			Sum:         change.Sum,
+			Current:     current,
+			ProcessedAt: processedAt,
+		},
+	))
 	if err != nil {
 		return err
 	}
@@ -84,15 +126,114 @@ func (repo *BalanceRepo) ChangeBalance(ctx context.Context, change *entities.Bal
 	return tx.Commit()
 }
 
-func (repo *BalanceRepo) Withdrawals(ctx context.Context, userID int64) ([]entities.BalanceChange, error) {
-	query := `
-		SELECT order_num, sum, processed
+// Отменяет последнее списание по заказу reversal.Order: возвращает списанные баллы на счёт пользователя
+// и добавляет в журнал операций со счётом запись об отмене. Заполняет остальные поля reversal.
+// Возвращает ErrWithdrawalNotFound, если списание не найдено,
+// и ErrWithdrawalAlreadyReversed, если списание уже отменено.
+func (repo *BalanceRepo) ReverseWithdrawal(ctx context.Context, reversal *entities.BalanceChange) (err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.ReverseWithdrawal")
+	defer tracing.End(span, &err)
+
+	query1 := `
+		SELECT id, user_id, sum
 		FROM user_balance_log
-		WHERE user_id = $1 AND operation = 'withdrawal'
-		ORDER BY processed ASC
+		WHERE order_num = $1 AND operation = 'withdrawal'
+		ORDER BY processed DESC, id DESC
+		LIMIT 1
+		FOR UPDATE
+	`
+
+	query2 := `
+		INSERT INTO user_balance_log(user_id, processed, operation, order_num, sum, admin_id, reversal_of)
+		VALUES ($1, now(), 'reversal', $2, $3, NULLIF($4::BIGINT, 0), $5)
+		RETURNING id, processed
+	`
+
+	query3 := `
+		UPDATE user_balance
+		SET balance = balance + $1
+		WHERE user_id = $2
+		RETURNING balance
 	`
 
-	rows, err := repo.db.QueryContext(ctx, query, userID)
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query1, reversal.Order).Scan(&reversal.ReversalOf, &reversal.UserID, &reversal.Sum)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.ErrWithdrawalNotFound
+		}
+
+		return err
+	}
+
+	err = tx.QueryRowContext(
+		ctx, query2, reversal.UserID, reversal.Order, reversal.Sum, reversal.AdminID, reversal.ReversalOf,
+	).Scan(&reversal.ID, &reversal.ProcessedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return entities.ErrWithdrawalAlreadyReversed
+		}
+
+		return err
+	}
+
+	var current entities.Money
+
+	err = tx.QueryRowContext(ctx, query3, reversal.Sum, reversal.UserID).Scan(&current)
+	if err != nil {
+		return err
+	}
+
+	reversal.Operation = entities.BalanceOperationReversal
+
+	err = enqueueWebhookEvent(ctx, tx, reversal.UserID, entities.NewWebhookEvent(
+		entities.WebhookEventBalanceChanged,
+		entities.BalanceEvent{
+			Operation:   reversal.Operation,
+			Order:       reversal.Order,
+			Sum:         reversal.Sum,
+			Current:     current,
+			ProcessedAt: reversal.ProcessedAt,
+		},
+	))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo *BalanceRepo) Withdrawals(
+	ctx context.Context, filter *entities.WithdrawalFilter,
+) (_ []entities.BalanceChange, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.Withdrawals")
+	defer tracing.End(span, &err)
+
+	var builder queryBuilder
+
+	builder.where("user_id = " + builder.arg(filter.UserID))
+	builder.where("operation = 'withdrawal'")
+	builder.dateRange("processed", filter.Processed)
+	pageClause := builder.page("processed", "id", filter.Page)
+
+	query := `
+		SELECT id, order_num, sum, processed, reversed
+		FROM (
+			SELECT w.id, w.user_id, w.operation, w.order_num, w.sum, w.processed, r.processed AS reversed
+			FROM user_balance_log w
+			LEFT JOIN user_balance_log r ON r.reversal_of = w.id
+		) withdrawals
+		` + builder.whereClause() + `
+		` + pageClause
+
+	rows, err := repo.db.QueryContext(ctx, query, builder.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -103,15 +244,21 @@ func (repo *BalanceRepo) Withdrawals(ctx context.Context, userID int64) ([]entit
 
 	for rows.Next() {
 		withdrawal := entities.BalanceChange{
-			UserID:    userID,
+			UserID:    filter.UserID,
 			Operation: entities.BalanceOperationWithdrawal,
 		}
 
-		err = rows.Scan(&withdrawal.Order, &withdrawal.Sum, &withdrawal.ProcessedAt)
+		var reversed sql.NullTime
+
+		err = rows.Scan(&withdrawal.ID, &withdrawal.Order, &withdrawal.Sum, &withdrawal.ProcessedAt, &reversed)
 		if err != nil {
 			return nil, err
 		}
 
+		if reversed.Valid {
+			withdrawal.ReversedAt = &reversed.Time
+		}
+
 		withdrawals = append(withdrawals, withdrawal)
 	}
 
@@ -121,3 +268,62 @@ func (repo *BalanceRepo) Withdrawals(ctx context.Context, userID int64) ([]entit
 
 	return withdrawals, nil
 }
+
+// Возвращает записи журнала операций со счётом пользователя с остатком после каждой операции.
+// Остаток рассчитывается по всей истории счёта до применения фильтров выборки.
+func (repo *BalanceRepo) Statement(
+	ctx context.Context, filter *entities.StatementFilter,
+) (_ []entities.StatementEntry, err error) {
+	ctx, span := startSpan(ctx, "BalanceRepo.Statement")
+	defer tracing.End(span, &err)
+
+	var builder queryBuilder
+
+	userArg := builder.arg(filter.UserID)
+
+	builder.dateRange("processed", filter.Processed)
+	pageClause := builder.page("processed", "id", filter.Page)
+
+	query := `
+		SELECT id, operation, order_num, sum, processed, balance, reason, reference
+		FROM (
+			SELECT
+				id, operation, order_num, sum, processed,
+				COALESCE(reason, '') AS reason, COALESCE(reference, '') AS reference,
+				sum(CASE WHEN operation = 'withdrawal' THEN -sum ELSE sum END)
+					OVER (ORDER BY processed, id) AS balance
+			FROM user_balance_log
+			WHERE user_id = ` + userArg + `
+		) statement
+		` + builder.whereClause() + `
+		` + pageClause
+
+	rows, err := repo.db.QueryContext(ctx, query, builder.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := make([]entities.StatementEntry, 0)
+
+	for rows.Next() {
+		var entry entities.StatementEntry
+
+		err = rows.Scan(
+			&entry.ID, &entry.Operation, &entry.Order, &entry.Sum, &entry.ProcessedAt, &entry.Balance,
+			&entry.Reason, &entry.Reference,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
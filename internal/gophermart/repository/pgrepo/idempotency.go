@@ -0,0 +1,145 @@
+package pgrepo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
+	"github.com/KryukovO/gophermart/internal/postgres"
+)
+
+type IdempotencyRepo struct {
+	db *postgres.Postgres
+}
+
+func NewIdempotencyRepo(db *postgres.Postgres) *IdempotencyRepo {
+	return &IdempotencyRepo{db: db}
+}
+
+// Сохраняет ключ идемпотентности запроса. Истёкший ключ с тем же значением заменяется новым.
+// Ключ незавершённого запроса с тем же отпечатком, захват которого истёк, захватывается заново.
+// Если действующий ключ уже сохранён, не изменяет его и возвращает сохранённый ключ вместе с ответом и true.
+func (repo *IdempotencyRepo) AddIdempotencyKey(
+	ctx context.Context, key *entities.IdempotencyKey,
+) (_ entities.IdempotencyKey, _ bool, err error) {
+	ctx, span := startSpan(ctx, "IdempotencyRepo.AddIdempotencyKey")
+	defer tracing.End(span, &err)
+
+	query1 := `
+		INSERT INTO idempotency_keys(user_id, key, request_hash, created, expires, locked_until)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, key) DO UPDATE
+		SET
+			request_hash = EXCLUDED.request_hash,
+			status = NULL,
+			content_type = NULL,
+			body = NULL,
+			created = EXCLUDED.created,
+			expires = EXCLUDED.expires,
+			locked_until = EXCLUDED.locked_until
+		WHERE idempotency_keys.expires < now()
+			OR (
+				idempotency_keys.status IS NULL
+				AND idempotency_keys.request_hash = EXCLUDED.request_hash
+				AND idempotency_keys.locked_until < now()
+			)
+	`
+
+	query2 := `
+		SELECT request_hash, status, content_type, body, created, expires, locked_until
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2
+	`
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.IdempotencyKey{}, false, err
+	}
+
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(
+		ctx, query1,
+		key.UserID, key.Key, key.RequestHash, key.CreatedAt, key.ExpiresAt, key.LockedUntil,
+	)
+	if err != nil {
+		return entities.IdempotencyKey{}, false, err
+	}
+
+	added, err := res.RowsAffected()
+	if err != nil {
+		return entities.IdempotencyKey{}, false, err
+	}
+
+	if added > 0 {
+		return entities.IdempotencyKey{}, false, tx.Commit()
+	}
+
+	var (
+		status      sql.NullInt64
+		contentType sql.NullString
+	)
+
+	stored := entities.IdempotencyKey{
+		Key:    key.Key,
+		UserID: key.UserID,
+	}
+
+	err = tx.QueryRowContext(ctx, query2, key.UserID, key.Key).Scan(
+		&stored.RequestHash, &status, &contentType, &stored.Body, &stored.CreatedAt, &stored.ExpiresAt, &stored.LockedUntil,
+	)
+	if err != nil {
+		return entities.IdempotencyKey{}, false, err
+	}
+
+	stored.Status = int(status.Int64)
+	stored.ContentType = contentType.String
+
+	return stored, true, tx.Commit()
+}
+
+// Сохраняет ответ на запрос с ключом идемпотентности key, если ответ ещё не сохранён.
+func (repo *IdempotencyRepo) SaveIdempotentResponse(ctx context.Context, key *entities.IdempotencyKey) (err error) {
+	ctx, span := startSpan(ctx, "IdempotencyRepo.SaveIdempotentResponse")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE idempotency_keys
+		SET status = $3, content_type = $4, body = $5
+		WHERE user_id = $1 AND key = $2 AND status IS NULL
+	`
+
+	_, err = repo.db.ExecContext(ctx, query, key.UserID, key.Key, key.Status, key.ContentType, key.Body)
+
+	return err
+}
+
+// Удаляет ключ идемпотентности key незавершённого запроса.
+func (repo *IdempotencyRepo) DeleteIdempotencyKey(ctx context.Context, key *entities.IdempotencyKey) (err error) {
+	ctx, span := startSpan(ctx, "IdempotencyRepo.DeleteIdempotencyKey")
+	defer tracing.End(span, &err)
+
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2 AND status IS NULL
+	`
+
+	_, err = repo.db.ExecContext(ctx, query, key.UserID, key.Key)
+
+	return err
+}
+
+func (repo *IdempotencyRepo) DeleteExpiredIdempotencyKeys(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "IdempotencyRepo.DeleteExpiredIdempotencyKeys")
+	defer tracing.End(span, &err)
+
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE expires < now()
+	`
+
+	_, err = repo.db.ExecContext(ctx, query)
+
+	return err
+}
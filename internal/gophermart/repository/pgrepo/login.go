@@ -0,0 +1,144 @@
+package pgrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
+	"github.com/KryukovO/gophermart/internal/postgres"
+)
+
+type LoginAttemptRepo struct {
+	db *postgres.Postgres
+}
+
+func NewLoginAttemptRepo(db *postgres.Postgres) *LoginAttemptRepo {
+	return &LoginAttemptRepo{db: db}
+}
+
+// Резервирует попытку входа attempt по правилам policy и заполняет время блокировки,
+// установленной попыткой. Запись ключа блокируется на время резервирования, поэтому
+// параллельные попытки входа по одному ключу учитываются последовательно.
+// Возвращает LoginLockedError, если вход заблокирован.
+func (repo *LoginAttemptRepo) ReserveLoginAttempt(
+	ctx context.Context, attempt *entities.LoginAttempt, policy entities.LoginPolicy,
+) (err error) {
+	ctx, span := startSpan(ctx, "LoginAttemptRepo.ReserveLoginAttempt")
+	defer tracing.End(span, &err)
+
+	query1 := `
+		INSERT INTO login_attempts(key, failures, last_failure)
+		VALUES ($1, 0, now())
+		ON CONFLICT (key) DO NOTHING
+	`
+
+	query2 := `
+		SELECT failures, last_failure, locked_until, now()
+		FROM login_attempts
+		WHERE key = $1
+		FOR UPDATE
+	`
+
+	query3 := `
+		UPDATE login_attempts
+		SET failures = $2, last_failure = $3, locked_until = $4
+		WHERE key = $1
+	`
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query1, attempt.Key)
+	if err != nil {
+		return err
+	}
+
+	var (
+		failures    entities.LoginFailures
+		lockedUntil sql.NullTime
+		now         time.Time
+	)
+
+	err = tx.QueryRowContext(ctx, query2, attempt.Key).Scan(
+		&failures.Failures, &failures.LastFailure, &lockedUntil, &now,
+	)
+	if err != nil {
+		return err
+	}
+
+	failures.LockedUntil = lockedUntil.Time
+
+	err = policy.Reserve(&failures, attempt.MaxAttempts, now)
+	if err != nil {
+		return err
+	}
+
+	lockedUntil = sql.NullTime{Time: failures.LockedUntil, Valid: !failures.LockedUntil.IsZero()}
+
+	_, err = tx.ExecContext(ctx, query3, attempt.Key, failures.Failures, failures.LastFailure, lockedUntil)
+	if err != nil {
+		return err
+	}
+
+	attempt.LockedUntil = failures.LockedUntil
+
+	return tx.Commit()
+}
+
+// Отменяет зарезервированную попытку входа attempt, которая не должна считаться неудачной.
+// Блокировка, установленная попыткой, снимается, если после резервирования её не изменили другие попытки.
+func (repo *LoginAttemptRepo) ReleaseLoginAttempt(ctx context.Context, attempt *entities.LoginAttempt) (err error) {
+	ctx, span := startSpan(ctx, "LoginAttemptRepo.ReleaseLoginAttempt")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE login_attempts
+		SET
+			failures = GREATEST(failures - 1, 0),
+			locked_until = CASE WHEN locked_until = $2 THEN NULL ELSE locked_until END
+		WHERE key = $1
+	`
+
+	lockedUntil := sql.NullTime{Time: attempt.LockedUntil, Valid: !attempt.LockedUntil.IsZero()}
+
+	_, err = repo.db.ExecContext(ctx, query, attempt.Key, lockedUntil)
+
+	return err
+}
+
+func (repo *LoginAttemptRepo) ResetLoginFailures(ctx context.Context, key string) (err error) {
+	ctx, span := startSpan(ctx, "LoginAttemptRepo.ResetLoginFailures")
+	defer tracing.End(span, &err)
+
+	query := `
+		DELETE FROM login_attempts
+		WHERE key = $1
+	`
+
+	_, err = repo.db.ExecContext(ctx, query, key)
+
+	return err
+}
+
+// Удаляет записи о неудачных попытках входа, последняя из которых была раньше, чем window назад,
+// если вход по ним не заблокирован: такие счётчики всё равно начались бы заново.
+func (repo *LoginAttemptRepo) DeleteStaleLoginAttempts(ctx context.Context, window time.Duration) (err error) {
+	ctx, span := startSpan(ctx, "LoginAttemptRepo.DeleteStaleLoginAttempts")
+	defer tracing.End(span, &err)
+
+	query := `
+		DELETE FROM login_attempts
+		WHERE last_failure < now() - $1 * interval '1 millisecond'
+			AND (locked_until IS NULL OR locked_until < now())
+	`
+
+	_, err = repo.db.ExecContext(ctx, query, window.Milliseconds())
+
+	return err
+}
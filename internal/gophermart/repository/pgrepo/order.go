@@ -4,8 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"sort"
+	"time"
 
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
 	"github.com/KryukovO/gophermart/internal/postgres"
 
 	"github.com/jackc/pgerrcode"
@@ -20,7 +23,10 @@ func NewOrderRepo(db *postgres.Postgres) *OrderRepo {
 	return &OrderRepo{db: db}
 }
 
-func (repo *OrderRepo) AddOrder(ctx context.Context, order *entities.Order) error {
+func (repo *OrderRepo) AddOrder(ctx context.Context, order *entities.Order) (err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.AddOrder")
+	defer tracing.End(span, &err)
+
 	query := `
 		INSERT INTO orders(user_id, order_num, status, uploaded)
 		VALUES($1, $2, $3, now())
@@ -55,39 +61,50 @@ func (repo *OrderRepo) AddOrder(ctx context.Context, order *entities.Order) erro
 	return tx.Commit()
 }
 
-func (repo *OrderRepo) OrderByNumber(ctx context.Context, number string) (*entities.Order, error) {
+func (repo *OrderRepo) OrderByNumber(ctx context.Context, number string) (_ *entities.Order, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.OrderByNumber")
+	defer tracing.End(span, &err)
+
 	query := `
 		SELECT user_id, order_num, status, accrual, uploaded
 		FROM orders 
 		WHERE order_num = $1
 	`
 
-	var (
-		accrual sql.NullFloat64
-		order   = &entities.Order{}
-	)
+	order := &entities.Order{}
 
-	err := repo.db.QueryRowContext(ctx, query, number).Scan(
-		&order.UserID, &order.Number, &order.Status, &accrual, &order.UploadedAt,
+	err = repo.db.QueryRowContext(ctx, query, number).Scan(
+		&order.UserID, &order.Number, &order.Status, &order.Accrual, &order.UploadedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	order.Accrual = accrual.Float64
-
 	return order, nil
 }
 
-func (repo *OrderRepo) Orders(ctx context.Context, userID int64) ([]entities.Order, error) {
+func (repo *OrderRepo) Orders(ctx context.Context, filter *entities.OrderFilter) (_ []entities.Order, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.Orders")
+	defer tracing.End(span, &err)
+
+	var builder queryBuilder
+
+	builder.where("user_id = " + builder.arg(filter.UserID))
+
+	if len(filter.Statuses) != 0 {
+		builder.where("status::TEXT = ANY(" + builder.arg(filter.Statuses) + ")")
+	}
+
+	builder.dateRange("uploaded", filter.Uploaded)
+	pageClause := builder.page("uploaded", "id", filter.Page)
+
 	query := `
-		SELECT order_num, status, accrual, uploaded
-		FROM orders 
-		WHERE user_id = $1
-		ORDER BY uploaded ASC
-	`
+		SELECT id, order_num, status, accrual, uploaded
+		FROM orders
+		` + builder.whereClause() + `
+		` + pageClause
 
-	rows, err := repo.db.QueryContext(ctx, query, userID)
+	rows, err := repo.db.QueryContext(ctx, query, builder.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -97,18 +114,13 @@ func (repo *OrderRepo) Orders(ctx context.Context, userID int64) ([]entities.Ord
 	orders := make([]entities.Order, 0)
 
 	for rows.Next() {
-		var (
-			accrual sql.NullFloat64
-			order   = entities.Order{UserID: userID}
-		)
+		order := entities.Order{UserID: filter.UserID}
 
-		err = rows.Scan(&order.Number, &order.Status, &accrual, &order.UploadedAt)
+		err = rows.Scan(&order.ID, &order.Number, &order.Status, &order.Accrual, &order.UploadedAt)
 		if err != nil {
 			return nil, err
 		}
 
-		order.Accrual = accrual.Float64
-
 		orders = append(orders, order)
 	}
 
@@ -119,15 +131,30 @@ func (repo *OrderRepo) Orders(ctx context.Context, userID int64) ([]entities.Ord
 	return orders, nil
 }
 
-func (repo *OrderRepo) ProcessableOrders(ctx context.Context) ([]entities.Order, error) {
+func (repo *OrderRepo) ProcessableOrders(
+	ctx context.Context, instance string, limit uint, lease time.Duration,
+) (_ []entities.Order, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.ProcessableOrders")
+	defer tracing.End(span, &err)
+
 	query := `
-		SELECT user_id, order_num, status, uploaded
-		FROM orders 
-		WHERE status = 'NEW' OR status = 'PROCESSING'
-		ORDER BY uploaded ASC
+		UPDATE orders o
+		SET claimed_by = $1, claimed_until = now() + $3 * interval '1 millisecond'
+		FROM (
+			SELECT id
+			FROM orders
+			WHERE (status = 'NEW' OR status = 'PROCESSING')
+				AND (next_poll_at IS NULL OR next_poll_at <= now())
+				AND (claimed_until IS NULL OR claimed_until < now() OR claimed_by = $1)
+			ORDER BY uploaded ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		) claimed
+		WHERE o.id = claimed.id
+		RETURNING o.user_id, o.order_num, o.status, o.uploaded, o.attempts
 	`
 
-	rows, err := repo.db.QueryContext(ctx, query)
+	rows, err := repo.db.QueryContext(ctx, query, instance, limit, lease.Milliseconds())
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +166,7 @@ func (repo *OrderRepo) ProcessableOrders(ctx context.Context) ([]entities.Order,
 	for rows.Next() {
 		order := entities.Order{}
 
-		err = rows.Scan(&order.UserID, &order.Number, &order.Status, &order.UploadedAt)
+		err = rows.Scan(&order.UserID, &order.Number, &order.Status, &order.UploadedAt, &order.Attempts)
 		if err != nil {
 			return nil, err
 		}
@@ -151,14 +178,29 @@ func (repo *OrderRepo) ProcessableOrders(ctx context.Context) ([]entities.Order,
 		return nil, err
 	}
 
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].UploadedAt.Before(orders[j].UploadedAt)
+	})
+
 	return orders, nil
 }
 
-func (repo *OrderRepo) UpdateOrder(ctx context.Context, order *entities.Order) error {
+func (repo *OrderRepo) UpdateOrder(ctx context.Context, instance string, order *entities.Order) (err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.UpdateOrder")
+	defer tracing.End(span, &err)
+
 	query := `
-		UPDATE orders
-		SET status = $1, accrual = $2
-		WHERE order_num = $3
+		UPDATE orders o
+		SET status = $1, accrual = $2, attempts = $3, last_error = NULLIF($4, ''), next_poll_at = $5,
+			claimed_by = NULL, claimed_until = NULL
+		FROM (
+			SELECT id, status
+			FROM orders
+			WHERE order_num = $6 AND status IN ('NEW', 'PROCESSING') AND claimed_by = $7
+			FOR UPDATE
+		) prev
+		WHERE o.id = prev.id
+		RETURNING o.user_id, prev.status
 	`
 
 	tx, err := repo.db.BeginTx(ctx, nil)
@@ -168,10 +210,167 @@ func (repo *OrderRepo) UpdateOrder(ctx context.Context, order *entities.Order) e
 
 	defer tx.Rollback()
 
-	_, err = tx.ExecContext(ctx, query, order.Status, order.Accrual, order.Number)
+	nextPollAt := sql.NullTime{Time: order.NextPollAt, Valid: !order.NextPollAt.IsZero()}
+
+	var (
+		userID     int64
+		prevStatus string
+	)
+
+	err = tx.QueryRowContext(
+		ctx, query,
+		order.Status, order.Accrual, order.Attempts, order.LastError, nextPollAt, order.Number, instance,
+	).Scan(&userID, &prevStatus)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Заказ уже обработан либо захвачен другим экземпляром
+			return entities.ErrOrderLeaseLost
+		}
+
 		return err
 	}
 
+	if order.Status != prevStatus {
+		err = enqueueWebhookEvent(ctx, tx, userID, entities.NewWebhookEvent(
+			entities.WebhookEventOrderStatusChanged,
+			entities.OrderStatusEvent{
+				Number:         order.Number,
+				Status:         order.Status,
+				PreviousStatus: prevStatus,
+				Accrual:        order.Accrual,
+			},
+		))
+		if err != nil {
+			return err
+		}
+	}
+
 	return tx.Commit()
 }
+
+func (repo *OrderRepo) ProcessOrder(ctx context.Context, instance string, order *entities.Order) (err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.ProcessOrder")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE orders o
+		SET status = 'PROCESSED', accrual = $1, last_error = NULL, next_poll_at = NULL,
+			claimed_by = NULL, claimed_until = NULL
+		FROM (
+			SELECT id, status
+			FROM orders
+			WHERE order_num = $2 AND status IN ('NEW', 'PROCESSING') AND claimed_by = $3
+			FOR UPDATE
+		) prev
+		WHERE o.id = prev.id
+		RETURNING o.user_id, prev.status
+	`
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var (
+		userID     int64
+		prevStatus string
+	)
+
+	err = tx.QueryRowContext(ctx, query, order.Accrual, order.Number, instance).Scan(&userID, &prevStatus)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Заказ уже обработан либо захвачен другим экземпляром
+			return entities.ErrOrderLeaseLost
+		}
+
+		return err
+	}
+
+	err = enqueueWebhookEvent(ctx, tx, userID, entities.NewWebhookEvent(
+		entities.WebhookEventOrderStatusChanged,
+		entities.OrderStatusEvent{
+			Number:         order.Number,
+			Status:         entities.OrderStatusProcessed,
+			PreviousStatus: prevStatus,
+			Accrual:        order.Accrual,
+		},
+	))
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO user_balance_log(user_id, processed, operation, order_num, sum)
+		VALUES ($1, now(), 'refill', $2, $3)
+		ON CONFLICT (order_num) WHERE operation = 'refill' DO NOTHING
+		RETURNING processed
+	`
+
+	var processedAt time.Time
+
+	err = tx.QueryRowContext(ctx, query, userID, order.Number, order.Accrual).Scan(&processedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Начисление по заказу уже зачислено
+			return tx.Commit()
+		}
+
+		return err
+	}
+
+	query = `
+		UPDATE user_balance
+		SET balance = balance + $1
+		WHERE user_id = $2
+		RETURNING balance
+	`
+
+	var current entities.Money
+
+	err = tx.QueryRowContext(ctx, query, order.Accrual, userID).Scan(&current)
+	if err != nil {
+		return err
+	}
+
+	err = enqueueWebhookEvent(ctx, tx, userID, entities.NewWebhookEvent(
+		entities.WebhookEventBalanceChanged,
+		entities.BalanceEvent{
+			Operation:   entities.BalanceOperationRefill,
+			Order:       order.Number,
+			Sum:         order.Accrual,
+			Current:     current,
+			ProcessedAt: processedAt,
+		},
+	))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo *OrderRepo) PendingOrders(ctx context.Context) (_ entities.PendingOrders, err error) {
+	ctx, span := startSpan(ctx, "OrderRepo.PendingOrders")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			count(*) FILTER (WHERE status = 'NEW'),
+			count(*) FILTER (WHERE status = 'PROCESSING')
+		FROM orders
+		WHERE status = 'NEW' OR status = 'PROCESSING'
+	`
+
+	var pending entities.PendingOrders
+
+	err = repo.db.QueryRowContext(ctx, query).Scan(&pending.New, &pending.Processing)
+	if err != nil {
+		return entities.PendingOrders{}, err
+	}
+
+	pending.Total = pending.New + pending.Processing
+
+	return pending, nil
+}
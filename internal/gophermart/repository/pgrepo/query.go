@@ -0,0 +1,86 @@
+package pgrepo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+)
+
+// Построитель условий запроса с позиционными параметрами.
+type queryBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+// Добавляет аргумент запроса и возвращает его позиционный параметр.
+func (b *queryBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *queryBuilder) where(condition string) {
+	b.conditions = append(b.conditions, condition)
+}
+
+// Добавляет условия попадания значения столбца column в диапазон dateRange.
+func (b *queryBuilder) dateRange(column string, dateRange entities.DateRange) {
+	if !dateRange.From.IsZero() {
+		b.where(fmt.Sprintf("%s >= %s", column, b.arg(dateRange.From)))
+	}
+
+	if !dateRange.To.IsZero() {
+		b.where(fmt.Sprintf("%s <= %s", column, b.arg(dateRange.To)))
+	}
+}
+
+// Добавляет условие продолжения выборки после курсора страницы
+// и возвращает выражения сортировки и ограничения количества строк.
+func (b *queryBuilder) page(timeColumn, idColumn string, page entities.Page) string {
+	direction, comparison := "ASC", ">"
+	if page.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if page.Cursor != nil {
+		b.where(fmt.Sprintf(
+			"(%s, %s) %s (%s, %s)",
+			timeColumn, idColumn, comparison, b.arg(page.Cursor.Time), b.arg(page.Cursor.ID),
+		))
+	}
+
+	return fmt.Sprintf(
+		"ORDER BY %s %s, %s %s LIMIT %s",
+		timeColumn, direction, idColumn, direction, b.arg(page.Limit),
+	)
+}
+
+// Добавляет условие продолжения выборки после курсора страницы, упорядоченной только
+// по идентификатору, и возвращает выражения сортировки и ограничения количества строк.
+// Время курсора не учитывается.
+func (b *queryBuilder) idPage(idColumn string, page entities.Page) string {
+	direction, comparison := "ASC", ">"
+	if page.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if page.Cursor != nil {
+		b.where(fmt.Sprintf("%s %s %s", idColumn, comparison, b.arg(page.Cursor.ID)))
+	}
+
+	return fmt.Sprintf("ORDER BY %s %s LIMIT %s", idColumn, direction, b.arg(page.Limit))
+}
+
+func (b *queryBuilder) whereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+// Экранирует символы шаблона LIKE, чтобы значение сравнивалось буквально.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,42 @@
+package pgrepo
+
+import (
+	"context"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
+	"github.com/KryukovO/gophermart/internal/postgres"
+)
+
+type StatsRepo struct {
+	db *postgres.Postgres
+}
+
+func NewStatsRepo(db *postgres.Postgres) *StatsRepo {
+	return &StatsRepo{db: db}
+}
+
+func (repo *StatsRepo) Stats(ctx context.Context) (_ entities.Stats, err error) {
+	ctx, span := startSpan(ctx, "StatsRepo.Stats")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			(SELECT count(*) FROM orders WHERE status = 'NEW' OR status = 'PROCESSING'),
+			(SELECT COALESCE(sum(sum), 0) FROM user_balance_log WHERE operation = 'refill'),
+			(
+				SELECT COALESCE(sum(CASE WHEN operation = 'withdrawal' THEN sum ELSE -sum END), 0)
+				FROM user_balance_log
+				WHERE operation IN ('withdrawal', 'reversal')
+			)
+	`
+
+	var stats entities.Stats
+
+	err = repo.db.QueryRowContext(ctx, query).Scan(&stats.PendingOrders, &stats.Accrued, &stats.Withdrawn)
+	if err != nil {
+		return entities.Stats{}, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,274 @@
+package pgrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
+	"github.com/KryukovO/gophermart/internal/postgres"
+)
+
+type TokenRepo struct {
+	db *postgres.Postgres
+}
+
+func NewTokenRepo(db *postgres.Postgres) *TokenRepo {
+	return &TokenRepo{db: db}
+}
+
+// Сохраняет токен обновления и удаляет истёкшие токены обновления пользователя.
+func (repo *TokenRepo) AddRefreshToken(ctx context.Context, token *entities.RefreshToken) (err error) {
+	ctx, span := startSpan(ctx, "TokenRepo.AddRefreshToken")
+	defer tracing.End(span, &err)
+
+	query1 := `
+		DELETE FROM refresh_tokens
+		WHERE user_id = $1 AND expires < now()
+	`
+
+	query2 := `
+		INSERT INTO refresh_tokens(
+			token_hash, user_id, family, access_token_id, access_expires, issued, expires
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query1, token.UserID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx, query2,
+		token.Hash, token.UserID, token.Family, token.AccessTokenID,
+		token.AccessExpiresAt, token.IssuedAt, token.ExpiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Помечает действующий токен обновления с хешем token.Hash использованным
+// и заполняет остальные поля token, в том числе текущую роль пользователя.
+// Возвращает ErrRefreshTokenReused, если токен уже был использован,
+// и ErrInvalidToken, если токен не найден, истёк или отозван.
+func (repo *TokenRepo) UseRefreshToken(ctx context.Context, token *entities.RefreshToken) (err error) {
+	ctx, span := startSpan(ctx, "TokenRepo.UseRefreshToken")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE refresh_tokens t
+		SET used = now()
+		FROM users u
+		WHERE t.token_hash = $1 AND t.used IS NULL AND t.revoked IS NULL AND t.expires > now()
+			AND u.id = t.user_id
+		RETURNING t.user_id, u.role, t.family, t.access_token_id, t.access_expires, t.issued, t.expires
+	`
+
+	err = repo.db.QueryRowContext(ctx, query, token.Hash).Scan(
+		&token.UserID, &token.Role, &token.Family, &token.AccessTokenID,
+		&token.AccessExpiresAt, &token.IssuedAt, &token.ExpiresAt,
+	)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	query = `
+		SELECT user_id, family
+		FROM refresh_tokens
+		WHERE token_hash = $1 AND used IS NOT NULL AND revoked IS NULL
+	`
+
+	err = repo.db.QueryRowContext(ctx, query, token.Hash).Scan(&token.UserID, &token.Family)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.ErrInvalidToken
+		}
+
+		return err
+	}
+
+	return entities.ErrRefreshTokenReused
+}
+
+// Отзывает все токены обновления семейства и выпущенные вместе с ними токены доступа.
+func (repo *TokenRepo) RevokeFamily(ctx context.Context, family string) (err error) {
+	ctx, span := startSpan(ctx, "TokenRepo.RevokeFamily")
+	defer tracing.End(span, &err)
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = revokeFamily(ctx, tx, family)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Отзывает токен доступа и семейство токенов обновления, выпущенное вместе с ним.
+func (repo *TokenRepo) RevokeSession(ctx context.Context, token *entities.AccessToken) (err error) {
+	ctx, span := startSpan(ctx, "TokenRepo.RevokeSession")
+	defer tracing.End(span, &err)
+
+	query1 := `
+		INSERT INTO revoked_tokens(token_id, expires)
+		VALUES ($1, $2)
+		ON CONFLICT (token_id) DO NOTHING
+	`
+
+	query2 := `
+		SELECT family
+		FROM refresh_tokens
+		WHERE access_token_id = $1
+	`
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query1, token.ID, token.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	var family string
+
+	err = tx.QueryRowContext(ctx, query2, token.ID).Scan(&family)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if family != "" {
+		err = revokeFamily(ctx, tx, family)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Отзывает все токены обновления пользователя и выпущенные вместе с ними токены доступа.
+func (repo *TokenRepo) RevokeUser(ctx context.Context, userID int64) (err error) {
+	ctx, span := startSpan(ctx, "TokenRepo.RevokeUser")
+	defer tracing.End(span, &err)
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = revokeUser(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo *TokenRepo) AccessTokenRevoked(ctx context.Context, tokenID string) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "TokenRepo.AccessTokenRevoked")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM revoked_tokens WHERE token_id = $1
+		)
+	`
+
+	var revoked bool
+
+	err = repo.db.QueryRowContext(ctx, query, tokenID).Scan(&revoked)
+	if err != nil {
+		return false, err
+	}
+
+	return revoked, nil
+}
+
+// Отзывает семейство токенов в рамках транзакции tx.
+// Заодно удаляет записи об отозванных токенах доступа, срок действия которых истёк.
+func revokeFamily(ctx context.Context, tx *sql.Tx, family string) error {
+	query1 := `
+		UPDATE refresh_tokens
+		SET revoked = now()
+		WHERE family = $1 AND revoked IS NULL
+	`
+
+	query2 := `
+		INSERT INTO revoked_tokens(token_id, expires)
+		SELECT access_token_id, access_expires
+		FROM refresh_tokens
+		WHERE family = $1 AND access_expires > now()
+		ON CONFLICT (token_id) DO NOTHING
+	`
+
+	query3 := `
+		DELETE FROM revoked_tokens
+		WHERE expires < now()
+	`
+
+	for _, query := range []string{query1, query2} {
+		_, err := tx.ExecContext(ctx, query, family)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.ExecContext(ctx, query3)
+
+	return err
+}
+
+// Отзывает все токены обновления пользователя userID и выпущенные вместе с ними токены доступа
+// в транзакции tx.
+func revokeUser(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query1 := `
+		UPDATE refresh_tokens
+		SET revoked = now()
+		WHERE user_id = $1 AND revoked IS NULL
+	`
+
+	query2 := `
+		INSERT INTO revoked_tokens(token_id, expires)
+		SELECT access_token_id, access_expires
+		FROM refresh_tokens
+		WHERE user_id = $1 AND access_expires > now()
+		ON CONFLICT (token_id) DO NOTHING
+	`
+
+	for _, query := range []string{query1, query2} {
+		_, err := tx.ExecContext(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
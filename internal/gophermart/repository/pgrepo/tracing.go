@@ -0,0 +1,20 @@
+package pgrepo
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/KryukovO/gophermart/internal/gophermart/repository/pgrepo")
+
+// Начинает спан запроса к БД, выполняемого методом репозитория name.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer.Start(
+		ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL),
+	)
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
 	"github.com/KryukovO/gophermart/internal/postgres"
 
 	"github.com/jackc/pgerrcode"
@@ -20,9 +21,12 @@ func NewUserRepo(db *postgres.Postgres) *UserRepo {
 	return &UserRepo{db: db}
 }
 
-func (repo *UserRepo) AddUser(ctx context.Context, user *entities.User) error {
+func (repo *UserRepo) AddUser(ctx context.Context, user *entities.User) (err error) {
+	ctx, span := startSpan(ctx, "UserRepo.AddUser")
+	defer tracing.End(span, &err)
+
 	query := `
-		INSERT INTO users(login, password, salt) VALUES($1, $2, $3)
+		INSERT INTO users(login, password, salt, role) VALUES($1, $2, $3, $4)
 		RETURNING id
 	`
 
@@ -35,7 +39,7 @@ func (repo *UserRepo) AddUser(ctx context.Context, user *entities.User) error {
 
 	var id int64
 
-	err = tx.QueryRowContext(ctx, query, user.Login, user.EncryptedPassword, user.Salt).Scan(&id)
+	err = tx.QueryRowContext(ctx, query, user.Login, user.EncryptedPassword, user.Salt, user.Role).Scan(&id)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
@@ -60,15 +64,20 @@ func (repo *UserRepo) AddUser(ctx context.Context, user *entities.User) error {
 	return tx.Commit()
 }
 
-func (repo *UserRepo) User(ctx context.Context, user *entities.User) error {
+func (repo *UserRepo) User(ctx context.Context, user *entities.User) (err error) {
+	ctx, span := startSpan(ctx, "UserRepo.User")
+	defer tracing.End(span, &err)
+
 	query := `
 		SELECT 
-			id, password, salt 
+			id, password, salt, role
 		FROM users
 		WHERE login = $1
 	`
 
-	err := repo.db.QueryRowContext(ctx, query, user.Login).Scan(&user.ID, &user.EncryptedPassword, &user.Salt)
+	err = repo.db.QueryRowContext(ctx, query, user.Login).Scan(
+		&user.ID, &user.EncryptedPassword, &user.Salt, &user.Role,
+	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return entities.ErrInvalidLoginPassword
@@ -79,3 +88,234 @@ func (repo *UserRepo) User(ctx context.Context, user *entities.User) error {
 
 	return nil
 }
+
+// Заполняет логин, пароль и роль пользователя с идентификатором user.ID.
+// Возвращает ErrUserNotFound, если пользователь не найден.
+func (repo *UserRepo) UserByID(ctx context.Context, user *entities.User) (err error) {
+	ctx, span := startSpan(ctx, "UserRepo.UserByID")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT 
+			login, password, salt, role
+		FROM users
+		WHERE id = $1
+	`
+
+	err = repo.db.QueryRowContext(ctx, query, user.ID).Scan(
+		&user.Login, &user.EncryptedPassword, &user.Salt, &user.Role,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.ErrUserNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Сохраняет пересчитанный хеш пароля пользователя.
+// Токены сброса пароля и сессии пользователя при этом остаются действительными.
+func (repo *UserRepo) UpdatePassword(ctx context.Context, user *entities.User) (err error) {
+	ctx, span := startSpan(ctx, "UserRepo.UpdatePassword")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE users
+		SET password = $1, salt = $2
+		WHERE id = $3
+	`
+
+	_, err = repo.db.ExecContext(ctx, query, user.EncryptedPassword, user.Salt, user.ID)
+
+	return err
+}
+
+// Сохраняет новый пароль пользователя, делает недействительными выданные ранее
+// токены сброса пароля и отзывает все сессии пользователя в одной транзакции.
+func (repo *UserRepo) ChangePassword(ctx context.Context, user *entities.User) (err error) {
+	ctx, span := startSpan(ctx, "UserRepo.ChangePassword")
+	defer tracing.End(span, &err)
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = changePassword(ctx, tx, user)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Сохраняет токен сброса пароля и удаляет истёкшие токены пользователя.
+func (repo *UserRepo) AddResetToken(ctx context.Context, token *entities.ResetToken) (err error) {
+	ctx, span := startSpan(ctx, "UserRepo.AddResetToken")
+	defer tracing.End(span, &err)
+
+	query1 := `
+		DELETE FROM password_reset_tokens
+		WHERE user_id = $1 AND expires < now()
+	`
+
+	query2 := `
+		INSERT INTO password_reset_tokens(token_hash, user_id, expires)
+		VALUES ($1, $2, $3)
+	`
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query1, token.UserID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query2, token.Hash, token.UserID, token.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Помечает действующий токен сброса пароля с хешем token.Hash использованным
+// и сохраняет новый пароль его владельца в одной транзакции с ChangePassword.
+// Заполняет остальные поля token, а также идентификатор и логин user.
+// Возвращает ErrInvalidResetToken, если токен не найден, истёк или уже был использован.
+func (repo *UserRepo) ResetPassword(
+	ctx context.Context, token *entities.ResetToken, user *entities.User,
+) (err error) {
+	ctx, span := startSpan(ctx, "UserRepo.ResetPassword")
+	defer tracing.End(span, &err)
+
+	query1 := `
+		UPDATE password_reset_tokens
+		SET used = now()
+		WHERE token_hash = $1 AND used IS NULL AND expires > now()
+		RETURNING user_id, expires, used
+	`
+
+	query2 := `
+		SELECT login
+		FROM users
+		WHERE id = $1
+	`
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query1, token.Hash).Scan(&token.UserID, &token.ExpiresAt, &token.UsedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.ErrInvalidResetToken
+		}
+
+		return err
+	}
+
+	user.ID = token.UserID
+
+	err = tx.QueryRowContext(ctx, query2, user.ID).Scan(&user.Login)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.ErrUserNotFound
+		}
+
+		return err
+	}
+
+	err = changePassword(ctx, tx, user)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Сохраняет новый пароль пользователя, удаляет его токены сброса пароля
+// и отзывает все его сессии в рамках транзакции tx.
+func changePassword(ctx context.Context, tx *sql.Tx, user *entities.User) error {
+	query1 := `
+		UPDATE users
+		SET password = $1, salt = $2
+		WHERE id = $3
+	`
+
+	query2 := `
+		DELETE FROM password_reset_tokens
+		WHERE user_id = $1
+	`
+
+	_, err := tx.ExecContext(ctx, query1, user.EncryptedPassword, user.Salt, user.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query2, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return revokeUser(ctx, tx, user.ID)
+}
+
+// Возвращает страницу пользователей, логин которых содержит filter.Login без учёта регистра.
+func (repo *UserRepo) Users(ctx context.Context, filter *entities.UserFilter) (_ []entities.UserProfile, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.Users")
+	defer tracing.End(span, &err)
+
+	var builder queryBuilder
+
+	if filter.Login != "" {
+		builder.where("login ILIKE '%' || " + builder.arg(escapeLike(filter.Login)) + " || '%'")
+	}
+
+	pageClause := builder.idPage("id", filter.Page)
+
+	query := `
+		SELECT id, login, role
+		FROM users
+		` + builder.whereClause() + `
+		` + pageClause
+
+	rows, err := repo.db.QueryContext(ctx, query, builder.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := make([]entities.UserProfile, 0)
+
+	for rows.Next() {
+		var user entities.UserProfile
+
+		err = rows.Scan(&user.ID, &user.Login, &user.Role)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
@@ -0,0 +1,288 @@
+package pgrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
+	"github.com/KryukovO/gophermart/internal/postgres"
+)
+
+type WebhookRepo struct {
+	db *postgres.Postgres
+}
+
+func NewWebhookRepo(db *postgres.Postgres) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+func (repo *WebhookRepo) AddWebhook(ctx context.Context, webhook *entities.Webhook) (err error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.AddWebhook")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO webhooks(user_id, url, secret, created)
+		VALUES ($1, $2, $3, now())
+		RETURNING id, created
+	`
+
+	return repo.db.QueryRowContext(ctx, query, webhook.UserID, webhook.URL, webhook.Secret).Scan(
+		&webhook.ID, &webhook.CreatedAt,
+	)
+}
+
+func (repo *WebhookRepo) Webhooks(ctx context.Context, userID int64) (_ []entities.Webhook, err error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.Webhooks")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT id, url, created
+		FROM webhooks
+		WHERE user_id = $1
+		ORDER BY id
+	`
+
+	rows, err := repo.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	webhooks := make([]entities.Webhook, 0)
+
+	for rows.Next() {
+		webhook := entities.Webhook{UserID: userID}
+
+		err = rows.Scan(&webhook.ID, &webhook.URL, &webhook.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// Заполняет подписку webhook.ID пользователя webhook.UserID.
+// Возвращает ErrWebhookNotFound, если у пользователя нет такой подписки.
+func (repo *WebhookRepo) Webhook(ctx context.Context, webhook *entities.Webhook) (err error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.Webhook")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT url, created
+		FROM webhooks
+		WHERE id = $1 AND user_id = $2
+	`
+
+	err = repo.db.QueryRowContext(ctx, query, webhook.ID, webhook.UserID).Scan(&webhook.URL, &webhook.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entities.ErrWebhookNotFound
+	}
+
+	return err
+}
+
+// Удаляет подписку webhook.ID пользователя webhook.UserID вместе с журналом её доставки.
+// Возвращает ErrWebhookNotFound, если у пользователя нет такой подписки.
+func (repo *WebhookRepo) DeleteWebhook(ctx context.Context, webhook *entities.Webhook) (err error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.DeleteWebhook")
+	defer tracing.End(span, &err)
+
+	query := `
+		DELETE FROM webhooks
+		WHERE id = $1 AND user_id = $2
+	`
+
+	res, err := repo.db.ExecContext(ctx, query, webhook.ID, webhook.UserID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return entities.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (repo *WebhookRepo) WebhookDeliveries(
+	ctx context.Context, filter *entities.WebhookDeliveryFilter,
+) (_ []entities.WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.WebhookDeliveries")
+	defer tracing.End(span, &err)
+
+	var builder queryBuilder
+
+	builder.where("w.user_id = " + builder.arg(filter.UserID))
+	builder.where("d.webhook_id = " + builder.arg(filter.WebhookID))
+	builder.dateRange("d.created", filter.Created)
+	pageClause := builder.page("d.created", "d.id", filter.Page)
+
+	query := `
+		SELECT
+			d.id, d.event_id, d.event_type, d.status, d.attempts, COALESCE(d.response_status, 0),
+			COALESCE(d.last_error, ''), d.created, d.next_attempt_at, d.delivered
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		` + builder.whereClause() + `
+		` + pageClause
+
+	rows, err := repo.db.QueryContext(ctx, query, builder.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	deliveries := make([]entities.WebhookDelivery, 0)
+
+	for rows.Next() {
+		delivery := entities.WebhookDelivery{WebhookID: filter.WebhookID}
+
+		var nextAttemptAt, deliveredAt sql.NullTime
+
+		err = rows.Scan(
+			&delivery.ID, &delivery.EventID, &delivery.EventType, &delivery.Status, &delivery.Attempts,
+			&delivery.ResponseStatus, &delivery.LastError, &delivery.CreatedAt, &nextAttemptAt, &deliveredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if nextAttemptAt.Valid {
+			delivery.NextAttemptAt = &nextAttemptAt.Time
+		}
+
+		if deliveredAt.Valid {
+			delivery.DeliveredAt = &deliveredAt.Time
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Захватывает для экземпляра instance на время lease не более limit уведомлений,
+// время очередной попытки доставки которых наступило.
+func (repo *WebhookRepo) PendingDeliveries(
+	ctx context.Context, instance string, limit uint, lease time.Duration,
+) (_ []entities.WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.PendingDeliveries")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE webhook_deliveries d
+		SET claimed_by = $1, claimed_until = now() + $3 * interval '1 millisecond'
+		FROM (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'pending'
+				AND next_attempt_at <= now()
+				AND (claimed_until IS NULL OR claimed_until < now() OR claimed_by = $1)
+			ORDER BY next_attempt_at ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		) claimed, webhooks w
+		WHERE d.id = claimed.id AND w.id = d.webhook_id
+		RETURNING
+			d.id, d.webhook_id, w.url, w.secret, d.event_id, d.event_type, d.payload, d.status,
+			d.attempts, d.created
+	`
+
+	rows, err := repo.db.QueryContext(ctx, query, instance, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	deliveries := make([]entities.WebhookDelivery, 0)
+
+	for rows.Next() {
+		delivery := entities.WebhookDelivery{}
+
+		err = rows.Scan(
+			&delivery.ID, &delivery.WebhookID, &delivery.URL, &delivery.Secret, &delivery.EventID,
+			&delivery.EventType, &delivery.Payload, &delivery.Status, &delivery.Attempts, &delivery.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID < deliveries[j].ID
+	})
+
+	return deliveries, nil
+}
+
+// Сохраняет результат попытки доставки уведомления и освобождает его захват.
+func (repo *WebhookRepo) UpdateDelivery(ctx context.Context, delivery *entities.WebhookDelivery) (err error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.UpdateDelivery")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, response_status = NULLIF($3, 0), last_error = NULLIF($4, ''),
+			next_attempt_at = $5, delivered = $6, claimed_by = NULL, claimed_until = NULL
+		WHERE id = $7
+	`
+
+	_, err = repo.db.ExecContext(
+		ctx, query,
+		delivery.Status, delivery.Attempts, delivery.ResponseStatus, delivery.LastError,
+		delivery.NextAttemptAt, delivery.DeliveredAt, delivery.ID,
+	)
+
+	return err
+}
+
+// Добавляет событие event в очередь доставки каждой подписки пользователя userID.
+// Выполняется в транзакции tx изменения, о котором уведомляет событие,
+// поэтому событие попадает в очередь только вместе с зафиксированным изменением.
+func enqueueWebhookEvent(ctx context.Context, tx *sql.Tx, userID int64, event entities.WebhookEvent) error {
+	query := `
+		INSERT INTO webhook_deliveries(webhook_id, event_id, event_type, payload, status, created, next_attempt_at)
+		SELECT id, $2, $3, $4, 'pending', now(), now()
+		FROM webhooks
+		WHERE user_id = $1
+	`
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, userID, event.ID, event.Type, payload)
+
+	return err
+}
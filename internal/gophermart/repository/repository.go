@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
 )
@@ -9,17 +10,73 @@ import (
 type UserRepo interface {
 	AddUser(ctx context.Context, user *entities.User) error
 	User(ctx context.Context, user *entities.User) error
+	UserByID(ctx context.Context, user *entities.User) error
+	UpdatePassword(ctx context.Context, user *entities.User) error
+	ChangePassword(ctx context.Context, user *entities.User) error
+	AddResetToken(ctx context.Context, token *entities.ResetToken) error
+	ResetPassword(ctx context.Context, token *entities.ResetToken, user *entities.User) error
+	Users(ctx context.Context, filter *entities.UserFilter) ([]entities.UserProfile, error)
 }
 
 type OrderRepo interface {
 	AddOrder(ctx context.Context, order *entities.Order) error
-	Orders(ctx context.Context, userID int64) ([]entities.Order, error)
-	ProcessableOrders(ctx context.Context) ([]entities.Order, error)
-	UpdateOrder(ctx context.Context, order *entities.Order) error
+	Orders(ctx context.Context, filter *entities.OrderFilter) ([]entities.Order, error)
+	ProcessableOrders(
+		ctx context.Context, instance string, limit uint, lease time.Duration,
+	) ([]entities.Order, error)
+	UpdateOrder(ctx context.Context, instance string, order *entities.Order) error
+	ProcessOrder(ctx context.Context, instance string, order *entities.Order) error
+	PendingOrders(ctx context.Context) (entities.PendingOrders, error)
 }
 
 type BalanceRepo interface {
 	Balance(ctx context.Context, userID int64) (entities.Balance, error)
 	ChangeBalance(ctx context.Context, change *entities.BalanceChange) error
-	Withdrawals(ctx context.Context, userID int64) ([]entities.BalanceChange, error)
+	ReverseWithdrawal(ctx context.Context, reversal *entities.BalanceChange) error
+	Withdrawals(ctx context.Context, filter *entities.WithdrawalFilter) ([]entities.BalanceChange, error)
+	Statement(ctx context.Context, filter *entities.StatementFilter) ([]entities.StatementEntry, error)
+}
+
+type TokenRepo interface {
+	AddRefreshToken(ctx context.Context, token *entities.RefreshToken) error
+	UseRefreshToken(ctx context.Context, token *entities.RefreshToken) error
+	RevokeFamily(ctx context.Context, family string) error
+	RevokeSession(ctx context.Context, token *entities.AccessToken) error
+	RevokeUser(ctx context.Context, userID int64) error
+	AccessTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// Хранилище неудачных попыток входа. Ключ идентифицирует логин или IP-адрес клиента.
+type LoginAttemptRepo interface {
+	ReserveLoginAttempt(ctx context.Context, attempt *entities.LoginAttempt, policy entities.LoginPolicy) error
+	ReleaseLoginAttempt(ctx context.Context, attempt *entities.LoginAttempt) error
+	ResetLoginFailures(ctx context.Context, key string) error
+	DeleteStaleLoginAttempts(ctx context.Context, window time.Duration) error
+}
+
+// Хранилище ключей идемпотентности запросов пользователей.
+type IdempotencyRepo interface {
+	AddIdempotencyKey(ctx context.Context, key *entities.IdempotencyKey) (entities.IdempotencyKey, bool, error)
+	SaveIdempotentResponse(ctx context.Context, key *entities.IdempotencyKey) error
+	DeleteIdempotencyKey(ctx context.Context, key *entities.IdempotencyKey) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) error
+}
+
+// Хранилище подписок пользователей на уведомления и очереди их доставки.
+// События добавляются в очередь хранилищами заказов и баланса в одной транзакции с изменением,
+// о котором они уведомляют.
+type WebhookRepo interface {
+	AddWebhook(ctx context.Context, webhook *entities.Webhook) error
+	Webhooks(ctx context.Context, userID int64) ([]entities.Webhook, error)
+	Webhook(ctx context.Context, webhook *entities.Webhook) error
+	DeleteWebhook(ctx context.Context, webhook *entities.Webhook) error
+	WebhookDeliveries(ctx context.Context, filter *entities.WebhookDeliveryFilter) ([]entities.WebhookDelivery, error)
+	PendingDeliveries(
+		ctx context.Context, instance string, limit uint, lease time.Duration,
+	) ([]entities.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *entities.WebhookDelivery) error
+}
+
+type StatsRepo interface {
+	Stats(ctx context.Context) (entities.Stats, error)
 }
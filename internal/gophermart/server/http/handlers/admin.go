@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/logging"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
+	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/labstack/echo/v4"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Обработчики запросов администраторов. В отличие от остальных обработчиков,
+// выборки не ограничены пользователем, выполняющим запрос.
+type AdminController struct {
+	user    usecases.User
+	order   usecases.Order
+	balance usecases.Balance
+	mw      *middleware.Manager
+	logger  *log.Logger
+}
+
+func NewAdminController(
+	user usecases.User, order usecases.Order, balance usecases.Balance,
+	mwManager *middleware.Manager, logger *log.Logger,
+) (*AdminController, error) {
+	if user == nil || order == nil || balance == nil {
+		return nil, ErrUseCaseIsNil
+	}
+
+	controllerLogger := log.StandardLogger()
+	if logger != nil {
+		controllerLogger = logger
+	}
+
+	return &AdminController{
+		user:    user,
+		order:   order,
+		balance: balance,
+		mw:      mwManager,
+		logger:  controllerLogger,
+	}, nil
+}
+
+func (c *AdminController) MapHandlers(group *echo.Group) error {
+	if group == nil {
+		return ErrGroupIsNil
+	}
+
+	group.Add(http.MethodGet, "/admin/users", c.guard(c.usersHandler))
+	group.Add(http.MethodGet, "/admin/users/:id", c.guard(c.userHandler))
+	group.Add(http.MethodGet, "/admin/users/:id/orders", c.guard(c.userOrdersHandler))
+	group.Add(http.MethodGet, "/admin/users/:id/balance", c.guard(c.userBalanceHandler))
+	group.Add(http.MethodGet, "/admin/users/:id/balance/history", c.guard(c.userStatementHandler))
+	group.Add(http.MethodPost, "/admin/users/:id/balance/adjustments", c.guard(c.adjustBalanceHandler))
+	group.Add(http.MethodPost, "/admin/withdrawals/:order/reversal", c.guard(c.reverseWithdrawalHandler))
+	group.Add(http.MethodGet, "/admin/orders/pending", c.guard(c.pendingOrdersHandler))
+
+	return nil
+}
+
+// Разрешает выполнение обработчика next только аутентифицированным администраторам.
+func (c *AdminController) guard(next echo.HandlerFunc) echo.HandlerFunc {
+	return c.mw.AuthenticationMiddleware(c.mw.AdminMiddleware(next))
+}
+
+// @Summary       Search users
+// @Description   Get a list of users whose login contains the given string (case-insensitive).
+// @Description   Users are ordered by ID and returned page by page, the cursor of the next page
+// @Description   is passed in the X-Next-Cursor response header. Available to administrators only.
+// @Tags          Gophermart HTTP API
+// @Produce       json
+// @Param         login    query      string    false   "Part of the user login."
+// @Param         limit    query      int       false   "Page size (default 100, maximum 1000)."
+// @Param         cursor   query      string    false   "Cursor of the page from the X-Next-Cursor header."
+// @Param         sort     query      string    false   "Sort direction by user ID."   Enums(asc, desc)
+// @Success       200      {array}    entities.UserProfile
+// @Header        200      {string}   X-Next-Cursor   "Cursor of the next page."
+// @Success       204
+// @Failure       400      {object}   Problem
+// @Failure       401      {object}   Problem
+// @Failure       403      {object}   Problem
+// @Failure       500      {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/admin/users [get]
+func (c *AdminController) usersHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	page, err := parsePage(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	filter := entities.UserFilter{
+		Login: e.QueryParam("login"),
+		Page:  page,
+	}
+
+	users, next, err := c.user.Users(e.Request().Context(), &filter)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	if len(users) == 0 {
+		return e.NoContent(http.StatusNoContent)
+	}
+
+	if next != nil {
+		e.Response().Header().Set(nextCursorHeader, next.String())
+	}
+
+	return e.JSON(http.StatusOK, users)
+}
+
+// @Summary       Get user
+// @Description   Get the account data of any user. Available to administrators only.
+// @Tags          Gophermart HTTP API
+// @Produce       json
+// @Param         id    path       int   true   "User ID."
+// @Success       200   {object}   entities.UserProfile
+// @Failure       400   {object}   Problem
+// @Failure       401   {object}   Problem
+// @Failure       403   {object}   Problem
+// @Failure       404   {object}   Problem
+// @Failure       500   {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/admin/users/{id} [get]
+func (c *AdminController) userHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	userID, err := parseUserID(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	profile, err := c.user.Profile(e.Request().Context(), userID)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	return e.JSON(http.StatusOK, profile)
+}
+
+// @Summary       Get user orders
+// @Description   Get a list of orders uploaded by any user.
+// @Description   Orders are returned page by page, the cursor of the next page
+// @Description   is passed in the X-Next-Cursor response header. Available to administrators only.
+// @Tags          Gophermart HTTP API
+// @Produce       json
+// @Param         id       path       int       true    "User ID."
+// @Param         limit    query      int       false   "Page size (default 100, maximum 1000)."
+// @Param         cursor   query      string    false   "Cursor of the page from the X-Next-Cursor header."
+// @Param         sort     query      string    false   "Sort direction by upload time."   Enums(asc, desc)
+// @Param         status   query      []string  false   "Order statuses."                  collectionFormat(csv)
+// @Param         from     query      string    false   "Minimum upload time (RFC 3339)."
+// @Param         to       query      string    false   "Maximum upload time (RFC 3339)."
+// @Success       200      {array}    entities.Order
+// @Header        200      {string}   X-Next-Cursor   "Cursor of the next page."
+// @Success       204
+// @Failure       400      {object}   Problem
+// @Failure       401      {object}   Problem
+// @Failure       403      {object}   Problem
+// @Failure       404      {object}   Problem
+// @Failure       500      {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/admin/users/{id}/orders [get]
+func (c *AdminController) userOrdersHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	userID, err := c.existingUserID(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	filter, err := parseOrderFilter(e, userID)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	orders, next, err := c.order.Orders(e.Request().Context(), &filter)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	if len(orders) == 0 {
+		return e.NoContent(http.StatusNoContent)
+	}
+
+	if next != nil {
+		e.Response().Header().Set(nextCursorHeader, next.String())
+	}
+
+	return e.JSON(http.StatusOK, orders)
+}
+
+// @Summary       Get user balance
+// @Description   Get the current balance of any user's loyalty points account. Available to administrators only.
+// @Tags          Gophermart HTTP API
+// @Produce       json
+// @Param         id    path       int   true   "User ID."
+// @Success       200   {object}   entities.Balance
+// @Failure       400   {object}   Problem
+// @Failure       401   {object}   Problem
+// @Failure       403   {object}   Problem
+// @Failure       404   {object}   Problem
+// @Failure       500   {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/admin/users/{id}/balance [get]
+func (c *AdminController) userBalanceHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	userID, err := c.existingUserID(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	balance, err := c.balance.Balance(e.Request().Context(), userID)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	return e.JSON(http.StatusOK, balance)
+}
+
+// @Summary       Get user account statement
+// @Description   Get all refills and withdrawals of any user's loyalty points account
+// @Description   with the account balance after each operation.
+// @Description   Entries are returned page by page, the cursor of the next page
+// @Description   is passed in the X-Next-Cursor response header. Available to administrators only.
+// @Tags          Gophermart HTTP API
+// @Produce       json
+// @Param         id       path       int       true    "User ID."
+// @Param         limit    query      int       false   "Page size (default 100, maximum 1000)."
+// @Param         cursor   query      string    false   "Cursor of the page from the X-Next-Cursor header."
+// @Param         sort     query      string    false   "Sort direction by processing time."   Enums(asc, desc)
+// @Param         from     query      string    false   "Minimum processing time (RFC 3339)."
+// @Param         to       query      string    false   "Maximum processing time (RFC 3339)."
+// @Success       200      {array}    entities.StatementEntry
+// @Header        200      {string}   X-Next-Cursor   "Cursor of the next page."
+// @Success       204
+// @Failure       400      {object}   Problem
+// @Failure       401      {object}   Problem
+// @Failure       403      {object}   Problem
+// @Failure       404      {object}   Problem
+// @Failure       500      {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/admin/users/{id}/balance/history [get]
+func (c *AdminController) userStatementHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	userID, err := c.existingUserID(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	filter, err := parseStatementFilter(e, userID)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	entries, next, err := c.balance.Statement(e.Request().Context(), &filter)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	if len(entries) == 0 {
+		return e.NoContent(http.StatusNoContent)
+	}
+
+	if next != nil {
+		e.Response().Header().Set(nextCursorHeader, next.String())
+	}
+
+	return e.JSON(http.StatusOK, entries)
+}
+
+// @Summary       Adjust user balance
+// @Description   Credit (positive sum) or debit (negative sum) any user's loyalty points account.
+// @Description   The adjustment is recorded in the user's statement together with the acting administrator,
+// @Description   the reason and the reference. Available to administrators only.
+// @Tags          Gophermart HTTP API
+// @Accept        json
+// @Produce       json
+// @Param         id           path       int                          true   "User ID."
+// @Param         adjustment   body       entities.BalanceAdjustment   true   "Sum, reason and reference."
+// @Success       200          {object}   entities.Balance
+// @Failure       400          {object}   Problem
+// @Failure       401          {object}   Problem
+// @Failure       402          {object}   Problem
+// @Failure       403          {object}   Problem
+// @Failure       404          {object}   Problem
+// @Failure       500          {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/admin/users/{id}/balance/adjustments [post]
+func (c *AdminController) adjustBalanceHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	adminID, ok := e.Get("userID").(int64)
+	if !ok {
+		return writeError(e, logger, ErrUnauthorized)
+	}
+
+	userID, err := c.existingUserID(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	body, err := io.ReadAll(e.Request().Body)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	var adjustment entities.BalanceAdjustment
+
+	err = json.Unmarshal(body, &adjustment)
+	if err != nil {
+		return writeError(e, logger, fmt.Errorf("%w: %s", ErrInvalidRequestBody, err))
+	}
+
+	logger.Debugf("Request body: %+v", adjustment)
+
+	change := entities.BalanceChange{
+		UserID:    userID,
+		Sum:       adjustment.Sum,
+		AdminID:   adminID,
+		Reason:    adjustment.Reason,
+		Reference: adjustment.Reference,
+	}
+
+	balance, err := c.balance.Adjust(e.Request().Context(), &change)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	return e.JSON(http.StatusOK, balance)
+}
+
+// @Summary       Reverse withdrawal
+// @Description   Return the points withdrawn to pay for the order to the user's loyalty points account,
+// @Description   e.g. when the shop cancels the order. The last withdrawal for the order is reversed
+// @Description   for the exact amount; a withdrawal can be reversed only once. Available to administrators only.
+// @Tags          Gophermart HTTP API
+// @Produce       json
+// @Param         order   path       string    true   "Order number."
+// @Success       200     {object}   entities.BalanceChange
+// @Failure       401     {object}   Problem
+// @Failure       403     {object}   Problem
+// @Failure       404     {object}   Problem
+// @Failure       409     {object}   Problem
+// @Failure       422     {object}   Problem
+// @Failure       500     {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/admin/withdrawals/{order}/reversal [post]
+func (c *AdminController) reverseWithdrawalHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	adminID, ok := e.Get("userID").(int64)
+	if !ok {
+		return writeError(e, logger, ErrUnauthorized)
+	}
+
+	reversal := entities.BalanceChange{
+		Order:   e.Param("order"),
+		AdminID: adminID,
+	}
+
+	err := c.balance.ReverseWithdrawal(e.Request().Context(), &reversal)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	return e.JSON(http.StatusOK, reversal)
+}
+
+// @Summary       Get pending orders count
+// @Description   Get the number of orders of all users whose accrual calculation is not completed.
+// @Description   Available to administrators only.
+// @Tags          Gophermart HTTP API
+// @Produce       json
+// @Success       200   {object}   entities.PendingOrders
+// @Failure       401   {object}   Problem
+// @Failure       403   {object}   Problem
+// @Failure       500   {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/admin/orders/pending [get]
+func (c *AdminController) pendingOrdersHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	pending, err := c.order.PendingOrders(e.Request().Context())
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	return e.JSON(http.StatusOK, pending)
+}
+
+// Считывает идентификатор пользователя из пути запроса и проверяет, что пользователь существует.
+// Без проверки запрос данных несуществующего пользователя возвращал бы пустую выборку вместо ошибки.
+func (c *AdminController) existingUserID(e echo.Context) (int64, error) {
+	userID, err := parseUserID(e)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = c.user.Profile(e.Request().Context(), userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,641 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/repository/mocks"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/problem"
+	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/KryukovO/gophermart/internal/utils"
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAdminController(t *testing.T) {
+	user := newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t)))
+	order := usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second)
+	balance := usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second)
+
+	ctrl, err := NewAdminController(user, order, balance, newTestManager(t), nil)
+	require.NoError(t, err)
+	assert.NotNil(t, ctrl.logger)
+
+	_, err = NewAdminController(nil, order, balance, newTestManager(t), log.New())
+	assert.ErrorIs(t, err, ErrUseCaseIsNil)
+
+	_, err = NewAdminController(user, nil, balance, newTestManager(t), log.New())
+	assert.ErrorIs(t, err, ErrUseCaseIsNil)
+
+	_, err = NewAdminController(user, order, nil, newTestManager(t), log.New())
+	assert.ErrorIs(t, err, ErrUseCaseIsNil)
+
+	err = ctrl.MapHandlers(nil)
+	assert.ErrorIs(t, err, ErrGroupIsNil)
+}
+
+func TestAdminAccess(t *testing.T) {
+	path := "/api/admin/orders/pending"
+
+	signingKey, err := testKeys.SigningKey()
+	require.NoError(t, err)
+
+	adminToken, err := utils.BuildJSWTString(signingKey, "admin-token", entities.RoleAdmin, time.Minute, int64(1))
+	require.NoError(t, err)
+
+	userToken, err := utils.BuildJSWTString(signingKey, "user-token", entities.RoleUser, time.Minute, int64(2))
+	require.NoError(t, err)
+
+	legacyToken, err := utils.BuildJSWTString(signingKey, "legacy-token", "", time.Minute, int64(3))
+	require.NoError(t, err)
+
+	type wants struct {
+		status int
+		code   string
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		wants wants
+	}{
+		{
+			name:  "Administrator",
+			token: adminToken,
+			wants: wants{
+				status: http.StatusOK,
+			},
+		},
+		{
+			name:  "User",
+			token: userToken,
+			wants: wants{
+				status: http.StatusForbidden,
+				code:   problem.CodeForbidden,
+			},
+		},
+		{
+			name:  "Token without role",
+			token: legacyToken,
+			wants: wants{
+				status: http.StatusForbidden,
+				code:   problem.CodeForbidden,
+			},
+		},
+		{
+			name: "Not authenticated",
+			wants: wants{
+				status: http.StatusUnauthorized,
+				code:   problem.CodeUnauthorized,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tokenRepo := mocks.NewMockTokenRepo(gomock.NewController(t))
+		tokenRepo.EXPECT().AccessTokenRevoked(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+
+		orderRepo := mocks.NewMockOrderRepo(gomock.NewController(t))
+		orderRepo.EXPECT().PendingOrders(gomock.Any()).Return(entities.PendingOrders{New: 1, Total: 1}, nil).AnyTimes()
+
+		token := usecases.NewTokenUseCase(tokenRepo, testKeys, time.Minute, time.Hour, time.Second)
+
+		mwManager, err := middleware.NewManager(token, nil, middleware.TokenSourceHeader, nil, log.New())
+		require.NoError(t, err)
+
+		ctrl, err := NewAdminController(
+			newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+			usecases.NewOrderUseCase(orderRepo, time.Second),
+			usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
+			mwManager, log.New(),
+		)
+		require.NoError(t, err)
+
+		server := echo.New()
+
+		err = ctrl.MapHandlers(server.Group("/api"))
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if test.token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+test.token)
+		}
+
+		rec := httptest.NewRecorder()
+
+		server.ServeHTTP(rec, req)
+
+		assert.Equal(t, test.wants.status, rec.Code, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code, test.name)
+		} else {
+			assert.JSONEq(t, `{"new":1,"processing":0,"total":1}`, rec.Body.String(), test.name)
+		}
+	}
+}
+
+func TestUsersHandler(t *testing.T) {
+	path := "/api/admin/users"
+	users := []entities.UserProfile{
+		{ID: 1, Login: "admin", Role: entities.RoleAdmin},
+		{ID: 2, Login: "user", Role: entities.RoleUser},
+	}
+
+	type wants struct {
+		status     int
+		nextCursor bool
+		code       string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockUserRepo)
+		query   string
+		wants   wants
+	}{
+		{
+			name: "Correct search",
+			prepare: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().
+					Users(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, filter *entities.UserFilter) ([]entities.UserProfile, error) {
+						assert.Equal(t, "adm", filter.Login)
+
+						return users[:1], nil
+					})
+			},
+			query: "login=adm",
+			wants: wants{
+				status: http.StatusOK,
+			},
+		},
+		{
+			name: "Nothing found",
+			prepare: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().Users(gomock.Any(), gomock.Any()).Return([]entities.UserProfile{}, nil)
+			},
+			query: "login=unknown",
+			wants: wants{
+				status: http.StatusNoContent,
+			},
+		},
+		{
+			name: "Next page exists",
+			prepare: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().Users(gomock.Any(), gomock.Any()).Return(users, nil)
+			},
+			query: "limit=1",
+			wants: wants{
+				status:     http.StatusOK,
+				nextCursor: true,
+			},
+		},
+		{
+			name:  "Invalid query parameters",
+			query: "sort=up",
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidQueryParam,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockUserRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path+"?"+test.query, nil)
+		echoCtx := echo.New().NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+
+		ac := AdminController{
+			user:   newTestUserUseCase(t, repo),
+			logger: log.StandardLogger(),
+		}
+
+		err := ac.usersHandler(echoCtx)
+		require.NoError(t, err)
+
+		assert.Equal(t, test.wants.status, rec.Code, test.name)
+		assert.Equal(t, test.wants.nextCursor, rec.Header().Get(nextCursorHeader) != "", test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code, test.name)
+		}
+	}
+}
+
+func TestUserOrdersHandler(t *testing.T) {
+	path := "/api/admin/users/:id/orders"
+	order := entities.Order{
+		Number:     "2377225624",
+		Status:     entities.OrderStatusProcessed,
+		Accrual:    entities.NewMoney(500, 0),
+		UploadedAt: time.Now(),
+	}
+
+	type wants struct {
+		status int
+		code   string
+	}
+
+	tests := []struct {
+		name        string
+		prepareUser func(mock *mocks.MockUserRepo)
+		prepare     func(mock *mocks.MockOrderRepo)
+		id          string
+		wants       wants
+	}{
+		{
+			name: "Correct orders request",
+			prepareUser: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().UserByID(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			prepare: func(mock *mocks.MockOrderRepo) {
+				mock.EXPECT().
+					Orders(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, filter *entities.OrderFilter) ([]entities.Order, error) {
+						assert.Equal(t, int64(42), filter.UserID)
+
+						return []entities.Order{order}, nil
+					})
+			},
+			id: "42",
+			wants: wants{
+				status: http.StatusOK,
+			},
+		},
+		{
+			name: "User not found",
+			prepareUser: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().UserByID(gomock.Any(), gomock.Any()).Return(entities.ErrUserNotFound)
+			},
+			id: "42",
+			wants: wants{
+				status: http.StatusNotFound,
+				code:   problem.CodeUserNotFound,
+			},
+		},
+		{
+			name: "Invalid user ID",
+			id:   "abc",
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidPathParam,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		userRepo := mocks.NewMockUserRepo(gomock.NewController(t))
+		orderRepo := mocks.NewMockOrderRepo(gomock.NewController(t))
+
+		if test.prepareUser != nil {
+			test.prepareUser(userRepo)
+		}
+
+		if test.prepare != nil {
+			test.prepare(orderRepo)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/users/"+test.id+"/orders", nil)
+		echoCtx := echo.New().NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+		echoCtx.SetParamNames("id")
+		echoCtx.SetParamValues(test.id)
+
+		ac := AdminController{
+			user:   newTestUserUseCase(t, userRepo),
+			order:  usecases.NewOrderUseCase(orderRepo, time.Second),
+			logger: log.StandardLogger(),
+		}
+
+		err := ac.userOrdersHandler(echoCtx)
+		require.NoError(t, err)
+
+		assert.Equal(t, test.wants.status, rec.Code, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code, test.name)
+		}
+	}
+}
+
+func TestUserStatementHandler(t *testing.T) {
+	path := "/api/admin/users/:id/balance/history"
+	entry := entities.StatementEntry{
+		ID:          1,
+		Operation:   entities.BalanceOperationRefill,
+		Order:       "2377225624",
+		Sum:         entities.NewMoney(751, 0),
+		Balance:     entities.NewMoney(751, 0),
+		ProcessedAt: time.Now(),
+	}
+
+	userRepo := mocks.NewMockUserRepo(gomock.NewController(t))
+	userRepo.EXPECT().UserByID(gomock.Any(), gomock.Any()).Return(nil)
+
+	balanceRepo := mocks.NewMockBalanceRepo(gomock.NewController(t))
+	balanceRepo.EXPECT().
+		Statement(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, filter *entities.StatementFilter) ([]entities.StatementEntry, error) {
+			assert.Equal(t, int64(7), filter.UserID)
+
+			return []entities.StatementEntry{entry}, nil
+		})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/users/7/balance/history", nil)
+	echoCtx := echo.New().NewContext(req, rec)
+
+	echoCtx.SetPath(path)
+	echoCtx.SetParamNames("id")
+	echoCtx.SetParamValues("7")
+
+	ac := AdminController{
+		user:    newTestUserUseCase(t, userRepo),
+		balance: usecases.NewBalanceUseCase(balanceRepo, time.Second),
+		logger:  log.StandardLogger(),
+	}
+
+	err := ac.userStatementHandler(echoCtx)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAdjustBalanceHandler(t *testing.T) {
+	path := "/api/admin/users/:id/balance/adjustments"
+
+	type args struct {
+		adminID interface{}
+		body    string
+	}
+
+	type wants struct {
+		status int
+		code   string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockBalanceRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "Correct adjustment",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().
+					ChangeBalance(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, change *entities.BalanceChange) error {
+						assert.Equal(t, entities.BalanceChange{
+							UserID:    7,
+							Operation: entities.BalanceOperationAdjustment,
+							Sum:       entities.NewMoney(150, 50),
+							AdminID:   1,
+							Reason:    "Goodwill",
+							Reference: "SUP-1234",
+						}, *change)
+
+						return nil
+					})
+				mock.EXPECT().Balance(gomock.Any(), int64(7)).Return(entities.Balance{UserID: 7}, nil)
+			},
+			args: args{
+				adminID: int64(1),
+				body:    `{"sum": 150.5, "reason": "Goodwill", "reference": "SUP-1234"}`,
+			},
+			wants: wants{
+				status: http.StatusOK,
+			},
+		},
+		{
+			name: "Balance would become negative",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().ChangeBalance(gomock.Any(), gomock.Any()).Return(entities.ErrNotEnoughFunds)
+			},
+			args: args{
+				adminID: int64(1),
+				body:    `{"sum": -1000, "reason": "Fraudulent accrual", "reference": "SUP-1234"}`,
+			},
+			wants: wants{
+				status: http.StatusPaymentRequired,
+				code:   problem.CodeNotEnoughFunds,
+			},
+		},
+		{
+			name: "Reason is missing",
+			args: args{
+				adminID: int64(1),
+				body:    `{"sum": 100, "reference": "SUP-1234"}`,
+			},
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidAdjustment,
+			},
+		},
+		{
+			name: "Invalid request body",
+			args: args{
+				adminID: int64(1),
+				body:    `{"sum": "a lot"}`,
+			},
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidRequest,
+			},
+		},
+		{
+			name: "User unauthorized",
+			args: args{
+				body: `{"sum": 100, "reason": "Goodwill", "reference": "SUP-1234"}`,
+			},
+			wants: wants{
+				status: http.StatusUnauthorized,
+				code:   problem.CodeUnauthorized,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		userRepo := mocks.NewMockUserRepo(gomock.NewController(t))
+		userRepo.EXPECT().UserByID(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+		balanceRepo := mocks.NewMockBalanceRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(balanceRepo)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(
+			http.MethodPost, "/api/admin/users/7/balance/adjustments", strings.NewReader(test.args.body),
+		)
+		echoCtx := echo.New().NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+		echoCtx.SetParamNames("id")
+		echoCtx.SetParamValues("7")
+		echoCtx.Set("userID", test.args.adminID)
+
+		ac := AdminController{
+			user:    newTestUserUseCase(t, userRepo),
+			balance: usecases.NewBalanceUseCase(balanceRepo, time.Second),
+			logger:  log.StandardLogger(),
+		}
+
+		err := ac.adjustBalanceHandler(echoCtx)
+		require.NoError(t, err)
+
+		assert.Equal(t, test.wants.status, rec.Code, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code, test.name)
+		}
+	}
+}
+
+func TestReverseWithdrawalHandler(t *testing.T) {
+	path := "/api/admin/withdrawals/:order/reversal"
+
+	type args struct {
+		adminID interface{}
+		order   string
+	}
+
+	type wants struct {
+		status int
+		code   string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockBalanceRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "Correct reversal",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().
+					ReverseWithdrawal(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, reversal *entities.BalanceChange) error {
+						assert.Equal(t, entities.BalanceChange{
+							Operation: entities.BalanceOperationReversal,
+							Order:     "4561261212345467",
+							AdminID:   1,
+						}, *reversal)
+
+						reversal.Sum = entities.NewMoney(500, 0)
+
+						return nil
+					})
+			},
+			args: args{
+				adminID: int64(1),
+				order:   "4561261212345467",
+			},
+			wants: wants{
+				status: http.StatusOK,
+			},
+		},
+		{
+			name: "Withdrawal not found",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().ReverseWithdrawal(gomock.Any(), gomock.Any()).Return(entities.ErrWithdrawalNotFound)
+			},
+			args: args{
+				adminID: int64(1),
+				order:   "4561261212345467",
+			},
+			wants: wants{
+				status: http.StatusNotFound,
+				code:   problem.CodeWithdrawalNotFound,
+			},
+		},
+		{
+			name: "Withdrawal already reversed",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().ReverseWithdrawal(gomock.Any(), gomock.Any()).Return(entities.ErrWithdrawalAlreadyReversed)
+			},
+			args: args{
+				adminID: int64(1),
+				order:   "4561261212345467",
+			},
+			wants: wants{
+				status: http.StatusConflict,
+				code:   problem.CodeWithdrawalAlreadyReversed,
+			},
+		},
+		{
+			name: "Invalid order number",
+			args: args{
+				adminID: int64(1),
+				order:   "4561261212345464",
+			},
+			wants: wants{
+				status: http.StatusUnprocessableEntity,
+				code:   problem.CodeInvalidOrderNumber,
+			},
+		},
+		{
+			name: "User unauthorized",
+			args: args{
+				order: "4561261212345467",
+			},
+			wants: wants{
+				status: http.StatusUnauthorized,
+				code:   problem.CodeUnauthorized,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		balanceRepo := mocks.NewMockBalanceRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(balanceRepo)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/withdrawals/"+test.args.order+"/reversal", nil)
+		echoCtx := echo.New().NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+		echoCtx.SetParamNames("order")
+		echoCtx.SetParamValues(test.args.order)
+		echoCtx.Set("userID", test.args.adminID)
+
+		ac := AdminController{
+			balance: usecases.NewBalanceUseCase(balanceRepo, time.Second),
+			logger:  log.StandardLogger(),
+		}
+
+		err := ac.reverseWithdrawalHandler(echoCtx)
+		require.NoError(t, err)
+
+		assert.Equal(t, test.wants.status, rec.Code, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code, test.name)
+		}
+	}
+}
@@ -2,11 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/logging"
 	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
 	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
 	"github.com/labstack/echo/v4"
@@ -45,7 +46,14 @@ func (c *BalanceController) MapHandlers(group *echo.Group) error {
 	}
 
 	group.Add(http.MethodGet, "/user/balance", c.mw.AuthenticationMiddleware(c.balanceHandler))
-	group.Add(http.MethodPost, "/user/balance/withdraw", c.mw.AuthenticationMiddleware(c.withdrawHandler))
+	group.Add(http.MethodGet, "/user/balance/history", c.mw.AuthenticationMiddleware(c.statementHandler))
+	group.Add(
+		http.MethodGet, "/user/balance/history/export", c.mw.AuthenticationMiddleware(c.exportStatementHandler),
+	)
+	group.Add(
+		http.MethodPost, "/user/balance/withdraw",
+		c.mw.AuthenticationMiddleware(c.mw.IdempotencyMiddleware(c.withdrawHandler)),
+	)
 	group.Add(http.MethodGet, "/user/withdrawals", c.mw.AuthenticationMiddleware(c.withdrawalsHandler))
 
 	return nil
@@ -56,28 +64,24 @@ func (c *BalanceController) MapHandlers(group *echo.Group) error {
 // @Tags          Gophermart HTTP API
 // @Produce       json
 // @Success       200    {object}   entities.Balance
-// @Failure       401    {object}   echo.HTTPError
-// @Failure       500    {object}   echo.HTTPError
+// @Failure       401    {object}   Problem
+// @Failure       500    {object}   Problem
 // @Security      JWT
+// @Security      Bearer
 // @Router        /api/user/balance [get]
 func (c *BalanceController) balanceHandler(e echo.Context) error {
-	uuid := e.Get("uuid")
-	if uuid == nil {
-		uuid = ""
-	}
+	logger := logging.FromContext(e.Request().Context(), c.logger)
 
 	userID := e.Get("userID")
 
 	user, ok := userID.(int64)
 	if !ok {
-		return e.NoContent(http.StatusUnauthorized)
+		return writeError(e, logger, ErrUnauthorized)
 	}
 
 	balance, err := c.balance.Balance(e.Request().Context(), user)
 	if err != nil {
-		c.logger.Errorf("[%s] Something went wrong: %s", uuid, err)
-
-		return e.NoContent(http.StatusInternalServerError)
+		return writeError(e, logger, err)
 	}
 
 	return e.JSON(http.StatusOK, &balance)
@@ -85,65 +89,56 @@ func (c *BalanceController) balanceHandler(e echo.Context) error {
 
 // @Summary       Withdrawal request
 // @Description   Withdraw points from the loyalty points account to pay for a new order.
+// @Description   A retry with the same Idempotency-Key header and body returns the stored response
+// @Description   instead of withdrawing the points again.
 // @Tags          Gophermart HTTP API
 // @Accept        json
-// @Param         withdrawal   body       entities.BalanceChange   true   "Order number and withdrawal sum."
+// @Param         withdrawal        body       entities.BalanceChange   true    "Order number and withdrawal sum."
+// @Param         Idempotency-Key   header     string                   false   "Unique request key for safe retries."
 // @Success       200
-// @Failure       401          {object}   echo.HTTPError
-// @Failure       402          {object}   echo.HTTPError
-// @Failure       422          {object}   echo.HTTPError
-// @Failure       500          {object}   echo.HTTPError
+// @Failure       400               {object}   Problem
+// @Failure       401               {object}   Problem
+// @Failure       402               {object}   Problem
+// @Failure       409               {object}   Problem
+// @Failure       422               {object}   Problem
+// @Failure       500               {object}   Problem
 // @Security      JWT
+// @Security      Bearer
 // @Router        /api/user/balance/withdraw [post]
 func (c *BalanceController) withdrawHandler(e echo.Context) error {
-	uuid := e.Get("uuid")
-	if uuid == nil {
-		uuid = ""
-	}
+	logger := logging.FromContext(e.Request().Context(), c.logger)
 
 	userID := e.Get("userID")
 
 	user, ok := userID.(int64)
 	if !ok {
-		return e.NoContent(http.StatusUnauthorized)
+		return writeError(e, logger, ErrUnauthorized)
 	}
 
 	body, err := io.ReadAll(e.Request().Body)
 	if err != nil {
-		c.logger.Errorf("[%s] Something went wrong: %s", uuid, err)
-
-		return e.NoContent(http.StatusInternalServerError)
+		return writeError(e, logger, err)
 	}
 
 	var change entities.BalanceChange
 
 	err = json.Unmarshal(body, &change)
 	if err != nil {
-		return e.NoContent(http.StatusBadRequest)
+		return writeError(e, logger, fmt.Errorf("%w: %s", ErrInvalidRequestBody, err))
 	}
 
 	if change.Order == "" || change.Sum <= 0 {
-		return e.NoContent(http.StatusBadRequest)
+		return writeError(e, logger, fmt.Errorf("%w: order and positive sum are required", ErrInvalidRequestBody))
 	}
 
-	c.logger.Debugf("[%s] Request body: %+v", uuid, change)
+	logger.Debugf("Request body: %+v", change)
 
 	change.UserID = user
 	change.Operation = entities.BalanceOperationWithdrawal
 
 	err = c.balance.ChangeBalance(e.Request().Context(), &change)
 	if err != nil {
-		if errors.Is(err, entities.ErrNotEnoughFunds) {
-			return e.NoContent(http.StatusPaymentRequired)
-		}
-
-		if errors.Is(err, entities.ErrInvalidOrderNumber) {
-			return e.NoContent(http.StatusUnprocessableEntity)
-		}
-
-		c.logger.Errorf("[%s] Something went wrong: %s", uuid, err)
-
-		return e.NoContent(http.StatusInternalServerError)
+		return writeError(e, logger, err)
 	}
 
 	return e.NoContent(http.StatusOK)
@@ -151,37 +146,186 @@ func (c *BalanceController) withdrawHandler(e echo.Context) error {
 
 // @Summary       Get withdrawals list
 // @Description   Get a list of withdrawals from a user's loyalty points account.
+// @Description   Withdrawals are returned page by page, the cursor of the next page
+// @Description   is passed in the X-Next-Cursor response header.
 // @Tags          Gophermart HTTP API
 // @Produce       json
-// @Success       200    {object}   entities.BalanceChange
+// @Param         limit    query      int       false   "Page size (default 100, maximum 1000)."
+// @Param         cursor   query      string    false   "Cursor of the page from the X-Next-Cursor header."
+// @Param         sort     query      string    false   "Sort direction by processing time."   Enums(asc, desc)
+// @Param         from     query      string    false   "Minimum processing time (RFC 3339)."
+// @Param         to       query      string    false   "Maximum processing time (RFC 3339)."
+// @Success       200      {array}    entities.BalanceChange
+// @Header        200      {string}   X-Next-Cursor   "Cursor of the next page."
 // @Success       204
-// @Failure       401    {object}   echo.HTTPError
-// @Failure       500    {object}   echo.HTTPError
+// @Failure       400      {object}   Problem
+// @Failure       401      {object}   Problem
+// @Failure       500      {object}   Problem
 // @Security      JWT
+// @Security      Bearer
 // @Router        /api/user/withdrawals [get]
 func (c *BalanceController) withdrawalsHandler(e echo.Context) error {
-	uuid := e.Get("uuid")
-	if uuid == nil {
-		uuid = ""
-	}
+	logger := logging.FromContext(e.Request().Context(), c.logger)
 
 	userID := e.Get("userID")
 
 	user, ok := userID.(int64)
 	if !ok {
-		return e.NoContent(http.StatusUnauthorized)
+		return writeError(e, logger, ErrUnauthorized)
 	}
 
-	withdrawals, err := c.balance.Withdrawals(e.Request().Context(), user)
+	page, err := parsePage(e)
 	if err != nil {
-		c.logger.Errorf("[%s] Something went wrong: %s", uuid, err)
+		return writeError(e, logger, err)
+	}
 
-		return e.NoContent(http.StatusInternalServerError)
+	processed, err := parseDateRange(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	filter := entities.WithdrawalFilter{
+		UserID:    user,
+		Processed: processed,
+		Page:      page,
+	}
+
+	withdrawals, next, err := c.balance.Withdrawals(e.Request().Context(), &filter)
+	if err != nil {
+		return writeError(e, logger, err)
 	}
 
 	if len(withdrawals) == 0 {
 		return e.NoContent(http.StatusNoContent)
 	}
 
+	if next != nil {
+		e.Response().Header().Set(nextCursorHeader, next.String())
+	}
+
 	return e.JSON(http.StatusOK, withdrawals)
 }
+
+// @Summary       Get account statement
+// @Description   Get all refills and withdrawals of a user's loyalty points account
+// @Description   with the account balance after each operation.
+// @Description   Entries are returned page by page, the cursor of the next page
+// @Description   is passed in the X-Next-Cursor response header.
+// @Tags          Gophermart HTTP API
+// @Produce       json
+// @Param         limit    query      int       false   "Page size (default 100, maximum 1000)."
+// @Param         cursor   query      string    false   "Cursor of the page from the X-Next-Cursor header."
+// @Param         sort     query      string    false   "Sort direction by processing time."   Enums(asc, desc)
+// @Param         from     query      string    false   "Minimum processing time (RFC 3339)."
+// @Param         to       query      string    false   "Maximum processing time (RFC 3339)."
+// @Success       200      {array}    entities.StatementEntry
+// @Header        200      {string}   X-Next-Cursor   "Cursor of the next page."
+// @Success       204
+// @Failure       400      {object}   Problem
+// @Failure       401      {object}   Problem
+// @Failure       500      {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/user/balance/history [get]
+func (c *BalanceController) statementHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	userID := e.Get("userID")
+
+	user, ok := userID.(int64)
+	if !ok {
+		return writeError(e, logger, ErrUnauthorized)
+	}
+
+	filter, err := parseStatementFilter(e, user)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	entries, next, err := c.balance.Statement(e.Request().Context(), &filter)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	if len(entries) == 0 {
+		return e.NoContent(http.StatusNoContent)
+	}
+
+	if next != nil {
+		e.Response().Header().Set(nextCursorHeader, next.String())
+	}
+
+	return e.JSON(http.StatusOK, entries)
+}
+
+// @Summary       Export account statement
+// @Description   Export all refills and withdrawals of a user's loyalty points account
+// @Description   with the account balance after each operation as CSV or NDJSON.
+// @Description   The format is selected by the format query parameter or the Accept header, CSV is used by default.
+// @Description   Entries are streamed to the client without loading them all into memory.
+// @Tags          Gophermart HTTP API
+// @Produce       text/csv,application/x-ndjson
+// @Param         format   query      string    false   "Export format."   Enums(csv, ndjson)
+// @Param         sort     query      string    false   "Sort direction by processing time."   Enums(asc, desc)
+// @Param         from     query      string    false   "Minimum processing time (RFC 3339)."
+// @Param         to       query      string    false   "Maximum processing time (RFC 3339)."
+// @Success       200      {array}    entities.StatementEntry
+// @Failure       400      {object}   Problem
+// @Failure       401      {object}   Problem
+// @Failure       406      {object}   Problem
+// @Failure       500      {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/user/balance/history/export [get]
+func (c *BalanceController) exportStatementHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	userID := e.Get("userID")
+
+	user, ok := userID.(int64)
+	if !ok {
+		return writeError(e, logger, ErrUnauthorized)
+	}
+
+	format, err := parseExportFormat(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	descending, err := parseSort(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	processed, err := parseDateRange(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	filter := entities.StatementFilter{
+		UserID:    user,
+		Processed: processed,
+		Page:      entities.Page{Descending: descending},
+	}
+
+	exp := newExporter(e, format, "statement", statementExportColumns)
+
+	err = c.balance.ExportStatement(e.Request().Context(), &filter, func(entry entities.StatementEntry) error {
+		return exp.write(entry, statementExportRow(entry))
+	})
+	if err == nil {
+		err = exp.flush()
+	}
+
+	if err != nil {
+		if exp.started {
+			logger.Errorf("Export interrupted: %s", err)
+
+			return nil
+		}
+
+		return writeError(e, logger, err)
+	}
+
+	return nil
+}
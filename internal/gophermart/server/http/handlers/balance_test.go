@@ -10,6 +10,7 @@ import (
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
 	"github.com/KryukovO/gophermart/internal/gophermart/repository/mocks"
 	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/problem"
 	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
@@ -38,7 +39,7 @@ func TestNewBalanceController(t *testing.T) {
 			name: "Correct creation",
 			args: args{
 				balance:   usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
-				mwManager: middleware.NewManager([]byte("secret"), log.New()),
+				mwManager: newTestManager(t),
 				logger:    log.New(),
 			},
 			wants: wants{
@@ -49,7 +50,7 @@ func TestNewBalanceController(t *testing.T) {
 			name: "Nil logger",
 			args: args{
 				balance:   usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
-				mwManager: middleware.NewManager([]byte("secret"), log.New()),
+				mwManager: newTestManager(t),
 				logger:    nil,
 			},
 			wants: wants{
@@ -60,7 +61,7 @@ func TestNewBalanceController(t *testing.T) {
 			name: "Nil balance",
 			args: args{
 				balance:   nil,
-				mwManager: middleware.NewManager([]byte("secret"), log.New()),
+				mwManager: newTestManager(t),
 				logger:    log.New(),
 			},
 			wants: wants{
@@ -129,7 +130,7 @@ func TestBalanceMapHandlers(t *testing.T) {
 	for _, test := range tests {
 		ctrl, err := NewBalanceController(
 			usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
-			middleware.NewManager([]byte("secret"), log.New()),
+			newTestManager(t),
 			log.New(),
 		)
 
@@ -150,8 +151,8 @@ func TestBalanceHandler(t *testing.T) {
 	path := "/api/user/balance"
 	balance := entities.Balance{
 		UserID:    1,
-		Current:   500,
-		Withdrawn: 42,
+		Current:   entities.NewMoney(500, 0),
+		Withdrawn: entities.NewMoney(42, 0),
 	}
 
 	type args struct {
@@ -161,6 +162,7 @@ func TestBalanceHandler(t *testing.T) {
 	type wants struct {
 		status      int
 		contentType string
+		code        string
 	}
 
 	tests := []struct {
@@ -186,7 +188,9 @@ func TestBalanceHandler(t *testing.T) {
 			name: "User unauthorized",
 			args: args{},
 			wants: wants{
-				status: http.StatusUnauthorized,
+				status:      http.StatusUnauthorized,
+				code:        problem.CodeUnauthorized,
+				contentType: problem.ContentType,
 			},
 		},
 	}
@@ -217,6 +221,10 @@ func TestBalanceHandler(t *testing.T) {
 		defer res.Body.Close()
 
 		assert.Equal(t, test.wants.status, res.StatusCode)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code)
+		}
 		assert.Equal(t, test.wants.contentType, res.Header.Get("Content-Type"))
 	}
 }
@@ -231,6 +239,7 @@ func TestWithdrawHandler(t *testing.T) {
 
 	type wants struct {
 		status int
+		code   string
 	}
 
 	tests := []struct {
@@ -263,6 +272,7 @@ func TestWithdrawHandler(t *testing.T) {
 			},
 			wants: wants{
 				status: http.StatusPaymentRequired,
+				code:   problem.CodeNotEnoughFunds,
 			},
 		},
 		{
@@ -273,6 +283,7 @@ func TestWithdrawHandler(t *testing.T) {
 			},
 			wants: wants{
 				status: http.StatusUnprocessableEntity,
+				code:   problem.CodeInvalidOrderNumber,
 			},
 		},
 		{
@@ -283,6 +294,7 @@ func TestWithdrawHandler(t *testing.T) {
 			},
 			wants: wants{
 				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidRequest,
 			},
 		},
 		{
@@ -293,6 +305,7 @@ func TestWithdrawHandler(t *testing.T) {
 			},
 			wants: wants{
 				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidRequest,
 			},
 		},
 		{
@@ -303,6 +316,7 @@ func TestWithdrawHandler(t *testing.T) {
 			},
 			wants: wants{
 				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidRequest,
 			},
 		},
 		{
@@ -310,6 +324,7 @@ func TestWithdrawHandler(t *testing.T) {
 			args: args{},
 			wants: wants{
 				status: http.StatusUnauthorized,
+				code:   problem.CodeUnauthorized,
 			},
 		},
 	}
@@ -341,6 +356,10 @@ func TestWithdrawHandler(t *testing.T) {
 		defer res.Body.Close()
 
 		assert.Equal(t, test.wants.status, res.StatusCode)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code)
+		}
 	}
 }
 
@@ -349,17 +368,20 @@ func TestWithdrawalsHandler(t *testing.T) {
 	change := entities.BalanceChange{
 		UserID:      1,
 		Order:       "2377225624",
-		Sum:         751,
+		Sum:         entities.NewMoney(751, 0),
 		ProcessedAt: time.Now(),
 	}
 
 	type args struct {
 		userID interface{}
+		query  string
 	}
 
 	type wants struct {
 		status      int
 		contentType string
+		nextCursor  bool
+		code        string
 	}
 
 	tests := []struct {
@@ -393,11 +415,52 @@ func TestWithdrawalsHandler(t *testing.T) {
 				status: http.StatusNoContent,
 			},
 		},
+		{
+			name: "Next page exists",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().Withdrawals(gomock.Any(), gomock.Any()).Return([]entities.BalanceChange{change, change}, nil)
+			},
+			args: args{
+				userID: int64(1),
+				query:  "limit=1&sort=desc",
+			},
+			wants: wants{
+				status:      http.StatusOK,
+				contentType: "application/json; charset=UTF-8",
+				nextCursor:  true,
+			},
+		},
+		{
+			name: "Invalid query parameters",
+			args: args{
+				userID: int64(1),
+				query:  "limit=-1",
+			},
+			wants: wants{
+				status:      http.StatusBadRequest,
+				code:        problem.CodeInvalidQueryParam,
+				contentType: problem.ContentType,
+			},
+		},
+		{
+			name: "Invalid cursor",
+			args: args{
+				userID: int64(1),
+				query:  "cursor=abc",
+			},
+			wants: wants{
+				status:      http.StatusBadRequest,
+				code:        problem.CodeInvalidCursor,
+				contentType: problem.ContentType,
+			},
+		},
 		{
 			name: "User unauthorized",
 			args: args{},
 			wants: wants{
-				status: http.StatusUnauthorized,
+				status:      http.StatusUnauthorized,
+				code:        problem.CodeUnauthorized,
+				contentType: problem.ContentType,
 			},
 		},
 	}
@@ -410,7 +473,7 @@ func TestWithdrawalsHandler(t *testing.T) {
 		}
 
 		rec := httptest.NewRecorder()
-		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req := httptest.NewRequest(http.MethodGet, path+"?"+test.args.query, nil)
 		server := echo.New()
 		echoCtx := server.NewContext(req, rec)
 
@@ -429,6 +492,309 @@ func TestWithdrawalsHandler(t *testing.T) {
 		defer res.Body.Close()
 
 		assert.Equal(t, test.wants.status, res.StatusCode)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code)
+		}
 		assert.Equal(t, test.wants.contentType, res.Header.Get("Content-Type"))
+		assert.Equal(t, test.wants.nextCursor, res.Header.Get(nextCursorHeader) != "")
+	}
+}
+
+func TestStatementHandler(t *testing.T) {
+	path := "/api/user/balance/history"
+	entry := entities.StatementEntry{
+		ID:          1,
+		Operation:   entities.BalanceOperationRefill,
+		Order:       "2377225624",
+		Sum:         entities.NewMoney(751, 0),
+		Balance:     entities.NewMoney(751, 0),
+		ProcessedAt: time.Now(),
+	}
+
+	type args struct {
+		userID interface{}
+		query  string
+	}
+
+	type wants struct {
+		status      int
+		contentType string
+		nextCursor  bool
+		code        string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockBalanceRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "Correct statement request",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().Statement(gomock.Any(), gomock.Any()).Return([]entities.StatementEntry{entry}, nil)
+			},
+			args: args{
+				userID: int64(1),
+			},
+			wants: wants{
+				status:      http.StatusOK,
+				contentType: "application/json; charset=UTF-8",
+			},
+		},
+		{
+			name: "Statement is empty",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().Statement(gomock.Any(), gomock.Any()).Return([]entities.StatementEntry{}, nil)
+			},
+			args: args{
+				userID: int64(1),
+			},
+			wants: wants{
+				status: http.StatusNoContent,
+			},
+		},
+		{
+			name: "Next page exists",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().Statement(gomock.Any(), gomock.Any()).Return([]entities.StatementEntry{entry, entry}, nil)
+			},
+			args: args{
+				userID: int64(1),
+				query:  "limit=1&sort=desc",
+			},
+			wants: wants{
+				status:      http.StatusOK,
+				contentType: "application/json; charset=UTF-8",
+				nextCursor:  true,
+			},
+		},
+		{
+			name: "Invalid query parameters",
+			args: args{
+				userID: int64(1),
+				query:  "limit=-1",
+			},
+			wants: wants{
+				status:      http.StatusBadRequest,
+				code:        problem.CodeInvalidQueryParam,
+				contentType: problem.ContentType,
+			},
+		},
+		{
+			name: "Invalid date range",
+			args: args{
+				userID: int64(1),
+				query:  "from=2023-02-01T00:00:00Z&to=2023-01-01T00:00:00Z",
+			},
+			wants: wants{
+				status:      http.StatusBadRequest,
+				code:        problem.CodeInvalidDateRange,
+				contentType: problem.ContentType,
+			},
+		},
+		{
+			name: "Invalid cursor",
+			args: args{
+				userID: int64(1),
+				query:  "cursor=abc",
+			},
+			wants: wants{
+				status:      http.StatusBadRequest,
+				code:        problem.CodeInvalidCursor,
+				contentType: problem.ContentType,
+			},
+		},
+		{
+			name: "User unauthorized",
+			args: args{},
+			wants: wants{
+				status:      http.StatusUnauthorized,
+				code:        problem.CodeUnauthorized,
+				contentType: problem.ContentType,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockBalanceRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path+"?"+test.args.query, nil)
+		server := echo.New()
+		echoCtx := server.NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+		echoCtx.Set("userID", test.args.userID)
+
+		bc := BalanceController{
+			balance: usecases.NewBalanceUseCase(repo, time.Minute),
+			logger:  log.StandardLogger(),
+		}
+
+		err := bc.statementHandler(echoCtx)
+		require.NoError(t, err)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, test.wants.status, res.StatusCode)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code)
+		}
+		assert.Equal(t, test.wants.contentType, res.Header.Get("Content-Type"))
+		assert.Equal(t, test.wants.nextCursor, res.Header.Get(nextCursorHeader) != "")
+	}
+}
+
+func TestExportStatementHandler(t *testing.T) {
+	path := "/api/user/balance/history/export"
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := []entities.StatementEntry{
+		{
+			ID:          1,
+			Operation:   entities.BalanceOperationRefill,
+			Order:       "4561261212345467",
+			Sum:         entities.NewMoney(500, 0),
+			Balance:     entities.NewMoney(500, 0),
+			ProcessedAt: ts,
+		},
+		{
+			ID:          2,
+			Operation:   entities.BalanceOperationWithdrawal,
+			Order:       "2377225624",
+			Sum:         entities.NewMoney(42, 25),
+			Balance:     entities.NewMoney(457, 75),
+			ProcessedAt: ts.Add(time.Hour),
+		},
+	}
+
+	type args struct {
+		userID interface{}
+		query  string
+	}
+
+	type wants struct {
+		status      int
+		contentType string
+		body        string
+		code        string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockBalanceRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "CSV export",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().Statement(gomock.Any(), gomock.Any()).Return(entries, nil)
+			},
+			args: args{
+				userID: int64(1),
+				query:  "format=csv",
+			},
+			wants: wants{
+				status:      http.StatusOK,
+				contentType: "text/csv; charset=UTF-8",
+				body: "operation,order,sum,balance,processed_at,reason,reference\n" +
+					"refill,4561261212345467,500,500,2023-01-02T03:04:05Z,,\n" +
+					"withdrawal,2377225624,42.25,457.75,2023-01-02T04:04:05Z,,\n",
+			},
+		},
+		{
+			name: "NDJSON export",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().Statement(gomock.Any(), gomock.Any()).Return(entries[:1], nil)
+			},
+			args: args{
+				userID: int64(1),
+				query:  "format=ndjson",
+			},
+			wants: wants{
+				status:      http.StatusOK,
+				contentType: "application/x-ndjson",
+				body: `{"operation":"refill","order":"4561261212345467","sum":500,"balance":500,` +
+					`"processed_at":"2023-01-02T03:04:05Z"}` + "\n",
+			},
+		},
+		{
+			name: "Unsupported format",
+			args: args{
+				userID: int64(1),
+				query:  "format=xml",
+			},
+			wants: wants{
+				status:      http.StatusNotAcceptable,
+				code:        problem.CodeUnsupportedFormat,
+				contentType: problem.ContentType,
+			},
+		},
+		{
+			name: "Invalid date range",
+			args: args{
+				userID: int64(1),
+				query:  "from=2023-02-01T00:00:00Z&to=2023-01-01T00:00:00Z",
+			},
+			wants: wants{
+				status:      http.StatusBadRequest,
+				code:        problem.CodeInvalidDateRange,
+				contentType: problem.ContentType,
+			},
+		},
+		{
+			name: "User unauthorized",
+			args: args{},
+			wants: wants{
+				status:      http.StatusUnauthorized,
+				code:        problem.CodeUnauthorized,
+				contentType: problem.ContentType,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockBalanceRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path+"?"+test.args.query, nil)
+		server := echo.New()
+		echoCtx := server.NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+		echoCtx.Set("userID", test.args.userID)
+
+		bc := BalanceController{
+			balance: usecases.NewBalanceUseCase(repo, time.Minute),
+			logger:  log.StandardLogger(),
+		}
+
+		err := bc.exportStatementHandler(echoCtx)
+		require.NoError(t, err, test.name)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, test.wants.status, res.StatusCode, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code, test.name)
+		}
+		assert.Equal(t, test.wants.contentType, res.Header.Get("Content-Type"), test.name)
+		if test.wants.code == "" {
+			assert.Equal(t, test.wants.body, rec.Body.String(), test.name)
+		}
 	}
 }
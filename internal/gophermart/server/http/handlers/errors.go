@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/problem"
+	"github.com/labstack/echo/v4"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidRequestBody = errors.New("invalid request body")
+	ErrUnauthorized       = errors.New("authentication required")
+)
+
+// Статус ответа и код ошибки, возвращаемые клиенту при ошибке err.
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+}
+
+// Соответствие ошибок сценариев и обработчиков ответам с описанием ошибки.
+// Ошибки проверяются по порядку с помощью errors.Is.
+var errorMappings = []errorMapping{
+	{ErrInvalidRequestBody, http.StatusBadRequest, problem.CodeInvalidRequest},
+	{ErrInvalidQueryParam, http.StatusBadRequest, problem.CodeInvalidQueryParam},
+	{ErrInvalidPathParam, http.StatusBadRequest, problem.CodeInvalidPathParam},
+	{ErrUnsupportedFormat, http.StatusNotAcceptable, problem.CodeUnsupportedFormat},
+	{ErrUnauthorized, http.StatusUnauthorized, problem.CodeUnauthorized},
+
+	{entities.ErrInvalidCursor, http.StatusBadRequest, problem.CodeInvalidCursor},
+	{entities.ErrInvalidPageLimit, http.StatusBadRequest, problem.CodeInvalidPageLimit},
+	{entities.ErrInvalidDateRange, http.StatusBadRequest, problem.CodeInvalidDateRange},
+	{entities.ErrInvalidStatus, http.StatusBadRequest, problem.CodeInvalidStatus},
+	{entities.ErrInvalidMoney, http.StatusBadRequest, problem.CodeInvalidAmount},
+	{entities.ErrMoneyOverflow, http.StatusBadRequest, problem.CodeInvalidAmount},
+	{entities.ErrInvalidAdjustment, http.StatusBadRequest, problem.CodeInvalidAdjustment},
+
+	{entities.ErrRefreshTokenReused, http.StatusUnauthorized, problem.CodeRefreshTokenReused},
+	{entities.ErrInvalidToken, http.StatusUnauthorized, problem.CodeInvalidToken},
+	{entities.ErrTokenRevoked, http.StatusUnauthorized, problem.CodeTokenRevoked},
+	{entities.ErrInvalidLoginPassword, http.StatusUnauthorized, problem.CodeInvalidCredentials},
+	{entities.ErrLoginLocked, http.StatusTooManyRequests, problem.CodeLoginLocked},
+	{entities.ErrUserAlreadyExists, http.StatusConflict, problem.CodeUserAlreadyExists},
+	{entities.ErrUserNotFound, http.StatusNotFound, problem.CodeUserNotFound},
+	{entities.ErrInvalidResetToken, http.StatusBadRequest, problem.CodeInvalidResetToken},
+
+	{entities.ErrInvalidOrderNumber, http.StatusUnprocessableEntity, problem.CodeInvalidOrderNumber},
+	{entities.ErrOrderAddedByOther, http.StatusConflict, problem.CodeOrderAddedByOther},
+	{entities.ErrNotEnoughFunds, http.StatusPaymentRequired, problem.CodeNotEnoughFunds},
+	{entities.ErrWithdrawalNotFound, http.StatusNotFound, problem.CodeWithdrawalNotFound},
+	{entities.ErrWithdrawalAlreadyReversed, http.StatusConflict, problem.CodeWithdrawalAlreadyReversed},
+
+	{entities.ErrInvalidWebhook, http.StatusBadRequest, problem.CodeInvalidWebhook},
+	{entities.ErrWebhookNotFound, http.StatusNotFound, problem.CodeWebhookNotFound},
+}
+
+// Возвращает статус ответа и код ошибки err.
+// Для ошибок, отсутствующих в errorMappings, возвращает false.
+func mapError(err error) (int, string, bool) {
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.err) {
+			return mapping.status, mapping.code, true
+		}
+	}
+
+	return 0, "", false
+}
+
+// Отвечает на запрос описанием ошибки err в формате RFC 7807.
+// Неизвестные ошибки журналируются и возвращаются как внутренняя ошибка сервера без подробностей.
+func writeError(e echo.Context, logger *log.Entry, err error) error {
+	status, code, ok := mapError(err)
+	if !ok {
+		logger.Errorf("Something went wrong: %s", err)
+
+		return problem.WriteInternal(e)
+	}
+
+	return problem.Write(e, status, code, err.Error())
+}
+
+// Отвечает на запрос описанием ошибки err со статусом status вместо статуса по умолчанию.
+// Используется, когда одна и та же ошибка сценария означает для обработчика другое.
+func writeErrorStatus(e echo.Context, logger *log.Entry, status int, err error) error {
+	_, code, ok := mapError(err)
+	if !ok {
+		return writeError(e, logger, err)
+	}
+
+	return problem.Write(e, status, code, err.Error())
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+
+	mimeTextCSV           = "text/csv"
+	mimeApplicationNDJSON = "application/x-ndjson"
+
+	// Количество записей, после которого выгруженные данные отправляются клиенту.
+	exportFlushRows = 100
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// Определяет формат выгрузки по параметру format либо по заголовку Accept.
+// Если формат не задан, данные выгружаются в CSV.
+func parseExportFormat(e echo.Context) (string, error) {
+	switch format := strings.ToLower(e.QueryParam("format")); format {
+	case exportFormatCSV, exportFormatNDJSON:
+		return format, nil
+	case "":
+	default:
+		return "", ErrUnsupportedFormat
+	}
+
+	accept := e.Request().Header.Get(echo.HeaderAccept)
+	if accept == "" {
+		return exportFormatCSV, nil
+	}
+
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case mimeTextCSV, "text/*", "*/*":
+			return exportFormatCSV, nil
+		case mimeApplicationNDJSON, "application/jsonl":
+			return exportFormatNDJSON, nil
+		}
+	}
+
+	return "", ErrUnsupportedFormat
+}
+
+// Потоковая запись выгрузки в ответ сервера.
+// Заголовки ответа отправляются при записи первой строки,
+// поэтому до этого момента обработчик может вернуть код ошибки.
+type exporter struct {
+	e        echo.Context
+	format   string
+	filename string
+	columns  []string
+	csv      *csv.Writer
+	json     *json.Encoder
+	rows     int
+	started  bool
+}
+
+func newExporter(e echo.Context, format, name string, columns []string) *exporter {
+	return &exporter{
+		e:        e,
+		format:   format,
+		filename: fmt.Sprintf("%s.%s", name, format),
+		columns:  columns,
+	}
+}
+
+func (ex *exporter) start() error {
+	if ex.started {
+		return nil
+	}
+
+	ex.started = true
+
+	resp := ex.e.Response()
+
+	contentType := mimeTextCSV + "; charset=UTF-8"
+	if ex.format == exportFormatNDJSON {
+		contentType = mimeApplicationNDJSON
+	}
+
+	resp.Header().Set(echo.HeaderContentType, contentType)
+	resp.Header().Set(
+		echo.HeaderContentDisposition,
+		mime.FormatMediaType("attachment", map[string]string{"filename": ex.filename}),
+	)
+	resp.WriteHeader(http.StatusOK)
+
+	if ex.format == exportFormatNDJSON {
+		ex.json = json.NewEncoder(resp)
+
+		return nil
+	}
+
+	ex.csv = csv.NewWriter(resp)
+
+	return ex.csv.Write(ex.columns)
+}
+
+// Записывает одну запись выгрузки: value в формате NDJSON либо row в формате CSV.
+func (ex *exporter) write(value interface{}, row []string) error {
+	if err := ex.start(); err != nil {
+		return err
+	}
+
+	var err error
+
+	if ex.format == exportFormatNDJSON {
+		err = ex.json.Encode(value)
+	} else {
+		err = ex.csv.Write(row)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	ex.rows++
+	if ex.rows%exportFlushRows == 0 {
+		return ex.flush()
+	}
+
+	return nil
+}
+
+// Отправляет клиенту накопленные данные выгрузки.
+func (ex *exporter) flush() error {
+	if err := ex.start(); err != nil {
+		return err
+	}
+
+	if ex.csv != nil {
+		ex.csv.Flush()
+
+		if err := ex.csv.Error(); err != nil {
+			return err
+		}
+	}
+
+	ex.e.Response().Flush()
+
+	return nil
+}
+
+var orderExportColumns = []string{"number", "status", "accrual", "uploaded_at"}
+
+func orderExportRow(order entities.Order) []string {
+	return []string{
+		order.Number,
+		order.Status,
+		order.Accrual.String(),
+		order.UploadedAt.Format(time.RFC3339),
+	}
+}
+
+var statementExportColumns = []string{"operation", "order", "sum", "balance", "processed_at", "reason", "reference"}
+
+func statementExportRow(entry entities.StatementEntry) []string {
+	return []string{
+		entry.Operation,
+		entry.Order,
+		entry.Sum.String(),
+		entry.Balance.String(),
+		entry.ProcessedAt.Format(time.RFC3339),
+		entry.Reason,
+		entry.Reference,
+	}
+}
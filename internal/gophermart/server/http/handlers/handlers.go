@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"errors"
-	"time"
 
+	"github.com/KryukovO/gophermart/internal/gophermart/health"
+	"github.com/KryukovO/gophermart/internal/gophermart/metrics"
 	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
 	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/KryukovO/gophermart/internal/jwtkeys"
 
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
@@ -16,21 +18,31 @@ var (
 	ErrUseCaseIsNil = errors.New("usecase is nil")
 	ErrServerIsNil  = errors.New("server instance is nil")
 	ErrGroupIsNil   = errors.New("rout group is nil")
+	ErrKeySetIsNil  = errors.New("key set is nil")
+	ErrHealthIsNil  = errors.New("health check is nil")
 )
 
 func SetHandlers(
 	server *echo.Echo,
-	secret []byte, tokenLifetime time.Duration,
-	user usecases.User, order usecases.Order, balance usecases.Balance,
-	logger *log.Logger,
+	secret []byte, tokenSource middleware.TokenSource, keys *jwtkeys.KeySet,
+	user usecases.User, order usecases.Order, balance usecases.Balance, token usecases.Token,
+	webhook usecases.Webhook, idempotency usecases.Idempotency,
+	health *health.Health, metrics *metrics.Metrics, logger *log.Logger,
 ) error {
 	if server == nil {
 		return ErrServerIsNil
 	}
 
-	mwManager := middleware.NewManager(secret, logger)
+	if token == nil {
+		return ErrUseCaseIsNil
+	}
+
+	mwManager, err := middleware.NewManager(token, idempotency, tokenSource, metrics, logger)
+	if err != nil {
+		return err
+	}
 
-	userController, err := NewUserController(user, secret, tokenLifetime, logger)
+	userController, err := NewUserController(user, token, secret, mwManager, logger)
 	if err != nil {
 		return err
 	}
@@ -45,6 +57,23 @@ func SetHandlers(
 		return err
 	}
 
+	adminController, err := NewAdminController(user, order, balance, mwManager, logger)
+	if err != nil {
+		return err
+	}
+
+	webhookController, err := NewWebhookController(webhook, mwManager, logger)
+	if err != nil {
+		return err
+	}
+
+	keysController, err := NewKeysController(keys, mwManager, logger)
+	if err != nil {
+		return err
+	}
+
+	server.Use(mwManager.TracingMiddleware, mwManager.MetricsMiddleware)
+
 	group := server.Group("/api")
 	group.Use(
 		mwManager.LoggingMiddleware,
@@ -66,7 +95,34 @@ func SetHandlers(
 		return err
 	}
 
+	err = adminController.MapHandlers(group)
+	if err != nil {
+		return err
+	}
+
+	err = webhookController.MapHandlers(group)
+	if err != nil {
+		return err
+	}
+
+	err = keysController.MapHandlers(server.Group("/.well-known"))
+	if err != nil {
+		return err
+	}
+
 	server.GET("/swagger/*", echoSwagger.WrapHandler)
 
+	if health != nil {
+		healthController, err := NewHealthController(health, mwManager, logger)
+		if err != nil {
+			return err
+		}
+
+		err = healthController.MapHandlers(server)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -1,26 +1,40 @@
 package handlers
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/metrics"
 	"github.com/KryukovO/gophermart/internal/gophermart/repository/mocks"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/problem"
 	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/KryukovO/gophermart/internal/jwtkeys"
+	"github.com/KryukovO/gophermart/internal/password"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestSetHandlers(t *testing.T) {
 	type args struct {
-		server        *echo.Echo
-		secret        []byte
-		tokenLifetime time.Duration
-		user          usecases.User
-		order         usecases.Order
-		balance       usecases.Balance
-		logger        *log.Logger
+		server  *echo.Echo
+		secret  []byte
+		source  middleware.TokenSource
+		keys    *jwtkeys.KeySet
+		user    usecases.User
+		order   usecases.Order
+		balance usecases.Balance
+		token   usecases.Token
+		webhook usecases.Webhook
+		logger  *log.Logger
 	}
 
 	type wants struct {
@@ -35,13 +49,16 @@ func TestSetHandlers(t *testing.T) {
 		{
 			name: "Correct setting",
 			args: args{
-				server:        echo.New(),
-				secret:        []byte{},
-				tokenLifetime: time.Second,
-				user:          usecases.NewUserUseCase(mocks.NewMockUserRepo(gomock.NewController(t)), time.Second),
-				order:         usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
-				balance:       usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
-				logger:        log.New(),
+				server:  echo.New(),
+				secret:  []byte{},
+				source:  middleware.TokenSourceHeader,
+				keys:    testKeys,
+				user:    newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+				order:   usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
+				balance: usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
+				token:   newTestTokenUseCase(t),
+				webhook: usecases.NewWebhookUseCase(mocks.NewMockWebhookRepo(gomock.NewController(t)), time.Second),
+				logger:  log.New(),
 			},
 			wants: wants{
 				wantErr: false,
@@ -50,26 +67,67 @@ func TestSetHandlers(t *testing.T) {
 		{
 			name: "Nil logger",
 			args: args{
-				server:        echo.New(),
-				secret:        []byte{},
-				tokenLifetime: time.Second,
-				user:          usecases.NewUserUseCase(mocks.NewMockUserRepo(gomock.NewController(t)), time.Second),
-				order:         usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
-				balance:       usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
+				server:  echo.New(),
+				secret:  []byte{},
+				source:  middleware.TokenSourceHeader,
+				keys:    testKeys,
+				user:    newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+				order:   usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
+				balance: usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
+				token:   newTestTokenUseCase(t),
+				webhook: usecases.NewWebhookUseCase(mocks.NewMockWebhookRepo(gomock.NewController(t)), time.Second),
 			},
 			wants: wants{
 				wantErr: false,
 			},
 		},
+		{
+			name: "Unknown token source",
+			args: args{
+				server:  echo.New(),
+				secret:  []byte{},
+				source:  "query",
+				keys:    testKeys,
+				user:    newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+				order:   usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
+				balance: usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
+				token:   newTestTokenUseCase(t),
+				webhook: usecases.NewWebhookUseCase(mocks.NewMockWebhookRepo(gomock.NewController(t)), time.Second),
+				logger:  log.New(),
+			},
+			wants: wants{
+				wantErr: true,
+			},
+		},
+		{
+			name: "Nil key set",
+			args: args{
+				server:  echo.New(),
+				secret:  []byte{},
+				source:  middleware.TokenSourceHeader,
+				user:    newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+				order:   usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
+				balance: usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
+				token:   newTestTokenUseCase(t),
+				webhook: usecases.NewWebhookUseCase(mocks.NewMockWebhookRepo(gomock.NewController(t)), time.Second),
+				logger:  log.New(),
+			},
+			wants: wants{
+				wantErr: true,
+			},
+		},
 		{
 			name: "Nil server",
 			args: args{
-				secret:        []byte{},
-				tokenLifetime: time.Second,
-				user:          usecases.NewUserUseCase(mocks.NewMockUserRepo(gomock.NewController(t)), time.Second),
-				order:         usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
-				balance:       usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
-				logger:        log.New(),
+				secret:  []byte{},
+				source:  middleware.TokenSourceHeader,
+				keys:    testKeys,
+				user:    newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+				order:   usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
+				balance: usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
+				token:   newTestTokenUseCase(t),
+				webhook: usecases.NewWebhookUseCase(mocks.NewMockWebhookRepo(gomock.NewController(t)), time.Second),
+				logger:  log.New(),
 			},
 			wants: wants{
 				wantErr: true,
@@ -78,12 +136,15 @@ func TestSetHandlers(t *testing.T) {
 		{
 			name: "Nil user",
 			args: args{
-				server:        echo.New(),
-				secret:        []byte{},
-				tokenLifetime: time.Second,
-				order:         usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
-				balance:       usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
-				logger:        log.New(),
+				server:  echo.New(),
+				secret:  []byte{},
+				source:  middleware.TokenSourceHeader,
+				keys:    testKeys,
+				order:   usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
+				balance: usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
+				token:   newTestTokenUseCase(t),
+				webhook: usecases.NewWebhookUseCase(mocks.NewMockWebhookRepo(gomock.NewController(t)), time.Second),
+				logger:  log.New(),
 			},
 			wants: wants{
 				wantErr: true,
@@ -92,12 +153,15 @@ func TestSetHandlers(t *testing.T) {
 		{
 			name: "Nil order",
 			args: args{
-				server:        echo.New(),
-				secret:        []byte{},
-				tokenLifetime: time.Second,
-				user:          usecases.NewUserUseCase(mocks.NewMockUserRepo(gomock.NewController(t)), time.Second),
-				balance:       usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
-				logger:        log.New(),
+				server:  echo.New(),
+				secret:  []byte{},
+				source:  middleware.TokenSourceHeader,
+				keys:    testKeys,
+				user:    newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+				balance: usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
+				token:   newTestTokenUseCase(t),
+				webhook: usecases.NewWebhookUseCase(mocks.NewMockWebhookRepo(gomock.NewController(t)), time.Second),
+				logger:  log.New(),
 			},
 			wants: wants{
 				wantErr: true,
@@ -106,12 +170,49 @@ func TestSetHandlers(t *testing.T) {
 		{
 			name: "Nil balance",
 			args: args{
-				server:        echo.New(),
-				secret:        []byte{},
-				tokenLifetime: time.Second,
-				user:          usecases.NewUserUseCase(mocks.NewMockUserRepo(gomock.NewController(t)), time.Second),
-				order:         usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
-				logger:        log.New(),
+				server:  echo.New(),
+				secret:  []byte{},
+				source:  middleware.TokenSourceHeader,
+				keys:    testKeys,
+				user:    newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+				order:   usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
+				token:   newTestTokenUseCase(t),
+				webhook: usecases.NewWebhookUseCase(mocks.NewMockWebhookRepo(gomock.NewController(t)), time.Second),
+				logger:  log.New(),
+			},
+			wants: wants{
+				wantErr: true,
+			},
+		},
+		{
+			name: "Nil webhook",
+			args: args{
+				server:  echo.New(),
+				secret:  []byte{},
+				source:  middleware.TokenSourceHeader,
+				keys:    testKeys,
+				user:    newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+				order:   usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
+				balance: usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
+				token:   newTestTokenUseCase(t),
+				logger:  log.New(),
+			},
+			wants: wants{
+				wantErr: true,
+			},
+		},
+		{
+			name: "Nil token",
+			args: args{
+				server:  echo.New(),
+				secret:  []byte{},
+				source:  middleware.TokenSourceHeader,
+				keys:    testKeys,
+				user:    newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+				order:   usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
+				balance: usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
+				webhook: usecases.NewWebhookUseCase(mocks.NewMockWebhookRepo(gomock.NewController(t)), time.Second),
+				logger:  log.New(),
 			},
 			wants: wants{
 				wantErr: true,
@@ -121,8 +222,9 @@ func TestSetHandlers(t *testing.T) {
 
 	for _, test := range tests {
 		err := SetHandlers(
-			test.args.server, test.args.secret, test.args.tokenLifetime,
-			test.args.user, test.args.order, test.args.balance, test.args.logger,
+			test.args.server, test.args.secret, test.args.source, test.args.keys,
+			test.args.user, test.args.order, test.args.balance, test.args.token,
+			test.args.webhook, nil, nil, nil, test.args.logger,
 		)
 
 		if test.wants.wantErr {
@@ -132,3 +234,77 @@ func TestSetHandlers(t *testing.T) {
 		}
 	}
 }
+
+func TestMetricsNotPublic(t *testing.T) {
+	server := echo.New()
+
+	err := SetHandlers(
+		server, []byte{}, middleware.TokenSourceHeader, testKeys,
+		newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+		usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
+		usecases.NewBalanceUseCase(mocks.NewMockBalanceRepo(gomock.NewController(t)), time.Second),
+		newTestTokenUseCase(t),
+		usecases.NewWebhookUseCase(mocks.NewMockWebhookRepo(gomock.NewController(t)), time.Second),
+		nil, nil, metrics.NewMetrics(log.New()), log.New(),
+	)
+	require.NoError(t, err)
+
+	// Метрики обслуживаются отдельным сервером и не должны быть доступны через адрес API
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func newTestTokenUseCase(t *testing.T) *usecases.TokenUseCase {
+	t.Helper()
+
+	return usecases.NewTokenUseCase(
+		mocks.NewMockTokenRepo(gomock.NewController(t)), testKeys,
+		time.Minute, time.Hour, time.Second,
+	)
+}
+
+var testLoginPolicy = entities.LoginPolicy{
+	MaxAttempts:      5,
+	MaxAttemptsPerIP: 20,
+	Delay:            time.Second,
+	Lockout:          time.Minute,
+}
+
+var testKeys = jwtkeys.NewHMACKeySet([]byte("secret"))
+
+// Хешер с минимальной стоимостью, чтобы не замедлять тесты.
+var testHasher = password.NewBcryptHasher(bcrypt.MinCost)
+
+func newTestManager(t *testing.T) *middleware.Manager {
+	t.Helper()
+
+	mwManager, err := middleware.NewManager(newTestTokenUseCase(t), nil, middleware.TokenSourceHeader, nil, log.New())
+	require.NoError(t, err)
+
+	return mwManager
+}
+
+func newTestUserUseCase(t *testing.T, repo *mocks.MockUserRepo) *usecases.UserUseCase {
+	t.Helper()
+
+	return usecases.NewUserUseCase(
+		repo, mocks.NewMockLoginAttemptRepo(gomock.NewController(t)),
+		testHasher, testLoginPolicy, nil, time.Hour, time.Second,
+	)
+}
+
+// Проверяет, что ответ содержит описание ошибки в формате RFC 7807 с кодом code.
+func assertProblem(t *testing.T, rec *httptest.ResponseRecorder, code string, msgAndArgs ...interface{}) {
+	t.Helper()
+
+	assert.Equal(t, problem.ContentType, rec.Header().Get(echo.HeaderContentType), msgAndArgs...)
+
+	var body problem.Problem
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body), msgAndArgs...)
+	assert.Equal(t, rec.Code, body.Status, msgAndArgs...)
+	assert.Equal(t, code, body.Code, msgAndArgs...)
+	assert.Equal(t, "urn:gophermart:problem:"+code, body.Type, msgAndArgs...)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/health"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
+	"github.com/labstack/echo/v4"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type HealthController struct {
+	health *health.Health
+	mw     *middleware.Manager
+	logger *log.Logger
+}
+
+func NewHealthController(
+	health *health.Health, mwManager *middleware.Manager, logger *log.Logger,
+) (*HealthController, error) {
+	if health == nil {
+		return nil, ErrHealthIsNil
+	}
+
+	controllerLogger := log.StandardLogger()
+	if logger != nil {
+		controllerLogger = logger
+	}
+
+	return &HealthController{
+		health: health,
+		mw:     mwManager,
+		logger: controllerLogger,
+	}, nil
+}
+
+func (c *HealthController) MapHandlers(server *echo.Echo) error {
+	if server == nil {
+		return ErrServerIsNil
+	}
+
+	server.GET("/healthz", c.livenessHandler)
+	server.GET("/readyz", c.readinessHandler)
+
+	return nil
+}
+
+// @Summary       Liveness probe
+// @Description   Check that the service is running. Dependencies are not checked.
+// @Tags          Gophermart HTTP API
+// @Produce       json
+// @Success       200    {object}   health.Report
+// @Router        /healthz [get]
+func (c *HealthController) livenessHandler(e echo.Context) error {
+	return e.JSON(http.StatusOK, health.Report{Status: health.StatusUp})
+}
+
+// @Summary       Readiness probe
+// @Description   Check that the service is ready to handle requests:
+// @Description   the database is reachable, migrations are applied and accrual polling succeeds.
+// @Description   The service is not ready during graceful shutdown.
+// @Tags          Gophermart HTTP API
+// @Produce       json
+// @Success       200    {object}   health.Report
+// @Failure       503    {object}   health.Report   "Service is not ready"
+// @Router        /readyz [get]
+func (c *HealthController) readinessHandler(e echo.Context) error {
+	report, ready := c.health.Ready(e.Request().Context())
+	if !ready {
+		c.logger.Warnf("Service is not ready: %+v", report.Components)
+
+		return e.JSON(http.StatusServiceUnavailable, report)
+	}
+
+	return e.JSON(http.StatusOK, report)
+}
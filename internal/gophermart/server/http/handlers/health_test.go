@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/health"
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHealthController(t *testing.T) {
+	_, err := NewHealthController(health.NewHealth(time.Second), newTestManager(t), log.New())
+	assert.NoError(t, err)
+
+	_, err = NewHealthController(nil, newTestManager(t), log.New())
+	assert.ErrorIs(t, err, ErrHealthIsNil)
+}
+
+func TestHealthHandlers(t *testing.T) {
+	var dbErr error
+
+	serviceHealth := health.NewHealth(time.Second)
+	serviceHealth.AddCheck("database", func(ctx context.Context) error { return dbErr })
+
+	server := echo.New()
+
+	c, err := NewHealthController(serviceHealth, newTestManager(t), log.New())
+	require.NoError(t, err)
+	require.NoError(t, c.MapHandlers(server))
+
+	request := func(path string) (int, health.Report) {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var report health.Report
+
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+
+		return rec.Code, report
+	}
+
+	status, report := request("/readyz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, health.StatusUp, report.Status)
+	assert.Equal(t, health.StatusUp, report.Components["database"].Status)
+
+	dbErr = errors.New("connection refused")
+
+	status, report = request("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, health.StatusDown, report.Status)
+	assert.Equal(t, health.ComponentStatus{Status: health.StatusDown, Error: "connection refused"},
+		report.Components["database"])
+
+	// Проверка жизнеспособности не зависит от состояния компонентов
+	status, report = request("/healthz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, health.StatusUp, report.Status)
+
+	dbErr = nil
+	serviceHealth.Drain()
+
+	status, report = request("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, health.StatusDown, report.Components["server"].Status)
+
+	status, _ = request("/healthz")
+	assert.Equal(t, http.StatusOK, status)
+}
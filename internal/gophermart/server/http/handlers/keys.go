@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
+	"github.com/KryukovO/gophermart/internal/jwtkeys"
+	"github.com/labstack/echo/v4"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Время, в течение которого клиенты могут кешировать набор открытых ключей.
+const jwksCacheControl = "public, max-age=300"
+
+type KeysController struct {
+	keys   *jwtkeys.KeySet
+	mw     *middleware.Manager
+	logger *log.Logger
+}
+
+func NewKeysController(
+	keys *jwtkeys.KeySet, mwManager *middleware.Manager, logger *log.Logger,
+) (*KeysController, error) {
+	if keys == nil {
+		return nil, ErrKeySetIsNil
+	}
+
+	controllerLogger := log.StandardLogger()
+	if logger != nil {
+		controllerLogger = logger
+	}
+
+	return &KeysController{
+		keys:   keys,
+		mw:     mwManager,
+		logger: controllerLogger,
+	}, nil
+}
+
+func (c *KeysController) MapHandlers(group *echo.Group) error {
+	if group == nil {
+		return ErrGroupIsNil
+	}
+
+	group.Add(http.MethodGet, "/jwks.json", c.mw.LoggingMiddleware(c.jwksHandler))
+
+	return nil
+}
+
+// @Summary       Token signing keys
+// @Description   Get the public keys used to verify access tokens, selected by the kid token header.
+// @Description   Keys scheduled for future use and replaced keys within the grace period are included.
+// @Description   The set is empty if tokens are signed with a shared secret.
+// @Tags          Gophermart HTTP API
+// @Produce       json
+// @Success       200    {object}   jwtkeys.JWKS
+// @Router        /.well-known/jwks.json [get]
+func (c *KeysController) jwksHandler(e echo.Context) error {
+	e.Response().Header().Set(echo.HeaderCacheControl, jwksCacheControl)
+
+	return e.JSON(http.StatusOK, c.keys.JWKS())
+}
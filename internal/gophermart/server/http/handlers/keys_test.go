@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KryukovO/gophermart/internal/jwtkeys"
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeysController(t *testing.T) {
+	_, err := NewKeysController(testKeys, newTestManager(t), log.New())
+	assert.NoError(t, err)
+
+	_, err = NewKeysController(nil, newTestManager(t), log.New())
+	assert.ErrorIs(t, err, ErrKeySetIsNil)
+}
+
+func TestJWKSHandler(t *testing.T) {
+	path := "/.well-known/jwks.json"
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	echoCtx := echo.New().NewContext(req, rec)
+
+	echoCtx.SetPath(path)
+
+	c := KeysController{
+		keys:   testKeys,
+		logger: log.StandardLogger(),
+	}
+	err := c.jwksHandler(echoCtx)
+	require.NoError(t, err)
+
+	res := rec.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, jwksCacheControl, res.Header.Get(echo.HeaderCacheControl))
+
+	var jwks jwtkeys.JWKS
+
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&jwks))
+	assert.Empty(t, jwks.Keys, "shared secret must not be published")
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/logging"
 	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
 	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
 
@@ -43,67 +44,58 @@ func (c *OrderController) MapHandlers(group *echo.Group) error {
 		return ErrGroupIsNil
 	}
 
-	group.Add(http.MethodPost, "/user/orders", c.mw.AuthenticationMiddleware(c.addOrderHandler))
+	group.Add(
+		http.MethodPost, "/user/orders", c.mw.AuthenticationMiddleware(c.mw.IdempotencyMiddleware(c.addOrderHandler)),
+	)
 	group.Add(http.MethodGet, "/user/orders", c.mw.AuthenticationMiddleware(c.ordersHandler))
+	group.Add(http.MethodGet, "/user/orders/export", c.mw.AuthenticationMiddleware(c.exportOrdersHandler))
 
 	return nil
 }
 
 // @Summary       Add new order
 // @Description   Loading by the user of the order number.
+// @Description   A retry with the same Idempotency-Key header and body returns the stored response.
 // @Tags          Gophermart HTTP API
 // @Accept        plain
-// @Param         order   body       string   true   "Order number."
+// @Param         order             body       string   true    "Order number."
+// @Param         Idempotency-Key   header     string   false   "Unique request key for safe retries."
 // @Success       200
 // @Success       202
-// @Failure       400     {object}   echo.HTTPError
-// @Failure       401     {object}   echo.HTTPError
-// @Failure       409     {object}   echo.HTTPError
-// @Failure       422     {object}   echo.HTTPError
-// @Failure       500     {object}   echo.HTTPError
+// @Failure       400               {object}   Problem
+// @Failure       401               {object}   Problem
+// @Failure       409               {object}   Problem
+// @Failure       422               {object}   Problem
+// @Failure       500               {object}   Problem
 // @Security      JWT
+// @Security      Bearer
 // @Router        /api/user/orders [post]
 func (c *OrderController) addOrderHandler(e echo.Context) error {
-	uuid := e.Get("uuid")
-	if uuid == nil {
-		uuid = ""
-	}
+	logger := logging.FromContext(e.Request().Context(), c.logger)
 
 	body, err := io.ReadAll(e.Request().Body)
 	if err != nil {
-		c.logger.Errorf("[%s] Something went wrong: %s", uuid, err)
-
-		return e.NoContent(http.StatusInternalServerError)
+		return writeError(e, logger, err)
 	}
 
-	c.logger.Debugf("[%s] Request body: %s", uuid, string(body))
+	logger.Debugf("Request body: %s", string(body))
 
 	userID := e.Get("userID")
 
 	user, ok := userID.(int64)
 	if !ok {
-		return e.NoContent(http.StatusUnauthorized)
+		return writeError(e, logger, ErrUnauthorized)
 	}
 
 	order := entities.NewOrder(string(body), user)
 
 	err = c.order.AddOrder(e.Request().Context(), order)
 	if err != nil {
-		if errors.Is(err, entities.ErrInvalidOrderNumber) {
-			return e.NoContent(http.StatusUnprocessableEntity)
-		}
-
 		if errors.Is(err, entities.ErrOrderAlreadyAdded) {
 			return e.NoContent(http.StatusOK)
 		}
 
-		if errors.Is(err, entities.ErrOrderAddedByOther) {
-			return e.NoContent(http.StatusConflict)
-		}
-
-		c.logger.Errorf("[%s] Something went wrong: %s", uuid, err)
-
-		return e.NoContent(http.StatusInternalServerError)
+		return writeError(e, logger, err)
 	}
 
 	return e.NoContent(http.StatusAccepted)
@@ -112,37 +104,125 @@ func (c *OrderController) addOrderHandler(e echo.Context) error {
 // @Summary       Get uploaded orders
 // @Description   Get a list of order numbers uploaded by the user,
 // @Description   their processing statuses and information about accruals.
+// @Description   Orders are returned page by page, the cursor of the next page
+// @Description   is passed in the X-Next-Cursor response header.
 // @Tags          Gophermart HTTP API
 // @Produce       json
-// @Success       200    {array}    entities.Order
+// @Param         limit    query      int       false   "Page size (default 100, maximum 1000)."
+// @Param         cursor   query      string    false   "Cursor of the page from the X-Next-Cursor header."
+// @Param         sort     query      string    false   "Sort direction by upload time."   Enums(asc, desc)
+// @Param         status   query      []string  false   "Order statuses."                  collectionFormat(csv)
+// @Param         from     query      string    false   "Minimum upload time (RFC 3339)."
+// @Param         to       query      string    false   "Maximum upload time (RFC 3339)."
+// @Success       200      {array}    entities.Order
+// @Header        200      {string}   X-Next-Cursor   "Cursor of the next page."
 // @Success       204
-// @Failure       401    {object}   echo.HTTPError
-// @Failure       500    {object}   echo.HTTPError
+// @Failure       400      {object}   Problem
+// @Failure       401      {object}   Problem
+// @Failure       500      {object}   Problem
 // @Security      JWT
+// @Security      Bearer
 // @Router        /api/user/orders [get]
 func (c *OrderController) ordersHandler(e echo.Context) error {
-	uuid := e.Get("uuid")
-	if uuid == nil {
-		uuid = ""
-	}
+	logger := logging.FromContext(e.Request().Context(), c.logger)
 
 	userID := e.Get("userID")
 
 	user, ok := userID.(int64)
 	if !ok {
-		return e.NoContent(http.StatusUnauthorized)
+		return writeError(e, logger, ErrUnauthorized)
 	}
 
-	orders, err := c.order.Orders(e.Request().Context(), user)
+	filter, err := parseOrderFilter(e, user)
 	if err != nil {
-		c.logger.Errorf("[%s] Something went wrong: %s", uuid, err)
+		return writeError(e, logger, err)
+	}
 
-		return e.NoContent(http.StatusInternalServerError)
+	orders, next, err := c.order.Orders(e.Request().Context(), &filter)
+	if err != nil {
+		return writeError(e, logger, err)
 	}
 
 	if len(orders) == 0 {
 		return e.NoContent(http.StatusNoContent)
 	}
 
+	if next != nil {
+		e.Response().Header().Set(nextCursorHeader, next.String())
+	}
+
 	return e.JSON(http.StatusOK, orders)
 }
+
+// @Summary       Export orders
+// @Description   Export all orders uploaded by the user as CSV or NDJSON.
+// @Description   The format is selected by the format query parameter or the Accept header, CSV is used by default.
+// @Description   Orders are streamed to the client without loading them all into memory.
+// @Tags          Gophermart HTTP API
+// @Produce       text/csv,application/x-ndjson
+// @Param         format   query      string    false   "Export format."   Enums(csv, ndjson)
+// @Param         sort     query      string    false   "Sort direction by upload time."   Enums(asc, desc)
+// @Param         status   query      []string  false   "Order statuses."   collectionFormat(csv)
+// @Param         from     query      string    false   "Minimum upload time (RFC 3339)."
+// @Param         to       query      string    false   "Maximum upload time (RFC 3339)."
+// @Success       200      {array}    entities.Order
+// @Failure       400      {object}   Problem
+// @Failure       401      {object}   Problem
+// @Failure       406      {object}   Problem
+// @Failure       500      {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/user/orders/export [get]
+func (c *OrderController) exportOrdersHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	userID := e.Get("userID")
+
+	user, ok := userID.(int64)
+	if !ok {
+		return writeError(e, logger, ErrUnauthorized)
+	}
+
+	format, err := parseExportFormat(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	descending, err := parseSort(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	uploaded, err := parseDateRange(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	filter := entities.OrderFilter{
+		UserID:   user,
+		Statuses: parseList(e, "status"),
+		Uploaded: uploaded,
+		Page:     entities.Page{Descending: descending},
+	}
+
+	exp := newExporter(e, format, "orders", orderExportColumns)
+
+	err = c.order.ExportOrders(e.Request().Context(), &filter, func(order entities.Order) error {
+		return exp.write(order, orderExportRow(order))
+	})
+	if err == nil {
+		err = exp.flush()
+	}
+
+	if err != nil {
+		if exp.started {
+			logger.Errorf("Export interrupted: %s", err)
+
+			return nil
+		}
+
+		return writeError(e, logger, err)
+	}
+
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
 	"github.com/KryukovO/gophermart/internal/gophermart/repository/mocks"
 	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/problem"
 	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
@@ -38,7 +39,7 @@ func TestNewOrderController(t *testing.T) {
 			name: "Correct creation",
 			args: args{
 				order:     usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
-				mwManager: middleware.NewManager([]byte("secret"), log.New()),
+				mwManager: newTestManager(t),
 				logger:    log.New(),
 			},
 			wants: wants{
@@ -49,7 +50,7 @@ func TestNewOrderController(t *testing.T) {
 			name: "Nil logger",
 			args: args{
 				order:     usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
-				mwManager: middleware.NewManager([]byte("secret"), log.New()),
+				mwManager: newTestManager(t),
 				logger:    nil,
 			},
 			wants: wants{
@@ -60,7 +61,7 @@ func TestNewOrderController(t *testing.T) {
 			name: "Nil order",
 			args: args{
 				order:     nil,
-				mwManager: middleware.NewManager([]byte("secret"), log.New()),
+				mwManager: newTestManager(t),
 				logger:    log.New(),
 			},
 			wants: wants{
@@ -129,7 +130,7 @@ func TestOrderMapHandlers(t *testing.T) {
 	for _, test := range tests {
 		ctrl, err := NewOrderController(
 			usecases.NewOrderUseCase(mocks.NewMockOrderRepo(gomock.NewController(t)), time.Second),
-			middleware.NewManager([]byte("secret"), log.New()),
+			newTestManager(t),
 			log.New(),
 		)
 
@@ -156,6 +157,7 @@ func TestAddOrderHandler(t *testing.T) {
 
 	type wants struct {
 		status int
+		code   string
 	}
 
 	tests := []struct {
@@ -201,6 +203,7 @@ func TestAddOrderHandler(t *testing.T) {
 			},
 			wants: wants{
 				status: http.StatusConflict,
+				code:   problem.CodeOrderAddedByOther,
 			},
 		},
 		{
@@ -210,6 +213,7 @@ func TestAddOrderHandler(t *testing.T) {
 			},
 			wants: wants{
 				status: http.StatusUnauthorized,
+				code:   problem.CodeUnauthorized,
 			},
 		},
 		{
@@ -220,6 +224,7 @@ func TestAddOrderHandler(t *testing.T) {
 			},
 			wants: wants{
 				status: http.StatusUnprocessableEntity,
+				code:   problem.CodeInvalidOrderNumber,
 			},
 		},
 	}
@@ -251,6 +256,10 @@ func TestAddOrderHandler(t *testing.T) {
 		defer res.Body.Close()
 
 		assert.Equal(t, test.wants.status, res.StatusCode)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code)
+		}
 	}
 }
 
@@ -266,11 +275,14 @@ func TestOrdersHandler(t *testing.T) {
 
 	type args struct {
 		userID interface{}
+		query  string
 	}
 
 	type wants struct {
 		status      int
 		contentType string
+		nextCursor  bool
+		code        string
 	}
 
 	tests := []struct {
@@ -304,11 +316,52 @@ func TestOrdersHandler(t *testing.T) {
 				status: http.StatusNoContent,
 			},
 		},
+		{
+			name: "Next page exists",
+			prepare: func(mock *mocks.MockOrderRepo) {
+				mock.EXPECT().Orders(gomock.Any(), gomock.Any()).Return([]entities.Order{order, order}, nil)
+			},
+			args: args{
+				userID: int64(1),
+				query:  "limit=1&sort=desc",
+			},
+			wants: wants{
+				status:      http.StatusOK,
+				contentType: "application/json; charset=UTF-8",
+				nextCursor:  true,
+			},
+		},
+		{
+			name: "Invalid query parameters",
+			args: args{
+				userID: int64(1),
+				query:  "limit=-1",
+			},
+			wants: wants{
+				status:      http.StatusBadRequest,
+				code:        problem.CodeInvalidQueryParam,
+				contentType: problem.ContentType,
+			},
+		},
+		{
+			name: "Invalid cursor",
+			args: args{
+				userID: int64(1),
+				query:  "cursor=abc",
+			},
+			wants: wants{
+				status:      http.StatusBadRequest,
+				code:        problem.CodeInvalidCursor,
+				contentType: problem.ContentType,
+			},
+		},
 		{
 			name: "User unauthorized",
 			args: args{},
 			wants: wants{
-				status: http.StatusUnauthorized,
+				status:      http.StatusUnauthorized,
+				code:        problem.CodeUnauthorized,
+				contentType: problem.ContentType,
 			},
 		},
 	}
@@ -321,7 +374,7 @@ func TestOrdersHandler(t *testing.T) {
 		}
 
 		rec := httptest.NewRecorder()
-		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req := httptest.NewRequest(http.MethodGet, path+"?"+test.args.query, nil)
 		server := echo.New()
 		echoCtx := server.NewContext(req, rec)
 
@@ -340,6 +393,165 @@ func TestOrdersHandler(t *testing.T) {
 		defer res.Body.Close()
 
 		assert.Equal(t, test.wants.status, res.StatusCode)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code)
+		}
 		assert.Equal(t, test.wants.contentType, res.Header.Get("Content-Type"))
+		assert.Equal(t, test.wants.nextCursor, res.Header.Get(nextCursorHeader) != "")
+	}
+}
+
+func TestExportOrdersHandler(t *testing.T) {
+	path := "/api/user/orders/export"
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	order := entities.Order{
+		ID:         1,
+		UserID:     1,
+		Number:     "4561261212345467",
+		Status:     entities.OrderStatusProcessed,
+		Accrual:    entities.NewMoney(500, 50),
+		UploadedAt: ts,
+	}
+
+	type args struct {
+		userID interface{}
+		query  string
+		accept string
+	}
+
+	type wants struct {
+		status      int
+		contentType string
+		body        string
+		code        string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockOrderRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "CSV by default",
+			prepare: func(mock *mocks.MockOrderRepo) {
+				mock.EXPECT().Orders(gomock.Any(), gomock.Any()).Return([]entities.Order{order}, nil)
+			},
+			args: args{
+				userID: int64(1),
+			},
+			wants: wants{
+				status:      http.StatusOK,
+				contentType: "text/csv; charset=UTF-8",
+				body:        "number,status,accrual,uploaded_at\n4561261212345467,PROCESSED,500.5,2023-01-02T03:04:05Z\n",
+			},
+		},
+		{
+			name: "NDJSON by Accept header",
+			prepare: func(mock *mocks.MockOrderRepo) {
+				mock.EXPECT().Orders(gomock.Any(), gomock.Any()).Return([]entities.Order{order, order}, nil)
+			},
+			args: args{
+				userID: int64(1),
+				accept: "application/x-ndjson",
+			},
+			wants: wants{
+				status:      http.StatusOK,
+				contentType: "application/x-ndjson",
+				body: `{"number":"4561261212345467","status":"PROCESSED","accrual":500.5,"uploaded_at":"2023-01-02T03:04:05Z"}` +
+					"\n" +
+					`{"number":"4561261212345467","status":"PROCESSED","accrual":500.5,"uploaded_at":"2023-01-02T03:04:05Z"}` +
+					"\n",
+			},
+		},
+		{
+			name: "Empty export",
+			prepare: func(mock *mocks.MockOrderRepo) {
+				mock.EXPECT().Orders(gomock.Any(), gomock.Any()).Return([]entities.Order{}, nil)
+			},
+			args: args{
+				userID: int64(1),
+				query:  "format=csv",
+				accept: "application/json",
+			},
+			wants: wants{
+				status:      http.StatusOK,
+				contentType: "text/csv; charset=UTF-8",
+				body:        "number,status,accrual,uploaded_at\n",
+			},
+		},
+		{
+			name: "Unsupported format",
+			args: args{
+				userID: int64(1),
+				accept: "application/xml",
+			},
+			wants: wants{
+				status:      http.StatusNotAcceptable,
+				code:        problem.CodeUnsupportedFormat,
+				contentType: problem.ContentType,
+			},
+		},
+		{
+			name: "Invalid status",
+			args: args{
+				userID: int64(1),
+				query:  "status=unknown",
+			},
+			wants: wants{
+				status:      http.StatusBadRequest,
+				code:        problem.CodeInvalidStatus,
+				contentType: problem.ContentType,
+			},
+		},
+		{
+			name: "User unauthorized",
+			args: args{},
+			wants: wants{
+				status:      http.StatusUnauthorized,
+				code:        problem.CodeUnauthorized,
+				contentType: problem.ContentType,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockOrderRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path+"?"+test.args.query, nil)
+		req.Header.Set(echo.HeaderAccept, test.args.accept)
+
+		server := echo.New()
+		echoCtx := server.NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+		echoCtx.Set("userID", test.args.userID)
+
+		oc := OrderController{
+			order:  usecases.NewOrderUseCase(repo, time.Minute),
+			logger: log.StandardLogger(),
+		}
+
+		err := oc.exportOrdersHandler(echoCtx)
+		require.NoError(t, err, test.name)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, test.wants.status, res.StatusCode, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code, test.name)
+		}
+		assert.Equal(t, test.wants.contentType, res.Header.Get("Content-Type"), test.name)
+		if test.wants.code == "" {
+			assert.Equal(t, test.wants.body, rec.Body.String(), test.name)
+		}
 	}
 }
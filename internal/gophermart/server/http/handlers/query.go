@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/labstack/echo/v4"
+)
+
+const nextCursorHeader = "X-Next-Cursor"
+
+var (
+	ErrInvalidQueryParam = errors.New("invalid query parameter")
+	ErrInvalidPathParam  = errors.New("invalid path parameter")
+)
+
+// Считывает параметры постраничной выборки limit, cursor и sort.
+func parsePage(e echo.Context) (entities.Page, error) {
+	var page entities.Page
+
+	if limit := e.QueryParam("limit"); limit != "" {
+		value, err := strconv.ParseUint(limit, 10, 32)
+		if err != nil {
+			return entities.Page{}, ErrInvalidQueryParam
+		}
+
+		page.Limit = uint(value)
+	}
+
+	if cursor := e.QueryParam("cursor"); cursor != "" {
+		value, err := entities.ParseCursor(cursor)
+		if err != nil {
+			return entities.Page{}, err
+		}
+
+		page.Cursor = &value
+	}
+
+	descending, err := parseSort(e)
+	if err != nil {
+		return entities.Page{}, err
+	}
+
+	page.Descending = descending
+
+	return page, nil
+}
+
+// Считывает направление сортировки из параметра sort. Возвращает true для сортировки по убыванию.
+func parseSort(e echo.Context) (bool, error) {
+	switch strings.ToLower(e.QueryParam("sort")) {
+	case "", "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	default:
+		return false, ErrInvalidQueryParam
+	}
+}
+
+// Считывает диапазон дат из параметров from и to в формате RFC 3339.
+func parseDateRange(e echo.Context) (entities.DateRange, error) {
+	var (
+		dateRange entities.DateRange
+		err       error
+	)
+
+	if from := e.QueryParam("from"); from != "" {
+		dateRange.From, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			return entities.DateRange{}, ErrInvalidQueryParam
+		}
+	}
+
+	if to := e.QueryParam("to"); to != "" {
+		dateRange.To, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			return entities.DateRange{}, ErrInvalidQueryParam
+		}
+	}
+
+	return dateRange, nil
+}
+
+// Считывает список значений параметра, переданных через запятую либо повторением параметра.
+func parseList(e echo.Context, name string) []string {
+	values := make([]string, 0)
+
+	for _, param := range e.QueryParams()[name] {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, strings.ToUpper(value))
+			}
+		}
+	}
+
+	return values
+}
+
+// Считывает параметры выборки заказов пользователя userID.
+func parseOrderFilter(e echo.Context, userID int64) (entities.OrderFilter, error) {
+	page, err := parsePage(e)
+	if err != nil {
+		return entities.OrderFilter{}, err
+	}
+
+	uploaded, err := parseDateRange(e)
+	if err != nil {
+		return entities.OrderFilter{}, err
+	}
+
+	return entities.OrderFilter{
+		UserID:   userID,
+		Statuses: parseList(e, "status"),
+		Uploaded: uploaded,
+		Page:     page,
+	}, nil
+}
+
+// Считывает параметры выборки операций со счётом пользователя userID.
+func parseStatementFilter(e echo.Context, userID int64) (entities.StatementFilter, error) {
+	page, err := parsePage(e)
+	if err != nil {
+		return entities.StatementFilter{}, err
+	}
+
+	processed, err := parseDateRange(e)
+	if err != nil {
+		return entities.StatementFilter{}, err
+	}
+
+	return entities.StatementFilter{
+		UserID:    userID,
+		Processed: processed,
+		Page:      page,
+	}, nil
+}
+
+// Считывает идентификатор пользователя из параметра пути id.
+func parseUserID(e echo.Context) (int64, error) {
+	userID, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil || userID <= 0 {
+		return 0, ErrInvalidPathParam
+	}
+
+	return userID, nil
+}
+
+// Считывает идентификатор подписки на уведомления из параметра пути id.
+func parseWebhookID(e echo.Context) (int64, error) {
+	webhookID, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil || webhookID <= 0 {
+		return 0, ErrInvalidPathParam
+	}
+
+	return webhookID, nil
+}
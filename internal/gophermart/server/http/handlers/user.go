@@ -3,31 +3,42 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/logging"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
 	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
-	"github.com/KryukovO/gophermart/internal/utils"
 	"github.com/labstack/echo/v4"
 
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	accessTokenCookie  = middleware.AccessTokenCookie
+	refreshTokenCookie = "refresh_token"
+	bearerScheme       = "Bearer"
+)
+
 type UserController struct {
-	user          usecases.User
-	secret        []byte
-	tokenLifetime time.Duration
-	logger        *log.Logger
+	user   usecases.User
+	token  usecases.Token
+	secret []byte
+	mw     *middleware.Manager
+	logger *log.Logger
 }
 
 func NewUserController(
-	user usecases.User,
-	secret []byte, tokenLifetime time.Duration,
+	user usecases.User, token usecases.Token,
+	secret []byte, mwManager *middleware.Manager,
 	logger *log.Logger,
 ) (*UserController, error) {
-	if user == nil {
+	if user == nil || token == nil {
 		return nil, ErrUseCaseIsNil
 	}
 
@@ -37,10 +48,11 @@ func NewUserController(
 	}
 
 	return &UserController{
-		user:          user,
-		secret:        secret,
-		tokenLifetime: tokenLifetime,
-		logger:        controllerLogger,
+		user:   user,
+		token:  token,
+		secret: secret,
+		mw:     mwManager,
+		logger: controllerLogger,
 	}, nil
 }
 
@@ -51,134 +63,406 @@ func (c *UserController) MapHandlers(group *echo.Group) error {
 
 	group.Add(http.MethodPost, "/user/register", c.registerHandler)
 	group.Add(http.MethodPost, "/user/login", c.loginHandler)
+	group.Add(http.MethodPost, "/user/refresh", c.refreshHandler)
+	group.Add(http.MethodPost, "/user/logout", c.mw.AuthenticationMiddleware(c.logoutHandler))
+	group.Add(http.MethodPost, "/user/password", c.mw.AuthenticationMiddleware(c.changePasswordHandler))
+	group.Add(http.MethodPost, "/user/password/reset", c.passwordResetRequestHandler)
+	group.Add(http.MethodPost, "/user/password/reset/confirm", c.passwordResetHandler)
 
 	return nil
 }
 
 // @Summary       User registration
 // @Description   User registration by login and password.
+// @Description   Issued tokens are returned in the response body, the Authorization header and cookies.
 // @Tags          Gophermart HTTP API
 // @Accept        json
+// @Produce       json
 // @Param         user   body       entities.User   true   "User login and password."
-// @Success       200
-// @Failure       400    {object}   echo.HTTPError
-// @Failure       409    {object}   echo.HTTPError
-// @Failure       500    {object}   echo.HTTPError
+// @Success       200    {object}   entities.Tokens
+// @Header        200    {string}   Authorization   "Bearer access token"
+// @Failure       400    {object}   Problem
+// @Failure       409    {object}   Problem
+// @Failure       500    {object}   Problem
 // @Router        /api/user/register [post]
 func (c *UserController) registerHandler(e echo.Context) error {
-	uuid := e.Get("uuid")
-	if uuid == nil {
-		uuid = ""
-	}
+	logger := logging.FromContext(e.Request().Context(), c.logger)
 
 	body, err := io.ReadAll(e.Request().Body)
 	if err != nil {
-		c.logger.Errorf("[%s] Something went wrong: %s", uuid, err)
-
-		return e.NoContent(http.StatusInternalServerError)
+		return writeError(e, logger, err)
 	}
 
 	var user entities.User
 
 	err = json.Unmarshal(body, &user)
 	if err != nil {
-		return e.NoContent(http.StatusBadRequest)
+		return writeError(e, logger, fmt.Errorf("%w: %s", ErrInvalidRequestBody, err))
 	}
 
 	if user.Login == "" || user.Password == "" {
-		return e.NoContent(http.StatusBadRequest)
+		return writeError(e, logger, fmt.Errorf("%w: login and password are required", ErrInvalidRequestBody))
 	}
 
-	c.logger.Debugf("[%s] Request body: %+v", uuid, user)
+	logger.Debugf("Request body: %+v", user)
 
-	err = c.user.Register(e.Request().Context(), &user, c.secret)
+	err = c.user.Register(e.Request().Context(), &user)
 	if err != nil {
-		if errors.Is(err, entities.ErrUserAlreadyExists) {
-			return e.NoContent(http.StatusConflict)
-		}
-
-		c.logger.Errorf("[%s] Something went wrong: %s", uuid, err)
-
-		return e.NoContent(http.StatusInternalServerError)
+		return writeError(e, logger, err)
 	}
 
-	tokenString, err := utils.BuildJSWTString(c.secret, c.tokenLifetime, user.ID)
+	tokens, err := c.token.Issue(e.Request().Context(), user.ID, user.Role)
 	if err != nil {
-		c.logger.Errorf("[%s] Something went wrong: %s", uuid, err)
-
-		return e.NoContent(http.StatusInternalServerError)
+		return writeError(e, logger, err)
 	}
 
-	e.SetCookie(&http.Cookie{
-		Name:     "token",
-		Value:    tokenString,
-		HttpOnly: true,
-		SameSite: http.SameSiteDefaultMode,
-	})
-
-	return e.NoContent(http.StatusOK)
+	return writeTokens(e, tokens)
 }
 
 // @Summary       User authorization
 // @Description   User authorization by login and password.
+// @Description   After repeated failed attempts for the same login or from the same address
+// @Description   the login is temporarily locked.
+// @Description   Issued tokens are returned in the response body, the Authorization header and cookies.
 // @Tags          Gophermart HTTP API
 // @Accept        json
+// @Produce       json
 // @Param         user   body       entities.User   true   "User login and password."
-// @Success       200
-// @Failure       400    {object}   echo.HTTPError
-// @Failure       401    {object}   echo.HTTPError
-// @Failure       500    {object}   echo.HTTPError
+// @Success       200    {object}   entities.Tokens
+// @Header        200    {string}   Authorization   "Bearer access token"
+// @Failure       400    {object}   Problem
+// @Failure       401    {object}   Problem
+// @Failure       429    {object}   Problem
+// @Header        429    {integer}  Retry-After     "Seconds until the login is unlocked"
+// @Failure       500    {object}   Problem
 // @Router        /api/user/login [post]
 func (c *UserController) loginHandler(e echo.Context) error {
-	uuid := e.Get("uuid")
-	if uuid == nil {
-		uuid = ""
-	}
+	logger := logging.FromContext(e.Request().Context(), c.logger)
 
 	body, err := io.ReadAll(e.Request().Body)
 	if err != nil {
-		c.logger.Errorf("[%s] Something went wrong: %s", uuid, err)
-
-		return e.NoContent(http.StatusInternalServerError)
+		return writeError(e, logger, err)
 	}
 
 	var user entities.User
 
 	err = json.Unmarshal(body, &user)
 	if err != nil {
-		return e.NoContent(http.StatusBadRequest)
+		return writeError(e, logger, fmt.Errorf("%w: %s", ErrInvalidRequestBody, err))
 	}
 
 	if user.Login == "" || user.Password == "" {
-		return e.NoContent(http.StatusBadRequest)
+		return writeError(e, logger, fmt.Errorf("%w: login and password are required", ErrInvalidRequestBody))
+	}
+
+	logger.Debugf("Request body: %+v", user)
+
+	err = c.user.Login(e.Request().Context(), &user, c.secret, e.RealIP())
+	if err != nil {
+		var lockedErr *entities.LoginLockedError
+		if errors.As(err, &lockedErr) {
+			logger.Warnf("Login of %q is locked: %s", user.Login, lockedErr)
+
+			e.Response().Header().Set(echo.HeaderRetryAfter, retryAfterSeconds(lockedErr.RetryAfter))
+		}
+
+		return writeError(e, logger, err)
+	}
+
+	tokens, err := c.token.Issue(e.Request().Context(), user.ID, user.Role)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	return writeTokens(e, tokens)
+}
+
+// @Summary       Token refresh
+// @Description   Exchange the refresh token from the refresh_token cookie or the request body
+// @Description   for a new pair of tokens.
+// @Description   Each refresh token can be used only once. Reusing a refresh token revokes
+// @Description   all tokens issued since the user logged in.
+// @Tags          Gophermart HTTP API
+// @Accept        json
+// @Produce       json
+// @Param         token  body       entities.RefreshRequest   false   "Refresh token, if not passed in the cookie."
+// @Success       200    {object}   entities.Tokens
+// @Header        200    {string}   Authorization   "Bearer access token"
+// @Failure       400    {object}   Problem
+// @Failure       401    {object}   Problem
+// @Failure       500    {object}   Problem
+// @Router        /api/user/refresh [post]
+func (c *UserController) refreshHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	var refreshToken string
+
+	if refreshCookie, err := e.Cookie(refreshTokenCookie); err == nil {
+		refreshToken = refreshCookie.Value
+	}
+
+	if refreshToken == "" {
+		body, err := io.ReadAll(e.Request().Body)
+		if err != nil {
+			return writeError(e, logger, err)
+		}
+
+		if len(body) > 0 {
+			var req entities.RefreshRequest
+
+			err = json.Unmarshal(body, &req)
+			if err != nil {
+				return writeError(e, logger, fmt.Errorf("%w: %s", ErrInvalidRequestBody, err))
+			}
+
+			refreshToken = req.RefreshToken
+		}
+	}
+
+	if refreshToken == "" {
+		return writeError(e, logger, ErrUnauthorized)
+	}
+
+	tokens, err := c.token.Refresh(e.Request().Context(), refreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, entities.ErrRefreshTokenReused):
+			logger.Warn("Refresh token reuse detected, token family revoked")
+			clearTokenCookies(e)
+		case errors.Is(err, entities.ErrInvalidToken):
+			clearTokenCookies(e)
+		}
+
+		return writeError(e, logger, err)
 	}
 
-	c.logger.Debugf("[%s] Request body: %+v", uuid, user)
+	return writeTokens(e, tokens)
+}
+
+// @Summary       User logout
+// @Description   Revoke the current access token and all refresh tokens issued with it.
+// @Tags          Gophermart HTTP API
+// @Success       200
+// @Failure       401    {object}   Problem
+// @Failure       500    {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/user/logout [post]
+func (c *UserController) logoutHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	token, ok := e.Get("token").(entities.AccessToken)
+	if !ok {
+		return writeError(e, logger, ErrUnauthorized)
+	}
 
-	err = c.user.Login(e.Request().Context(), &user, c.secret)
+	err := c.token.Revoke(e.Request().Context(), &token)
 	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	clearTokenCookies(e)
+
+	return e.NoContent(http.StatusOK)
+}
+
+// @Summary       Password change
+// @Description   Change the password of the authorized user after checking the current password.
+// @Description   All user sessions are terminated, and a new pair of tokens is issued for the current one.
+// @Tags          Gophermart HTTP API
+// @Accept        json
+// @Produce       json
+// @Param         password   body       entities.PasswordChange   true   "Current and new passwords."
+// @Success       200        {object}   entities.Tokens
+// @Header        200        {string}   Authorization   "Bearer access token"
+// @Failure       400        {object}   Problem
+// @Failure       401        {object}   Problem
+// @Failure       403        {object}   Problem
+// @Failure       500        {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/user/password [post]
+func (c *UserController) changePasswordHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	userID, ok := e.Get("userID").(int64)
+	if !ok {
+		return writeError(e, logger, ErrUnauthorized)
+	}
+
+	body, err := io.ReadAll(e.Request().Body)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	var change entities.PasswordChange
+
+	err = json.Unmarshal(body, &change)
+	if err != nil {
+		return writeError(e, logger, fmt.Errorf("%w: %s", ErrInvalidRequestBody, err))
+	}
+
+	if change.OldPassword == "" || change.NewPassword == "" {
+		return writeError(e, logger, fmt.Errorf("%w: old and new passwords are required", ErrInvalidRequestBody))
+	}
+
+	err = c.user.ChangePassword(e.Request().Context(), userID, &change, c.secret)
+	if err != nil {
+		// Неверный текущий пароль не означает, что пользователь не аутентифицирован
 		if errors.Is(err, entities.ErrInvalidLoginPassword) {
-			return e.NoContent(http.StatusUnauthorized)
+			return writeErrorStatus(e, logger, http.StatusForbidden, err)
 		}
 
-		c.logger.Errorf("[%s] Something went wrong: %s", uuid, err)
+		// Пользователь удалён после выдачи токена
+		if errors.Is(err, entities.ErrUserNotFound) {
+			return writeErrorStatus(e, logger, http.StatusUnauthorized, err)
+		}
 
-		return e.NoContent(http.StatusInternalServerError)
+		return writeError(e, logger, err)
+	}
+
+	// Роль пользователя не меняется при смене пароля, поэтому берётся из токена доступа
+	token, _ := e.Get("token").(entities.AccessToken)
+
+	tokens, err := c.token.Issue(e.Request().Context(), userID, token.Role)
+	if err != nil {
+		return writeError(e, logger, err)
 	}
 
-	tokenString, err := utils.BuildJSWTString(c.secret, c.tokenLifetime, user.ID)
+	return writeTokens(e, tokens)
+}
+
+// @Summary       Password reset request
+// @Description   Send a single-use password reset token to the user.
+// @Description   The response does not depend on whether the user exists.
+// @Tags          Gophermart HTTP API
+// @Accept        json
+// @Param         login   body   entities.PasswordResetRequest   true   "User login."
+// @Success       202
+// @Failure       400     {object}   Problem
+// @Failure       500     {object}   Problem
+// @Router        /api/user/password/reset [post]
+func (c *UserController) passwordResetRequestHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	body, err := io.ReadAll(e.Request().Body)
 	if err != nil {
-		c.logger.Errorf("[%s] Something went wrong: %s", uuid, err)
+		return writeError(e, logger, err)
+	}
 
-		return e.NoContent(http.StatusInternalServerError)
+	var req entities.PasswordResetRequest
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		return writeError(e, logger, fmt.Errorf("%w: %s", ErrInvalidRequestBody, err))
 	}
 
+	if req.Login == "" {
+		return writeError(e, logger, fmt.Errorf("%w: login is required", ErrInvalidRequestBody))
+	}
+
+	err = c.user.RequestPasswordReset(e.Request().Context(), req.Login)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	return e.NoContent(http.StatusAccepted)
+}
+
+// @Summary       Password reset
+// @Description   Set a new password by the password reset token.
+// @Description   All user sessions are terminated.
+// @Tags          Gophermart HTTP API
+// @Accept        json
+// @Param         reset   body   entities.PasswordReset   true   "Password reset token and new password."
+// @Success       200
+// @Failure       400     {object}   Problem
+// @Failure       500     {object}   Problem
+// @Router        /api/user/password/reset/confirm [post]
+func (c *UserController) passwordResetHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	body, err := io.ReadAll(e.Request().Body)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	var reset entities.PasswordReset
+
+	err = json.Unmarshal(body, &reset)
+	if err != nil {
+		return writeError(e, logger, fmt.Errorf("%w: %s", ErrInvalidRequestBody, err))
+	}
+
+	if reset.Token == "" || reset.NewPassword == "" {
+		return writeError(e, logger, fmt.Errorf("%w: token and new password are required", ErrInvalidRequestBody))
+	}
+
+	err = c.user.ResetPassword(e.Request().Context(), &reset)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	clearTokenCookies(e)
+
+	return e.NoContent(http.StatusOK)
+}
+
+// Возвращает выданные токены в теле ответа, заголовке Authorization и cookie,
+// чтобы ими могли воспользоваться как браузерные, так и остальные клиенты.
+func writeTokens(e echo.Context, tokens entities.TokenPair) error {
+	setTokenCookies(e, tokens)
+
+	e.Response().Header().Set(echo.HeaderAuthorization, bearerScheme+" "+tokens.AccessToken)
+
+	return e.JSON(http.StatusOK, entities.Tokens{
+		AccessToken:      tokens.AccessToken,
+		TokenType:        bearerScheme,
+		ExpiresAt:        tokens.AccessExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+	})
+}
+
+func setTokenCookies(e echo.Context, tokens entities.TokenPair) {
 	e.SetCookie(&http.Cookie{
-		Name:     "token",
-		Value:    tokenString,
+		Name:     accessTokenCookie,
+		Value:    tokens.AccessToken,
 		HttpOnly: true,
 		SameSite: http.SameSiteDefaultMode,
 	})
 
-	return e.NoContent(http.StatusOK)
+	e.SetCookie(&http.Cookie{
+		Name:     refreshTokenCookie,
+		Value:    tokens.RefreshToken,
+		Path:     "/api/user",
+		Expires:  tokens.RefreshExpiresAt,
+		HttpOnly: true,
+		SameSite: http.SameSiteDefaultMode,
+	})
+}
+
+func clearTokenCookies(e echo.Context) {
+	e.SetCookie(&http.Cookie{
+		Name:     accessTokenCookie,
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	e.SetCookie(&http.Cookie{
+		Name:     refreshTokenCookie,
+		Path:     "/api/user",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+// Возвращает значение заголовка Retry-After в секундах, округлённое вверх.
+func retryAfterSeconds(retryAfter time.Duration) string {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return strconv.FormatInt(seconds, 10)
 }
@@ -2,14 +2,21 @@ package handlers
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	notifiermocks "github.com/KryukovO/gophermart/internal/gophermart/notifier/mocks"
 	"github.com/KryukovO/gophermart/internal/gophermart/repository/mocks"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/problem"
 	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/KryukovO/gophermart/internal/jwtkeys"
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
 
@@ -20,10 +27,11 @@ import (
 
 func TestNewUserController(t *testing.T) {
 	type args struct {
-		user          usecases.User
-		secret        []byte
-		tokenLifetime time.Duration
-		logger        *log.Logger
+		user      usecases.User
+		token     usecases.Token
+		secret    []byte
+		mwManager *middleware.Manager
+		logger    *log.Logger
 	}
 
 	type wants struct {
@@ -38,10 +46,11 @@ func TestNewUserController(t *testing.T) {
 		{
 			name: "Correct creation",
 			args: args{
-				user:          usecases.NewUserUseCase(mocks.NewMockUserRepo(gomock.NewController(t)), time.Second),
-				secret:        []byte{},
-				tokenLifetime: time.Second,
-				logger:        log.New(),
+				user:      newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+				token:     newTestTokenUseCase(t),
+				secret:    []byte{},
+				mwManager: newTestManager(t),
+				logger:    log.New(),
 			},
 			wants: wants{
 				wantErr: false,
@@ -50,10 +59,11 @@ func TestNewUserController(t *testing.T) {
 		{
 			name: "Nil logger",
 			args: args{
-				user:          usecases.NewUserUseCase(mocks.NewMockUserRepo(gomock.NewController(t)), time.Second),
-				secret:        []byte{},
-				tokenLifetime: time.Second,
-				logger:        nil,
+				user:      newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+				token:     newTestTokenUseCase(t),
+				secret:    []byte{},
+				mwManager: newTestManager(t),
+				logger:    nil,
 			},
 			wants: wants{
 				wantErr: false,
@@ -62,10 +72,23 @@ func TestNewUserController(t *testing.T) {
 		{
 			name: "Nil user",
 			args: args{
-				user:          nil,
-				secret:        []byte{},
-				tokenLifetime: time.Second,
-				logger:        log.New(),
+				user:      nil,
+				token:     newTestTokenUseCase(t),
+				secret:    []byte{},
+				mwManager: newTestManager(t),
+				logger:    log.New(),
+			},
+			wants: wants{
+				wantErr: true,
+			},
+		},
+		{
+			name: "Nil token",
+			args: args{
+				user:      newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+				secret:    []byte{},
+				mwManager: newTestManager(t),
+				logger:    log.New(),
 			},
 			wants: wants{
 				wantErr: true,
@@ -75,7 +98,7 @@ func TestNewUserController(t *testing.T) {
 
 	for _, test := range tests {
 		ctrl, err := NewUserController(
-			test.args.user, test.args.secret, test.args.tokenLifetime, test.args.logger,
+			test.args.user, test.args.token, test.args.secret, test.args.mwManager, test.args.logger,
 		)
 
 		if test.wants.wantErr {
@@ -85,8 +108,9 @@ func TestNewUserController(t *testing.T) {
 			require.NotNil(t, ctrl)
 
 			assert.Equal(t, test.args.user, ctrl.user)
+			assert.Equal(t, test.args.token, ctrl.token)
 			assert.Equal(t, test.args.secret, ctrl.secret)
-			assert.Equal(t, test.args.tokenLifetime, ctrl.tokenLifetime)
+			assert.Equal(t, test.args.mwManager, ctrl.mw)
 
 			if test.args.logger != nil {
 				assert.Equal(t, test.args.logger, ctrl.logger)
@@ -133,9 +157,10 @@ func TestUserMapHandlers(t *testing.T) {
 
 	for _, test := range tests {
 		ctrl, err := NewUserController(
-			usecases.NewUserUseCase(mocks.NewMockUserRepo(gomock.NewController(t)), time.Second),
+			newTestUserUseCase(t, mocks.NewMockUserRepo(gomock.NewController(t))),
+			newTestTokenUseCase(t),
 			[]byte{},
-			time.Second,
+			newTestManager(t),
 			log.New(),
 		)
 
@@ -165,6 +190,7 @@ func TestRegisterHandler(t *testing.T) {
 	type wants struct {
 		status    int
 		setCookie bool
+		code      string
 	}
 
 	tests := []struct {
@@ -196,6 +222,7 @@ func TestRegisterHandler(t *testing.T) {
 			},
 			wants: wants{
 				status:    http.StatusConflict,
+				code:      problem.CodeUserAlreadyExists,
 				setCookie: false,
 			},
 		},
@@ -207,6 +234,7 @@ func TestRegisterHandler(t *testing.T) {
 			},
 			wants: wants{
 				status:    http.StatusBadRequest,
+				code:      problem.CodeInvalidRequest,
 				setCookie: false,
 			},
 		},
@@ -218,6 +246,7 @@ func TestRegisterHandler(t *testing.T) {
 			},
 			wants: wants{
 				status:    http.StatusBadRequest,
+				code:      problem.CodeInvalidRequest,
 				setCookie: false,
 			},
 		},
@@ -229,18 +258,25 @@ func TestRegisterHandler(t *testing.T) {
 			},
 			wants: wants{
 				status:    http.StatusBadRequest,
+				code:      problem.CodeInvalidRequest,
 				setCookie: false,
 			},
 		},
 	}
 
 	for _, test := range tests {
-		repo := mocks.NewMockUserRepo(gomock.NewController(t))
+		ctr := gomock.NewController(t)
+		repo := mocks.NewMockUserRepo(ctr)
+		tokenRepo := mocks.NewMockTokenRepo(ctr)
 
 		if test.prepare != nil {
 			test.prepare(repo)
 		}
 
+		if test.wants.setCookie {
+			tokenRepo.EXPECT().AddRefreshToken(gomock.Any(), gomock.Any()).Return(nil)
+		}
+
 		rec := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(test.args.body))
 		server := echo.New()
@@ -249,10 +285,10 @@ func TestRegisterHandler(t *testing.T) {
 		echoCtx.SetPath(path)
 
 		uc := UserController{
-			user:          usecases.NewUserUseCase(repo, time.Minute),
-			secret:        secret,
-			tokenLifetime: time.Minute,
-			logger:        log.StandardLogger(),
+			user:   newTestUserUseCase(t, repo),
+			token:  usecases.NewTokenUseCase(tokenRepo, jwtkeys.NewHMACKeySet(secret), time.Minute, time.Hour, time.Minute),
+			secret: secret,
+			logger: log.StandardLogger(),
 		}
 		err := uc.registerHandler(echoCtx)
 		require.NoError(t, err)
@@ -262,8 +298,13 @@ func TestRegisterHandler(t *testing.T) {
 
 		assert.Equal(t, test.wants.status, res.StatusCode)
 
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code)
+		}
+
 		if test.wants.setCookie {
-			assert.NotEmpty(t, res.Cookies())
+			assert.Len(t, res.Cookies(), 2)
+			assertTokens(t, res)
 		}
 	}
 }
@@ -279,20 +320,29 @@ func TestLoginHandler(t *testing.T) {
 	}
 
 	type wants struct {
-		status    int
-		setCookie bool
+		status     int
+		setCookie  bool
+		retryAfter string
+		code       string
 	}
 
 	tests := []struct {
 		name    string
-		prepare func(mock *mocks.MockUserRepo)
+		prepare func(mock *mocks.MockUserRepo, attempts *mocks.MockLoginAttemptRepo)
 		args    args
 		wants   wants
 	}{
 		{
 			name: "Correct login",
-			prepare: func(mock *mocks.MockUserRepo) {
-				mock.EXPECT().User(gomock.Any(), gomock.Any()).Return(nil)
+			prepare: func(mock *mocks.MockUserRepo, attempts *mocks.MockLoginAttemptRepo) {
+				attempts.EXPECT().ReserveLoginAttempt(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+				mock.EXPECT().User(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, user *entities.User) error {
+						return user.Encrypt(testHasher)
+					},
+				)
+				attempts.EXPECT().ResetLoginFailures(gomock.Any(), "login:user1").Return(nil)
+				attempts.EXPECT().ReleaseLoginAttempt(gomock.Any(), mocks.LoginAttemptMatcher("ip:192.0.2.1")).Return(nil)
 			},
 			args: args{
 				body: []byte(`{"login":"user1","password":"1234"}`),
@@ -304,7 +354,8 @@ func TestLoginHandler(t *testing.T) {
 		},
 		{
 			name: "Invalid login/password",
-			prepare: func(mock *mocks.MockUserRepo) {
+			prepare: func(mock *mocks.MockUserRepo, attempts *mocks.MockLoginAttemptRepo) {
+				attempts.EXPECT().ReserveLoginAttempt(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
 				mock.EXPECT().User(gomock.Any(), gomock.Any()).Return(entities.ErrInvalidLoginPassword)
 			},
 			args: args{
@@ -312,9 +363,26 @@ func TestLoginHandler(t *testing.T) {
 			},
 			wants: wants{
 				status:    http.StatusUnauthorized,
+				code:      problem.CodeInvalidCredentials,
 				setCookie: false,
 			},
 		},
+		{
+			name: "Login locked",
+			prepare: func(mock *mocks.MockUserRepo, attempts *mocks.MockLoginAttemptRepo) {
+				attempts.EXPECT().ReserveLoginAttempt(gomock.Any(), mocks.LoginAttemptMatcher("login:user1"), gomock.Any()).
+					Return(&entities.LoginLockedError{RetryAfter: 90 * time.Second})
+			},
+			args: args{
+				body: []byte(`{"login":"user1","password":"1234"}`),
+			},
+			wants: wants{
+				status:     http.StatusTooManyRequests,
+				code:       problem.CodeLoginLocked,
+				setCookie:  false,
+				retryAfter: "90",
+			},
+		},
 		{
 			name:    "Incorrect request body #1",
 			prepare: nil,
@@ -323,6 +391,7 @@ func TestLoginHandler(t *testing.T) {
 			},
 			wants: wants{
 				status:    http.StatusBadRequest,
+				code:      problem.CodeInvalidRequest,
 				setCookie: false,
 			},
 		},
@@ -334,6 +403,7 @@ func TestLoginHandler(t *testing.T) {
 			},
 			wants: wants{
 				status:    http.StatusBadRequest,
+				code:      problem.CodeInvalidRequest,
 				setCookie: false,
 			},
 		},
@@ -345,16 +415,24 @@ func TestLoginHandler(t *testing.T) {
 			},
 			wants: wants{
 				status:    http.StatusBadRequest,
+				code:      problem.CodeInvalidRequest,
 				setCookie: false,
 			},
 		},
 	}
 
 	for _, test := range tests {
-		repo := mocks.NewMockUserRepo(gomock.NewController(t))
+		ctr := gomock.NewController(t)
+		repo := mocks.NewMockUserRepo(ctr)
+		attempts := mocks.NewMockLoginAttemptRepo(ctr)
+		tokenRepo := mocks.NewMockTokenRepo(ctr)
 
 		if test.prepare != nil {
-			test.prepare(repo)
+			test.prepare(repo, attempts)
+		}
+
+		if test.wants.setCookie {
+			tokenRepo.EXPECT().AddRefreshToken(gomock.Any(), gomock.Any()).Return(nil)
 		}
 
 		rec := httptest.NewRecorder()
@@ -365,10 +443,10 @@ func TestLoginHandler(t *testing.T) {
 		echoCtx.SetPath(path)
 
 		uc := UserController{
-			user:          usecases.NewUserUseCase(repo, time.Minute),
-			secret:        secret,
-			tokenLifetime: time.Minute,
-			logger:        log.StandardLogger(),
+			user:   usecases.NewUserUseCase(repo, attempts, testHasher, testLoginPolicy, nil, time.Hour, time.Minute),
+			token:  usecases.NewTokenUseCase(tokenRepo, jwtkeys.NewHMACKeySet(secret), time.Minute, time.Hour, time.Minute),
+			secret: secret,
+			logger: log.StandardLogger(),
 		}
 		err := uc.loginHandler(echoCtx)
 		require.NoError(t, err)
@@ -376,10 +454,636 @@ func TestLoginHandler(t *testing.T) {
 		res := rec.Result()
 		defer res.Body.Close()
 
-		assert.Equal(t, test.wants.status, res.StatusCode)
+		assert.Equal(t, test.wants.status, res.StatusCode, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code, test.name)
+		}
+		assert.Equal(t, test.wants.retryAfter, res.Header.Get(echo.HeaderRetryAfter), test.name)
 
 		if test.wants.setCookie {
-			assert.NotEmpty(t, res.Cookies())
+			assert.Len(t, res.Cookies(), 2)
+			assertTokens(t, res)
+		}
+	}
+}
+
+func TestRefreshHandler(t *testing.T) {
+	path := "/api/user/refresh"
+
+	type args struct {
+		cookie string
+		body   string
+	}
+
+	type wants struct {
+		status  int
+		cookies int
+		code    string
+	}
+
+	correctRefresh := func(mock *mocks.MockTokenRepo) {
+		mock.EXPECT().UseRefreshToken(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, token *entities.RefreshToken) error {
+				token.UserID = 1
+				token.Family = "family"
+
+				return nil
+			},
+		)
+		mock.EXPECT().AddRefreshToken(gomock.Any(), gomock.Any()).Return(nil)
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockTokenRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name:    "Correct refresh",
+			prepare: correctRefresh,
+			args: args{
+				cookie: "refresh",
+			},
+			wants: wants{
+				status:  http.StatusOK,
+				cookies: 2,
+			},
+		},
+		{
+			name:    "Refresh token in body",
+			prepare: correctRefresh,
+			args: args{
+				body: `{"refresh_token":"refresh"}`,
+			},
+			wants: wants{
+				status:  http.StatusOK,
+				cookies: 2,
+			},
+		},
+		{
+			name: "Incorrect request body",
+			args: args{
+				body: `{"refresh_token":`,
+			},
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidRequest,
+			},
+		},
+		{
+			name: "Refresh token reused",
+			prepare: func(mock *mocks.MockTokenRepo) {
+				mock.EXPECT().UseRefreshToken(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, token *entities.RefreshToken) error {
+						token.Family = "family"
+
+						return entities.ErrRefreshTokenReused
+					},
+				)
+				mock.EXPECT().RevokeFamily(gomock.Any(), "family").Return(nil)
+			},
+			args: args{
+				cookie: "refresh",
+			},
+			wants: wants{
+				status:  http.StatusUnauthorized,
+				code:    problem.CodeRefreshTokenReused,
+				cookies: 2,
+			},
+		},
+		{
+			name: "Invalid refresh token",
+			prepare: func(mock *mocks.MockTokenRepo) {
+				mock.EXPECT().UseRefreshToken(gomock.Any(), gomock.Any()).Return(entities.ErrInvalidToken)
+			},
+			args: args{
+				cookie: "refresh",
+			},
+			wants: wants{
+				status:  http.StatusUnauthorized,
+				code:    problem.CodeInvalidToken,
+				cookies: 2,
+			},
+		},
+		{
+			name: "No refresh token",
+			args: args{},
+			wants: wants{
+				status: http.StatusUnauthorized,
+				code:   problem.CodeUnauthorized,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockTokenRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(test.args.body))
+
+		if test.args.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: refreshTokenCookie, Value: test.args.cookie})
+		}
+
+		server := echo.New()
+		echoCtx := server.NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+
+		uc := UserController{
+			token:  usecases.NewTokenUseCase(repo, testKeys, time.Minute, time.Hour, time.Minute),
+			logger: log.StandardLogger(),
+		}
+		err := uc.refreshHandler(echoCtx)
+		require.NoError(t, err, test.name)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, test.wants.status, res.StatusCode, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code, test.name)
+		}
+		assert.Len(t, res.Cookies(), test.wants.cookies, test.name)
+
+		if test.wants.status == http.StatusOK {
+			assertTokens(t, res)
 		}
 	}
 }
+
+func TestLogoutHandler(t *testing.T) {
+	path := "/api/user/logout"
+	token := entities.AccessToken{
+		ID:        "4f3c5a76-0d5a-4c1e-9a6c-2b1d0c7b8e9f",
+		UserID:    1,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	type args struct {
+		token interface{}
+	}
+
+	type wants struct {
+		status int
+		code   string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockTokenRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "Correct logout",
+			prepare: func(mock *mocks.MockTokenRepo) {
+				mock.EXPECT().RevokeSession(gomock.Any(), &token).Return(nil)
+			},
+			args: args{
+				token: token,
+			},
+			wants: wants{
+				status: http.StatusOK,
+			},
+		},
+		{
+			name: "User unauthorized",
+			args: args{},
+			wants: wants{
+				status: http.StatusUnauthorized,
+				code:   problem.CodeUnauthorized,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockTokenRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		server := echo.New()
+		echoCtx := server.NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+		echoCtx.Set("token", test.args.token)
+
+		uc := UserController{
+			token:  usecases.NewTokenUseCase(repo, testKeys, time.Minute, time.Hour, time.Minute),
+			logger: log.StandardLogger(),
+		}
+		err := uc.logoutHandler(echoCtx)
+		require.NoError(t, err, test.name)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, test.wants.status, res.StatusCode, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code, test.name)
+		}
+	}
+}
+
+func TestChangePasswordHandler(t *testing.T) {
+	path := "/api/user/password"
+
+	hash, err := testHasher.Hash("1234")
+	require.NoError(t, err)
+
+	storedUser := func(_ context.Context, user *entities.User) error {
+		user.Login = "user1"
+		user.EncryptedPassword = hash
+
+		return nil
+	}
+
+	type args struct {
+		userID interface{}
+		body   string
+	}
+
+	type wants struct {
+		status int
+		code   string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(repo *mocks.MockUserRepo, tokenRepo *mocks.MockTokenRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "Correct password change",
+			prepare: func(repo *mocks.MockUserRepo, tokenRepo *mocks.MockTokenRepo) {
+				repo.EXPECT().UserByID(gomock.Any(), gomock.Any()).DoAndReturn(storedUser)
+				repo.EXPECT().ChangePassword(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, user *entities.User) error {
+						assert.NoError(t, testHasher.Verify("4321", user.EncryptedPassword))
+
+						return nil
+					},
+				)
+				tokenRepo.EXPECT().AddRefreshToken(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			args: args{
+				userID: int64(1),
+				body:   `{"old_password":"1234","new_password":"4321"}`,
+			},
+			wants: wants{
+				status: http.StatusOK,
+			},
+		},
+		{
+			name: "Wrong old password",
+			prepare: func(repo *mocks.MockUserRepo, _ *mocks.MockTokenRepo) {
+				repo.EXPECT().UserByID(gomock.Any(), gomock.Any()).DoAndReturn(storedUser)
+			},
+			args: args{
+				userID: int64(1),
+				body:   `{"old_password":"0000","new_password":"4321"}`,
+			},
+			wants: wants{
+				status: http.StatusForbidden,
+				code:   problem.CodeInvalidCredentials,
+			},
+		},
+		{
+			name: "User not found",
+			prepare: func(repo *mocks.MockUserRepo, _ *mocks.MockTokenRepo) {
+				repo.EXPECT().UserByID(gomock.Any(), gomock.Any()).Return(entities.ErrUserNotFound)
+			},
+			args: args{
+				userID: int64(1),
+				body:   `{"old_password":"1234","new_password":"4321"}`,
+			},
+			wants: wants{
+				status: http.StatusUnauthorized,
+				code:   problem.CodeUserNotFound,
+			},
+		},
+		{
+			name: "Empty new password",
+			args: args{
+				userID: int64(1),
+				body:   `{"old_password":"1234","new_password":""}`,
+			},
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidRequest,
+			},
+		},
+		{
+			name: "Incorrect request body",
+			args: args{
+				userID: int64(1),
+				body:   `{"old_password":`,
+			},
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidRequest,
+			},
+		},
+		{
+			name: "User unauthorized",
+			args: args{
+				body: `{"old_password":"1234","new_password":"4321"}`,
+			},
+			wants: wants{
+				status: http.StatusUnauthorized,
+				code:   problem.CodeUnauthorized,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ctr := gomock.NewController(t)
+		repo := mocks.NewMockUserRepo(ctr)
+		tokenRepo := mocks.NewMockTokenRepo(ctr)
+
+		if test.prepare != nil {
+			test.prepare(repo, tokenRepo)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(test.args.body))
+		server := echo.New()
+		echoCtx := server.NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+		echoCtx.Set("userID", test.args.userID)
+
+		uc := UserController{
+			user:   newTestUserUseCase(t, repo),
+			token:  usecases.NewTokenUseCase(tokenRepo, testKeys, time.Minute, time.Hour, time.Minute),
+			logger: log.StandardLogger(),
+		}
+		err := uc.changePasswordHandler(echoCtx)
+		require.NoError(t, err, test.name)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, test.wants.status, res.StatusCode, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code, test.name)
+		}
+
+		if test.wants.status == http.StatusOK {
+			assertTokens(t, res)
+		}
+	}
+}
+
+func TestPasswordResetRequestHandler(t *testing.T) {
+	path := "/api/user/password/reset"
+
+	type args struct {
+		body string
+	}
+
+	type wants struct {
+		status int
+		code   string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(repo *mocks.MockUserRepo, notifier *notifiermocks.MockNotifier)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "Correct request",
+			prepare: func(repo *mocks.MockUserRepo, notifier *notifiermocks.MockNotifier) {
+				repo.EXPECT().User(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, user *entities.User) error {
+						user.ID = 1
+
+						return nil
+					},
+				)
+				repo.EXPECT().AddResetToken(gomock.Any(), gomock.Any()).Return(nil)
+				notifier.EXPECT().PasswordReset(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			},
+			args: args{
+				body: `{"login":"user1"}`,
+			},
+			wants: wants{
+				status: http.StatusAccepted,
+			},
+		},
+		{
+			name: "Unknown user",
+			prepare: func(repo *mocks.MockUserRepo, _ *notifiermocks.MockNotifier) {
+				repo.EXPECT().User(gomock.Any(), gomock.Any()).Return(entities.ErrInvalidLoginPassword)
+			},
+			args: args{
+				body: `{"login":"user2"}`,
+			},
+			wants: wants{
+				status: http.StatusAccepted,
+			},
+		},
+		{
+			name: "Empty login",
+			args: args{
+				body: `{"login":""}`,
+			},
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidRequest,
+			},
+		},
+		{
+			name: "Notification error",
+			prepare: func(repo *mocks.MockUserRepo, notifier *notifiermocks.MockNotifier) {
+				repo.EXPECT().User(gomock.Any(), gomock.Any()).Return(nil)
+				repo.EXPECT().AddResetToken(gomock.Any(), gomock.Any()).Return(nil)
+				notifier.EXPECT().PasswordReset(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(context.DeadlineExceeded)
+			},
+			args: args{
+				body: `{"login":"user1"}`,
+			},
+			wants: wants{
+				status: http.StatusInternalServerError,
+				code:   problem.CodeInternal,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ctr := gomock.NewController(t)
+		repo := mocks.NewMockUserRepo(ctr)
+		notifier := notifiermocks.NewMockNotifier(ctr)
+
+		if test.prepare != nil {
+			test.prepare(repo, notifier)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(test.args.body))
+		server := echo.New()
+		echoCtx := server.NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+
+		uc := UserController{
+			user: usecases.NewUserUseCase(
+				repo, mocks.NewMockLoginAttemptRepo(ctr),
+				testHasher, testLoginPolicy, notifier, time.Hour, time.Minute,
+			),
+			logger: log.StandardLogger(),
+		}
+		err := uc.passwordResetRequestHandler(echoCtx)
+		require.NoError(t, err, test.name)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, test.wants.status, res.StatusCode, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code, test.name)
+		}
+	}
+}
+
+func TestPasswordResetHandler(t *testing.T) {
+	path := "/api/user/password/reset/confirm"
+
+	type args struct {
+		body string
+	}
+
+	type wants struct {
+		status int
+		code   string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(repo *mocks.MockUserRepo, attempts *mocks.MockLoginAttemptRepo, tokenRepo *mocks.MockTokenRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "Correct reset",
+			prepare: func(repo *mocks.MockUserRepo, attempts *mocks.MockLoginAttemptRepo, tokenRepo *mocks.MockTokenRepo) {
+				repo.EXPECT().ResetPassword(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, token *entities.ResetToken, user *entities.User) error {
+						token.UserID = 1
+						user.ID = 1
+						user.Login = "user1"
+
+						return nil
+					},
+				)
+				attempts.EXPECT().ResetLoginFailures(gomock.Any(), "login:user1").Return(nil)
+			},
+			args: args{
+				body: `{"token":"reset","new_password":"4321"}`,
+			},
+			wants: wants{
+				status: http.StatusOK,
+			},
+		},
+		{
+			name: "Invalid reset token",
+			prepare: func(repo *mocks.MockUserRepo, _ *mocks.MockLoginAttemptRepo, _ *mocks.MockTokenRepo) {
+				repo.EXPECT().ResetPassword(gomock.Any(), gomock.Any(), gomock.Any()).Return(entities.ErrInvalidResetToken)
+			},
+			args: args{
+				body: `{"token":"reset","new_password":"4321"}`,
+			},
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidResetToken,
+			},
+		},
+		{
+			name: "Empty token",
+			args: args{
+				body: `{"token":"","new_password":"4321"}`,
+			},
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidRequest,
+			},
+		},
+		{
+			name: "Incorrect request body",
+			args: args{
+				body: `{"token":`,
+			},
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidRequest,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ctr := gomock.NewController(t)
+		repo := mocks.NewMockUserRepo(ctr)
+		attempts := mocks.NewMockLoginAttemptRepo(ctr)
+		tokenRepo := mocks.NewMockTokenRepo(ctr)
+
+		if test.prepare != nil {
+			test.prepare(repo, attempts, tokenRepo)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(test.args.body))
+		server := echo.New()
+		echoCtx := server.NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+
+		uc := UserController{
+			user:   usecases.NewUserUseCase(repo, attempts, testHasher, testLoginPolicy, nil, time.Hour, time.Minute),
+			token:  usecases.NewTokenUseCase(tokenRepo, testKeys, time.Minute, time.Hour, time.Minute),
+			logger: log.StandardLogger(),
+		}
+		err := uc.passwordResetHandler(echoCtx)
+		require.NoError(t, err, test.name)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, test.wants.status, res.StatusCode, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code, test.name)
+		}
+	}
+}
+
+// Проверяет, что токен доступа возвращён в заголовке Authorization и совпадает с токеном в теле ответа.
+func assertTokens(t *testing.T, res *http.Response) {
+	t.Helper()
+
+	var tokens entities.Tokens
+
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&tokens))
+
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.Equal(t, "Bearer "+tokens.AccessToken, res.Header.Get(echo.HeaderAuthorization))
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/logging"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
+	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/labstack/echo/v4"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type WebhookController struct {
+	webhook usecases.Webhook
+	mw      *middleware.Manager
+	logger  *log.Logger
+}
+
+func NewWebhookController(
+	webhook usecases.Webhook, mwManager *middleware.Manager, logger *log.Logger,
+) (*WebhookController, error) {
+	if webhook == nil {
+		return nil, ErrUseCaseIsNil
+	}
+
+	controllerLogger := log.StandardLogger()
+	if logger != nil {
+		controllerLogger = logger
+	}
+
+	return &WebhookController{
+		webhook: webhook,
+		mw:      mwManager,
+		logger:  controllerLogger,
+	}, nil
+}
+
+func (c *WebhookController) MapHandlers(group *echo.Group) error {
+	if group == nil {
+		return ErrGroupIsNil
+	}
+
+	group.Add(http.MethodPost, "/user/webhooks", c.mw.AuthenticationMiddleware(c.addWebhookHandler))
+	group.Add(http.MethodGet, "/user/webhooks", c.mw.AuthenticationMiddleware(c.webhooksHandler))
+	group.Add(http.MethodDelete, "/user/webhooks/:id", c.mw.AuthenticationMiddleware(c.deleteWebhookHandler))
+	group.Add(
+		http.MethodGet, "/user/webhooks/:id/deliveries", c.mw.AuthenticationMiddleware(c.deliveriesHandler),
+	)
+
+	return nil
+}
+
+// @Summary       Register webhook
+// @Description   Subscribe to notifications about order status and balance changes.
+// @Description   Each notification is sent as a POST request signed with HMAC-SHA256 using the secret.
+// @Tags          Gophermart HTTP API
+// @Accept        json
+// @Produce       json
+// @Param         webhook   body       entities.WebhookRegistration   true   "Notification URL and signing secret."
+// @Success       201       {object}   entities.Webhook
+// @Failure       400       {object}   Problem
+// @Failure       401       {object}   Problem
+// @Failure       500       {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/user/webhooks [post]
+func (c *WebhookController) addWebhookHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	userID := e.Get("userID")
+
+	user, ok := userID.(int64)
+	if !ok {
+		return writeError(e, logger, ErrUnauthorized)
+	}
+
+	body, err := io.ReadAll(e.Request().Body)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	var registration entities.WebhookRegistration
+
+	err = json.Unmarshal(body, &registration)
+	if err != nil {
+		return writeError(e, logger, fmt.Errorf("%w: %s", ErrInvalidRequestBody, err))
+	}
+
+	webhook := entities.Webhook{
+		UserID: user,
+		URL:    registration.URL,
+		Secret: registration.Secret,
+	}
+
+	err = c.webhook.AddWebhook(e.Request().Context(), &webhook)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	return e.JSON(http.StatusCreated, &webhook)
+}
+
+// @Summary       Get webhooks list
+// @Description   Get the notification subscriptions of the user.
+// @Tags          Gophermart HTTP API
+// @Produce       json
+// @Success       200   {array}    entities.Webhook
+// @Success       204
+// @Failure       401   {object}   Problem
+// @Failure       500   {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/user/webhooks [get]
+func (c *WebhookController) webhooksHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	userID := e.Get("userID")
+
+	user, ok := userID.(int64)
+	if !ok {
+		return writeError(e, logger, ErrUnauthorized)
+	}
+
+	webhooks, err := c.webhook.Webhooks(e.Request().Context(), user)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	if len(webhooks) == 0 {
+		return e.NoContent(http.StatusNoContent)
+	}
+
+	return e.JSON(http.StatusOK, webhooks)
+}
+
+// @Summary       Delete webhook
+// @Description   Delete the notification subscription together with its delivery log.
+// @Tags          Gophermart HTTP API
+// @Param         id    path       int   true   "Webhook ID."
+// @Success       204
+// @Failure       400   {object}   Problem
+// @Failure       401   {object}   Problem
+// @Failure       404   {object}   Problem
+// @Failure       500   {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/user/webhooks/{id} [delete]
+func (c *WebhookController) deleteWebhookHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	userID := e.Get("userID")
+
+	user, ok := userID.(int64)
+	if !ok {
+		return writeError(e, logger, ErrUnauthorized)
+	}
+
+	webhookID, err := parseWebhookID(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	err = c.webhook.DeleteWebhook(e.Request().Context(), &entities.Webhook{ID: webhookID, UserID: user})
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	return e.NoContent(http.StatusNoContent)
+}
+
+// @Summary       Get webhook delivery log
+// @Description   Get the delivery attempts of notifications sent to the subscription.
+// @Description   Deliveries are returned page by page, the cursor of the next page
+// @Description   is passed in the X-Next-Cursor response header.
+// @Tags          Gophermart HTTP API
+// @Produce       json
+// @Param         id       path       int       true    "Webhook ID."
+// @Param         limit    query      int       false   "Page size (default 100, maximum 1000)."
+// @Param         cursor   query      string    false   "Cursor of the page from the X-Next-Cursor header."
+// @Param         sort     query      string    false   "Sort direction by event time."   Enums(asc, desc)
+// @Param         from     query      string    false   "Minimum event time (RFC 3339)."
+// @Param         to       query      string    false   "Maximum event time (RFC 3339)."
+// @Success       200      {array}    entities.WebhookDelivery
+// @Header        200      {string}   X-Next-Cursor   "Cursor of the next page."
+// @Success       204
+// @Failure       400      {object}   Problem
+// @Failure       401      {object}   Problem
+// @Failure       404      {object}   Problem
+// @Failure       500      {object}   Problem
+// @Security      JWT
+// @Security      Bearer
+// @Router        /api/user/webhooks/{id}/deliveries [get]
+func (c *WebhookController) deliveriesHandler(e echo.Context) error {
+	logger := logging.FromContext(e.Request().Context(), c.logger)
+
+	userID := e.Get("userID")
+
+	user, ok := userID.(int64)
+	if !ok {
+		return writeError(e, logger, ErrUnauthorized)
+	}
+
+	webhookID, err := parseWebhookID(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	page, err := parsePage(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	created, err := parseDateRange(e)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	filter := entities.WebhookDeliveryFilter{
+		UserID:    user,
+		WebhookID: webhookID,
+		Created:   created,
+		Page:      page,
+	}
+
+	deliveries, next, err := c.webhook.Deliveries(e.Request().Context(), &filter)
+	if err != nil {
+		return writeError(e, logger, err)
+	}
+
+	if len(deliveries) == 0 {
+		return e.NoContent(http.StatusNoContent)
+	}
+
+	if next != nil {
+		e.Response().Header().Set(nextCursorHeader, next.String())
+	}
+
+	return e.JSON(http.StatusOK, deliveries)
+}
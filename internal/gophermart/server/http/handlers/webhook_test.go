@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/repository/mocks"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/problem"
+	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookMapHandlers(t *testing.T) {
+	ctrl, err := NewWebhookController(nil, newTestManager(t), log.New())
+	assert.ErrorIs(t, err, ErrUseCaseIsNil)
+	assert.Nil(t, ctrl)
+
+	ctrl, err = NewWebhookController(
+		usecases.NewWebhookUseCase(mocks.NewMockWebhookRepo(gomock.NewController(t)), time.Second),
+		newTestManager(t),
+		nil,
+	)
+	require.NoError(t, err)
+	require.NotNil(t, ctrl)
+
+	assert.NoError(t, ctrl.MapHandlers(echo.New().Group("/")))
+	assert.ErrorIs(t, ctrl.MapHandlers(nil), ErrGroupIsNil)
+}
+
+func TestAddWebhookHandler(t *testing.T) {
+	path := "/api/user/webhooks"
+
+	type args struct {
+		userID interface{}
+		body   string
+	}
+
+	type wants struct {
+		status int
+		code   string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockWebhookRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "Correct registration",
+			prepare: func(mock *mocks.MockWebhookRepo) {
+				mock.EXPECT().AddWebhook(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			args: args{
+				userID: int64(1),
+				body:   `{"url":"https://shop.example.com/hooks","secret":"9f86d081884c7d659a2feaa0c55ad015"}`,
+			},
+			wants: wants{
+				status: http.StatusCreated,
+			},
+		},
+		{
+			name: "Invalid webhook",
+			args: args{
+				userID: int64(1),
+				body:   `{"url":"shop.example.com","secret":"9f86d081884c7d659a2feaa0c55ad015"}`,
+			},
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidWebhook,
+			},
+		},
+		{
+			name: "Invalid request body",
+			args: args{
+				userID: int64(1),
+				body:   `{"url":`,
+			},
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidRequest,
+			},
+		},
+		{
+			name: "User unauthorized",
+			args: args{
+				body: `{"url":"https://shop.example.com/hooks","secret":"9f86d081884c7d659a2feaa0c55ad015"}`,
+			},
+			wants: wants{
+				status: http.StatusUnauthorized,
+				code:   problem.CodeUnauthorized,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockWebhookRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(test.args.body))
+		echoCtx := echo.New().NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+		echoCtx.Set("userID", test.args.userID)
+
+		wc := WebhookController{
+			webhook: usecases.NewWebhookUseCase(repo, time.Minute),
+			logger:  log.StandardLogger(),
+		}
+
+		err := wc.addWebhookHandler(echoCtx)
+		require.NoError(t, err, test.name)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, test.wants.status, res.StatusCode, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code)
+		} else {
+			assert.NotContains(t, rec.Body.String(), "secret", test.name)
+		}
+	}
+}
+
+func TestDeleteWebhookHandler(t *testing.T) {
+	path := "/api/user/webhooks/:id"
+
+	type args struct {
+		userID interface{}
+		id     string
+	}
+
+	type wants struct {
+		status int
+		code   string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockWebhookRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "Webhook deleted",
+			prepare: func(mock *mocks.MockWebhookRepo) {
+				mock.EXPECT().DeleteWebhook(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			args: args{
+				userID: int64(1),
+				id:     "1",
+			},
+			wants: wants{
+				status: http.StatusNoContent,
+			},
+		},
+		{
+			name: "Webhook not found",
+			prepare: func(mock *mocks.MockWebhookRepo) {
+				mock.EXPECT().DeleteWebhook(gomock.Any(), gomock.Any()).Return(entities.ErrWebhookNotFound)
+			},
+			args: args{
+				userID: int64(1),
+				id:     "2",
+			},
+			wants: wants{
+				status: http.StatusNotFound,
+				code:   problem.CodeWebhookNotFound,
+			},
+		},
+		{
+			name: "Invalid webhook ID",
+			args: args{
+				userID: int64(1),
+				id:     "abc",
+			},
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidPathParam,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockWebhookRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/api/user/webhooks/"+test.args.id, nil)
+		echoCtx := echo.New().NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+		echoCtx.SetParamNames("id")
+		echoCtx.SetParamValues(test.args.id)
+		echoCtx.Set("userID", test.args.userID)
+
+		wc := WebhookController{
+			webhook: usecases.NewWebhookUseCase(repo, time.Minute),
+			logger:  log.StandardLogger(),
+		}
+
+		err := wc.deleteWebhookHandler(echoCtx)
+		require.NoError(t, err, test.name)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, test.wants.status, res.StatusCode, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code)
+		}
+	}
+}
+
+func TestDeliveriesHandler(t *testing.T) {
+	path := "/api/user/webhooks/:id/deliveries"
+	delivery := entities.WebhookDelivery{
+		ID:        1,
+		WebhookID: 1,
+		EventID:   "8d1c2c34-5d1a-4c4e-9a0b-3f0e0a1e2b3c",
+		EventType: entities.WebhookEventBalanceChanged,
+		Status:    entities.WebhookDeliveryPending,
+		CreatedAt: time.Now(),
+	}
+
+	type args struct {
+		userID interface{}
+		query  string
+	}
+
+	type wants struct {
+		status     int
+		nextCursor bool
+		code       string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockWebhookRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "Correct deliveries request",
+			prepare: func(mock *mocks.MockWebhookRepo) {
+				mock.EXPECT().Webhook(gomock.Any(), gomock.Any()).Return(nil)
+				mock.EXPECT().WebhookDeliveries(gomock.Any(), gomock.Any()).
+					Return([]entities.WebhookDelivery{delivery}, nil)
+			},
+			args: args{
+				userID: int64(1),
+			},
+			wants: wants{
+				status: http.StatusOK,
+			},
+		},
+		{
+			name: "Next page exists",
+			prepare: func(mock *mocks.MockWebhookRepo) {
+				mock.EXPECT().Webhook(gomock.Any(), gomock.Any()).Return(nil)
+				mock.EXPECT().WebhookDeliveries(gomock.Any(), gomock.Any()).
+					Return([]entities.WebhookDelivery{delivery, delivery}, nil)
+			},
+			args: args{
+				userID: int64(1),
+				query:  "limit=1",
+			},
+			wants: wants{
+				status:     http.StatusOK,
+				nextCursor: true,
+			},
+		},
+		{
+			name: "Deliveries not found",
+			prepare: func(mock *mocks.MockWebhookRepo) {
+				mock.EXPECT().Webhook(gomock.Any(), gomock.Any()).Return(nil)
+				mock.EXPECT().WebhookDeliveries(gomock.Any(), gomock.Any()).Return([]entities.WebhookDelivery{}, nil)
+			},
+			args: args{
+				userID: int64(1),
+			},
+			wants: wants{
+				status: http.StatusNoContent,
+			},
+		},
+		{
+			name: "Webhook not found",
+			prepare: func(mock *mocks.MockWebhookRepo) {
+				mock.EXPECT().Webhook(gomock.Any(), gomock.Any()).Return(entities.ErrWebhookNotFound)
+			},
+			args: args{
+				userID: int64(2),
+			},
+			wants: wants{
+				status: http.StatusNotFound,
+				code:   problem.CodeWebhookNotFound,
+			},
+		},
+		{
+			name: "Invalid cursor",
+			args: args{
+				userID: int64(1),
+				query:  "cursor=abc",
+			},
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidCursor,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockWebhookRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/user/webhooks/1/deliveries?"+test.args.query, nil)
+		echoCtx := echo.New().NewContext(req, rec)
+
+		echoCtx.SetPath(path)
+		echoCtx.SetParamNames("id")
+		echoCtx.SetParamValues("1")
+		echoCtx.Set("userID", test.args.userID)
+
+		wc := WebhookController{
+			webhook: usecases.NewWebhookUseCase(repo, time.Minute),
+			logger:  log.StandardLogger(),
+		}
+
+		err := wc.deliveriesHandler(echoCtx)
+		require.NoError(t, err, test.name)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, test.wants.status, res.StatusCode, test.name)
+
+		if test.wants.code != "" {
+			assertProblem(t, rec, test.wants.code)
+		}
+
+		assert.Equal(t, test.wants.nextCursor, res.Header.Get(nextCursorHeader) != "", test.name)
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/metrics"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/problem"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrMetricsIsNil = errors.New("metrics is nil")
+
+// Сервер, отдающий метрики сервиса на отдельном адресе,
+// чтобы они не были доступны через публичный адрес API.
+type MetricsServer struct {
+	address    string
+	httpServer *echo.Echo
+}
+
+func NewMetricsServer(address string, metrics *metrics.Metrics, logger *log.Logger) (*MetricsServer, error) {
+	if metrics == nil {
+		return nil, ErrMetricsIsNil
+	}
+
+	serverLogger := log.StandardLogger()
+	if logger != nil {
+		serverLogger = logger
+	}
+
+	httpServer := echo.New()
+	httpServer.HideBanner = true
+	httpServer.HidePort = true
+	httpServer.HTTPErrorHandler = problem.NewErrorHandler(serverLogger)
+
+	httpServer.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+
+	return &MetricsServer{
+		address:    address,
+		httpServer: httpServer,
+	}, nil
+}
+
+func (s *MetricsServer) Run() error {
+	return s.httpServer.Start(s.address)
+}
+
+func (s *MetricsServer) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
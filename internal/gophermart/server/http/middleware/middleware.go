@@ -1,76 +1,195 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strings"
+	"time"
 
-	"github.com/KryukovO/gophermart/internal/utils"
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/logging"
+	"github.com/KryukovO/gophermart/internal/gophermart/metrics"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/problem"
+	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
 	"github.com/google/uuid"
 
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/semconv/v1.17.0/httpconv"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Источник токена доступа, проверяемый в первую очередь.
+type TokenSource string
+
+const (
+	TokenSourceHeader TokenSource = "header" // Заголовок Authorization: Bearer <token>
+	TokenSourceCookie TokenSource = "cookie" // Cookie token
+)
+
+const (
+	AccessTokenCookie = "token"
+	bearerScheme      = "Bearer"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+var ErrUnknownTokenSource = errors.New("unknown token source")
+
+var tracer = otel.Tracer("github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware")
+
+// Максимальная длина идентификатора запроса, принимаемого от клиента.
+const maxRequestIDLength = 128
+
+// Маршрут, указываемый в метриках и спанах запросов, не соответствующих ни одному маршруту сервера.
+const unmatchedRoute = "unmatched"
+
 type Manager struct {
-	secret []byte
-	logger *log.Logger
+	token       usecases.Token
+	idempotency usecases.Idempotency
+	tokenSource TokenSource
+	metrics     *metrics.Metrics
+	logger      *log.Logger
 }
 
-func NewManager(secret []byte, logger *log.Logger) *Manager {
+func NewManager(
+	token usecases.Token, idempotency usecases.Idempotency, tokenSource TokenSource,
+	metrics *metrics.Metrics, logger *log.Logger,
+) (*Manager, error) {
+	if tokenSource != TokenSourceHeader && tokenSource != TokenSourceCookie {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTokenSource, tokenSource)
+	}
+
 	middlewareLogger := log.StandardLogger()
 	if logger != nil {
 		middlewareLogger = logger
 	}
 
 	return &Manager{
-		secret: secret,
-		logger: middlewareLogger,
-	}
+		token:       token,
+		idempotency: idempotency,
+		tokenSource: tokenSource,
+		metrics:     metrics,
+		logger:      middlewareLogger,
+	}, nil
 }
 
+// Журналирует получение и завершение обработки запроса.
+// Запросу присваивается идентификатор из заголовка X-Request-ID, а при его отсутствии - новый,
+// который возвращается клиенту в том же заголовке.
+// Журнал с идентификатором запроса сохраняется в контексте запроса, поэтому записи обработчиков
+// и вызываемых ими сценариев содержат идентификатор запроса.
 func (mw *Manager) LoggingMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return echo.HandlerFunc(func(e echo.Context) error {
-		uuid := uuid.New()
+		start := time.Now()
+		req := e.Request()
+
+		requestID := requestID(req.Header.Get(echo.HeaderXRequestID))
+		e.Response().Header().Set(echo.HeaderXRequestID, requestID)
+
+		logger := logging.FromContext(req.Context(), mw.logger).WithFields(log.Fields{
+			logging.FieldRequestID: requestID,
+			logging.FieldMethod:    req.Method,
+			logging.FieldRoute:     e.Path(),
+		})
+
+		e.SetRequest(req.WithContext(logging.WithLogger(req.Context(), logger)))
+
+		logger.Infof("Request received: %s", req.URL.Path)
+
+		err := next(e)
+
+		// Журнал в контексте запроса дополняется идентификатором пользователя при аутентификации
+		logging.FromContext(e.Request().Context(), mw.logger).WithFields(log.Fields{
+			logging.FieldStatus:  responseStatus(e, err),
+			logging.FieldLatency: time.Since(start).String(),
+		}).Info("Request completed")
+
+		return err
+	})
+}
+
+// Собирает количество и длительность обработки запросов в разрезе маршрутов и статусов ответа.
+// Маршрут указывается шаблоном, а не фактическим путём запроса, чтобы количество серий метрик
+// не зависело от параметров запросов.
+func (mw *Manager) MetricsMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return echo.HandlerFunc(func(e echo.Context) error {
+		start := time.Now()
 
-		e.Set("uuid", uuid)
+		err := next(e)
+
+		status := responseStatus(e, err)
+
+		mw.metrics.ObserveHTTPRequest(e.Request().Method, routeTemplate(e, status), status, time.Since(start))
+
+		return err
+	})
+}
+
+// Создаёт спан обработки запроса, продолжающий трассировку из заголовков запроса.
+// Контекст запроса заменяется контекстом спана, поэтому спаны, создаваемые обработчиками,
+// становятся дочерними по отношению к нему.
+func (mw *Manager) TracingMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return echo.HandlerFunc(func(e echo.Context) error {
+		req := e.Request()
 
-		mw.logger.Infof(
-			"[%s] Request received with %s method: %s",
-			uuid, e.Request().Method, e.Request().URL.Path,
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := tracer.Start(
+			ctx, req.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(httpconv.ServerRequest("", req)...),
 		)
 
+		defer span.End()
+
+		e.SetRequest(req.WithContext(ctx))
+
 		err := next(e)
 
+		status := responseStatus(e, err)
+		route := routeTemplate(e, status)
+
+		span.SetName(req.Method + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPStatusCode(status))
+
+		if requestID := e.Response().Header().Get(echo.HeaderXRequestID); requestID != "" {
+			span.SetAttributes(attribute.String("request.id", requestID))
+		}
+
 		if err != nil {
-			var echoErr *echo.HTTPError
-			if errors.As(err, &echoErr) {
-				mw.logger.Infof("[%s] Request response status: %d", uuid, echoErr.Code)
-			}
-		} else {
-			mw.logger.Infof("[%s] Request response status: %d", uuid, e.Response().Status)
+			span.RecordError(err)
 		}
 
+		span.SetStatus(httpconv.ServerStatus(status))
+
 		return err
 	})
 }
 
 func (mw *Manager) GZipMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return echo.HandlerFunc(func(e echo.Context) error {
-		uuid := e.Get("uuid")
-		if uuid == nil {
-			uuid = ""
-		}
-
 		contentEncoding := e.Request().Header.Get("Content-Encoding")
 		sendsGZip := strings.Contains(contentEncoding, "gzip")
 
 		if sendsGZip {
 			reader, err := NewReader(e.Request().Body)
 			if err != nil {
-				mw.logger.Errorf("[%s] Something went wrong: %s", uuid, err)
+				logging.FromContext(e.Request().Context(), mw.logger).Errorf("Something went wrong: %s", err)
 
-				return e.NoContent(http.StatusInternalServerError)
+				return problem.WriteInternal(e)
 			}
 
 			defer reader.Close()
@@ -95,20 +214,247 @@ func (mw *Manager) GZipMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 
 func (mw *Manager) AuthenticationMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return echo.HandlerFunc(func(e echo.Context) error {
-		tokenCookie, err := e.Cookie("token")
+		tokenString := mw.accessToken(e)
+		if tokenString == "" {
+			return problem.Write(e, http.StatusUnauthorized, problem.CodeUnauthorized, "access token is required")
+		}
+
+		token, err := mw.token.Authenticate(e.Request().Context(), tokenString)
 		if err != nil {
-			return e.NoContent(http.StatusUnauthorized)
+			if errors.Is(err, entities.ErrTokenRevoked) {
+				return problem.Write(e, http.StatusUnauthorized, problem.CodeTokenRevoked, err.Error())
+			}
+
+			if errors.Is(err, entities.ErrInvalidToken) {
+				return problem.Write(e, http.StatusUnauthorized, problem.CodeInvalidToken, err.Error())
+			}
+
+			logging.FromContext(e.Request().Context(), mw.logger).Errorf("Something went wrong: %s", err)
+
+			return problem.WriteInternal(e)
 		}
 
-		var userID int64
+		e.Set("userID", token.UserID)
+		e.Set("token", token)
 
-		err = utils.ParseTokenString(&userID, tokenCookie.Value, mw.secret)
-		if err != nil {
-			return e.NoContent(http.StatusUnauthorized)
+		ctx := e.Request().Context()
+		logger := logging.FromContext(ctx, mw.logger).WithField(logging.FieldUserID, token.UserID)
+		e.SetRequest(e.Request().WithContext(logging.WithLogger(ctx, logger)))
+
+		return next(e)
+	})
+}
+
+// Разрешает доступ только администраторам. Используется после AuthenticationMiddleware,
+// которая сохраняет токен доступа в контексте запроса.
+func (mw *Manager) AdminMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return echo.HandlerFunc(func(e echo.Context) error {
+		token, ok := e.Get("token").(entities.AccessToken)
+		if !ok {
+			return problem.Write(e, http.StatusUnauthorized, problem.CodeUnauthorized, "access token is required")
 		}
 
-		e.Set("userID", userID)
+		if token.Role != entities.RoleAdmin {
+			logging.FromContext(e.Request().Context(), mw.logger).Warnf("Access to %s denied", e.Path())
+
+			return problem.Write(e, http.StatusForbidden, problem.CodeForbidden, "administrator role is required")
+		}
 
 		return next(e)
 	})
 }
+
+// Обеспечивает однократную обработку запроса с заголовком Idempotency-Key.
+// Ответ на запрос сохраняется, и повторный запрос пользователя с тем же ключом, методом, маршрутом
+// и телом получает сохранённый ответ с заголовком Idempotent-Replayed без повторной обработки.
+// Запрос с тем же ключом, но другим телом отклоняется. Ответы с кодом 5xx не сохраняются,
+// чтобы клиент мог повторить запрос с тем же ключом. Используется после AuthenticationMiddleware.
+func (mw *Manager) IdempotencyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return echo.HandlerFunc(func(e echo.Context) error {
+		header := e.Request().Header.Get(HeaderIdempotencyKey)
+		if header == "" || mw.idempotency == nil {
+			return next(e)
+		}
+
+		userID, ok := e.Get("userID").(int64)
+		if !ok {
+			return problem.Write(e, http.StatusUnauthorized, problem.CodeUnauthorized, "access token is required")
+		}
+
+		ctx := e.Request().Context()
+		logger := logging.FromContext(ctx, mw.logger)
+
+		body, err := io.ReadAll(e.Request().Body)
+		if err != nil {
+			logger.Errorf("Something went wrong: %s", err)
+
+			return problem.WriteInternal(e)
+		}
+
+		e.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+		key := entities.IdempotencyKey{
+			Key:         header,
+			UserID:      userID,
+			RequestHash: requestHash(e.Request().Method, e.Path(), body),
+		}
+
+		stored, replay, err := mw.idempotency.Begin(ctx, &key)
+		if err != nil {
+			switch {
+			case errors.Is(err, entities.ErrInvalidIdempotencyKey):
+				return problem.Write(e, http.StatusBadRequest, problem.CodeInvalidIdempotencyKey, err.Error())
+			case errors.Is(err, entities.ErrIdempotencyKeyInUse):
+				return problem.Write(e, http.StatusConflict, problem.CodeIdempotencyKeyInUse, err.Error())
+			case errors.Is(err, entities.ErrIdempotencyKeyReused):
+				return problem.Write(e, http.StatusUnprocessableEntity, problem.CodeIdempotencyKeyReused, err.Error())
+			}
+
+			logger.Errorf("Something went wrong: %s", err)
+
+			return problem.WriteInternal(e)
+		}
+
+		if replay {
+			logger.Debugf("Replay response to the request with idempotency key %s", key.Key)
+
+			e.Response().Header().Set(HeaderIdempotentReplayed, "true")
+
+			if len(stored.Body) == 0 {
+				return e.NoContent(stored.Status)
+			}
+
+			return e.Blob(stored.Status, stored.ContentType, stored.Body)
+		}
+
+		writer := e.Response().Writer
+		recorder := NewRecorder(writer)
+		e.Response().Writer = recorder
+
+		err = next(e)
+
+		e.Response().Writer = writer
+
+		// Ключ сохраняется или освобождается и после отмены контекста запроса, например при отключении
+		// клиента, который затем повторит запрос. Таймаут операций с ключом задаётся вариантом использования.
+		detached := trace.ContextWithSpan(context.Background(), trace.SpanFromContext(ctx))
+
+		status := responseStatus(e, err)
+		if err != nil || !e.Response().Committed || status >= http.StatusInternalServerError {
+			if abortErr := mw.idempotency.Abort(detached, &key); abortErr != nil {
+				logger.Errorf("Unable to release idempotency key: %s", abortErr)
+			}
+
+			return err
+		}
+
+		key.Status = status
+		key.ContentType = e.Response().Header().Get(echo.HeaderContentType)
+		key.Body = recorder.Body()
+
+		if completeErr := mw.idempotency.Complete(detached, &key); completeErr != nil {
+			logger.Errorf("Unable to save idempotent response: %s", completeErr)
+		}
+
+		return nil
+	})
+}
+
+// Возвращает отпечаток запроса с методом method к маршруту route с телом body.
+func requestHash(method, route string, body []byte) string {
+	hash := sha256.New()
+
+	hash.Write([]byte(method + " " + route + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Возвращает статус ответа на запрос, обработка которого завершилась ошибкой err.
+func responseStatus(e echo.Context, err error) int {
+	if err == nil {
+		return e.Response().Status
+	}
+
+	var echoErr *echo.HTTPError
+	if errors.As(err, &echoErr) {
+		return echoErr.Code
+	}
+
+	return http.StatusInternalServerError
+}
+
+// Возвращает шаблон маршрута, которому соответствует запрос, получивший ответ со статусом status.
+func routeTemplate(e echo.Context, status int) string {
+	route := e.Path()
+
+	if status == http.StatusNotFound || status == http.StatusMethodNotAllowed {
+		if !routeRegistered(e.Echo(), route) {
+			return unmatchedRoute
+		}
+	}
+
+	return route
+}
+
+// Возвращает true, если на сервере зарегистрирован маршрут с шаблоном route.
+// Для запросов, не соответствующих ни одному маршруту, echo возвращает в качестве шаблона
+// путь запроса, поэтому такие запросы необходимо отличать от зарегистрированных маршрутов.
+func routeRegistered(server *echo.Echo, route string) bool {
+	for _, registered := range server.Routes() {
+		if registered.Path == route {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Возвращает идентификатор запроса, переданный клиентом в заголовке X-Request-ID,
+// если он не длиннее maxRequestIDLength и состоит из печатных символов ASCII без пробелов.
+// Иначе возвращает новый идентификатор.
+func requestID(header string) string {
+	if header == "" || len(header) > maxRequestIDLength {
+		return uuid.NewString()
+	}
+
+	for _, r := range header {
+		if r <= ' ' || r > '~' {
+			return uuid.NewString()
+		}
+	}
+
+	return header
+}
+
+// Возвращает токен доступа из источника tokenSource,
+// а при его отсутствии - из другого источника.
+func (mw *Manager) accessToken(e echo.Context) string {
+	headerToken := bearerToken(e.Request().Header.Get(echo.HeaderAuthorization))
+
+	var cookieToken string
+	if cookie, err := e.Cookie(AccessTokenCookie); err == nil {
+		cookieToken = cookie.Value
+	}
+
+	if mw.tokenSource == TokenSourceCookie && cookieToken != "" {
+		return cookieToken
+	}
+
+	if headerToken != "" {
+		return headerToken
+	}
+
+	return cookieToken
+}
+
+// Возвращает токен из значения заголовка Authorization со схемой Bearer.
+// Возвращает пустую строку, если заголовок имеет другую схему.
+func bearerToken(header string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, bearerScheme) {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,661 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/logging"
+	"github.com/KryukovO/gophermart/internal/gophermart/metrics"
+	"github.com/KryukovO/gophermart/internal/gophermart/repository/memrepo"
+	"github.com/KryukovO/gophermart/internal/gophermart/repository/mocks"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/problem"
+	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/KryukovO/gophermart/internal/jwtkeys"
+	"github.com/KryukovO/gophermart/internal/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewManager(t *testing.T) {
+	_, err := NewManager(nil, nil, TokenSourceHeader, nil, nil)
+	assert.NoError(t, err)
+
+	_, err = NewManager(nil, nil, TokenSourceCookie, nil, nil)
+	assert.NoError(t, err)
+
+	_, err = NewManager(nil, nil, "query", nil, nil)
+	assert.ErrorIs(t, err, ErrUnknownTokenSource)
+}
+
+func TestAuthenticationMiddleware(t *testing.T) {
+	keys := jwtkeys.NewHMACKeySet([]byte("secret"))
+
+	signingKey, err := keys.SigningKey()
+	require.NoError(t, err)
+
+	headerToken, err := utils.BuildJSWTString(signingKey, "header-token", "", time.Minute, int64(1))
+	require.NoError(t, err)
+
+	cookieToken, err := utils.BuildJSWTString(signingKey, "cookie-token", "", time.Minute, int64(2))
+	require.NoError(t, err)
+
+	type args struct {
+		source TokenSource
+		header string
+		cookie string
+	}
+
+	type wants struct {
+		status int
+		userID int64
+		code   string
+	}
+
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name: "Header only",
+			args: args{
+				source: TokenSourceCookie,
+				header: "Bearer " + headerToken,
+			},
+			wants: wants{
+				status: http.StatusOK,
+				userID: 1,
+			},
+		},
+		{
+			name: "Cookie only",
+			args: args{
+				source: TokenSourceHeader,
+				cookie: cookieToken,
+			},
+			wants: wants{
+				status: http.StatusOK,
+				userID: 2,
+			},
+		},
+		{
+			name: "Header takes precedence",
+			args: args{
+				source: TokenSourceHeader,
+				header: "Bearer " + headerToken,
+				cookie: cookieToken,
+			},
+			wants: wants{
+				status: http.StatusOK,
+				userID: 1,
+			},
+		},
+		{
+			name: "Cookie takes precedence",
+			args: args{
+				source: TokenSourceCookie,
+				header: "Bearer " + headerToken,
+				cookie: cookieToken,
+			},
+			wants: wants{
+				status: http.StatusOK,
+				userID: 2,
+			},
+		},
+		{
+			name: "Case-insensitive scheme",
+			args: args{
+				source: TokenSourceHeader,
+				header: "bearer " + headerToken,
+			},
+			wants: wants{
+				status: http.StatusOK,
+				userID: 1,
+			},
+		},
+		{
+			name: "Other scheme",
+			args: args{
+				source: TokenSourceHeader,
+				header: "Basic dXNlcjoxMjM0",
+			},
+			wants: wants{
+				status: http.StatusUnauthorized,
+				code:   problem.CodeUnauthorized,
+			},
+		},
+		{
+			name: "Invalid token",
+			args: args{
+				source: TokenSourceHeader,
+				header: "Bearer invalid",
+			},
+			wants: wants{
+				status: http.StatusUnauthorized,
+				code:   problem.CodeInvalidToken,
+			},
+		},
+		{
+			name: "No token",
+			args: args{
+				source: TokenSourceHeader,
+			},
+			wants: wants{
+				status: http.StatusUnauthorized,
+				code:   problem.CodeUnauthorized,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockTokenRepo(gomock.NewController(t))
+		repo.EXPECT().AccessTokenRevoked(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+
+		token := usecases.NewTokenUseCase(repo, keys, time.Minute, time.Hour, time.Second)
+
+		mwManager, err := NewManager(token, nil, test.args.source, nil, log.New())
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/user/balance", nil)
+		if test.args.header != "" {
+			req.Header.Set(echo.HeaderAuthorization, test.args.header)
+		}
+
+		if test.args.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: AccessTokenCookie, Value: test.args.cookie})
+		}
+
+		rec := httptest.NewRecorder()
+		echoCtx := echo.New().NewContext(req, rec)
+
+		var userID int64
+
+		handler := mwManager.AuthenticationMiddleware(func(e echo.Context) error {
+			userID, _ = e.Get("userID").(int64)
+
+			return e.NoContent(http.StatusOK)
+		})
+
+		err = handler(echoCtx)
+		require.NoError(t, err, test.name)
+
+		assert.Equal(t, test.wants.status, rec.Code, test.name)
+
+		if test.wants.code != "" {
+			assert.Equal(t, problem.ContentType, rec.Header().Get(echo.HeaderContentType), test.name)
+			assert.Contains(t, rec.Body.String(), `"code":"`+test.wants.code+`"`, test.name)
+		}
+		assert.Equal(t, test.wants.userID, userID, test.name)
+	}
+}
+
+func TestAdminMiddleware(t *testing.T) {
+	type wants struct {
+		status int
+		code   string
+	}
+
+	tests := []struct {
+		name  string
+		token interface{}
+		wants wants
+	}{
+		{
+			name:  "Administrator",
+			token: entities.AccessToken{ID: "token", UserID: 1, Role: entities.RoleAdmin},
+			wants: wants{
+				status: http.StatusOK,
+			},
+		},
+		{
+			name:  "User",
+			token: entities.AccessToken{ID: "token", UserID: 1, Role: entities.RoleUser},
+			wants: wants{
+				status: http.StatusForbidden,
+				code:   problem.CodeForbidden,
+			},
+		},
+		{
+			name:  "Not authenticated",
+			token: nil,
+			wants: wants{
+				status: http.StatusUnauthorized,
+				code:   problem.CodeUnauthorized,
+			},
+		},
+	}
+
+	mwManager, err := NewManager(nil, nil, TokenSourceHeader, nil, log.New())
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/orders/pending", nil)
+		rec := httptest.NewRecorder()
+		echoCtx := echo.New().NewContext(req, rec)
+
+		if test.token != nil {
+			echoCtx.Set("token", test.token)
+		}
+
+		called := false
+
+		handler := mwManager.AdminMiddleware(func(e echo.Context) error {
+			called = true
+
+			return e.NoContent(http.StatusOK)
+		})
+
+		err = handler(echoCtx)
+		require.NoError(t, err, test.name)
+
+		assert.Equal(t, test.wants.status, rec.Code, test.name)
+		assert.Equal(t, test.wants.code == "", called, test.name)
+
+		if test.wants.code != "" {
+			assert.Contains(t, rec.Body.String(), `"code":"`+test.wants.code+`"`, test.name)
+		}
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	keys := jwtkeys.NewHMACKeySet([]byte("secret"))
+
+	signingKey, err := keys.SigningKey()
+	require.NoError(t, err)
+
+	accessToken, err := utils.BuildJSWTString(signingKey, "token", "", time.Minute, int64(7))
+	require.NoError(t, err)
+
+	repo := mocks.NewMockTokenRepo(gomock.NewController(t))
+	repo.EXPECT().AccessTokenRevoked(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+
+	token := usecases.NewTokenUseCase(repo, keys, time.Minute, time.Hour, time.Second)
+
+	logger, hook := logtest.NewNullLogger()
+
+	mwManager, err := NewManager(token, nil, TokenSourceHeader, nil, logger)
+	require.NoError(t, err)
+
+	server := echo.New()
+	server.GET(
+		"/api/user/balance",
+		func(e echo.Context) error {
+			logging.FromContext(e.Request().Context(), nil).Info("Handler")
+
+			return e.NoContent(http.StatusOK)
+		},
+		mwManager.LoggingMiddleware, mwManager.AuthenticationMiddleware,
+	)
+
+	tests := []struct {
+		name      string
+		requestID string
+		generated bool
+	}{
+		{
+			name:      "Request ID from header",
+			requestID: "client-request-1",
+		},
+		{
+			name:      "No request ID",
+			generated: true,
+		},
+		{
+			name:      "Request ID with spaces",
+			requestID: "client request",
+			generated: true,
+		},
+		{
+			name:      "Too long request ID",
+			requestID: strings.Repeat("a", maxRequestIDLength+1),
+			generated: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hook.Reset()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/balance", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+accessToken)
+
+			if test.requestID != "" {
+				req.Header.Set(echo.HeaderXRequestID, test.requestID)
+			}
+
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+
+			requestID := rec.Header().Get(echo.HeaderXRequestID)
+			if test.generated {
+				assert.NotEqual(t, test.requestID, requestID)
+				assert.NotEmpty(t, requestID)
+			} else {
+				assert.Equal(t, test.requestID, requestID)
+			}
+
+			entries := hook.AllEntries()
+			require.Len(t, entries, 3)
+
+			for _, entry := range entries {
+				assert.Equal(t, requestID, entry.Data[logging.FieldRequestID])
+				assert.Equal(t, "/api/user/balance", entry.Data[logging.FieldRoute])
+			}
+
+			assert.NotContains(t, entries[0].Data, logging.FieldUserID)
+			assert.Equal(t, int64(7), entries[1].Data[logging.FieldUserID])
+
+			assert.Equal(t, "Request completed", entries[2].Message)
+			assert.Equal(t, int64(7), entries[2].Data[logging.FieldUserID])
+			assert.Equal(t, http.StatusOK, entries[2].Data[logging.FieldStatus])
+			assert.Contains(t, entries[2].Data, logging.FieldLatency)
+		})
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	serviceMetrics := metrics.NewMetrics(log.New())
+
+	mwManager, err := NewManager(nil, nil, TokenSourceHeader, serviceMetrics, log.New())
+	require.NoError(t, err)
+
+	server := echo.New()
+	server.Use(mwManager.MetricsMiddleware)
+	server.GET("/api/user/orders/:number", func(e echo.Context) error {
+		return e.NoContent(http.StatusOK)
+	})
+	server.GET("/api/user/balance", func(e echo.Context) error {
+		return echo.NewHTTPError(http.StatusUnauthorized)
+	})
+
+	paths := []string{"/api/user/orders/1", "/api/user/orders/2", "/api/user/balance", "/unknown", "/other"}
+
+	for _, path := range paths {
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	serviceMetrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body, err := io.ReadAll(rec.Result().Body)
+	require.NoError(t, err)
+
+	for _, line := range []string{
+		`gophermart_http_requests_total{method="GET",route="/api/user/orders/:number",status="200"} 2`,
+		`gophermart_http_requests_total{method="GET",route="/api/user/balance",status="401"} 1`,
+		`gophermart_http_requests_total{method="GET",route="unmatched",status="404"} 2`,
+	} {
+		assert.Contains(t, string(body), line)
+	}
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	mwManager, err := NewManager(nil, nil, TokenSourceHeader, nil, log.New())
+	require.NoError(t, err)
+
+	server := echo.New()
+	server.Use(mwManager.TracingMiddleware)
+	server.GET("/api/user/orders/:number", func(e echo.Context) error {
+		assert.True(t, trace.SpanContextFromContext(e.Request().Context()).IsValid())
+
+		return e.NoContent(http.StatusOK)
+	})
+	server.GET("/api/user/balance", func(e echo.Context) error {
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	})
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user/orders/1", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	server.ServeHTTP(httptest.NewRecorder(), req)
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/user/balance", nil))
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	assert.Equal(t, "GET /api/user/orders/:number", spans[0].Name())
+	assert.Equal(t, traceID, spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Attributes(), semconv.HTTPStatusCode(http.StatusOK))
+
+	assert.Equal(t, "GET /api/user/balance", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+
+	assert.Equal(t, "GET unmatched", spans[2].Name())
+	assert.Contains(t, spans[2].Attributes(), semconv.HTTPStatusCode(http.StatusNotFound))
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	type wants struct {
+		status   int
+		code     string
+		body     string
+		replayed bool
+		calls    int
+	}
+
+	tests := []struct {
+		name   string
+		key    string
+		body   string
+		status int
+		wants  wants
+	}{
+		{
+			name:   "First request",
+			key:    "key1",
+			body:   `{"order": "2377225624", "sum": 751}`,
+			status: http.StatusOK,
+			wants: wants{
+				status: http.StatusOK,
+				body:   `{"calls":1}`,
+				calls:  1,
+			},
+		},
+		{
+			name:   "Retry with the same key and body",
+			key:    "key1",
+			body:   `{"order": "2377225624", "sum": 751}`,
+			status: http.StatusOK,
+			wants: wants{
+				status:   http.StatusOK,
+				body:     `{"calls":1}`,
+				replayed: true,
+				calls:    1,
+			},
+		},
+		{
+			name:   "Same key with a different body",
+			key:    "key1",
+			body:   `{"order": "2377225624", "sum": 1000}`,
+			status: http.StatusOK,
+			wants: wants{
+				status: http.StatusUnprocessableEntity,
+				code:   problem.CodeIdempotencyKeyReused,
+				calls:  1,
+			},
+		},
+		{
+			name:   "Request without a key",
+			body:   `{"order": "2377225624", "sum": 751}`,
+			status: http.StatusOK,
+			wants: wants{
+				status: http.StatusOK,
+				body:   `{"calls":2}`,
+				calls:  2,
+			},
+		},
+		{
+			name:   "Server error is not stored",
+			key:    "key2",
+			body:   `{"order": "2377225624", "sum": 751}`,
+			status: http.StatusInternalServerError,
+			wants: wants{
+				status: http.StatusInternalServerError,
+				body:   `{"calls":3}`,
+				calls:  3,
+			},
+		},
+		{
+			name:   "Retry after a server error",
+			key:    "key2",
+			body:   `{"order": "2377225624", "sum": 751}`,
+			status: http.StatusOK,
+			wants: wants{
+				status: http.StatusOK,
+				body:   `{"calls":4}`,
+				calls:  4,
+			},
+		},
+		{
+			name: "Invalid key",
+			key:  strings.Repeat("k", 256),
+			body: `{"order": "2377225624", "sum": 751}`,
+			wants: wants{
+				status: http.StatusBadRequest,
+				code:   problem.CodeInvalidIdempotencyKey,
+				calls:  4,
+			},
+		},
+	}
+
+	idempotency := usecases.NewIdempotencyUseCase(
+		memrepo.NewIdempotencyRepo(memrepo.NewStorage()), time.Hour, time.Minute, time.Second,
+	)
+
+	mwManager, err := NewManager(nil, idempotency, TokenSourceHeader, nil, log.New())
+	require.NoError(t, err)
+
+	calls := 0
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", strings.NewReader(test.body))
+		rec := httptest.NewRecorder()
+		echoCtx := echo.New().NewContext(req, rec)
+
+		if test.key != "" {
+			req.Header.Set(HeaderIdempotencyKey, test.key)
+		}
+
+		echoCtx.SetPath("/api/user/balance/withdraw")
+		echoCtx.Set("userID", int64(1))
+
+		status := test.status
+		handler := mwManager.IdempotencyMiddleware(func(e echo.Context) error {
+			body, err := io.ReadAll(e.Request().Body)
+			require.NoError(t, err, test.name)
+			assert.Equal(t, test.body, string(body), test.name)
+
+			calls++
+
+			return e.JSON(status, map[string]int{"calls": calls})
+		})
+
+		err = handler(echoCtx)
+		require.NoError(t, err, test.name)
+
+		assert.Equal(t, test.wants.status, rec.Code, test.name)
+		assert.Equal(t, test.wants.calls, calls, test.name)
+		assert.Equal(t, test.wants.replayed, rec.Header().Get(HeaderIdempotentReplayed) == "true", test.name)
+
+		if test.wants.code != "" {
+			assert.Contains(t, rec.Body.String(), `"code":"`+test.wants.code+`"`, test.name)
+		} else {
+			assert.JSONEq(t, test.wants.body, rec.Body.String(), test.name)
+		}
+	}
+}
+
+func TestIdempotencyMiddlewareCancelledRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		prepare func(mock *mocks.MockIdempotencyRepo)
+	}{
+		{
+			name:   "Response is stored",
+			status: http.StatusOK,
+			prepare: func(mock *mocks.MockIdempotencyRepo) {
+				mock.EXPECT().
+					SaveIdempotentResponse(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, key *entities.IdempotencyKey) error {
+						assert.Equal(t, http.StatusOK, key.Status)
+
+						return ctx.Err()
+					})
+			},
+		},
+		{
+			name:   "Key is released",
+			status: http.StatusInternalServerError,
+			prepare: func(mock *mocks.MockIdempotencyRepo) {
+				mock.EXPECT().
+					DeleteIdempotencyKey(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, _ *entities.IdempotencyKey) error {
+						return ctx.Err()
+					})
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockIdempotencyRepo(gomock.NewController(t))
+		repo.EXPECT().AddIdempotencyKey(gomock.Any(), gomock.Any()).Return(entities.IdempotencyKey{}, false, nil)
+		test.prepare(repo)
+
+		idempotency := usecases.NewIdempotencyUseCase(repo, time.Hour, time.Minute, time.Second)
+
+		logger, hook := logtest.NewNullLogger()
+
+		mwManager, err := NewManager(nil, idempotency, TokenSourceHeader, nil, logger)
+		require.NoError(t, err, test.name)
+
+		// Клиент отключается, пока запрос обрабатывается.
+		ctx, cancel := context.WithCancel(context.Background())
+
+		req := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", strings.NewReader(`{}`))
+		req = req.WithContext(ctx)
+		req.Header.Set(HeaderIdempotencyKey, "key")
+
+		rec := httptest.NewRecorder()
+		echoCtx := echo.New().NewContext(req, rec)
+		echoCtx.SetPath("/api/user/balance/withdraw")
+		echoCtx.Set("userID", int64(1))
+
+		status := test.status
+		handler := mwManager.IdempotencyMiddleware(func(e echo.Context) error {
+			cancel()
+
+			return e.NoContent(status)
+		})
+
+		err = handler(echoCtx)
+		require.NoError(t, err, test.name)
+		assert.Empty(t, hook.AllEntries(), test.name)
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// Передаёт ответ клиенту, сохраняя копию тела ответа.
+type Recorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func NewRecorder(writer http.ResponseWriter) *Recorder {
+	return &Recorder{ResponseWriter: writer}
+}
+
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.body.Write(p)
+
+	return r.ResponseWriter.Write(p)
+}
+
+// Отправляет клиенту буферизованные данные ответа.
+func (r *Recorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Возвращает копию тела ответа.
+func (r *Recorder) Body() []byte {
+	return r.body.Bytes()
+}
@@ -46,6 +46,13 @@ func (w *Writer) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Отправляет клиенту буферизованные данные ответа.
+func (w *Writer) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func (w *Writer) Close() error {
 	return w.zw.Close()
 }
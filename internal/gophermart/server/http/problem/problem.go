@@ -0,0 +1,166 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/logging"
+	"github.com/labstack/echo/v4"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Тип содержимого ответа с описанием ошибки (RFC 7807).
+const ContentType = "application/problem+json"
+
+// Префикс URI типа ошибки. Тип ошибки однозначно определяется её кодом.
+const typePrefix = "urn:gophermart:problem:"
+
+// Коды ошибок. Коды не меняются между версиями сервиса,
+// поэтому клиенты могут обрабатывать ошибки по коду, а не по тексту.
+const (
+	CodeInternal                  = "internal_error"
+	CodeInvalidRequest            = "invalid_request"
+	CodeInvalidQueryParam         = "invalid_query_parameter"
+	CodeInvalidPathParam          = "invalid_path_parameter"
+	CodeInvalidCursor             = "invalid_cursor"
+	CodeInvalidPageLimit          = "invalid_page_limit"
+	CodeInvalidDateRange          = "invalid_date_range"
+	CodeInvalidStatus             = "invalid_status"
+	CodeInvalidAmount             = "invalid_amount"
+	CodeInvalidAdjustment         = "invalid_adjustment"
+	CodeUnsupportedFormat         = "unsupported_format"
+	CodeUnauthorized              = "unauthorized"
+	CodeInvalidToken              = "invalid_token"
+	CodeTokenRevoked              = "token_revoked"
+	CodeRefreshTokenReused        = "refresh_token_reused"
+	CodeForbidden                 = "forbidden"
+	CodeInvalidCredentials        = "invalid_credentials"
+	CodeLoginLocked               = "login_locked"
+	CodeUserAlreadyExists         = "user_already_exists"
+	CodeUserNotFound              = "user_not_found"
+	CodeInvalidResetToken         = "invalid_reset_token"
+	CodeInvalidIdempotencyKey     = "invalid_idempotency_key"
+	CodeIdempotencyKeyInUse       = "idempotency_key_in_use"
+	CodeIdempotencyKeyReused      = "idempotency_key_reused"
+	CodeInvalidOrderNumber        = "invalid_order_number"
+	CodeOrderAddedByOther         = "order_added_by_other"
+	CodeNotEnoughFunds            = "not_enough_funds"
+	CodeWithdrawalNotFound        = "withdrawal_not_found"
+	CodeWithdrawalAlreadyReversed = "withdrawal_already_reversed"
+	CodeInvalidWebhook            = "invalid_webhook"
+	CodeWebhookNotFound           = "webhook_not_found"
+	CodeNotFound                  = "not_found"
+	CodeMethodNotAllowed          = "method_not_allowed"
+)
+
+// @Description Error description in the RFC 7807 problem details format.
+type Problem struct {
+	Type      string `json:"type"                 example:"urn:gophermart:problem:not_enough_funds"`
+	Title     string `json:"title"                example:"Payment Required"`
+	Status    int    `json:"status"               example:"402"`
+	Detail    string `json:"detail,omitempty"     example:"not enough funds"`
+	Instance  string `json:"instance,omitempty"   example:"/api/user/balance/withdraw"`
+	Code      string `json:"code"                 example:"not_enough_funds"`
+	RequestID string `json:"request_id,omitempty" example:"5d1c5b9e-8f0e-4a55-9a5c-3f5f2a1d7b61"`
+} // @name Problem
+
+// Создаёт описание ошибки с кодом code и пояснением detail для ответа со статусом status.
+func New(status int, code, detail string) Problem {
+	return Problem{
+		Type:   typePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Отвечает на запрос описанием ошибки с кодом code и пояснением detail.
+// В описание добавляются путь запроса и его идентификатор из заголовка X-Request-ID.
+func Write(e echo.Context, status int, code, detail string) error {
+	problem := New(status, code, detail)
+	problem.Instance = e.Request().URL.Path
+	problem.RequestID = e.Response().Header().Get(echo.HeaderXRequestID)
+
+	data, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+
+	return e.Blob(status, ContentType, data)
+}
+
+// Отвечает на запрос описанием внутренней ошибки сервера.
+// Подробности ошибки клиенту не передаются.
+func WriteInternal(e echo.Context) error {
+	return Write(e, http.StatusInternalServerError, CodeInternal, "")
+}
+
+// Создаёт обработчик ошибок сервера, отвечающий описанием ошибки вместо сообщения echo.
+// Ошибки echo (например, отсутствие маршрута) возвращаются со своим статусом,
+// остальные ошибки журналируются и возвращаются как внутренняя ошибка сервера.
+func NewErrorHandler(logger *log.Logger) echo.HTTPErrorHandler {
+	return func(err error, e echo.Context) {
+		if e.Response().Committed {
+			return
+		}
+
+		status := http.StatusInternalServerError
+		code := CodeInternal
+		detail := ""
+
+		var echoErr *echo.HTTPError
+		if errors.As(err, &echoErr) {
+			status = echoErr.Code
+			code = statusCode(status)
+
+			if status < http.StatusInternalServerError {
+				if message, ok := echoErr.Message.(string); ok && message != http.StatusText(status) {
+					detail = message
+				}
+			}
+		}
+
+		if status >= http.StatusInternalServerError {
+			logging.FromContext(e.Request().Context(), logger).Errorf("Something went wrong: %s", err)
+		}
+
+		if e.Request().Method == http.MethodHead {
+			err = e.NoContent(status)
+		} else {
+			err = Write(e, status, code, detail)
+		}
+
+		if err != nil {
+			logging.FromContext(e.Request().Context(), logger).Errorf("Failed to write error response: %s", err)
+		}
+	}
+}
+
+// Возвращает код ошибки, соответствующий статусу ответа status.
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusInternalServerError:
+		return CodeInternal
+	}
+
+	text := http.StatusText(status)
+	if text == "" {
+		return CodeInvalidRequest
+	}
+
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(text))
+}
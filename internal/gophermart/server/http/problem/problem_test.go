@@ -0,0 +1,131 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", nil)
+	rec := httptest.NewRecorder()
+	echoCtx := echo.New().NewContext(req, rec)
+
+	echoCtx.Response().Header().Set(echo.HeaderXRequestID, "request-id")
+
+	err := Write(echoCtx, http.StatusPaymentRequired, CodeNotEnoughFunds, "not enough funds")
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusPaymentRequired, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get(echo.HeaderContentType))
+
+	var body Problem
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, Problem{
+		Type:      "urn:gophermart:problem:not_enough_funds",
+		Title:     "Payment Required",
+		Status:    http.StatusPaymentRequired,
+		Detail:    "not enough funds",
+		Instance:  "/api/user/balance/withdraw",
+		Code:      CodeNotEnoughFunds,
+		RequestID: "request-id",
+	}, body)
+}
+
+func TestErrorHandler(t *testing.T) {
+	type wants struct {
+		status int
+		code   string
+		detail string
+		body   bool
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		err    error
+		wants  wants
+	}{
+		{
+			name:   "Route not found",
+			method: http.MethodGet,
+			err:    echo.ErrNotFound,
+			wants: wants{
+				status: http.StatusNotFound,
+				code:   CodeNotFound,
+				body:   true,
+			},
+		},
+		{
+			name:   "Method not allowed",
+			method: http.MethodPut,
+			err:    echo.ErrMethodNotAllowed,
+			wants: wants{
+				status: http.StatusMethodNotAllowed,
+				code:   CodeMethodNotAllowed,
+				body:   true,
+			},
+		},
+		{
+			name:   "Error with message",
+			method: http.MethodPost,
+			err:    echo.NewHTTPError(http.StatusRequestEntityTooLarge, "body is too large"),
+			wants: wants{
+				status: http.StatusRequestEntityTooLarge,
+				code:   "request_entity_too_large",
+				detail: "body is too large",
+				body:   true,
+			},
+		},
+		{
+			name:   "Internal error",
+			method: http.MethodGet,
+			err:    errors.New("database is down"),
+			wants: wants{
+				status: http.StatusInternalServerError,
+				code:   CodeInternal,
+				body:   true,
+			},
+		},
+		{
+			name:   "HEAD request",
+			method: http.MethodHead,
+			err:    echo.ErrNotFound,
+			wants: wants{
+				status: http.StatusNotFound,
+			},
+		},
+	}
+
+	handler := NewErrorHandler(log.New())
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, "/api/unknown", nil)
+		rec := httptest.NewRecorder()
+
+		handler(test.err, echo.New().NewContext(req, rec))
+
+		assert.Equal(t, test.wants.status, rec.Code, test.name)
+
+		if !test.wants.body {
+			assert.Empty(t, rec.Body.String(), test.name)
+
+			continue
+		}
+
+		var body Problem
+
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body), test.name)
+		assert.Equal(t, test.wants.code, body.Code, test.name)
+		assert.Equal(t, test.wants.detail, body.Detail, test.name)
+		assert.Equal(t, ContentType, rec.Header().Get(echo.HeaderContentType), test.name)
+	}
+}
@@ -3,10 +3,14 @@ package server
 import (
 	"context"
 	"errors"
-	"time"
 
+	"github.com/KryukovO/gophermart/internal/gophermart/health"
+	"github.com/KryukovO/gophermart/internal/gophermart/metrics"
 	"github.com/KryukovO/gophermart/internal/gophermart/server/http/handlers"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/middleware"
+	"github.com/KryukovO/gophermart/internal/gophermart/server/http/problem"
 	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/KryukovO/gophermart/internal/jwtkeys"
 
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
@@ -21,9 +25,10 @@ type Server struct {
 }
 
 func NewServer(
-	address string, secret []byte, tokenLifetime time.Duration,
-	user usecases.User, order usecases.Order, balance usecases.Balance,
-	logger *log.Logger,
+	address string, secret []byte, tokenSource middleware.TokenSource, keys *jwtkeys.KeySet,
+	user usecases.User, order usecases.Order, balance usecases.Balance, token usecases.Token,
+	webhook usecases.Webhook, idempotency usecases.Idempotency,
+	health *health.Health, metrics *metrics.Metrics, logger *log.Logger,
 ) (*Server, error) {
 	if user == nil {
 		return nil, ErrUseCaseIsNil
@@ -37,12 +42,16 @@ func NewServer(
 	httpServer := echo.New()
 	httpServer.HideBanner = true
 	httpServer.HidePort = true
+	// Адрес клиента берётся из X-Forwarded-For, только если запрос пришёл от прокси
+	// из локальной или частной сети; иначе используется адрес соединения
+	httpServer.IPExtractor = echo.ExtractIPFromXFFHeader()
+	httpServer.HTTPErrorHandler = problem.NewErrorHandler(serverLogger)
 
 	err := handlers.SetHandlers(
 		httpServer,
-		secret, tokenLifetime,
-		user, order, balance,
-		logger,
+		secret, tokenSource, keys,
+		user, order, balance, token, webhook, idempotency,
+		health, metrics, logger,
 	)
 	if err != nil {
 		return nil, err
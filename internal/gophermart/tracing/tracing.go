@@ -0,0 +1,165 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const serviceName = "gophermart"
+
+// Значение пути вывода, при котором спаны выводятся в стандартный поток вывода.
+const OutputStdout = "stdout"
+
+var ErrInvalidEndpoint = errors.New("invalid OTLP endpoint")
+
+// Трассировка запросов в формате OpenTelemetry.
+// Спаны экспортируются в коллектор по протоколу OTLP/HTTP,
+// а если коллектор не задан - в файл или стандартный поток вывода.
+type Tracing struct {
+	provider *sdktrace.TracerProvider
+	output   io.Closer
+}
+
+// Создаёт и регистрирует глобальный провайдер трассировки.
+// Если не заданы ни endpoint, ни output, трассировка отключена.
+// Доля трассируемых запросов ratio применяется к запросам без родительского спана.
+func NewTracing(ctx context.Context, endpoint, output string, ratio float64, logger *log.Logger) (*Tracing, error) {
+	tracingLogger := log.StandardLogger()
+	if logger != nil {
+		tracingLogger = logger
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		tracingLogger.Errorf("Tracing error: %s", err)
+	}))
+
+	tracing := &Tracing{}
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+
+	switch {
+	case endpoint != "":
+		exporter, err = newOTLPExporter(ctx, endpoint)
+	case output == OutputStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case output != "":
+		var file *os.File
+
+		file, err = os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+
+		tracing.output = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return tracing, nil
+	}
+
+	if err != nil {
+		tracing.closeOutput()
+
+		return nil, err
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(semconv.ServiceName(serviceName)),
+	)
+	if err != nil {
+		tracing.closeOutput()
+
+		return nil, err
+	}
+
+	tracing.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+
+	otel.SetTracerProvider(tracing.provider)
+
+	return tracing, nil
+}
+
+// Возвращает true, если трассировка включена.
+func (tracing *Tracing) Enabled() bool {
+	return tracing.provider != nil
+}
+
+// Экспортирует накопленные спаны и останавливает трассировку.
+func (tracing *Tracing) Shutdown(ctx context.Context) error {
+	if tracing.provider == nil {
+		return nil
+	}
+
+	err := tracing.provider.Shutdown(ctx)
+	tracing.closeOutput()
+
+	return err
+}
+
+func (tracing *Tracing) closeOutput() {
+	if tracing.output != nil {
+		tracing.output.Close()
+	}
+}
+
+// Завершает спан, отмечая его как ошибочный, если *err не равна nil.
+// Предназначена для вызова через defer с указателем на именованный результат функции.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+
+	span.End()
+}
+
+// Создаёт экспортёр OTLP/HTTP для коллектора по адресу endpoint вида http(s)://host:port[/path].
+func newOTLPExporter(ctx context.Context, endpoint string) (sdktrace.SpanExporter, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidEndpoint, endpoint)
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpointURL.Host)}
+
+	switch endpointURL.Scheme {
+	case "http":
+		opts = append(opts, otlptracehttp.WithInsecure())
+	case "https":
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidEndpoint, endpoint)
+	}
+
+	if endpointURL.Path != "" && endpointURL.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(endpointURL.Path))
+	}
+
+	return otlptracehttp.New(ctx, opts...)
+}
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestNewTracing(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		output   string
+		enabled  bool
+		wantErr  error
+	}{
+		{
+			name: "Disabled",
+		},
+		{
+			name:     "Endpoint without scheme",
+			endpoint: "collector:4318",
+			wantErr:  ErrInvalidEndpoint,
+		},
+		{
+			name:     "Unsupported scheme",
+			endpoint: "grpc://collector:4317",
+			wantErr:  ErrInvalidEndpoint,
+		},
+		{
+			name:     "OTLP endpoint",
+			endpoint: "http://collector:4318/v1/traces",
+			enabled:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracing, err := NewTracing(context.Background(), test.endpoint, test.output, 1, log.New())
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.enabled, tracing.Enabled())
+
+			// Коллектор недоступен, поэтому спаны не экспортируются
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			tracing.Shutdown(ctx)
+		})
+	}
+}
+
+func TestFileOutput(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "spans.json")
+
+	tracing, err := NewTracing(context.Background(), "", output, 1, log.New())
+	require.NoError(t, err)
+	require.True(t, tracing.Enabled())
+
+	func() (err error) {
+		_, span := otel.Tracer("test").Start(context.Background(), "TestFileOutput")
+		defer End(span, &err)
+
+		return errors.New("failure")
+	}()
+
+	require.NoError(t, tracing.Shutdown(context.Background()))
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(data), `"Name":"TestFileOutput"`)
+	assert.Contains(t, string(data), `"Description":"failure"`)
+	assert.Contains(t, string(data), `"Value":"gophermart"`)
+}
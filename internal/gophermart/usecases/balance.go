@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/logging"
 	"github.com/KryukovO/gophermart/internal/gophermart/repository"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
 )
 
 type BalanceUseCase struct {
@@ -20,14 +22,20 @@ func NewBalanceUseCase(repo repository.BalanceRepo, timeout time.Duration) *Bala
 	}
 }
 
-func (uc *BalanceUseCase) Balance(ctx context.Context, userID int64) (entities.Balance, error) {
+func (uc *BalanceUseCase) Balance(ctx context.Context, userID int64) (_ entities.Balance, err error) {
+	ctx, span := tracer.Start(ctx, "BalanceUseCase.Balance")
+	defer tracing.End(span, &err)
+
 	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
 	defer cancel()
 
 	return uc.repo.Balance(ctx, userID)
 }
 
-func (uc *BalanceUseCase) ChangeBalance(ctx context.Context, change *entities.BalanceChange) error {
+func (uc *BalanceUseCase) ChangeBalance(ctx context.Context, change *entities.BalanceChange) (err error) {
+	ctx, span := tracer.Start(ctx, "BalanceUseCase.ChangeBalance")
+	defer tracing.End(span, &err)
+
 	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
 	defer cancel()
 
@@ -38,9 +46,161 @@ func (uc *BalanceUseCase) ChangeBalance(ctx context.Context, change *entities.Ba
 	return uc.repo.ChangeBalance(ctx, change)
 }
 
-func (uc *BalanceUseCase) Withdrawals(ctx context.Context, userID int64) ([]entities.BalanceChange, error) {
+// Корректирует баланс пользователя от имени администратора и возвращает баланс после корректировки.
+// Возвращает ErrNotEnoughFunds, если баланс после корректировки стал бы отрицательным.
+func (uc *BalanceUseCase) Adjust(
+	ctx context.Context, change *entities.BalanceChange,
+) (_ entities.Balance, err error) {
+	ctx, span := tracer.Start(ctx, "BalanceUseCase.Adjust")
+	defer tracing.End(span, &err)
+
+	change.Operation = entities.BalanceOperationAdjustment
+	change.Order = ""
+
+	if err := change.Validate(); err != nil {
+		return entities.Balance{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	err = uc.repo.ChangeBalance(ctx, change)
+	if err != nil {
+		return entities.Balance{}, err
+	}
+
+	logging.FromContext(ctx, nil).Infof(
+		"Balance of user %d adjusted by %s by administrator %d: %s (%s)",
+		change.UserID, change.Sum, change.AdminID, change.Reason, change.Reference,
+	)
+
+	return uc.repo.Balance(ctx, change.UserID)
+}
+
+// Отменяет списание по заказу reversal.Order и возвращает списанные баллы на счёт пользователя.
+// Повторная отмена того же списания завершается ошибкой ErrWithdrawalAlreadyReversed.
+func (uc *BalanceUseCase) ReverseWithdrawal(ctx context.Context, reversal *entities.BalanceChange) (err error) {
+	ctx, span := tracer.Start(ctx, "BalanceUseCase.ReverseWithdrawal")
+	defer tracing.End(span, &err)
+
+	reversal.Operation = entities.BalanceOperationReversal
+
+	if err := reversal.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	err = uc.repo.ReverseWithdrawal(ctx, reversal)
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx, nil).Infof(
+		"Withdrawal of %s for order %s of user %d reversed by administrator %d",
+		reversal.Sum, reversal.Order, reversal.UserID, reversal.AdminID,
+	)
+
+	return nil
+}
+
+// Возвращает страницу списаний пользователя и курсор следующей страницы.
+// Курсор равен nil, если страница последняя.
+func (uc *BalanceUseCase) Withdrawals(
+	ctx context.Context, filter *entities.WithdrawalFilter,
+) (_ []entities.BalanceChange, _ *entities.Cursor, err error) {
+	ctx, span := tracer.Start(ctx, "BalanceUseCase.Withdrawals")
+	defer tracing.End(span, &err)
+
+	if err := filter.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	query := *filter
+	query.Limit++
+
+	withdrawals, err := uc.repo.Withdrawals(ctx, &query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if uint(len(withdrawals)) <= filter.Limit {
+		return withdrawals, nil, nil
+	}
+
+	withdrawals = withdrawals[:filter.Limit]
+	last := withdrawals[len(withdrawals)-1]
+
+	return withdrawals, &entities.Cursor{Time: last.ProcessedAt, ID: last.ID}, nil
+}
+
+// Возвращает страницу выписки по счёту пользователя и курсор следующей страницы.
+// Каждая запись выписки содержит остаток на счёте после операции.
+// Курсор равен nil, если страница последняя.
+func (uc *BalanceUseCase) Statement(
+	ctx context.Context, filter *entities.StatementFilter,
+) (_ []entities.StatementEntry, _ *entities.Cursor, err error) {
+	ctx, span := tracer.Start(ctx, "BalanceUseCase.Statement")
+	defer tracing.End(span, &err)
+
+	if err := filter.Validate(); err != nil {
+		return nil, nil, err
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
 	defer cancel()
 
-	return uc.repo.Withdrawals(ctx, userID)
+	query := *filter
+	query.Limit++
+
+	entries, err := uc.repo.Statement(ctx, &query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if uint(len(entries)) <= filter.Limit {
+		return entries, nil, nil
+	}
+
+	entries = entries[:filter.Limit]
+	last := entries[len(entries)-1]
+
+	return entries, &entities.Cursor{Time: last.ProcessedAt, ID: last.ID}, nil
+}
+
+// Передаёт в yield все записи выписки по счёту пользователя, удовлетворяющие фильтру.
+// Записи выбираются страницами максимального размера, поэтому
+// выгрузка не требует загрузки всей выписки в память.
+func (uc *BalanceUseCase) ExportStatement(
+	ctx context.Context, filter *entities.StatementFilter, yield func(entities.StatementEntry) error,
+) (err error) {
+	ctx, span := tracer.Start(ctx, "BalanceUseCase.ExportStatement")
+	defer tracing.End(span, &err)
+
+	query := *filter
+	query.Limit = entities.MaxPageLimit
+	query.Cursor = nil
+
+	for {
+		entries, next, err := uc.Statement(ctx, &query)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if err := yield(entry); err != nil {
+				return err
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+
+		query.Cursor = next
+	}
 }
@@ -14,8 +14,8 @@ import (
 func TestBalance(t *testing.T) {
 	balance := entities.Balance{
 		UserID:    1,
-		Current:   1000,
-		Withdrawn: 500,
+		Current:   entities.NewMoney(1000, 0),
+		Withdrawn: entities.NewMoney(500, 0),
 	}
 
 	type args struct {
@@ -72,13 +72,13 @@ func TestChangeBalance(t *testing.T) {
 		UserID:    1,
 		Operation: entities.BalanceOperationWithdrawal,
 		Order:     "4561261212345467",
-		Sum:       1000,
+		Sum:       entities.NewMoney(1000, 0),
 	}
 	change2 := entities.BalanceChange{
 		UserID:    1,
 		Operation: entities.BalanceOperationWithdrawal,
 		Order:     "4561261212345464",
-		Sum:       1000,
+		Sum:       entities.NewMoney(1000, 0),
 	}
 
 	type args struct {
@@ -148,20 +148,202 @@ func TestChangeBalance(t *testing.T) {
 	}
 }
 
-func TestWithdrawals(t *testing.T) {
-	change := entities.BalanceChange{
+func TestAdjust(t *testing.T) {
+	adjustment := entities.BalanceChange{
 		UserID:    1,
-		Operation: entities.BalanceOperationWithdrawal,
+		Operation: entities.BalanceOperationAdjustment,
+		Sum:       -entities.NewMoney(100, 0),
+		AdminID:   2,
+		Reason:    "Fraudulent accrual",
+		Reference: "SUP-1234",
+	}
+
+	type wants struct {
+		balance entities.Balance
+		err     error
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockBalanceRepo)
+		change  entities.BalanceChange
+		wants   wants
+	}{
+		{
+			name: "Correct adjustment",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().ChangeBalance(gomock.Any(), &adjustment).Return(nil)
+				mock.EXPECT().Balance(gomock.Any(), int64(1)).Return(entities.Balance{
+					UserID:  1,
+					Current: entities.NewMoney(400, 0),
+				}, nil)
+			},
+			change: entities.BalanceChange{
+				UserID:    1,
+				Sum:       -entities.NewMoney(100, 0),
+				AdminID:   2,
+				Reason:    " Fraudulent accrual ",
+				Reference: "SUP-1234",
+			},
+			wants: wants{
+				balance: entities.Balance{UserID: 1, Current: entities.NewMoney(400, 0)},
+			},
+		},
+		{
+			name: "Zero sum",
+			change: entities.BalanceChange{
+				UserID:    1,
+				AdminID:   2,
+				Reason:    "Goodwill",
+				Reference: "SUP-1234",
+			},
+			wants: wants{
+				err: entities.ErrInvalidAdjustment,
+			},
+		},
+		{
+			name: "Reason is missing",
+			change: entities.BalanceChange{
+				UserID:    1,
+				Sum:       entities.NewMoney(100, 0),
+				AdminID:   2,
+				Reason:    " ",
+				Reference: "SUP-1234",
+			},
+			wants: wants{
+				err: entities.ErrInvalidAdjustment,
+			},
+		},
+		{
+			name: "Reference is missing",
+			change: entities.BalanceChange{
+				UserID:  1,
+				Sum:     entities.NewMoney(100, 0),
+				AdminID: 2,
+				Reason:  "Goodwill",
+			},
+			wants: wants{
+				err: entities.ErrInvalidAdjustment,
+			},
+		},
+		{
+			name: "Not enough funds",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().ChangeBalance(gomock.Any(), &adjustment).Return(entities.ErrNotEnoughFunds)
+			},
+			change: adjustment,
+			wants: wants{
+				err: entities.ErrNotEnoughFunds,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockBalanceRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		balance := NewBalanceUseCase(repo, time.Minute)
+
+		result, err := balance.Adjust(context.Background(), &test.change)
+		if test.wants.err != nil {
+			assert.ErrorIs(t, err, test.wants.err, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+			assert.Equal(t, test.wants.balance, result, test.name)
+		}
+	}
+}
+
+func TestReverseWithdrawal(t *testing.T) {
+	reversal := entities.BalanceChange{
+		Operation: entities.BalanceOperationReversal,
 		Order:     "4561261212345467",
-		Sum:       1000,
+		AdminID:   2,
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockBalanceRepo)
+		order   string
+		wantErr error
+	}{
+		{
+			name: "Correct reversal",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().ReverseWithdrawal(gomock.Any(), &reversal).Return(nil)
+			},
+			order: "4561261212345467",
+		},
+		{
+			name:    "Invalid order number",
+			order:   "4561261212345464",
+			wantErr: entities.ErrInvalidOrderNumber,
+		},
+		{
+			name: "Withdrawal not found",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().ReverseWithdrawal(gomock.Any(), &reversal).Return(entities.ErrWithdrawalNotFound)
+			},
+			order:   "4561261212345467",
+			wantErr: entities.ErrWithdrawalNotFound,
+		},
+		{
+			name: "Withdrawal already reversed",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().ReverseWithdrawal(gomock.Any(), &reversal).Return(entities.ErrWithdrawalAlreadyReversed)
+			},
+			order:   "4561261212345467",
+			wantErr: entities.ErrWithdrawalAlreadyReversed,
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockBalanceRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		balance := NewBalanceUseCase(repo, time.Minute)
+
+		err := balance.ReverseWithdrawal(context.Background(), &entities.BalanceChange{Order: test.order, AdminID: 2})
+		if test.wantErr != nil {
+			assert.ErrorIs(t, err, test.wantErr, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+		}
+	}
+}
+
+func TestWithdrawals(t *testing.T) {
+	ts := time.Now()
+	change1 := entities.BalanceChange{
+		ID:          1,
+		UserID:      1,
+		Operation:   entities.BalanceOperationWithdrawal,
+		Order:       "4561261212345467",
+		Sum:         entities.NewMoney(1000, 0),
+		ProcessedAt: ts,
+	}
+	change2 := entities.BalanceChange{
+		ID:          2,
+		UserID:      1,
+		Operation:   entities.BalanceOperationWithdrawal,
+		Order:       "2377225624",
+		Sum:         entities.NewMoney(500, 0),
+		ProcessedAt: ts.Add(time.Second),
 	}
 
 	type args struct {
-		userID int64
+		filter entities.WithdrawalFilter
 	}
 
 	type wants struct {
 		expected []entities.BalanceChange
+		next     *entities.Cursor
 		wantErr  bool
 	}
 
@@ -174,13 +356,28 @@ func TestWithdrawals(t *testing.T) {
 		{
 			name: "Correct withdrawals request",
 			prepare: func(mock *mocks.MockBalanceRepo) {
-				mock.EXPECT().Withdrawals(gomock.Any(), gomock.Any()).Return([]entities.BalanceChange{change}, nil)
+				mock.EXPECT().Withdrawals(gomock.Any(), gomock.Any()).Return([]entities.BalanceChange{change1}, nil)
 			},
 			args: args{
-				userID: int64(1),
+				filter: entities.WithdrawalFilter{UserID: 1},
+			},
+			wants: wants{
+				expected: []entities.BalanceChange{change1},
+				wantErr:  false,
+			},
+		},
+		{
+			name: "Next page exists",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().Withdrawals(gomock.Any(), gomock.Any()).
+					Return([]entities.BalanceChange{change1, change2}, nil)
+			},
+			args: args{
+				filter: entities.WithdrawalFilter{UserID: 1, Page: entities.Page{Limit: 1}},
 			},
 			wants: wants{
-				expected: []entities.BalanceChange{change},
+				expected: []entities.BalanceChange{change1},
+				next:     &entities.Cursor{Time: change1.ProcessedAt, ID: change1.ID},
 				wantErr:  false,
 			},
 		},
@@ -190,13 +387,22 @@ func TestWithdrawals(t *testing.T) {
 				mock.EXPECT().Withdrawals(gomock.Any(), gomock.Any()).Return([]entities.BalanceChange{}, nil)
 			},
 			args: args{
-				userID: int64(1),
+				filter: entities.WithdrawalFilter{UserID: 1},
 			},
 			wants: wants{
 				expected: []entities.BalanceChange{},
 				wantErr:  false,
 			},
 		},
+		{
+			name: "Invalid page limit",
+			args: args{
+				filter: entities.WithdrawalFilter{UserID: 1, Page: entities.Page{Limit: entities.MaxPageLimit + 1}},
+			},
+			wants: wants{
+				wantErr: true,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -208,12 +414,111 @@ func TestWithdrawals(t *testing.T) {
 
 		order := NewBalanceUseCase(repo, time.Minute)
 
-		result, err := order.Withdrawals(context.Background(), test.args.userID)
+		result, next, err := order.Withdrawals(context.Background(), &test.args.filter)
 		if test.wants.wantErr {
 			assert.Error(t, err)
 		} else {
 			assert.NoError(t, err)
 			assert.ElementsMatch(t, test.wants.expected, result)
+			assert.Equal(t, test.wants.next, next)
+		}
+	}
+}
+
+func TestStatement(t *testing.T) {
+	ts := time.Now()
+	entry1 := entities.StatementEntry{
+		ID:          1,
+		Operation:   entities.BalanceOperationRefill,
+		Order:       "4561261212345467",
+		Sum:         entities.NewMoney(1000, 0),
+		Balance:     entities.NewMoney(1000, 0),
+		ProcessedAt: ts,
+	}
+	entry2 := entities.StatementEntry{
+		ID:          2,
+		Operation:   entities.BalanceOperationWithdrawal,
+		Order:       "2377225624",
+		Sum:         entities.NewMoney(500, 0),
+		Balance:     entities.NewMoney(500, 0),
+		ProcessedAt: ts.Add(time.Second),
+	}
+
+	type args struct {
+		filter entities.StatementFilter
+	}
+
+	type wants struct {
+		expected []entities.StatementEntry
+		next     *entities.Cursor
+		wantErr  bool
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockBalanceRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "Correct statement request",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().Statement(gomock.Any(), gomock.Any()).
+					Return([]entities.StatementEntry{entry1, entry2}, nil)
+			},
+			args: args{
+				filter: entities.StatementFilter{UserID: 1},
+			},
+			wants: wants{
+				expected: []entities.StatementEntry{entry1, entry2},
+				wantErr:  false,
+			},
+		},
+		{
+			name: "Next page exists",
+			prepare: func(mock *mocks.MockBalanceRepo) {
+				mock.EXPECT().Statement(gomock.Any(), gomock.Any()).
+					Return([]entities.StatementEntry{entry1, entry2}, nil)
+			},
+			args: args{
+				filter: entities.StatementFilter{UserID: 1, Page: entities.Page{Limit: 1}},
+			},
+			wants: wants{
+				expected: []entities.StatementEntry{entry1},
+				next:     &entities.Cursor{Time: entry1.ProcessedAt, ID: entry1.ID},
+				wantErr:  false,
+			},
+		},
+		{
+			name: "Invalid date range",
+			args: args{
+				filter: entities.StatementFilter{
+					UserID:    1,
+					Processed: entities.DateRange{From: ts, To: ts.Add(-time.Hour)},
+				},
+			},
+			wants: wants{
+				wantErr: true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockBalanceRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		balance := NewBalanceUseCase(repo, time.Minute)
+
+		result, next, err := balance.Statement(context.Background(), &test.args.filter)
+		if test.wants.wantErr {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, test.wants.expected, result)
+			assert.Equal(t, test.wants.next, next)
 		}
 	}
 }
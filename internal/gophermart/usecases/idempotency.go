@@ -0,0 +1,101 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/repository"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
+)
+
+type IdempotencyUseCase struct {
+	repo      repository.IdempotencyRepo
+	retention time.Duration
+	lease     time.Duration
+	timeout   time.Duration
+}
+
+func NewIdempotencyUseCase(
+	repo repository.IdempotencyRepo, retention time.Duration, lease time.Duration, timeout time.Duration,
+) *IdempotencyUseCase {
+	return &IdempotencyUseCase{
+		repo:      repo,
+		retention: retention,
+		lease:     lease,
+		timeout:   timeout,
+	}
+}
+
+// Начинает обработку запроса с ключом идемпотентности key. Ключ хранится в течение retention
+// и захватывается для обработки запроса на время lease.
+// Если запрос с этим ключом уже был обработан, возвращает сохранённый ответ и true:
+// в этом случае запрос не должен обрабатываться повторно.
+// Возвращает ErrIdempotencyKeyReused, если ключ использовался для другого запроса,
+// и ErrIdempotencyKeyInUse, если запрос с этим ключом ещё обрабатывается.
+func (uc *IdempotencyUseCase) Begin(
+	ctx context.Context, key *entities.IdempotencyKey,
+) (_ entities.IdempotencyKey, _ bool, err error) {
+	ctx, span := tracer.Start(ctx, "IdempotencyUseCase.Begin")
+	defer tracing.End(span, &err)
+
+	if err := key.Validate(); err != nil {
+		return entities.IdempotencyKey{}, false, err
+	}
+
+	key.CreatedAt = time.Now()
+	key.ExpiresAt = key.CreatedAt.Add(uc.retention)
+	key.LockedUntil = key.CreatedAt.Add(uc.lease)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	stored, exists, err := uc.repo.AddIdempotencyKey(ctx, key)
+	if err != nil || !exists {
+		return entities.IdempotencyKey{}, false, err
+	}
+
+	if stored.RequestHash != key.RequestHash {
+		return entities.IdempotencyKey{}, false, entities.ErrIdempotencyKeyReused
+	}
+
+	if !stored.Completed() {
+		return entities.IdempotencyKey{}, false, entities.ErrIdempotencyKeyInUse
+	}
+
+	return stored, true, nil
+}
+
+// Сохраняет ответ на запрос с ключом идемпотентности key для повторных запросов.
+func (uc *IdempotencyUseCase) Complete(ctx context.Context, key *entities.IdempotencyKey) (err error) {
+	ctx, span := tracer.Start(ctx, "IdempotencyUseCase.Complete")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	return uc.repo.SaveIdempotentResponse(ctx, key)
+}
+
+// Освобождает ключ идемпотентности key, если запрос не удалось обработать,
+// чтобы клиент мог повторить запрос с тем же ключом.
+func (uc *IdempotencyUseCase) Abort(ctx context.Context, key *entities.IdempotencyKey) (err error) {
+	ctx, span := tracer.Start(ctx, "IdempotencyUseCase.Abort")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	return uc.repo.DeleteIdempotencyKey(ctx, key)
+}
+
+// Удаляет ключи идемпотентности, срок хранения которых истёк.
+func (uc *IdempotencyUseCase) Cleanup(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "IdempotencyUseCase.Cleanup")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	return uc.repo.DeleteExpiredIdempotencyKeys(ctx)
+}
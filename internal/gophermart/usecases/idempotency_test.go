@@ -0,0 +1,112 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/repository/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBegin(t *testing.T) {
+	completed := entities.IdempotencyKey{
+		Key:         "key",
+		UserID:      1,
+		RequestHash: "hash",
+		Status:      200,
+		ContentType: "application/json",
+		Body:        []byte(`{}`),
+	}
+
+	type wants struct {
+		stored entities.IdempotencyKey
+		replay bool
+		err    error
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockIdempotencyRepo)
+		key     entities.IdempotencyKey
+		wants   wants
+	}{
+		{
+			name: "New key",
+			prepare: func(mock *mocks.MockIdempotencyRepo) {
+				mock.EXPECT().
+					AddIdempotencyKey(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, key *entities.IdempotencyKey) (entities.IdempotencyKey, bool, error) {
+						assert.WithinDuration(t, time.Now().Add(time.Hour), key.ExpiresAt, time.Second)
+						assert.WithinDuration(t, time.Now().Add(time.Minute), key.LockedUntil, time.Second)
+
+						return entities.IdempotencyKey{}, false, nil
+					})
+			},
+			key: entities.IdempotencyKey{Key: "key", UserID: 1, RequestHash: "hash"},
+		},
+		{
+			name: "Completed request",
+			prepare: func(mock *mocks.MockIdempotencyRepo) {
+				mock.EXPECT().AddIdempotencyKey(gomock.Any(), gomock.Any()).Return(completed, true, nil)
+			},
+			key: entities.IdempotencyKey{Key: "key", UserID: 1, RequestHash: "hash"},
+			wants: wants{
+				stored: completed,
+				replay: true,
+			},
+		},
+		{
+			name: "Different request",
+			prepare: func(mock *mocks.MockIdempotencyRepo) {
+				mock.EXPECT().AddIdempotencyKey(gomock.Any(), gomock.Any()).Return(completed, true, nil)
+			},
+			key: entities.IdempotencyKey{Key: "key", UserID: 1, RequestHash: "other"},
+			wants: wants{
+				err: entities.ErrIdempotencyKeyReused,
+			},
+		},
+		{
+			name: "Request in progress",
+			prepare: func(mock *mocks.MockIdempotencyRepo) {
+				mock.EXPECT().
+					AddIdempotencyKey(gomock.Any(), gomock.Any()).
+					Return(entities.IdempotencyKey{Key: "key", UserID: 1, RequestHash: "hash"}, true, nil)
+			},
+			key: entities.IdempotencyKey{Key: "key", UserID: 1, RequestHash: "hash"},
+			wants: wants{
+				err: entities.ErrIdempotencyKeyInUse,
+			},
+		},
+		{
+			name: "Invalid key",
+			key:  entities.IdempotencyKey{Key: "key\n", UserID: 1, RequestHash: "hash"},
+			wants: wants{
+				err: entities.ErrInvalidIdempotencyKey,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockIdempotencyRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		idempotency := NewIdempotencyUseCase(repo, time.Hour, time.Minute, time.Second)
+
+		stored, replay, err := idempotency.Begin(context.Background(), &test.key)
+		if test.wants.err != nil {
+			assert.ErrorIs(t, err, test.wants.err, test.name)
+
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.wants.replay, replay, test.name)
+		assert.Equal(t, test.wants.stored, stored, test.name)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
 	"github.com/KryukovO/gophermart/internal/gophermart/repository"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
 )
 
 type OrderUseCase struct {
@@ -20,7 +21,10 @@ func NewOrderUseCase(repo repository.OrderRepo, timeout time.Duration) *OrderUse
 	}
 }
 
-func (uc *OrderUseCase) AddOrder(ctx context.Context, order *entities.Order) error {
+func (uc *OrderUseCase) AddOrder(ctx context.Context, order *entities.Order) (err error) {
+	ctx, span := tracer.Start(ctx, "OrderUseCase.AddOrder")
+	defer tracing.End(span, &err)
+
 	if err := order.Validate(); err != nil {
 		return err
 	}
@@ -31,23 +35,122 @@ func (uc *OrderUseCase) AddOrder(ctx context.Context, order *entities.Order) err
 	return uc.repo.AddOrder(ctx, order)
 }
 
-func (uc *OrderUseCase) Orders(ctx context.Context, userID int64) ([]entities.Order, error) {
+// Возвращает страницу заказов пользователя и курсор следующей страницы.
+// Курсор равен nil, если страница последняя.
+func (uc *OrderUseCase) Orders(
+	ctx context.Context, filter *entities.OrderFilter,
+) (_ []entities.Order, _ *entities.Cursor, err error) {
+	ctx, span := tracer.Start(ctx, "OrderUseCase.Orders")
+	defer tracing.End(span, &err)
+
+	if err := filter.Validate(); err != nil {
+		return nil, nil, err
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
 	defer cancel()
 
-	return uc.repo.Orders(ctx, userID)
+	query := *filter
+	query.Limit++
+
+	orders, err := uc.repo.Orders(ctx, &query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if uint(len(orders)) <= filter.Limit {
+		return orders, nil, nil
+	}
+
+	orders = orders[:filter.Limit]
+	last := orders[len(orders)-1]
+
+	return orders, &entities.Cursor{Time: last.UploadedAt, ID: last.ID}, nil
 }
 
-func (uc *OrderUseCase) ProcessableOrders(ctx context.Context) ([]entities.Order, error) {
+// Передаёт в yield все заказы пользователя, удовлетворяющие фильтру.
+// Заказы выбираются страницами максимального размера, поэтому
+// выгрузка не требует загрузки всех заказов в память.
+func (uc *OrderUseCase) ExportOrders(
+	ctx context.Context, filter *entities.OrderFilter, yield func(entities.Order) error,
+) (err error) {
+	ctx, span := tracer.Start(ctx, "OrderUseCase.ExportOrders")
+	defer tracing.End(span, &err)
+
+	query := *filter
+	query.Limit = entities.MaxPageLimit
+	query.Cursor = nil
+
+	for {
+		orders, next, err := uc.Orders(ctx, &query)
+		if err != nil {
+			return err
+		}
+
+		for _, order := range orders {
+			if err := yield(order); err != nil {
+				return err
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+
+		query.Cursor = next
+	}
+}
+
+// Захватывает для экземпляра instance не более limit заказов, ожидающих обработки,
+// на время lease. Заказы, захваченные другими экземплярами, не возвращаются
+// до истечения их аренды.
+func (uc *OrderUseCase) ProcessableOrders(
+	ctx context.Context, instance string, limit uint, lease time.Duration,
+) (_ []entities.Order, err error) {
+	ctx, span := tracer.Start(ctx, "OrderUseCase.ProcessableOrders")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	return uc.repo.ProcessableOrders(ctx, instance, limit, lease)
+}
+
+// Сохраняет результат опроса заказа, захваченного экземпляром instance.
+// Если аренда заказа перешла к другому экземпляру, возвращает entities.ErrOrderLeaseLost.
+func (uc *OrderUseCase) UpdateOrder(ctx context.Context, instance string, order *entities.Order) (err error) {
+	ctx, span := tracer.Start(ctx, "OrderUseCase.UpdateOrder")
+	defer tracing.End(span, &err)
+
 	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
 	defer cancel()
 
-	return uc.repo.ProcessableOrders(ctx)
+	return uc.repo.UpdateOrder(ctx, instance, order)
 }
 
-func (uc *OrderUseCase) UpdateOrder(ctx context.Context, order *entities.Order) error {
+// Переводит заказ в статус PROCESSED и начисляет баллы на счёт пользователя
+// в рамках одной транзакции. Если заказ уже обработан либо его аренда
+// перешла к другому экземпляру, возвращает entities.ErrOrderLeaseLost
+// без повторного начисления.
+func (uc *OrderUseCase) ProcessOrder(ctx context.Context, instance string, order *entities.Order) (err error) {
+	ctx, span := tracer.Start(ctx, "OrderUseCase.ProcessOrder")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	order.Status = entities.OrderStatusProcessed
+
+	return uc.repo.ProcessOrder(ctx, instance, order)
+}
+
+// Возвращает количество заказов, расчёт начисления по которым не завершён, в разрезе статусов.
+func (uc *OrderUseCase) PendingOrders(ctx context.Context) (_ entities.PendingOrders, err error) {
+	ctx, span := tracer.Start(ctx, "OrderUseCase.PendingOrders")
+	defer tracing.End(span, &err)
+
 	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
 	defer cancel()
 
-	return uc.repo.UpdateOrder(ctx, order)
+	return uc.repo.PendingOrders(ctx)
 }
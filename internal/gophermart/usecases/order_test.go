@@ -2,6 +2,7 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/KryukovO/gophermart/internal/gophermart/repository/mocks"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAddOrder(t *testing.T) {
@@ -107,20 +109,31 @@ func TestAddOrder(t *testing.T) {
 }
 
 func TestOrders(t *testing.T) {
+	ts := time.Now()
 	order1 := entities.Order{
+		ID:         1,
 		UserID:     1,
 		Number:     "4561261212345467",
 		Status:     "New",
 		Accrual:    0,
-		UploadedAt: time.Now(),
+		UploadedAt: ts,
+	}
+	order2 := entities.Order{
+		ID:         2,
+		UserID:     1,
+		Number:     "2377225624",
+		Status:     "New",
+		Accrual:    0,
+		UploadedAt: ts.Add(time.Second),
 	}
 
 	type args struct {
-		userID int64
+		filter entities.OrderFilter
 	}
 
 	type wants struct {
 		expected []entities.Order
+		next     *entities.Cursor
 		wantErr  bool
 	}
 
@@ -136,10 +149,24 @@ func TestOrders(t *testing.T) {
 				mock.EXPECT().Orders(gomock.Any(), gomock.Any()).Return([]entities.Order{order1}, nil)
 			},
 			args: args{
-				userID: int64(1),
+				filter: entities.OrderFilter{UserID: 1},
+			},
+			wants: wants{
+				expected: []entities.Order{order1},
+				wantErr:  false,
+			},
+		},
+		{
+			name: "Next page exists",
+			prepare: func(mock *mocks.MockOrderRepo) {
+				mock.EXPECT().Orders(gomock.Any(), gomock.Any()).Return([]entities.Order{order1, order2}, nil)
+			},
+			args: args{
+				filter: entities.OrderFilter{UserID: 1, Page: entities.Page{Limit: 1}},
 			},
 			wants: wants{
 				expected: []entities.Order{order1},
+				next:     &entities.Cursor{Time: order1.UploadedAt, ID: order1.ID},
 				wantErr:  false,
 			},
 		},
@@ -149,13 +176,22 @@ func TestOrders(t *testing.T) {
 				mock.EXPECT().Orders(gomock.Any(), gomock.Any()).Return([]entities.Order{}, nil)
 			},
 			args: args{
-				userID: int64(1),
+				filter: entities.OrderFilter{UserID: 1},
 			},
 			wants: wants{
 				expected: []entities.Order{},
 				wantErr:  false,
 			},
 		},
+		{
+			name: "Invalid status",
+			args: args{
+				filter: entities.OrderFilter{UserID: 1, Statuses: []string{"UNKNOWN"}},
+			},
+			wants: wants{
+				wantErr: true,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -167,12 +203,13 @@ func TestOrders(t *testing.T) {
 
 		order := NewOrderUseCase(repo, time.Minute)
 
-		orders, err := order.Orders(context.Background(), test.args.userID)
+		orders, next, err := order.Orders(context.Background(), &test.args.filter)
 		if test.wants.wantErr {
 			assert.Error(t, err)
 		} else {
 			assert.NoError(t, err)
 			assert.ElementsMatch(t, test.wants.expected, orders)
+			assert.Equal(t, test.wants.next, next)
 		}
 	}
 }
@@ -199,7 +236,7 @@ func TestProcessableOrders(t *testing.T) {
 		{
 			name: "Order list",
 			prepare: func(mock *mocks.MockOrderRepo) {
-				mock.EXPECT().ProcessableOrders(gomock.Any()).Return([]entities.Order{order1}, nil)
+				mock.EXPECT().ProcessableOrders(gomock.Any(), "instance", uint(10), time.Minute).Return([]entities.Order{order1}, nil)
 			},
 			wants: wants{
 				expected: []entities.Order{order1},
@@ -209,7 +246,7 @@ func TestProcessableOrders(t *testing.T) {
 		{
 			name: "Orders not found",
 			prepare: func(mock *mocks.MockOrderRepo) {
-				mock.EXPECT().ProcessableOrders(gomock.Any()).Return([]entities.Order{}, nil)
+				mock.EXPECT().ProcessableOrders(gomock.Any(), "instance", uint(10), time.Minute).Return([]entities.Order{}, nil)
 			},
 			wants: wants{
 				expected: []entities.Order{},
@@ -227,7 +264,7 @@ func TestProcessableOrders(t *testing.T) {
 
 		order := NewOrderUseCase(repo, time.Minute)
 
-		orders, err := order.ProcessableOrders(context.Background())
+		orders, err := order.ProcessableOrders(context.Background(), "instance", 10, time.Minute)
 		if test.wants.wantErr {
 			assert.Error(t, err)
 		} else {
@@ -242,7 +279,7 @@ func TestUpdateOrder(t *testing.T) {
 		UserID:     1,
 		Number:     "4561261212345467",
 		Status:     "PROCESSED",
-		Accrual:    500,
+		Accrual:    entities.NewMoney(500, 0),
 		UploadedAt: time.Now().AddDate(0, 0, -1),
 	}
 
@@ -258,12 +295,70 @@ func TestUpdateOrder(t *testing.T) {
 		{
 			name: "Successful update",
 			prepare: func(mock *mocks.MockOrderRepo) {
-				mock.EXPECT().UpdateOrder(gomock.Any(), gomock.Any()).Return(nil)
+				mock.EXPECT().UpdateOrder(gomock.Any(), "instance", gomock.Any()).Return(nil)
+			},
+			wants: wants{
+				wantErr: false,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockOrderRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		order := NewOrderUseCase(repo, time.Minute)
+
+		err := order.UpdateOrder(context.Background(), "instance", &order1)
+		if test.wants.wantErr {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestProcessOrder(t *testing.T) {
+	order1 := entities.Order{
+		UserID:     1,
+		Number:     "4561261212345467",
+		Status:     "PROCESSING",
+		Accrual:    entities.NewMoney(500, 0),
+		UploadedAt: time.Now().AddDate(0, 0, -1),
+	}
+	orderExpected := order1
+	orderExpected.Status = entities.OrderStatusProcessed
+
+	type wants struct {
+		wantErr bool
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockOrderRepo)
+		wants   wants
+	}{
+		{
+			name: "Successful processing",
+			prepare: func(mock *mocks.MockOrderRepo) {
+				mock.EXPECT().ProcessOrder(gomock.Any(), "instance", mocks.OrderMatcher(&orderExpected)).Return(nil)
 			},
 			wants: wants{
 				wantErr: false,
 			},
 		},
+		{
+			name: "Repository error",
+			prepare: func(mock *mocks.MockOrderRepo) {
+				mock.EXPECT().ProcessOrder(gomock.Any(), gomock.Any(), gomock.Any()).Return(context.DeadlineExceeded)
+			},
+			wants: wants{
+				wantErr: true,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -274,8 +369,9 @@ func TestUpdateOrder(t *testing.T) {
 		}
 
 		order := NewOrderUseCase(repo, time.Minute)
+		processed := order1
 
-		err := order.UpdateOrder(context.Background(), &order1)
+		err := order.ProcessOrder(context.Background(), "instance", &processed)
 		if test.wants.wantErr {
 			assert.Error(t, err)
 		} else {
@@ -283,3 +379,81 @@ func TestUpdateOrder(t *testing.T) {
 		}
 	}
 }
+
+func TestExportOrders(t *testing.T) {
+	ts := time.Now()
+	firstPage := make([]entities.Order, entities.MaxPageLimit+1)
+
+	for i := range firstPage {
+		firstPage[i] = entities.Order{
+			ID:         int64(i + 1),
+			UserID:     1,
+			Number:     "4561261212345467",
+			Status:     entities.OrderStatusNew,
+			UploadedAt: ts.Add(time.Duration(i) * time.Second),
+		}
+	}
+
+	last := firstPage[entities.MaxPageLimit-1]
+	secondPage := []entities.Order{firstPage[entities.MaxPageLimit]}
+
+	repo := mocks.NewMockOrderRepo(gomock.NewController(t))
+	gomock.InOrder(
+		repo.EXPECT().Orders(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, filter *entities.OrderFilter) ([]entities.Order, error) {
+				assert.Nil(t, filter.Cursor)
+				assert.Equal(t, entities.MaxPageLimit+1, filter.Limit)
+
+				return firstPage, nil
+			},
+		),
+		repo.EXPECT().Orders(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, filter *entities.OrderFilter) ([]entities.Order, error) {
+				assert.Equal(t, &entities.Cursor{Time: last.UploadedAt, ID: last.ID}, filter.Cursor)
+
+				return secondPage, nil
+			},
+		),
+	)
+
+	order := NewOrderUseCase(repo, time.Minute)
+	exported := make([]entities.Order, 0)
+
+	err := order.ExportOrders(
+		context.Background(),
+		&entities.OrderFilter{UserID: 1, Page: entities.Page{Limit: 1, Cursor: &entities.Cursor{ID: 1}}},
+		func(order entities.Order) error {
+			exported = append(exported, order)
+
+			return nil
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, firstPage, exported)
+
+	stop := errors.New("stop")
+
+	repo.EXPECT().Orders(gomock.Any(), gomock.Any()).Return(secondPage, nil)
+
+	err = order.ExportOrders(
+		context.Background(),
+		&entities.OrderFilter{UserID: 1},
+		func(entities.Order) error {
+			return stop
+		},
+	)
+	assert.ErrorIs(t, err, stop)
+}
+
+func TestPendingOrders(t *testing.T) {
+	pending := entities.PendingOrders{New: 2, Processing: 1, Total: 3}
+
+	repo := mocks.NewMockOrderRepo(gomock.NewController(t))
+	repo.EXPECT().PendingOrders(gomock.Any()).Return(pending, nil)
+
+	order := NewOrderUseCase(repo, time.Minute)
+
+	result, err := order.PendingOrders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, pending, result)
+}
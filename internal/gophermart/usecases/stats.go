@@ -0,0 +1,32 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/repository"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
+)
+
+type StatsUseCase struct {
+	repo    repository.StatsRepo
+	timeout time.Duration
+}
+
+func NewStatsUseCase(repo repository.StatsRepo, timeout time.Duration) *StatsUseCase {
+	return &StatsUseCase{
+		repo:    repo,
+		timeout: timeout,
+	}
+}
+
+func (uc *StatsUseCase) Stats(ctx context.Context) (_ entities.Stats, err error) {
+	ctx, span := tracer.Start(ctx, "StatsUseCase.Stats")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	return uc.repo.Stats(ctx)
+}
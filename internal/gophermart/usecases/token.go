@@ -0,0 +1,204 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/repository"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
+	"github.com/KryukovO/gophermart/internal/jwtkeys"
+	"github.com/KryukovO/gophermart/internal/utils"
+	"github.com/google/uuid"
+)
+
+const tokenBytes = 32
+
+type TokenUseCase struct {
+	repo       repository.TokenRepo
+	keys       *jwtkeys.KeySet
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	timeout    time.Duration
+}
+
+func NewTokenUseCase(
+	repo repository.TokenRepo, keys *jwtkeys.KeySet,
+	accessTTL, refreshTTL time.Duration, timeout time.Duration,
+) *TokenUseCase {
+	return &TokenUseCase{
+		repo:       repo,
+		keys:       keys,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		timeout:    timeout,
+	}
+}
+
+// Выдаёт пользователю с ролью role пару токенов, открывающую новое семейство токенов обновления.
+func (uc *TokenUseCase) Issue(ctx context.Context, userID int64, role string) (_ entities.TokenPair, err error) {
+	ctx, span := tracer.Start(ctx, "TokenUseCase.Issue")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	return uc.issue(ctx, userID, role, uuid.NewString())
+}
+
+// Обменивает токен обновления на новую пару токенов того же семейства.
+// Повторное использование токена обновления считается признаком его компрометации:
+// в этом случае отзывается всё семейство токенов и возвращается ErrRefreshTokenReused.
+func (uc *TokenUseCase) Refresh(ctx context.Context, refreshToken string) (_ entities.TokenPair, err error) {
+	ctx, span := tracer.Start(ctx, "TokenUseCase.Refresh")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	token := entities.RefreshToken{Hash: hashToken(refreshToken)}
+
+	err = uc.repo.UseRefreshToken(ctx, &token)
+	if err != nil {
+		if errors.Is(err, entities.ErrRefreshTokenReused) {
+			if revokeErr := uc.repo.RevokeFamily(ctx, token.Family); revokeErr != nil {
+				return entities.TokenPair{}, revokeErr
+			}
+		}
+
+		return entities.TokenPair{}, err
+	}
+
+	return uc.issue(ctx, token.UserID, token.Role, token.Family)
+}
+
+// Проверяет токен доступа и возвращает его данные.
+// Возвращает ErrTokenRevoked, если токен был отозван.
+// Токенам, выпущенным без роли, присваивается роль RoleUser.
+func (uc *TokenUseCase) Authenticate(ctx context.Context, accessToken string) (_ entities.AccessToken, err error) {
+	ctx, span := tracer.Start(ctx, "TokenUseCase.Authenticate")
+	defer tracing.End(span, &err)
+
+	var userID int64
+
+	claims, err := utils.ParseTokenString(&userID, accessToken, uc.keys.VerificationKey)
+	if err != nil {
+		return entities.AccessToken{}, entities.ErrInvalidToken
+	}
+
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return entities.AccessToken{}, entities.ErrInvalidToken
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	revoked, err := uc.repo.AccessTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return entities.AccessToken{}, err
+	}
+
+	if revoked {
+		return entities.AccessToken{}, entities.ErrTokenRevoked
+	}
+
+	role := claims.Role
+	if role == "" {
+		role = entities.RoleUser
+	}
+
+	return entities.AccessToken{
+		ID:        claims.ID,
+		UserID:    userID,
+		Role:      role,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+// Отзывает токен доступа вместе со всем семейством токенов обновления, выпущенных с ним.
+func (uc *TokenUseCase) Revoke(ctx context.Context, token *entities.AccessToken) (err error) {
+	ctx, span := tracer.Start(ctx, "TokenUseCase.Revoke")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	return uc.repo.RevokeSession(ctx, token)
+}
+
+// Отзывает все токены пользователя, завершая все его сеансы.
+func (uc *TokenUseCase) RevokeUser(ctx context.Context, userID int64) (err error) {
+	ctx, span := tracer.Start(ctx, "TokenUseCase.RevokeUser")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	return uc.repo.RevokeUser(ctx, userID)
+}
+
+func (uc *TokenUseCase) issue(ctx context.Context, userID int64, role, family string) (entities.TokenPair, error) {
+	now := time.Now()
+	accessID := uuid.NewString()
+
+	signingKey, err := uc.keys.SigningKey()
+	if err != nil {
+		return entities.TokenPair{}, err
+	}
+
+	accessToken, err := utils.BuildJSWTString(signingKey, accessID, role, uc.accessTTL, userID)
+	if err != nil {
+		return entities.TokenPair{}, err
+	}
+
+	refreshToken, err := generateToken()
+	if err != nil {
+		return entities.TokenPair{}, err
+	}
+
+	token := entities.RefreshToken{
+		Hash:            hashToken(refreshToken),
+		UserID:          userID,
+		Family:          family,
+		AccessTokenID:   accessID,
+		AccessExpiresAt: now.Add(uc.accessTTL),
+		IssuedAt:        now,
+		ExpiresAt:       now.Add(uc.refreshTTL),
+	}
+
+	err = uc.repo.AddRefreshToken(ctx, &token)
+	if err != nil {
+		return entities.TokenPair{}, err
+	}
+
+	return entities.TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  token.AccessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: token.ExpiresAt,
+	}, nil
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, tokenBytes)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// В репозитории хранятся только хеши токенов обновления и сброса пароля,
+// поэтому утечка данных хранилища не позволяет воспользоваться токенами.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,225 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/repository/mocks"
+	"github.com/KryukovO/gophermart/internal/jwtkeys"
+	"github.com/KryukovO/gophermart/internal/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssue(t *testing.T) {
+	keys := jwtkeys.NewHMACKeySet([]byte("secret"))
+
+	var stored entities.RefreshToken
+
+	repo := mocks.NewMockTokenRepo(gomock.NewController(t))
+	repo.EXPECT().AddRefreshToken(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, token *entities.RefreshToken) error {
+			stored = *token
+
+			return nil
+		},
+	)
+
+	token := NewTokenUseCase(repo, keys, time.Minute, time.Hour, time.Second)
+
+	pair, err := token.Issue(context.Background(), 1, entities.RoleAdmin)
+	require.NoError(t, err)
+
+	var userID int64
+
+	claims, err := utils.ParseTokenString(&userID, pair.AccessToken, keys.VerificationKey)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), userID)
+	assert.Equal(t, entities.RoleAdmin, claims.Role)
+	assert.Equal(t, stored.AccessTokenID, claims.ID)
+	assert.Equal(t, int64(1), stored.UserID)
+	assert.NotEmpty(t, stored.Family)
+	assert.Equal(t, hashToken(pair.RefreshToken), stored.Hash)
+	assert.NotEqual(t, pair.RefreshToken, stored.Hash)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), pair.RefreshExpiresAt, time.Second)
+}
+
+func TestRefresh(t *testing.T) {
+	type wants struct {
+		err error
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockTokenRepo)
+		wants   wants
+	}{
+		{
+			name: "Token rotated within the family",
+			prepare: func(mock *mocks.MockTokenRepo) {
+				mock.EXPECT().UseRefreshToken(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, token *entities.RefreshToken) error {
+						assert.Equal(t, hashToken("refresh"), token.Hash)
+
+						token.UserID = 1
+						token.Family = "family"
+
+						return nil
+					},
+				)
+				mock.EXPECT().AddRefreshToken(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, token *entities.RefreshToken) error {
+						assert.Equal(t, "family", token.Family)
+						assert.Equal(t, int64(1), token.UserID)
+
+						return nil
+					},
+				)
+			},
+		},
+		{
+			name: "Reused token revokes the family",
+			prepare: func(mock *mocks.MockTokenRepo) {
+				mock.EXPECT().UseRefreshToken(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, token *entities.RefreshToken) error {
+						token.Family = "family"
+
+						return entities.ErrRefreshTokenReused
+					},
+				)
+				mock.EXPECT().RevokeFamily(gomock.Any(), "family").Return(nil)
+			},
+			wants: wants{
+				err: entities.ErrRefreshTokenReused,
+			},
+		},
+		{
+			name: "Invalid token",
+			prepare: func(mock *mocks.MockTokenRepo) {
+				mock.EXPECT().UseRefreshToken(gomock.Any(), gomock.Any()).Return(entities.ErrInvalidToken)
+			},
+			wants: wants{
+				err: entities.ErrInvalidToken,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockTokenRepo(gomock.NewController(t))
+		test.prepare(repo)
+
+		token := NewTokenUseCase(repo, jwtkeys.NewHMACKeySet([]byte("secret")), time.Minute, time.Hour, time.Second)
+
+		pair, err := token.Refresh(context.Background(), "refresh")
+		if test.wants.err != nil {
+			assert.ErrorIs(t, err, test.wants.err, test.name)
+		} else {
+			require.NoError(t, err, test.name)
+			assert.NotEmpty(t, pair.AccessToken, test.name)
+			assert.NotEqual(t, "refresh", pair.RefreshToken, test.name)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys := jwtkeys.NewHMACKeySet([]byte("secret"))
+
+	signingKey, err := keys.SigningKey()
+	require.NoError(t, err)
+
+	validToken, err := utils.BuildJSWTString(signingKey, "token-id", "", time.Minute, int64(1))
+	require.NoError(t, err)
+
+	expiredToken, err := utils.BuildJSWTString(signingKey, "token-id", "", -time.Minute, int64(1))
+	require.NoError(t, err)
+
+	noIDToken, err := utils.BuildJSWTString(signingKey, "", "", time.Minute, int64(1))
+	require.NoError(t, err)
+
+	adminToken, err := utils.BuildJSWTString(signingKey, "token-id", entities.RoleAdmin, time.Minute, int64(1))
+	require.NoError(t, err)
+
+	type wants struct {
+		userID int64
+		role   string
+		err    error
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		prepare func(mock *mocks.MockTokenRepo)
+		wants   wants
+	}{
+		{
+			name:  "Valid token",
+			token: validToken,
+			prepare: func(mock *mocks.MockTokenRepo) {
+				mock.EXPECT().AccessTokenRevoked(gomock.Any(), "token-id").Return(false, nil)
+			},
+			wants: wants{
+				userID: 1,
+				role:   entities.RoleUser,
+			},
+		},
+		{
+			name:  "Admin token",
+			token: adminToken,
+			prepare: func(mock *mocks.MockTokenRepo) {
+				mock.EXPECT().AccessTokenRevoked(gomock.Any(), "token-id").Return(false, nil)
+			},
+			wants: wants{
+				userID: 1,
+				role:   entities.RoleAdmin,
+			},
+		},
+		{
+			name:  "Revoked token",
+			token: validToken,
+			prepare: func(mock *mocks.MockTokenRepo) {
+				mock.EXPECT().AccessTokenRevoked(gomock.Any(), "token-id").Return(true, nil)
+			},
+			wants: wants{
+				err: entities.ErrTokenRevoked,
+			},
+		},
+		{
+			name:  "Expired token",
+			token: expiredToken,
+			wants: wants{
+				err: entities.ErrInvalidToken,
+			},
+		},
+		{
+			name:  "Token without ID",
+			token: noIDToken,
+			wants: wants{
+				err: entities.ErrInvalidToken,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockTokenRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		token := NewTokenUseCase(repo, keys, time.Minute, time.Hour, time.Second)
+
+		access, err := token.Authenticate(context.Background(), test.token)
+		if test.wants.err != nil {
+			assert.ErrorIs(t, err, test.wants.err, test.name)
+		} else {
+			require.NoError(t, err, test.name)
+			assert.Equal(t, test.wants.userID, access.UserID, test.name)
+			assert.Equal(t, test.wants.role, access.Role, test.name)
+			assert.Equal(t, "token-id", access.ID, test.name)
+		}
+	}
+}
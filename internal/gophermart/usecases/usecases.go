@@ -2,24 +2,84 @@ package usecases
 
 import (
 	"context"
+	"time"
 
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("github.com/KryukovO/gophermart/internal/gophermart/usecases")
+
 type User interface {
-	Register(ctx context.Context, user *entities.User, secret []byte) error
-	Login(ctx context.Context, user *entities.User, secret []byte) error
+	Register(ctx context.Context, user *entities.User) error
+	Login(ctx context.Context, user *entities.User, secret []byte, clientIP string) error
+	ChangePassword(ctx context.Context, userID int64, change *entities.PasswordChange, secret []byte) error
+	RequestPasswordReset(ctx context.Context, login string) error
+	ResetPassword(ctx context.Context, reset *entities.PasswordReset) error
+	Users(ctx context.Context, filter *entities.UserFilter) ([]entities.UserProfile, *entities.Cursor, error)
+	Profile(ctx context.Context, userID int64) (entities.UserProfile, error)
 }
 
 type Order interface {
 	AddOrder(ctx context.Context, order *entities.Order) error
-	Orders(ctx context.Context, userID int64) ([]entities.Order, error)
-	ProcessableOrders(ctx context.Context) ([]entities.Order, error)
-	UpdateOrder(ctx context.Context, order *entities.Order) error
+	Orders(ctx context.Context, filter *entities.OrderFilter) ([]entities.Order, *entities.Cursor, error)
+	ExportOrders(ctx context.Context, filter *entities.OrderFilter, yield func(entities.Order) error) error
+	ProcessableOrders(
+		ctx context.Context, instance string, limit uint, lease time.Duration,
+	) ([]entities.Order, error)
+	UpdateOrder(ctx context.Context, instance string, order *entities.Order) error
+	ProcessOrder(ctx context.Context, instance string, order *entities.Order) error
+	PendingOrders(ctx context.Context) (entities.PendingOrders, error)
 }
 
 type Balance interface {
 	Balance(ctx context.Context, userID int64) (entities.Balance, error)
 	ChangeBalance(ctx context.Context, change *entities.BalanceChange) error
-	Withdrawals(ctx context.Context, userID int64) ([]entities.BalanceChange, error)
+	Adjust(ctx context.Context, change *entities.BalanceChange) (entities.Balance, error)
+	ReverseWithdrawal(ctx context.Context, reversal *entities.BalanceChange) error
+	Withdrawals(
+		ctx context.Context, filter *entities.WithdrawalFilter,
+	) ([]entities.BalanceChange, *entities.Cursor, error)
+	Statement(
+		ctx context.Context, filter *entities.StatementFilter,
+	) ([]entities.StatementEntry, *entities.Cursor, error)
+	ExportStatement(
+		ctx context.Context, filter *entities.StatementFilter, yield func(entities.StatementEntry) error,
+	) error
+}
+
+type Token interface {
+	Issue(ctx context.Context, userID int64, role string) (entities.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (entities.TokenPair, error)
+	Authenticate(ctx context.Context, accessToken string) (entities.AccessToken, error)
+	Revoke(ctx context.Context, token *entities.AccessToken) error
+	RevokeUser(ctx context.Context, userID int64) error
+}
+
+type Stats interface {
+	Stats(ctx context.Context) (entities.Stats, error)
+}
+
+type Webhook interface {
+	AddWebhook(ctx context.Context, webhook *entities.Webhook) error
+	Webhooks(ctx context.Context, userID int64) ([]entities.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhook *entities.Webhook) error
+	Deliveries(
+		ctx context.Context, filter *entities.WebhookDeliveryFilter,
+	) ([]entities.WebhookDelivery, *entities.Cursor, error)
+	PendingDeliveries(
+		ctx context.Context, instance string, limit uint, lease time.Duration,
+	) ([]entities.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *entities.WebhookDelivery) error
+}
+
+// Удаляет устаревшие данные. Выполняется периодически в фоне.
+type Cleanup interface {
+	Cleanup(ctx context.Context) error
+}
+
+type Idempotency interface {
+	Begin(ctx context.Context, key *entities.IdempotencyKey) (entities.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, key *entities.IdempotencyKey) error
+	Abort(ctx context.Context, key *entities.IdempotencyKey) error
 }
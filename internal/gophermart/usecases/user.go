@@ -2,44 +2,371 @@ package usecases
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/logging"
+	"github.com/KryukovO/gophermart/internal/gophermart/notifier"
 	"github.com/KryukovO/gophermart/internal/gophermart/repository"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
+	"github.com/KryukovO/gophermart/internal/password"
+)
+
+const (
+	loginKeyPrefix = "login:"
+	ipKeyPrefix    = "ip:"
 )
 
 type UserUseCase struct {
-	repo    repository.UserRepo
-	timeout time.Duration
+	repo     repository.UserRepo
+	attempts repository.LoginAttemptRepo
+	hasher   password.Hasher
+	policy   entities.LoginPolicy
+	notifier notifier.Notifier
+	resetTTL time.Duration
+	timeout  time.Duration
+
+	dummyOnce sync.Once
+	dummyHash string // Хеш, с которым сравнивается пароль при входе с неизвестным логином
 }
 
-func NewUserUseCase(repo repository.UserRepo, timeout time.Duration) *UserUseCase {
+func NewUserUseCase(
+	repo repository.UserRepo, attempts repository.LoginAttemptRepo,
+	hasher password.Hasher, policy entities.LoginPolicy,
+	notifier notifier.Notifier, resetTTL time.Duration,
+	timeout time.Duration,
+) *UserUseCase {
 	return &UserUseCase{
-		repo:    repo,
-		timeout: timeout,
+		repo:     repo,
+		attempts: attempts,
+		hasher:   hasher,
+		policy:   policy,
+		notifier: notifier,
+		resetTTL: resetTTL,
+		timeout:  timeout,
 	}
 }
 
-func (uc *UserUseCase) Register(ctx context.Context, user *entities.User, secret []byte) error {
+func (uc *UserUseCase) Register(ctx context.Context, user *entities.User) (err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.Register")
+	defer tracing.End(span, &err)
+
 	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
 	defer cancel()
 
-	err := user.Encrypt(secret)
+	err = user.Encrypt(uc.hasher)
 	if err != nil {
 		return err
 	}
 
+	user.Role = entities.RoleUser
+
 	return uc.repo.AddUser(ctx, user)
 }
 
-func (uc *UserUseCase) Login(ctx context.Context, user *entities.User, secret []byte) error {
+// Проверяет пароль пользователя. secret используется для проверки паролей,
+// сохранённых в устаревшем формате HMAC-SHA256.
+// После успешной проверки хеш, полученный устаревшим алгоритмом или с устаревшими
+// параметрами, пересчитывается и сохраняется в текущем формате.
+// Неудачные попытки учитываются по логину и по IP-адресу клиента clientIP;
+// пока вход заблокирован, возвращается LoginLockedError без проверки пароля.
+// Попытка резервируется до проверки пароля, поэтому параллельные попытки не обходят
+// задержку и блокировку; после успешного входа резервирование отменяется.
+// Для неизвестного логина пароль проверяется по фиктивному хешу.
+func (uc *UserUseCase) Login(ctx context.Context, user *entities.User, secret []byte, clientIP string) (err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.Login")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	attempts, err := uc.reserveAttempts(ctx, user.Login, clientIP)
+	if err != nil {
+		return err
+	}
+
+	err = uc.repo.User(ctx, user)
+	if errors.Is(err, entities.ErrInvalidLoginPassword) {
+		uc.verifyDummy(ctx, user.Password)
+	}
+
+	if err == nil {
+		var rehash bool
+
+		rehash, err = user.Validate(uc.hasher, secret)
+		if err == nil && rehash {
+			// Пароль уже проверен, поэтому ошибка пересчёта хеша не должна препятствовать входу:
+			// хеш будет пересчитан при следующем успешном входе.
+			rehashErr := user.Encrypt(uc.hasher)
+			if rehashErr == nil {
+				rehashErr = uc.repo.UpdatePassword(ctx, user)
+			}
+
+			if rehashErr != nil {
+				logging.FromContext(ctx, nil).Warnf("Unable to rehash password: %s", rehashErr)
+			}
+		}
+	}
+
+	if err != nil {
+		// Попытка с неверным паролем уже учтена как неудачная,
+		// а попытка, прерванная внутренней ошибкой, неудачной не считается
+		if !errors.Is(err, entities.ErrInvalidLoginPassword) {
+			uc.releaseAttempts(ctx, attempts)
+		}
+
+		return err
+	}
+
+	// Счётчик по IP-адресу не сбрасывается, чтобы успешный вход в свою учётную запись
+	// не позволял продолжить перебор паролей к чужим: отменяется только текущая попытка
+	for i := range attempts {
+		if strings.HasPrefix(attempts[i].Key, loginKeyPrefix) {
+			err = uc.attempts.ResetLoginFailures(ctx, attempts[i].Key)
+		} else {
+			err = uc.attempts.ReleaseLoginAttempt(ctx, &attempts[i])
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Проверяет пароль по фиктивному хешу, полученному текущим алгоритмом хеширования.
+// Вызывается для неизвестного логина, чтобы время ответа на вход не зависело
+// от существования учётной записи и не позволяло подбирать логины.
+func (uc *UserUseCase) verifyDummy(ctx context.Context, pswd string) {
+	uc.dummyOnce.Do(func() {
+		hash, err := uc.hasher.Hash("dummy password")
+		if err != nil {
+			logging.FromContext(ctx, nil).Warnf("Unable to create dummy password hash: %s", err)
+
+			return
+		}
+
+		uc.dummyHash = hash
+	})
+
+	if uc.dummyHash != "" {
+		_ = uc.hasher.Verify(pswd, uc.dummyHash)
+	}
+}
+
+// Меняет пароль пользователя userID после проверки текущего пароля
+// и отзывает все сессии пользователя.
+// Возвращает ErrInvalidLoginPassword, если текущий пароль неверен.
+// secret используется для проверки паролей, сохранённых в устаревшем формате HMAC-SHA256.
+func (uc *UserUseCase) ChangePassword(
+	ctx context.Context, userID int64, change *entities.PasswordChange, secret []byte,
+) (err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.ChangePassword")
+	defer tracing.End(span, &err)
+
 	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
 	defer cancel()
 
-	err := uc.repo.User(ctx, user)
+	user := entities.User{ID: userID}
+
+	err = uc.repo.UserByID(ctx, &user)
+	if err != nil {
+		return err
+	}
+
+	user.Password = change.OldPassword
+
+	_, err = user.Validate(uc.hasher, secret)
 	if err != nil {
 		return err
 	}
 
-	return user.Validate(secret)
+	user.Password = change.NewPassword
+
+	err = user.Encrypt(uc.hasher)
+	if err != nil {
+		return err
+	}
+
+	return uc.repo.ChangePassword(ctx, &user)
+}
+
+// Выпускает одноразовый токен сброса пароля и передаёт его пользователю через notifier.
+// Чтобы запрос не позволял определить существование учётной записи,
+// для неизвестного логина ошибка не возвращается.
+func (uc *UserUseCase) RequestPasswordReset(ctx context.Context, login string) (err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.RequestPasswordReset")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	user := entities.User{Login: login}
+
+	err = uc.repo.User(ctx, &user)
+	if err != nil {
+		if errors.Is(err, entities.ErrInvalidLoginPassword) {
+			return nil
+		}
+
+		return err
+	}
+
+	resetToken, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	token := entities.ResetToken{
+		Hash:      hashToken(resetToken),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(uc.resetTTL),
+	}
+
+	err = uc.repo.AddResetToken(ctx, &token)
+	if err != nil {
+		return err
+	}
+
+	return uc.notifier.PasswordReset(ctx, user, resetToken, token.ExpiresAt)
+}
+
+// Устанавливает новый пароль по токену сброса пароля.
+// Возвращает ErrInvalidResetToken, если токен не найден, истёк или уже был использован.
+// Все сессии пользователя отзываются, а блокировка входа по его логину снимается.
+func (uc *UserUseCase) ResetPassword(ctx context.Context, reset *entities.PasswordReset) (err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.ResetPassword")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	user := entities.User{Password: reset.NewPassword}
+
+	err = user.Encrypt(uc.hasher)
+	if err != nil {
+		return err
+	}
+
+	// Токен расходуется в одной транзакции со сменой пароля,
+	// чтобы ошибка сохранения пароля не делала токен недействительным
+	token := entities.ResetToken{Hash: hashToken(reset.Token)}
+
+	err = uc.repo.ResetPassword(ctx, &token, &user)
+	if err != nil {
+		return err
+	}
+
+	// Пароль уже изменён, поэтому ошибка снятия блокировки не должна приводить к ошибке сброса:
+	// блокировка в любом случае снимется по истечении своего срока.
+	err = uc.attempts.ResetLoginFailures(ctx, loginKeyPrefix+user.Login)
+	if err != nil {
+		logging.FromContext(ctx, nil).Warnf("Unable to reset failed login attempts: %s", err)
+	}
+
+	return nil
+}
+
+// Возвращает страницу пользователей, логин которых содержит filter.Login, и курсор следующей страницы.
+// Курсор равен nil, если страница последняя.
+func (uc *UserUseCase) Users(
+	ctx context.Context, filter *entities.UserFilter,
+) (_ []entities.UserProfile, _ *entities.Cursor, err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.Users")
+	defer tracing.End(span, &err)
+
+	if err := filter.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	query := *filter
+	query.Limit++
+
+	users, err := uc.repo.Users(ctx, &query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if uint(len(users)) <= filter.Limit {
+		return users, nil, nil
+	}
+
+	users = users[:filter.Limit]
+
+	return users, &entities.Cursor{ID: users[len(users)-1].ID}, nil
+}
+
+// Возвращает данные учётной записи пользователя.
+// Возвращает ErrUserNotFound, если пользователь не найден.
+func (uc *UserUseCase) Profile(ctx context.Context, userID int64) (_ entities.UserProfile, err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.Profile")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	user := entities.User{ID: userID}
+
+	err = uc.repo.UserByID(ctx, &user)
+	if err != nil {
+		return entities.UserProfile{}, err
+	}
+
+	return entities.UserProfile{ID: user.ID, Login: user.Login, Role: user.Role}, nil
+}
+
+// Удаляет устаревшие записи о неудачных попытках входа.
+func (uc *UserUseCase) Cleanup(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.Cleanup")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	return uc.attempts.DeleteStaleLoginAttempts(ctx, uc.policy.Lockout)
+}
+
+// Резервирует попытки входа по логину и по IP-адресу клиента clientIP.
+// Если вход по одному из ключей заблокирован, уже зарезервированные попытки отменяются.
+func (uc *UserUseCase) reserveAttempts(
+	ctx context.Context, login, clientIP string,
+) ([]entities.LoginAttempt, error) {
+	attempts := []entities.LoginAttempt{
+		{Key: loginKeyPrefix + login, MaxAttempts: uc.policy.MaxAttempts},
+	}
+
+	if clientIP != "" {
+		attempts = append(attempts, entities.LoginAttempt{
+			Key:         ipKeyPrefix + clientIP,
+			MaxAttempts: uc.policy.MaxAttemptsPerIP,
+		})
+	}
+
+	for i := range attempts {
+		err := uc.attempts.ReserveLoginAttempt(ctx, &attempts[i], uc.policy)
+		if err != nil {
+			uc.releaseAttempts(ctx, attempts[:i])
+
+			return nil, err
+		}
+	}
+
+	return attempts, nil
+}
+
+// Отменяет зарезервированные попытки входа. Ошибка отмены не возвращается:
+// неотменённая попытка лишь учитывается как неудачная.
+func (uc *UserUseCase) releaseAttempts(ctx context.Context, attempts []entities.LoginAttempt) {
+	for i := range attempts {
+		if err := uc.attempts.ReleaseLoginAttempt(ctx, &attempts[i]); err != nil {
+			logging.FromContext(ctx, nil).Warnf("Unable to release login attempt: %s", err)
+		}
+	}
 }
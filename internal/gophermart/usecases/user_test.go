@@ -2,13 +2,21 @@ package usecases
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	notifiermocks "github.com/KryukovO/gophermart/internal/gophermart/notifier/mocks"
 	"github.com/KryukovO/gophermart/internal/gophermart/repository/mocks"
+	"github.com/KryukovO/gophermart/internal/password"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestRegister(t *testing.T) {
@@ -17,7 +25,7 @@ func TestRegister(t *testing.T) {
 			Login:    "user1",
 			Password: "1234",
 		}
-		secret = []byte("secret")
+		hasher = password.NewBcryptHasher(bcrypt.MinCost)
 	)
 
 	type args struct {
@@ -59,28 +67,39 @@ func TestRegister(t *testing.T) {
 			test.prepare(repo)
 		}
 
-		user := NewUserUseCase(repo, time.Minute)
+		attempts := mocks.NewMockLoginAttemptRepo(gomock.NewController(t))
+		user := NewUserUseCase(repo, attempts, hasher, entities.LoginPolicy{}, nil, time.Hour, time.Minute)
 
-		err := user.Register(context.Background(), test.args.user, secret)
+		err := user.Register(context.Background(), test.args.user)
 		if test.wantErr {
 			assert.Error(t, err)
 		} else {
 			assert.NoError(t, err)
+			assert.Equal(t, password.AlgorithmBcrypt, password.Algorithm(test.args.user.EncryptedPassword))
+			assert.Equal(t, entities.RoleUser, test.args.user.Role)
 		}
 	}
 }
 
 func TestLogin(t *testing.T) {
 	var (
-		user1 = entities.User{
-			Login:    "user1",
-			Password: "1234",
-		}
-		secret = []byte("secret")
+		secret    = []byte("secret")
+		hasher    = password.NewBcryptHasher(bcrypt.MinCost)
+		oldHasher = password.NewBcryptHasher(bcrypt.MinCost + 1)
 	)
 
+	hash, err := hasher.Hash("1234")
+	assert.NoError(t, err)
+
+	oldHash, err := oldHasher.Hash("1234")
+	assert.NoError(t, err)
+
+	enc := hmac.New(sha256.New, secret)
+	enc.Write([]byte("1234" + "salt"))
+	legacyHash := hex.EncodeToString(enc.Sum(nil))
+
 	type args struct {
-		user *entities.User
+		password string
 	}
 
 	type wants struct {
@@ -96,22 +115,131 @@ func TestLogin(t *testing.T) {
 		{
 			name: "Correct login",
 			prepare: func(mock *mocks.MockUserRepo) {
-				mock.EXPECT().User(gomock.Any(), gomock.Any()).Return(nil)
+				mock.EXPECT().User(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, user *entities.User) error {
+						user.EncryptedPassword = hash
+
+						return nil
+					},
+				)
 			},
 			args: args{
-				user: &user1,
+				password: "1234",
 			},
 			wants: wants{
 				wantErr: false,
 			},
 		},
+		{
+			name: "Correct login with legacy hash",
+			prepare: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().User(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, user *entities.User) error {
+						user.EncryptedPassword = legacyHash
+						user.Salt = "salt"
+
+						return nil
+					},
+				)
+				mock.EXPECT().UpdatePassword(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, user *entities.User) error {
+						assert.Equal(t, password.AlgorithmBcrypt, password.Algorithm(user.EncryptedPassword))
+						assert.Empty(t, user.Salt)
+
+						return nil
+					},
+				)
+			},
+			args: args{
+				password: "1234",
+			},
+			wants: wants{
+				wantErr: false,
+			},
+		},
+		{
+			name: "Correct login with outdated parameters",
+			prepare: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().User(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, user *entities.User) error {
+						user.EncryptedPassword = oldHash
+
+						return nil
+					},
+				)
+				mock.EXPECT().UpdatePassword(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			args: args{
+				password: "1234",
+			},
+			wants: wants{
+				wantErr: false,
+			},
+		},
+		{
+			name: "Rehash error",
+			prepare: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().User(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, user *entities.User) error {
+						user.EncryptedPassword = legacyHash
+						user.Salt = "salt"
+
+						return nil
+					},
+				)
+				mock.EXPECT().UpdatePassword(gomock.Any(), gomock.Any()).Return(context.DeadlineExceeded)
+			},
+			args: args{
+				password: "1234",
+			},
+			wants: wants{
+				wantErr: false,
+			},
+		},
+		{
+			name: "Wrong password",
+			prepare: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().User(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, user *entities.User) error {
+						user.EncryptedPassword = hash
+
+						return nil
+					},
+				)
+			},
+			args: args{
+				password: "4321",
+			},
+			wants: wants{
+				wantErr: true,
+			},
+		},
+		{
+			name: "Wrong password with legacy hash",
+			prepare: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().User(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, user *entities.User) error {
+						user.EncryptedPassword = legacyHash
+						user.Salt = "salt"
+
+						return nil
+					},
+				)
+			},
+			args: args{
+				password: "4321",
+			},
+			wants: wants{
+				wantErr: true,
+			},
+		},
 		{
 			name: "Invalid login/password",
 			prepare: func(mock *mocks.MockUserRepo) {
 				mock.EXPECT().User(gomock.Any(), gomock.Any()).Return(entities.ErrInvalidLoginPassword)
 			},
 			args: args{
-				user: &user1,
+				password: "1234",
 			},
 			wants: wants{
 				wantErr: true,
@@ -126,13 +254,522 @@ func TestLogin(t *testing.T) {
 			test.prepare(repo)
 		}
 
-		user := NewUserUseCase(repo, time.Minute)
+		attempts := mocks.NewMockLoginAttemptRepo(gomock.NewController(t))
+		attempts.EXPECT().ReserveLoginAttempt(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		attempts.EXPECT().ReleaseLoginAttempt(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		attempts.EXPECT().ResetLoginFailures(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
-		err := user.Login(context.Background(), test.args.user, secret)
+		user := NewUserUseCase(repo, attempts, hasher, testLoginPolicy, nil, time.Hour, time.Minute)
+
+		err := user.Login(
+			context.Background(),
+			&entities.User{Login: "user1", Password: test.args.password},
+			secret, "192.0.2.1",
+		)
 		if test.wants.wantErr {
-			assert.Error(t, err)
+			assert.ErrorIs(t, err, entities.ErrInvalidLoginPassword, test.name)
 		} else {
-			assert.NoError(t, err)
+			assert.NoError(t, err, test.name)
+		}
+	}
+}
+
+// Хешер, подсчитывающий количество проверок паролей.
+type countingHasher struct {
+	password.Hasher
+	verified int
+}
+
+func (h *countingHasher) Verify(pswd, hash string) error {
+	h.verified++
+
+	return h.Hasher.Verify(pswd, hash)
+}
+
+func TestLoginUnknownUser(t *testing.T) {
+	hasher := &countingHasher{Hasher: password.NewBcryptHasher(bcrypt.MinCost)}
+
+	repo := mocks.NewMockUserRepo(gomock.NewController(t))
+	repo.EXPECT().User(gomock.Any(), gomock.Any()).Return(entities.ErrInvalidLoginPassword).Times(2)
+
+	attempts := mocks.NewMockLoginAttemptRepo(gomock.NewController(t))
+	attempts.EXPECT().ReserveLoginAttempt(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	user := NewUserUseCase(repo, attempts, hasher, testLoginPolicy, nil, time.Hour, time.Minute)
+
+	for i := 1; i <= 2; i++ {
+		err := user.Login(
+			context.Background(),
+			&entities.User{Login: "unknown", Password: "1234"},
+			[]byte("secret"), "192.0.2.1",
+		)
+		assert.ErrorIs(t, err, entities.ErrInvalidLoginPassword)
+		// Пароль проверяется так же, как для существующего пользователя
+		assert.Equal(t, i, hasher.verified)
+	}
+}
+
+var testLoginPolicy = entities.LoginPolicy{
+	MaxAttempts:      3,
+	MaxAttemptsPerIP: 10,
+	Delay:            time.Second,
+	Lockout:          time.Hour,
+}
+
+func TestLoginAttempts(t *testing.T) {
+	hasher := password.NewBcryptHasher(bcrypt.MinCost)
+
+	hash, err := hasher.Hash("1234")
+	require.NoError(t, err)
+
+	storedUser := func(_ context.Context, user *entities.User) error {
+		user.ID = 1
+		user.EncryptedPassword = hash
+
+		return nil
+	}
+
+	type args struct {
+		password string
+		clientIP string
+	}
+
+	type wants struct {
+		err        error
+		retryAfter time.Duration
+	}
+
+	loginAttempt := mocks.LoginAttemptMatcher("login:user1")
+	ipAttempt := mocks.LoginAttemptMatcher("ip:192.0.2.1")
+	loginLockedUntil := time.Now().Add(time.Second)
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockUserRepo, attempts *mocks.MockLoginAttemptRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "Locked login",
+			prepare: func(mock *mocks.MockUserRepo, attempts *mocks.MockLoginAttemptRepo) {
+				attempts.EXPECT().ReserveLoginAttempt(gomock.Any(), loginAttempt, testLoginPolicy).
+					Return(&entities.LoginLockedError{RetryAfter: time.Minute})
+			},
+			args: args{
+				password: "1234",
+				clientIP: "192.0.2.1",
+			},
+			wants: wants{
+				err:        entities.ErrLoginLocked,
+				retryAfter: time.Minute,
+			},
+		},
+		{
+			name: "Locked IP",
+			prepare: func(mock *mocks.MockUserRepo, attempts *mocks.MockLoginAttemptRepo) {
+				attempts.EXPECT().ReserveLoginAttempt(gomock.Any(), loginAttempt, testLoginPolicy).DoAndReturn(
+					func(_ context.Context, attempt *entities.LoginAttempt, _ entities.LoginPolicy) error {
+						assert.Equal(t, testLoginPolicy.MaxAttempts, attempt.MaxAttempts)
+
+						attempt.LockedUntil = loginLockedUntil
+
+						return nil
+					},
+				)
+				attempts.EXPECT().ReserveLoginAttempt(gomock.Any(), ipAttempt, testLoginPolicy).
+					Return(&entities.LoginLockedError{RetryAfter: time.Hour})
+				attempts.EXPECT().ReleaseLoginAttempt(gomock.Any(), loginAttempt).DoAndReturn(
+					func(_ context.Context, attempt *entities.LoginAttempt) error {
+						assert.Equal(t, loginLockedUntil, attempt.LockedUntil)
+
+						return nil
+					},
+				)
+			},
+			args: args{
+				password: "1234",
+				clientIP: "192.0.2.1",
+			},
+			wants: wants{
+				err:        entities.ErrLoginLocked,
+				retryAfter: time.Hour,
+			},
+		},
+		{
+			name: "Wrong password is counted",
+			prepare: func(mock *mocks.MockUserRepo, attempts *mocks.MockLoginAttemptRepo) {
+				attempts.EXPECT().ReserveLoginAttempt(gomock.Any(), loginAttempt, testLoginPolicy).Return(nil)
+				attempts.EXPECT().ReserveLoginAttempt(gomock.Any(), ipAttempt, testLoginPolicy).DoAndReturn(
+					func(_ context.Context, attempt *entities.LoginAttempt, _ entities.LoginPolicy) error {
+						assert.Equal(t, testLoginPolicy.MaxAttemptsPerIP, attempt.MaxAttempts)
+
+						return nil
+					},
+				)
+				mock.EXPECT().User(gomock.Any(), gomock.Any()).DoAndReturn(storedUser)
+			},
+			args: args{
+				password: "4321",
+				clientIP: "192.0.2.1",
+			},
+			wants: wants{
+				err: entities.ErrInvalidLoginPassword,
+			},
+		},
+		{
+			name: "Internal error is not counted",
+			prepare: func(mock *mocks.MockUserRepo, attempts *mocks.MockLoginAttemptRepo) {
+				attempts.EXPECT().ReserveLoginAttempt(gomock.Any(), gomock.Any(), testLoginPolicy).Return(nil).Times(2)
+				mock.EXPECT().User(gomock.Any(), gomock.Any()).Return(context.DeadlineExceeded)
+				attempts.EXPECT().ReleaseLoginAttempt(gomock.Any(), loginAttempt).Return(nil)
+				attempts.EXPECT().ReleaseLoginAttempt(gomock.Any(), ipAttempt).Return(nil)
+			},
+			args: args{
+				password: "1234",
+				clientIP: "192.0.2.1",
+			},
+			wants: wants{
+				err: context.DeadlineExceeded,
+			},
+		},
+		{
+			name: "Success resets login failures only",
+			prepare: func(mock *mocks.MockUserRepo, attempts *mocks.MockLoginAttemptRepo) {
+				attempts.EXPECT().ReserveLoginAttempt(gomock.Any(), gomock.Any(), testLoginPolicy).Return(nil).Times(2)
+				mock.EXPECT().User(gomock.Any(), gomock.Any()).DoAndReturn(storedUser)
+				attempts.EXPECT().ResetLoginFailures(gomock.Any(), "login:user1").Return(nil)
+				attempts.EXPECT().ReleaseLoginAttempt(gomock.Any(), ipAttempt).Return(nil)
+			},
+			args: args{
+				password: "1234",
+				clientIP: "192.0.2.1",
+			},
+		},
+		{
+			name: "Success without client IP",
+			prepare: func(mock *mocks.MockUserRepo, attempts *mocks.MockLoginAttemptRepo) {
+				attempts.EXPECT().ReserveLoginAttempt(gomock.Any(), loginAttempt, testLoginPolicy).Return(nil)
+				mock.EXPECT().User(gomock.Any(), gomock.Any()).DoAndReturn(storedUser)
+				attempts.EXPECT().ResetLoginFailures(gomock.Any(), "login:user1").Return(nil)
+			},
+			args: args{
+				password: "1234",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ctr := gomock.NewController(t)
+		repo := mocks.NewMockUserRepo(ctr)
+		attempts := mocks.NewMockLoginAttemptRepo(ctr)
+
+		if test.prepare != nil {
+			test.prepare(repo, attempts)
+		}
+
+		user := NewUserUseCase(repo, attempts, hasher, testLoginPolicy, nil, time.Hour, time.Minute)
+
+		err := user.Login(
+			context.Background(),
+			&entities.User{Login: "user1", Password: test.args.password},
+			nil, test.args.clientIP,
+		)
+		if test.wants.err == nil {
+			assert.NoError(t, err, test.name)
+
+			continue
+		}
+
+		assert.ErrorIs(t, err, test.wants.err, test.name)
+
+		var lockedErr *entities.LoginLockedError
+		if errors.As(err, &lockedErr) {
+			assert.InDelta(t, test.wants.retryAfter, lockedErr.RetryAfter, float64(time.Second), test.name)
 		}
 	}
 }
+
+func TestChangePassword(t *testing.T) {
+	var (
+		secret = []byte("secret")
+		hasher = password.NewBcryptHasher(bcrypt.MinCost)
+	)
+
+	enc := hmac.New(sha256.New, secret)
+	enc.Write([]byte("1234" + "salt"))
+	legacyHash := hex.EncodeToString(enc.Sum(nil))
+
+	type args struct {
+		oldPassword string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockUserRepo)
+		args    args
+		wantErr error
+	}{
+		{
+			name: "Correct change of legacy password",
+			prepare: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().UserByID(gomock.Any(), &entities.User{ID: 1}).DoAndReturn(
+					func(_ context.Context, user *entities.User) error {
+						user.Login = "user1"
+						user.EncryptedPassword = legacyHash
+						user.Salt = "salt"
+
+						return nil
+					},
+				)
+				mock.EXPECT().ChangePassword(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, user *entities.User) error {
+						assert.Equal(t, int64(1), user.ID)
+						assert.Empty(t, user.Salt)
+						assert.NoError(t, hasher.Verify("4321", user.EncryptedPassword))
+
+						return nil
+					},
+				)
+			},
+			args: args{
+				oldPassword: "1234",
+			},
+		},
+		{
+			name: "Wrong old password",
+			prepare: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().UserByID(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, user *entities.User) error {
+						user.EncryptedPassword = legacyHash
+						user.Salt = "salt"
+
+						return nil
+					},
+				)
+			},
+			args: args{
+				oldPassword: "0000",
+			},
+			wantErr: entities.ErrInvalidLoginPassword,
+		},
+		{
+			name: "User not found",
+			prepare: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().UserByID(gomock.Any(), gomock.Any()).Return(entities.ErrUserNotFound)
+			},
+			args: args{
+				oldPassword: "1234",
+			},
+			wantErr: entities.ErrUserNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		ctr := gomock.NewController(t)
+		repo := mocks.NewMockUserRepo(ctr)
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		user := NewUserUseCase(
+			repo, mocks.NewMockLoginAttemptRepo(ctr), hasher, testLoginPolicy, nil, time.Hour, time.Minute,
+		)
+
+		err := user.ChangePassword(
+			context.Background(), 1,
+			&entities.PasswordChange{OldPassword: test.args.oldPassword, NewPassword: "4321"},
+			secret,
+		)
+		if test.wantErr != nil {
+			assert.ErrorIs(t, err, test.wantErr, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+		}
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctr := gomock.NewController(t)
+	repo := mocks.NewMockUserRepo(ctr)
+	attempts := mocks.NewMockLoginAttemptRepo(ctr)
+	notifier := notifiermocks.NewMockNotifier(ctr)
+	hasher := password.NewBcryptHasher(bcrypt.MinCost)
+
+	user := NewUserUseCase(repo, attempts, hasher, testLoginPolicy, notifier, time.Hour, time.Minute)
+
+	var (
+		sentToken   string
+		storedToken entities.ResetToken
+	)
+
+	repo.EXPECT().User(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, user *entities.User) error {
+			user.ID = 1
+
+			return nil
+		},
+	)
+	repo.EXPECT().AddResetToken(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, token *entities.ResetToken) error {
+			storedToken = *token
+
+			return nil
+		},
+	)
+	notifier.EXPECT().PasswordReset(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, user entities.User, token string, expiresAt time.Time) error {
+			assert.Equal(t, "user1", user.Login)
+			assert.Equal(t, storedToken.ExpiresAt, expiresAt)
+
+			sentToken = token
+
+			return nil
+		},
+	)
+
+	require.NoError(t, user.RequestPasswordReset(context.Background(), "user1"))
+
+	// В хранилище попадает только хеш токена
+	assert.Equal(t, int64(1), storedToken.UserID)
+	assert.Equal(t, hashToken(sentToken), storedToken.Hash)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), storedToken.ExpiresAt, time.Second)
+
+	repo.EXPECT().ResetPassword(gomock.Any(), &entities.ResetToken{Hash: storedToken.Hash}, gomock.Any()).DoAndReturn(
+		func(_ context.Context, token *entities.ResetToken, user *entities.User) error {
+			// Новый пароль зашифрован до обращения к хранилищу
+			assert.NoError(t, hasher.Verify("4321", user.EncryptedPassword))
+
+			*token = storedToken
+			user.ID = storedToken.UserID
+			user.Login = "user1"
+
+			return nil
+		},
+	)
+	attempts.EXPECT().ResetLoginFailures(gomock.Any(), "login:user1").Return(nil)
+
+	err := user.ResetPassword(
+		context.Background(), &entities.PasswordReset{Token: sentToken, NewPassword: "4321"},
+	)
+	require.NoError(t, err)
+
+	repo.EXPECT().ResetPassword(gomock.Any(), gomock.Any(), gomock.Any()).Return(entities.ErrInvalidResetToken)
+
+	err = user.ResetPassword(
+		context.Background(), &entities.PasswordReset{Token: sentToken, NewPassword: "4321"},
+	)
+	assert.ErrorIs(t, err, entities.ErrInvalidResetToken)
+
+	// Для неизвестного логина токен не выпускается, но ошибка не возвращается
+	repo.EXPECT().User(gomock.Any(), gomock.Any()).Return(entities.ErrInvalidLoginPassword)
+
+	assert.NoError(t, user.RequestPasswordReset(context.Background(), "user2"))
+}
+
+func TestUsers(t *testing.T) {
+	user1 := entities.UserProfile{ID: 1, Login: "user1", Role: entities.RoleAdmin}
+	user2 := entities.UserProfile{ID: 2, Login: "user2", Role: entities.RoleUser}
+
+	type wants struct {
+		expected []entities.UserProfile
+		next     *entities.Cursor
+		wantErr  bool
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockUserRepo)
+		filter  entities.UserFilter
+		wants   wants
+	}{
+		{
+			name: "User list",
+			prepare: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().Users(gomock.Any(), gomock.Any()).Return([]entities.UserProfile{user1, user2}, nil)
+			},
+			filter: entities.UserFilter{Login: "user"},
+			wants: wants{
+				expected: []entities.UserProfile{user1, user2},
+			},
+		},
+		{
+			name: "Next page exists",
+			prepare: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().Users(gomock.Any(), gomock.Any()).Return([]entities.UserProfile{user1, user2}, nil)
+			},
+			filter: entities.UserFilter{Page: entities.Page{Limit: 1}},
+			wants: wants{
+				expected: []entities.UserProfile{user1},
+				next:     &entities.Cursor{ID: user1.ID},
+			},
+		},
+		{
+			name: "Cursor time is ignored",
+			prepare: func(mock *mocks.MockUserRepo) {
+				mock.EXPECT().
+					Users(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, filter *entities.UserFilter) ([]entities.UserProfile, error) {
+						assert.Equal(t, &entities.Cursor{ID: user1.ID}, filter.Cursor)
+
+						return []entities.UserProfile{user2}, nil
+					})
+			},
+			filter: entities.UserFilter{Page: entities.Page{Cursor: &entities.Cursor{Time: time.Now(), ID: user1.ID}}},
+			wants: wants{
+				expected: []entities.UserProfile{user2},
+			},
+		},
+		{
+			name:   "Invalid page limit",
+			filter: entities.UserFilter{Page: entities.Page{Limit: entities.MaxPageLimit + 1}},
+			wants: wants{
+				wantErr: true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockUserRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		attempts := mocks.NewMockLoginAttemptRepo(gomock.NewController(t))
+		user := NewUserUseCase(repo, attempts, nil, entities.LoginPolicy{}, nil, time.Hour, time.Minute)
+
+		users, next, err := user.Users(context.Background(), &test.filter)
+		if test.wants.wantErr {
+			assert.Error(t, err, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+			assert.Equal(t, test.wants.expected, users, test.name)
+			assert.Equal(t, test.wants.next, next, test.name)
+		}
+	}
+}
+
+func TestProfile(t *testing.T) {
+	repo := mocks.NewMockUserRepo(gomock.NewController(t))
+	repo.EXPECT().
+		UserByID(gomock.Any(), &entities.User{ID: 1}).
+		DoAndReturn(func(_ context.Context, user *entities.User) error {
+			user.Login = "user1"
+			user.EncryptedPassword = "hash"
+			user.Role = entities.RoleAdmin
+
+			return nil
+		})
+	repo.EXPECT().UserByID(gomock.Any(), &entities.User{ID: 2}).Return(entities.ErrUserNotFound)
+
+	attempts := mocks.NewMockLoginAttemptRepo(gomock.NewController(t))
+	user := NewUserUseCase(repo, attempts, nil, entities.LoginPolicy{}, nil, time.Hour, time.Minute)
+
+	profile, err := user.Profile(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, entities.UserProfile{ID: 1, Login: "user1", Role: entities.RoleAdmin}, profile)
+
+	_, err = user.Profile(context.Background(), 2)
+	assert.ErrorIs(t, err, entities.ErrUserNotFound)
+}
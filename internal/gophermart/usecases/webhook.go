@@ -0,0 +1,121 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/repository"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
+)
+
+type WebhookUseCase struct {
+	repo    repository.WebhookRepo
+	timeout time.Duration
+}
+
+func NewWebhookUseCase(repo repository.WebhookRepo, timeout time.Duration) *WebhookUseCase {
+	return &WebhookUseCase{
+		repo:    repo,
+		timeout: timeout,
+	}
+}
+
+// Регистрирует подписку пользователя на уведомления о событиях.
+// Возвращает ErrInvalidWebhook, если адрес или секрет подписки некорректны.
+func (uc *WebhookUseCase) AddWebhook(ctx context.Context, webhook *entities.Webhook) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookUseCase.AddWebhook")
+	defer tracing.End(span, &err)
+
+	if err := webhook.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	return uc.repo.AddWebhook(ctx, webhook)
+}
+
+func (uc *WebhookUseCase) Webhooks(ctx context.Context, userID int64) (_ []entities.Webhook, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookUseCase.Webhooks")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	return uc.repo.Webhooks(ctx, userID)
+}
+
+// Удаляет подписку пользователя вместе с журналом её доставки.
+// Возвращает ErrWebhookNotFound, если у пользователя нет такой подписки.
+func (uc *WebhookUseCase) DeleteWebhook(ctx context.Context, webhook *entities.Webhook) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookUseCase.DeleteWebhook")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	return uc.repo.DeleteWebhook(ctx, webhook)
+}
+
+// Возвращает страницу журнала доставки уведомлений подписки пользователя и курсор следующей страницы.
+// Курсор равен nil, если страница последняя.
+// Возвращает ErrWebhookNotFound, если у пользователя нет такой подписки.
+func (uc *WebhookUseCase) Deliveries(
+	ctx context.Context, filter *entities.WebhookDeliveryFilter,
+) (_ []entities.WebhookDelivery, _ *entities.Cursor, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookUseCase.Deliveries")
+	defer tracing.End(span, &err)
+
+	if err := filter.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	err = uc.repo.Webhook(ctx, &entities.Webhook{ID: filter.WebhookID, UserID: filter.UserID})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := *filter
+	query.Limit++
+
+	deliveries, err := uc.repo.WebhookDeliveries(ctx, &query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if uint(len(deliveries)) <= filter.Limit {
+		return deliveries, nil, nil
+	}
+
+	deliveries = deliveries[:filter.Limit]
+	last := deliveries[len(deliveries)-1]
+
+	return deliveries, &entities.Cursor{Time: last.CreatedAt, ID: last.ID}, nil
+}
+
+func (uc *WebhookUseCase) PendingDeliveries(
+	ctx context.Context, instance string, limit uint, lease time.Duration,
+) (_ []entities.WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookUseCase.PendingDeliveries")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	return uc.repo.PendingDeliveries(ctx, instance, limit, lease)
+}
+
+func (uc *WebhookUseCase) UpdateDelivery(ctx context.Context, delivery *entities.WebhookDelivery) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookUseCase.UpdateDelivery")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	return uc.repo.UpdateDelivery(ctx, delivery)
+}
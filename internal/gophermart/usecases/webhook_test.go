@@ -0,0 +1,260 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/repository/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddWebhook(t *testing.T) {
+	type args struct {
+		webhook entities.Webhook
+	}
+
+	type wants struct {
+		err error
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockWebhookRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "Correct webhook",
+			prepare: func(mock *mocks.MockWebhookRepo) {
+				mock.EXPECT().AddWebhook(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			args: args{
+				webhook: entities.Webhook{
+					UserID: 1,
+					URL:    " https://shop.example.com/hooks ",
+					Secret: "9f86d081884c7d659a2feaa0c55ad015",
+				},
+			},
+			wants: wants{
+				err: nil,
+			},
+		},
+		{
+			name: "Relative URL",
+			args: args{
+				webhook: entities.Webhook{
+					UserID: 1,
+					URL:    "/hooks",
+					Secret: "9f86d081884c7d659a2feaa0c55ad015",
+				},
+			},
+			wants: wants{
+				err: entities.ErrInvalidWebhook,
+			},
+		},
+		{
+			name: "Unsupported scheme",
+			args: args{
+				webhook: entities.Webhook{
+					UserID: 1,
+					URL:    "ftp://shop.example.com/hooks",
+					Secret: "9f86d081884c7d659a2feaa0c55ad015",
+				},
+			},
+			wants: wants{
+				err: entities.ErrInvalidWebhook,
+			},
+		},
+		{
+			name: "Short secret",
+			args: args{
+				webhook: entities.Webhook{
+					UserID: 1,
+					URL:    "https://shop.example.com/hooks",
+					Secret: "secret",
+				},
+			},
+			wants: wants{
+				err: entities.ErrInvalidWebhook,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockWebhookRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		webhook := NewWebhookUseCase(repo, time.Minute)
+
+		err := webhook.AddWebhook(context.Background(), &test.args.webhook)
+		assert.ErrorIs(t, err, test.wants.err, test.name)
+
+		if test.wants.err == nil {
+			assert.Equal(t, "https://shop.example.com/hooks", test.args.webhook.URL, test.name)
+		}
+	}
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	type wants struct {
+		err error
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockWebhookRepo)
+		wants   wants
+	}{
+		{
+			name: "Webhook deleted",
+			prepare: func(mock *mocks.MockWebhookRepo) {
+				mock.EXPECT().DeleteWebhook(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			wants: wants{
+				err: nil,
+			},
+		},
+		{
+			name: "Webhook not found",
+			prepare: func(mock *mocks.MockWebhookRepo) {
+				mock.EXPECT().DeleteWebhook(gomock.Any(), gomock.Any()).Return(entities.ErrWebhookNotFound)
+			},
+			wants: wants{
+				err: entities.ErrWebhookNotFound,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockWebhookRepo(gomock.NewController(t))
+		test.prepare(repo)
+
+		webhook := NewWebhookUseCase(repo, time.Minute)
+
+		err := webhook.DeleteWebhook(context.Background(), &entities.Webhook{ID: 1, UserID: 1})
+		assert.ErrorIs(t, err, test.wants.err, test.name)
+	}
+}
+
+func TestDeliveries(t *testing.T) {
+	ts := time.Now()
+	delivery1 := entities.WebhookDelivery{
+		ID:        1,
+		WebhookID: 1,
+		EventID:   "8d1c2c34-5d1a-4c4e-9a0b-3f0e0a1e2b3c",
+		EventType: entities.WebhookEventBalanceChanged,
+		Status:    entities.WebhookDeliveryDelivered,
+		Attempts:  1,
+		CreatedAt: ts,
+	}
+	delivery2 := entities.WebhookDelivery{
+		ID:        2,
+		WebhookID: 1,
+		EventID:   "1b0e7c5a-2f4d-4b8e-8c1a-6d3f2e1a0b9c",
+		EventType: entities.WebhookEventOrderStatusChanged,
+		Status:    entities.WebhookDeliveryPending,
+		CreatedAt: ts.Add(time.Second),
+	}
+
+	type args struct {
+		filter entities.WebhookDeliveryFilter
+	}
+
+	type wants struct {
+		expected []entities.WebhookDelivery
+		next     *entities.Cursor
+		err      error
+		wantErr  bool
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(mock *mocks.MockWebhookRepo)
+		args    args
+		wants   wants
+	}{
+		{
+			name: "Correct deliveries request",
+			prepare: func(mock *mocks.MockWebhookRepo) {
+				mock.EXPECT().Webhook(gomock.Any(), gomock.Any()).Return(nil)
+				mock.EXPECT().WebhookDeliveries(gomock.Any(), gomock.Any()).
+					Return([]entities.WebhookDelivery{delivery1}, nil)
+			},
+			args: args{
+				filter: entities.WebhookDeliveryFilter{UserID: 1, WebhookID: 1},
+			},
+			wants: wants{
+				expected: []entities.WebhookDelivery{delivery1},
+				wantErr:  false,
+			},
+		},
+		{
+			name: "Next page exists",
+			prepare: func(mock *mocks.MockWebhookRepo) {
+				mock.EXPECT().Webhook(gomock.Any(), gomock.Any()).Return(nil)
+				mock.EXPECT().WebhookDeliveries(gomock.Any(), gomock.Any()).
+					Return([]entities.WebhookDelivery{delivery1, delivery2}, nil)
+			},
+			args: args{
+				filter: entities.WebhookDeliveryFilter{UserID: 1, WebhookID: 1, Page: entities.Page{Limit: 1}},
+			},
+			wants: wants{
+				expected: []entities.WebhookDelivery{delivery1},
+				next:     &entities.Cursor{Time: delivery1.CreatedAt, ID: delivery1.ID},
+				wantErr:  false,
+			},
+		},
+		{
+			name: "Webhook of another user",
+			prepare: func(mock *mocks.MockWebhookRepo) {
+				mock.EXPECT().Webhook(gomock.Any(), gomock.Any()).Return(entities.ErrWebhookNotFound)
+			},
+			args: args{
+				filter: entities.WebhookDeliveryFilter{UserID: 2, WebhookID: 1},
+			},
+			wants: wants{
+				err:     entities.ErrWebhookNotFound,
+				wantErr: true,
+			},
+		},
+		{
+			name: "Invalid page limit",
+			args: args{
+				filter: entities.WebhookDeliveryFilter{
+					UserID:    1,
+					WebhookID: 1,
+					Page:      entities.Page{Limit: entities.MaxPageLimit + 1},
+				},
+			},
+			wants: wants{
+				err:     entities.ErrInvalidPageLimit,
+				wantErr: true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := mocks.NewMockWebhookRepo(gomock.NewController(t))
+
+		if test.prepare != nil {
+			test.prepare(repo)
+		}
+
+		webhook := NewWebhookUseCase(repo, time.Minute)
+
+		result, next, err := webhook.Deliveries(context.Background(), &test.args.filter)
+		if test.wants.wantErr {
+			assert.ErrorIs(t, err, test.wants.err, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+			assert.ElementsMatch(t, test.wants.expected, result, test.name)
+			assert.Equal(t, test.wants.next, next, test.name)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package webhooksender
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/logging"
+	"github.com/KryukovO/gophermart/internal/gophermart/tracing"
+	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/KryukovO/gophermart/internal/utils"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+// Заголовки запроса доставки уведомления.
+const (
+	HeaderEvent     = "X-Gophermart-Event"
+	HeaderDelivery  = "X-Gophermart-Delivery"
+	HeaderTimestamp = "X-Gophermart-Timestamp"
+	HeaderSignature = "X-Gophermart-Signature"
+)
+
+// Префикс значения заголовка HeaderSignature, обозначающий алгоритм подписи.
+const signaturePrefix = "sha256="
+
+var (
+	ErrUnexpectedStatus = errors.New("unexpected response status")
+	ErrForbiddenAddress = errors.New("forbidden address")
+)
+
+var tracer = otel.Tracer("github.com/KryukovO/gophermart/internal/gophermart/webhooksender")
+
+// Отправляет уведомления из очереди доставки на адреса подписок пользователей.
+// Неудачная доставка повторяется с экспоненциально растущей задержкой,
+// после maxAttempts попыток уведомление помечается недоставленным.
+type WebhookSender struct {
+	workers     uint
+	interval    time.Duration
+	batchSize   uint
+	lease       time.Duration
+	timeout     time.Duration
+	maxAttempts uint
+	maxBackoff  time.Duration
+	instance    string
+	webhook     usecases.Webhook
+	client      *http.Client
+	logger      *log.Logger
+	close       chan struct{}
+}
+
+func NewWebhookSender(
+	workers uint, interval time.Duration,
+	batchSize uint, lease time.Duration, timeout time.Duration,
+	maxAttempts uint, maxBackoff time.Duration,
+	webhook usecases.Webhook, logger *log.Logger,
+) *WebhookSender {
+	senderLogger := log.StandardLogger()
+	if logger != nil {
+		senderLogger = logger
+	}
+
+	return &WebhookSender{
+		workers:     workers,
+		interval:    interval,
+		batchSize:   batchSize,
+		lease:       lease,
+		timeout:     timeout,
+		maxAttempts: maxAttempts,
+		maxBackoff:  maxBackoff,
+		instance:    uuid.NewString(),
+		webhook:     webhook,
+		client:      newClient(timeout),
+		logger:      senderLogger,
+		close:       make(chan struct{}),
+	}
+}
+
+// Создаёт клиент доставки уведомлений. Клиент не следует перенаправлениям
+// и не подключается к локальным, частным и link-local адресам:
+// IP-адрес проверяется при установке соединения, уже после разрешения имени через DNS.
+// Прокси из окружения не используются, иначе проверялся бы адрес прокси.
+func newClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: dialControl,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Запрещает соединения с адресами, которые не являются публичными.
+func dialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !utils.IsPublicIP(ip) {
+		return fmt.Errorf("%s: %w", host, ErrForbiddenAddress)
+	}
+
+	return nil
+}
+
+func (sender *WebhookSender) Run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sender.close:
+			return
+		case <-time.After(sender.interval):
+		}
+
+		deliveries, err := sender.webhook.PendingDeliveries(ctx, sender.instance, sender.batchSize, sender.lease)
+		if err != nil {
+			sender.logger.Errorf("WebhookSender error: %s", err)
+		}
+
+		tasks := sender.generateDeliveryTasks(ctx, deliveries)
+
+		group, gCtx := errgroup.WithContext(ctx)
+
+		for w := 0; w < int(sender.workers); w++ {
+			group.Go(func() error {
+				return sender.deliveryTaskWorker(gCtx, tasks)
+			})
+		}
+
+		if err := group.Wait(); err != nil {
+			sender.logger.Errorf("WebhookSender error: %s", err)
+		}
+	}
+}
+
+func (sender *WebhookSender) Shutdown(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		return
+	case sender.close <- struct{}{}:
+		return
+	}
+}
+
+func (sender *WebhookSender) generateDeliveryTasks(
+	ctx context.Context, deliveries []entities.WebhookDelivery,
+) chan entities.WebhookDelivery {
+	outCh := make(chan entities.WebhookDelivery, sender.workers)
+
+	go func() {
+		defer close(outCh)
+
+		for _, delivery := range deliveries {
+			dlv := delivery
+
+			select {
+			case <-ctx.Done():
+				return
+			case outCh <- dlv:
+			}
+		}
+	}()
+
+	return outCh
+}
+
+func (sender *WebhookSender) deliveryTaskWorker(
+	ctx context.Context, tasks <-chan entities.WebhookDelivery,
+) error {
+	for delivery := range tasks {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+			err := sender.deliver(ctx, sender.client, &delivery)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Отправляет уведомление и сохраняет результат попытки доставки.
+// Ответ с кодом 2xx считается успешной доставкой.
+func (sender *WebhookSender) deliver(
+	ctx context.Context, client *http.Client, delivery *entities.WebhookDelivery,
+) (err error) {
+	ctx, span := tracer.Start(
+		ctx, "WebhookSender.deliver",
+		trace.WithAttributes(
+			attribute.Int64("webhook.delivery", delivery.ID),
+			attribute.String("webhook.event", delivery.EventType),
+		),
+	)
+	defer tracing.End(span, &err)
+
+	delivery.Attempts++
+
+	status, sendErr := sender.send(ctx, client, delivery)
+	if sendErr != nil && ctx.Err() != nil {
+		return sendErr
+	}
+
+	delivery.ResponseStatus = status
+
+	if sendErr == nil {
+		now := time.Now()
+
+		delivery.Status = entities.WebhookDeliveryDelivered
+		delivery.LastError = ""
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+
+		return sender.webhook.UpdateDelivery(ctx, delivery)
+	}
+
+	delivery.LastError = sendErr.Error()
+
+	if delivery.Attempts >= sender.maxAttempts {
+		delivery.Status = entities.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+
+		logging.FromContext(ctx, sender.logger).Warnf(
+			"WebhookSender: delivery %d failed after %d attempts: %s",
+			delivery.ID, delivery.Attempts, delivery.LastError,
+		)
+	} else {
+		nextAttemptAt := time.Now().Add(sender.backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &nextAttemptAt
+	}
+
+	return sender.webhook.UpdateDelivery(ctx, delivery)
+}
+
+// Выполняет запрос доставки уведомления и возвращает код ответа.
+func (sender *WebhookSender) send(
+	ctx context.Context, client *http.Client, delivery *entities.WebhookDelivery,
+) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.EventID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign([]byte(delivery.Secret), timestamp, delivery.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Errorf("%s: %w", resp.Status, ErrUnexpectedStatus)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Возвращает задержку перед очередной попыткой доставки после attempts неудачных попыток.
+func (sender *WebhookSender) backoff(attempts uint) time.Duration {
+	return utils.Backoff(sender.interval, sender.maxBackoff, attempts)
+}
+
+// Возвращает значение заголовка HeaderSignature: HMAC-SHA256 секретом подписки secret
+// от строки "<timestamp>.<payload>", где timestamp - значение заголовка HeaderTimestamp.
+func Sign(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,270 @@
+package webhooksender
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/gophermart/entities"
+	"github.com/KryukovO/gophermart/internal/gophermart/repository/mocks"
+	"github.com/KryukovO/gophermart/internal/gophermart/usecases"
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWebhookSender(t *testing.T) {
+	type args struct {
+		workers     uint
+		interval    time.Duration
+		batchSize   uint
+		lease       time.Duration
+		timeout     time.Duration
+		maxAttempts uint
+		maxBackoff  time.Duration
+		webhook     usecases.Webhook
+		logger      *log.Logger
+	}
+
+	tests := []struct {
+		name string
+		args args
+	}{
+		{
+			name: "Correct creation",
+			args: args{
+				workers:     3,
+				interval:    time.Second,
+				batchSize:   100,
+				lease:       time.Minute,
+				timeout:     5 * time.Second,
+				maxAttempts: 10,
+				maxBackoff:  time.Hour,
+				webhook:     usecases.NewWebhookUseCase(mocks.NewMockWebhookRepo(gomock.NewController(t)), time.Second),
+				logger:      log.New(),
+			},
+		},
+		{
+			name: "Nil logger",
+			args: args{
+				workers:     3,
+				interval:    time.Second,
+				batchSize:   100,
+				lease:       time.Minute,
+				timeout:     5 * time.Second,
+				maxAttempts: 10,
+				maxBackoff:  time.Hour,
+				webhook:     usecases.NewWebhookUseCase(mocks.NewMockWebhookRepo(gomock.NewController(t)), time.Second),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		sender := NewWebhookSender(
+			test.args.workers, test.args.interval,
+			test.args.batchSize, test.args.lease, test.args.timeout,
+			test.args.maxAttempts, test.args.maxBackoff,
+			test.args.webhook, test.args.logger,
+		)
+
+		assert.NotNil(t, sender.logger, test.name)
+		assert.NotEmpty(t, sender.instance, test.name)
+		assert.NotNil(t, sender.client, test.name)
+		assert.NotNil(t, sender.close, test.name)
+	}
+}
+
+func TestClient(t *testing.T) {
+	var requests int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := newClient(time.Second)
+
+	resp, err := client.Post(server.URL, "application/json", nil)
+	if err == nil {
+		resp.Body.Close()
+	}
+
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+	assert.Zero(t, requests)
+
+	err = client.CheckRedirect(nil, nil)
+	assert.ErrorIs(t, err, http.ErrUseLastResponse)
+}
+
+func TestDeliver(t *testing.T) {
+	var (
+		secret  = "9f86d081884c7d659a2feaa0c55ad015"
+		payload = []byte(`{"id":"8d1c2c34-5d1a-4c4e-9a0b-3f0e0a1e2b3c","type":"balance.changed"}`)
+		status  = http.StatusOK
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		signature := Sign([]byte(secret), r.Header.Get(HeaderTimestamp), body)
+		if r.Header.Get(HeaderSignature) != signature ||
+			r.Header.Get(HeaderEvent) != entities.WebhookEventBalanceChanged ||
+			r.Header.Get(HeaderDelivery) == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	type args struct {
+		status   int
+		attempts uint
+	}
+
+	type wants struct {
+		status         string
+		attempts       uint
+		responseStatus int
+		delay          time.Duration
+	}
+
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name: "Delivered",
+			args: args{
+				status:   http.StatusNoContent,
+				attempts: 0,
+			},
+			wants: wants{
+				status:         entities.WebhookDeliveryDelivered,
+				attempts:       1,
+				responseStatus: http.StatusNoContent,
+			},
+		},
+		{
+			name: "Retry after unexpected status",
+			args: args{
+				status:   http.StatusInternalServerError,
+				attempts: 2,
+			},
+			wants: wants{
+				status:         entities.WebhookDeliveryPending,
+				attempts:       3,
+				responseStatus: http.StatusInternalServerError,
+				delay:          4 * time.Second,
+			},
+		},
+		{
+			name: "Attempts exhausted",
+			args: args{
+				status:   http.StatusInternalServerError,
+				attempts: 4,
+			},
+			wants: wants{
+				status:         entities.WebhookDeliveryFailed,
+				attempts:       5,
+				responseStatus: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var updated entities.WebhookDelivery
+
+		status = test.args.status
+
+		webhookRepo := mocks.NewMockWebhookRepo(gomock.NewController(t))
+		webhookRepo.EXPECT().UpdateDelivery(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, delivery *entities.WebhookDelivery) error {
+				updated = *delivery
+
+				return nil
+			},
+		)
+
+		sender := WebhookSender{
+			interval:    time.Second,
+			maxAttempts: 5,
+			maxBackoff:  time.Minute,
+			webhook:     usecases.NewWebhookUseCase(webhookRepo, time.Second),
+			logger:      log.New(),
+		}
+
+		delivery := entities.WebhookDelivery{
+			ID:        1,
+			WebhookID: 1,
+			URL:       server.URL,
+			Secret:    secret,
+			EventID:   "8d1c2c34-5d1a-4c4e-9a0b-3f0e0a1e2b3c",
+			EventType: entities.WebhookEventBalanceChanged,
+			Payload:   payload,
+			Status:    entities.WebhookDeliveryPending,
+			Attempts:  test.args.attempts,
+		}
+
+		err := sender.deliver(context.Background(), server.Client(), &delivery)
+		require.NoError(t, err, test.name)
+
+		assert.Equal(t, test.wants.status, updated.Status, test.name)
+		assert.Equal(t, test.wants.attempts, updated.Attempts, test.name)
+		assert.Equal(t, test.wants.responseStatus, updated.ResponseStatus, test.name)
+
+		if test.wants.status == entities.WebhookDeliveryDelivered {
+			assert.Empty(t, updated.LastError, test.name)
+			assert.NotNil(t, updated.DeliveredAt, test.name)
+		} else {
+			assert.NotEmpty(t, updated.LastError, test.name)
+			assert.Nil(t, updated.DeliveredAt, test.name)
+		}
+
+		if test.wants.delay != 0 {
+			require.NotNil(t, updated.NextAttemptAt, test.name)
+			assert.WithinDuration(t, time.Now().Add(test.wants.delay), *updated.NextAttemptAt, time.Second, test.name)
+		} else {
+			assert.Nil(t, updated.NextAttemptAt, test.name)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	secret := []byte("9f86d081884c7d659a2feaa0c55ad015")
+	payload := []byte(`{"type":"balance.changed"}`)
+
+	signature := Sign(secret, "1700000000", payload)
+
+	assert.Regexp(t, "^sha256=[0-9a-f]{64}$", signature)
+	assert.Equal(t, signature, Sign(secret, "1700000000", payload))
+	assert.NotEqual(t, signature, Sign(secret, "1700000001", payload))
+	assert.NotEqual(t, signature, Sign([]byte("another secret value"), "1700000000", payload))
+}
+
+func TestBackoff(t *testing.T) {
+	sender := WebhookSender{
+		interval:   time.Second,
+		maxBackoff: 10 * time.Second,
+	}
+
+	assert.Equal(t, time.Second, sender.backoff(1))
+	assert.Equal(t, 2*time.Second, sender.backoff(2))
+	assert.Equal(t, 8*time.Second, sender.backoff(4))
+	assert.Equal(t, 10*time.Second, sender.backoff(5))
+	assert.Equal(t, 10*time.Second, sender.backoff(100))
+}
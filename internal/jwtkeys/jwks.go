@@ -0,0 +1,60 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+// @Description Public key in the JWK format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"           swaggerignore:"false" enums:"RSA,OKP"`
+	KeyID     string `json:"kid"           swaggerignore:"false"`
+	Use       string `json:"use"           swaggerignore:"false" enums:"sig"`
+	Algorithm string `json:"alg"           swaggerignore:"false" enums:"RS256,EdDSA"`
+	N         string `json:"n,omitempty"   swaggerignore:"false"`
+	E         string `json:"e,omitempty"   swaggerignore:"false"`
+	Curve     string `json:"crv,omitempty" swaggerignore:"false" enums:"Ed25519"`
+	X         string `json:"x,omitempty"   swaggerignore:"false"`
+} // @name JWK
+
+// @Description Set of public keys used to verify access tokens.
+type JWKS struct {
+	Keys []JWK `json:"keys" swaggerignore:"false"`
+} // @name JWKS
+
+// Возвращает открытые ключи, которыми можно проверить подпись действительных токенов.
+// Ключи HMAC не публикуются.
+func (set *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	for _, key := range set.published() {
+		jwk := JWK{
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+		}
+
+		switch public := key.VerifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = encodeBase64URL(public.N.Bytes())
+			jwk.E = encodeBase64URL(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = encodeBase64URL(public)
+		default:
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+func encodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
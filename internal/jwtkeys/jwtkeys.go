@@ -0,0 +1,131 @@
+package jwtkeys
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/utils"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	ErrNoKeys         = errors.New("no signing keys")
+	ErrNoActiveKey    = errors.New("no active signing key")
+	ErrDuplicateKeyID = errors.New("duplicate key id")
+	ErrUnknownKey     = errors.New("unknown signing key")
+)
+
+// Ключ подписи токенов с моментом, начиная с которого он используется для подписи.
+type Key struct {
+	utils.JWTKey
+	ActiveFrom time.Time
+}
+
+// Набор ключей подписи токенов.
+// Для подписи используется ключ с наибольшим ActiveFrom, не превышающим текущее время.
+// Ключ, заменённый более новым, продолжает приниматься при проверке подписи
+// в течение grace, чтобы выданные им токены оставались действительными до истечения их срока.
+// Ключи, время использования которых ещё не наступило, заранее публикуются в JWKS.
+type KeySet struct {
+	keys  []Key
+	grace time.Duration
+	now   func() time.Time
+}
+
+func NewKeySet(keys []Key, grace time.Duration) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	ids := make(map[string]struct{}, len(keys))
+
+	for _, key := range keys {
+		if _, ok := ids[key.ID]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateKeyID, key.ID)
+		}
+
+		ids[key.ID] = struct{}{}
+	}
+
+	sorted := make([]Key, len(keys))
+	copy(sorted, keys)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ActiveFrom.Before(sorted[j].ActiveFrom)
+	})
+
+	set := &KeySet{
+		keys:  sorted,
+		grace: grace,
+		now:   time.Now,
+	}
+
+	if _, err := set.SigningKey(); err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// Возвращает набор из единственного ключа HMAC-SHA256 без идентификатора.
+func NewHMACKeySet(secret []byte) *KeySet {
+	return &KeySet{
+		keys: []Key{
+			{
+				JWTKey: utils.JWTKey{
+					Method:    jwt.SigningMethodHS256,
+					SignKey:   secret,
+					VerifyKey: secret,
+				},
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// Возвращает ключ, которым в данный момент подписываются токены.
+func (set *KeySet) SigningKey() (utils.JWTKey, error) {
+	now := set.now()
+
+	for i := len(set.keys) - 1; i >= 0; i-- {
+		if !set.keys[i].ActiveFrom.After(now) {
+			return set.keys[i].JWTKey, nil
+		}
+	}
+
+	return utils.JWTKey{}, ErrNoActiveKey
+}
+
+// Возвращает ключ проверки подписи с идентификатором kid.
+// Возвращает ErrUnknownKey, если ключ не найден или срок его использования для проверки истёк.
+func (set *KeySet) VerificationKey(kid string) (utils.JWTKey, error) {
+	for _, key := range set.published() {
+		if key.ID == kid {
+			return key.JWTKey, nil
+		}
+	}
+
+	return utils.JWTKey{}, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+}
+
+// Возвращает ключи, пригодные для проверки подписи: действующий ключ подписи,
+// заменённые ключи в течение grace и ключи, время использования которых ещё не наступило.
+func (set *KeySet) published() []Key {
+	now := set.now()
+	keys := make([]Key, 0, len(set.keys))
+
+	for i, key := range set.keys {
+		if i+1 < len(set.keys) {
+			replacedAt := set.keys[i+1].ActiveFrom
+			if !replacedAt.After(now) && replacedAt.Add(set.grace).Before(now) {
+				continue
+			}
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -0,0 +1,219 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/utils"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	rsaPath := writePEM(t, dir, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	edPath := writePEM(t, dir, "ed25519.pem", "PRIVATE KEY", marshalPKCS8(t, edKey))
+	weakPath := writePEM(t, dir, "weak.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(weakKey))
+	publicPath := writePEM(t, dir, "public.pem", "PUBLIC KEY", []byte("key"))
+
+	keys, err := LoadKeys([]string{rsaPath, edPath + "@2024-02-01T00:00:00Z"})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	assert.Equal(t, "rsa", keys[0].ID)
+	assert.Equal(t, jwt.SigningMethodRS256, keys[0].Method)
+	assert.True(t, keys[0].ActiveFrom.IsZero())
+
+	assert.Equal(t, "ed25519", keys[1].ID)
+	assert.Equal(t, jwt.SigningMethodEdDSA, keys[1].Method)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), keys[1].ActiveFrom.UTC())
+
+	_, err = LoadKeys([]string{weakPath})
+	assert.ErrorIs(t, err, ErrWeakKey)
+
+	_, err = LoadKeys([]string{publicPath})
+	assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+
+	_, err = LoadKeys([]string{rsaPath + "@tomorrow"})
+	assert.Error(t, err)
+
+	_, err = LoadKeys([]string{filepath.Join(dir, "missing.pem")})
+	assert.Error(t, err)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: marshalPKCS8(t, rsaKey)})
+	edPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: marshalPKCS8(t, edKey)})
+
+	for _, data := range [][]byte{rsaPEM, edPEM} {
+		key, err := ParsePEMKey("key", data)
+		require.NoError(t, err)
+
+		set, err := NewKeySet([]Key{key}, time.Minute)
+		require.NoError(t, err)
+
+		signingKey, err := set.SigningKey()
+		require.NoError(t, err)
+
+		token, err := utils.BuildJSWTString(signingKey, "token-id", "", time.Minute, int64(1))
+		require.NoError(t, err)
+
+		var userID int64
+
+		claims, err := utils.ParseTokenString(&userID, token, set.VerificationKey)
+		require.NoError(t, err, key.Method.Alg())
+
+		assert.Equal(t, "token-id", claims.ID)
+		assert.Equal(t, int64(1), userID)
+
+		hmacToken, err := utils.BuildJSWTString(
+			utils.JWTKey{ID: "key", Method: jwt.SigningMethodHS256, SignKey: []byte("secret")},
+			"token-id", "", time.Minute, int64(1),
+		)
+		require.NoError(t, err)
+
+		_, err = utils.ParseTokenString(&userID, hmacToken, set.VerificationKey)
+		assert.Error(t, err, "algorithm must match the key")
+	}
+}
+
+func TestRotation(t *testing.T) {
+	now := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	keys := []Key{
+		newTestKey(t, "next", now.Add(time.Hour)),
+		newTestKey(t, "old", now.Add(-2*time.Hour)),
+		newTestKey(t, "current", now.Add(-30*time.Minute)),
+		newTestKey(t, "retired", now.Add(-48*time.Hour)),
+	}
+
+	set, err := NewKeySet(keys, time.Hour)
+	require.NoError(t, err)
+
+	set.now = func() time.Time { return now }
+
+	signingKey, err := set.SigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, "current", signingKey.ID)
+
+	for _, kid := range []string{"old", "current", "next"} {
+		_, err = set.VerificationKey(kid)
+		assert.NoError(t, err, kid)
+	}
+
+	_, err = set.VerificationKey("retired")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	_, err = set.VerificationKey("")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	jwks := set.JWKS()
+	ids := make([]string, 0, len(jwks.Keys))
+
+	for _, jwk := range jwks.Keys {
+		ids = append(ids, jwk.KeyID)
+		assert.Equal(t, "OKP", jwk.KeyType)
+		assert.Equal(t, "Ed25519", jwk.Curve)
+		assert.Equal(t, "EdDSA", jwk.Algorithm)
+		assert.NotEmpty(t, jwk.X)
+	}
+
+	assert.Equal(t, []string{"old", "current", "next"}, ids)
+
+	// Через час после смены ключа предыдущий ключ перестаёт приниматься
+	set.now = func() time.Time { return now.Add(31 * time.Minute) }
+
+	_, err = set.VerificationKey("old")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	// После наступления времени следующего ключа подпись выполняется им
+	set.now = func() time.Time { return now.Add(time.Hour) }
+
+	signingKey, err = set.SigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, "next", signingKey.ID)
+}
+
+func TestNewKeySet(t *testing.T) {
+	_, err := NewKeySet(nil, time.Hour)
+	assert.ErrorIs(t, err, ErrNoKeys)
+
+	_, err = NewKeySet([]Key{newTestKey(t, "key", time.Time{}), newTestKey(t, "key", time.Time{})}, time.Hour)
+	assert.ErrorIs(t, err, ErrDuplicateKeyID)
+
+	_, err = NewKeySet([]Key{newTestKey(t, "key", time.Now().Add(time.Hour))}, time.Hour)
+	assert.ErrorIs(t, err, ErrNoActiveKey)
+}
+
+func TestHMACKeySet(t *testing.T) {
+	set := NewHMACKeySet([]byte("secret"))
+
+	signingKey, err := set.SigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, jwt.SigningMethodHS256, signingKey.Method)
+
+	_, err = set.VerificationKey("")
+	assert.NoError(t, err)
+
+	assert.Empty(t, set.JWKS().Keys)
+}
+
+func newTestKey(t *testing.T, id string, activeFrom time.Time) Key {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return Key{
+		JWTKey: utils.JWTKey{
+			ID:        id,
+			Method:    jwt.SigningMethodEdDSA,
+			SignKey:   privateKey,
+			VerifyKey: privateKey.Public(),
+		},
+		ActiveFrom: activeFrom,
+	}
+}
+
+func marshalPKCS8(t *testing.T, key interface{}) []byte {
+	t.Helper()
+
+	data, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	return data
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0o600)
+	require.NoError(t, err)
+
+	return path
+}
@@ -0,0 +1,127 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KryukovO/gophermart/internal/utils"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Минимальный размер ключа RSA в битах.
+const minRSAKeyBits = 2048
+
+var (
+	ErrInvalidPEM         = errors.New("invalid PEM data")
+	ErrUnsupportedKeyType = errors.New("unsupported key type")
+	ErrWeakKey            = errors.New("RSA key is too short")
+)
+
+// Загружает ключи по описаниям вида <путь к PEM-файлу>[@<время начала использования в RFC 3339>].
+// Идентификатором ключа служит имя файла без расширения.
+// Ключ без времени начала использования используется сразу.
+func LoadKeys(specs []string) ([]Key, error) {
+	keys := make([]Key, 0, len(specs))
+
+	for _, spec := range specs {
+		path, activeFrom, err := parseKeySpec(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+		key, err := ParsePEMKey(id, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		key.ActiveFrom = activeFrom
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// Разбирает закрытый ключ RSA (PKCS #1 или PKCS #8) или Ed25519 (PKCS #8) в формате PEM.
+// Ключи RSA подписывают токены алгоритмом RS256, ключи Ed25519 - алгоритмом EdDSA.
+func ParsePEMKey(id string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, ErrInvalidPEM
+	}
+
+	var (
+		privateKey interface{}
+		err        error
+	)
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("%w: %s", ErrUnsupportedKeyType, block.Type)
+	}
+
+	if err != nil {
+		return Key{}, err
+	}
+
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if privateKey.N.BitLen() < minRSAKeyBits {
+			return Key{}, fmt.Errorf("%w: %d bits", ErrWeakKey, privateKey.N.BitLen())
+		}
+
+		return Key{
+			JWTKey: utils.JWTKey{
+				ID:        id,
+				Method:    jwt.SigningMethodRS256,
+				SignKey:   privateKey,
+				VerifyKey: &privateKey.PublicKey,
+			},
+		}, nil
+	case ed25519.PrivateKey:
+		return Key{
+			JWTKey: utils.JWTKey{
+				ID:        id,
+				Method:    jwt.SigningMethodEdDSA,
+				SignKey:   privateKey,
+				VerifyKey: privateKey.Public(),
+			},
+		}, nil
+	default:
+		return Key{}, fmt.Errorf("%w: %T", ErrUnsupportedKeyType, privateKey)
+	}
+}
+
+func parseKeySpec(spec string) (string, time.Time, error) {
+	spec = strings.TrimSpace(spec)
+
+	idx := strings.LastIndex(spec, "@")
+	if idx < 0 {
+		return spec, time.Time{}, nil
+	}
+
+	activeFrom, err := time.Parse(time.RFC3339, spec[idx+1:])
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("key %s: %w", spec, err)
+	}
+
+	return spec[:idx], activeFrom, nil
+}
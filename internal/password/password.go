@@ -0,0 +1,106 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var (
+	ErrMismatchedPassword = errors.New("password does not match the hash")
+	ErrUnknownAlgorithm   = errors.New("unknown password hashing algorithm")
+	ErrInvalidHash        = errors.New("invalid password hash")
+)
+
+// Алгоритм хеширования паролей.
+// Хеш содержит идентификатор алгоритма и параметры, с которыми он был получен,
+// поэтому параметры можно менять без потери возможности проверить старые хеши.
+type Hasher interface {
+	Algorithm() string
+	// Возвращает хеш пароля с солью, идентификатором алгоритма и его параметрами.
+	Hash(password string) (string, error)
+	// Возвращает ErrMismatchedPassword, если пароль не соответствует хешу.
+	Verify(password, hash string) error
+	// Возвращает true, если хеш получен другим алгоритмом или с другими параметрами
+	// и должен быть пересчитан при следующей успешной проверке пароля.
+	NeedsRehash(hash string) bool
+}
+
+// Возвращает алгоритм, которым получен хеш, либо пустую строку, если алгоритм не распознан.
+func Algorithm(hash string) string {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return AlgorithmBcrypt
+	case strings.HasPrefix(hash, "$"+AlgorithmArgon2id+"$"):
+		return AlgorithmArgon2id
+	default:
+		return ""
+	}
+}
+
+// Хешер, создающий хеши основным алгоритмом и проверяющий хеши любого из поддерживаемых алгоритмов.
+type MultiHasher struct {
+	primary Hasher
+	hashers map[string]Hasher
+}
+
+func NewMultiHasher(primary Hasher, others ...Hasher) *MultiHasher {
+	hashers := map[string]Hasher{primary.Algorithm(): primary}
+
+	for _, hasher := range others {
+		if _, ok := hashers[hasher.Algorithm()]; !ok {
+			hashers[hasher.Algorithm()] = hasher
+		}
+	}
+
+	return &MultiHasher{
+		primary: primary,
+		hashers: hashers,
+	}
+}
+
+// Возвращает хешер, использующий алгоритм algorithm для новых хешей
+// и проверяющий хеши всех поддерживаемых алгоритмов.
+func NewHasher(algorithm string, bcryptCost int, argon2Params Argon2Params) (*MultiHasher, error) {
+	bcryptHasher := NewBcryptHasher(bcryptCost)
+	argon2Hasher := NewArgon2idHasher(argon2Params)
+
+	switch algorithm {
+	case AlgorithmBcrypt:
+		return NewMultiHasher(bcryptHasher, argon2Hasher), nil
+	case AlgorithmArgon2id:
+		return NewMultiHasher(argon2Hasher, bcryptHasher), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, algorithm)
+	}
+}
+
+func (h *MultiHasher) Algorithm() string {
+	return h.primary.Algorithm()
+}
+
+func (h *MultiHasher) Hash(password string) (string, error) {
+	return h.primary.Hash(password)
+}
+
+func (h *MultiHasher) Verify(password, hash string) error {
+	hasher, ok := h.hashers[Algorithm(hash)]
+	if !ok {
+		return ErrUnknownAlgorithm
+	}
+
+	return hasher.Verify(password, hash)
+}
+
+func (h *MultiHasher) NeedsRehash(hash string) bool {
+	if Algorithm(hash) != h.primary.Algorithm() {
+		return true
+	}
+
+	return h.primary.NeedsRehash(hash)
+}
@@ -0,0 +1,95 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2Params = Argon2Params{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+}
+
+func TestHashers(t *testing.T) {
+	tests := []struct {
+		name      string
+		hasher    Hasher
+		algorithm string
+	}{
+		{
+			name:      "bcrypt",
+			hasher:    NewBcryptHasher(bcrypt.MinCost),
+			algorithm: AlgorithmBcrypt,
+		},
+		{
+			name:      "argon2id",
+			hasher:    NewArgon2idHasher(testArgon2Params),
+			algorithm: AlgorithmArgon2id,
+		},
+	}
+
+	for _, test := range tests {
+		hash, err := test.hasher.Hash("1234")
+		require.NoError(t, err, test.name)
+
+		assert.Equal(t, test.algorithm, Algorithm(hash), test.name)
+		assert.NoError(t, test.hasher.Verify("1234", hash), test.name)
+		assert.ErrorIs(t, test.hasher.Verify("4321", hash), ErrMismatchedPassword, test.name)
+		assert.False(t, test.hasher.NeedsRehash(hash), test.name)
+
+		other, err := test.hasher.Hash("1234")
+		require.NoError(t, err, test.name)
+		assert.NotEqual(t, hash, other, test.name)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcryptHash, err := NewBcryptHasher(bcrypt.MinCost).Hash("1234")
+	require.NoError(t, err)
+
+	argon2Hash, err := NewArgon2idHasher(testArgon2Params).Hash("1234")
+	require.NoError(t, err)
+
+	assert.True(t, NewBcryptHasher(bcrypt.MinCost+1).NeedsRehash(bcryptHash))
+
+	params := testArgon2Params
+	params.Iterations++
+	assert.True(t, NewArgon2idHasher(params).NeedsRehash(argon2Hash))
+
+	hasher := NewMultiHasher(NewArgon2idHasher(testArgon2Params), NewBcryptHasher(bcrypt.MinCost))
+	assert.True(t, hasher.NeedsRehash(bcryptHash))
+	assert.False(t, hasher.NeedsRehash(argon2Hash))
+}
+
+func TestMultiHasherVerify(t *testing.T) {
+	bcryptHash, err := NewBcryptHasher(bcrypt.MinCost).Hash("1234")
+	require.NoError(t, err)
+
+	argon2Hash, err := NewArgon2idHasher(testArgon2Params).Hash("1234")
+	require.NoError(t, err)
+
+	hasher := NewMultiHasher(NewBcryptHasher(bcrypt.MinCost), NewArgon2idHasher(testArgon2Params))
+
+	assert.NoError(t, hasher.Verify("1234", bcryptHash))
+	assert.NoError(t, hasher.Verify("1234", argon2Hash))
+	assert.ErrorIs(t, hasher.Verify("4321", argon2Hash), ErrMismatchedPassword)
+	assert.ErrorIs(t, hasher.Verify("1234", "0123abcd"), ErrUnknownAlgorithm)
+	assert.ErrorIs(t, hasher.Verify("1234", "$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5"), ErrInvalidHash)
+}
+
+func TestNewHasher(t *testing.T) {
+	hasher, err := NewHasher(AlgorithmBcrypt, bcrypt.MinCost, testArgon2Params)
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmBcrypt, hasher.Algorithm())
+
+	hasher, err = NewHasher(AlgorithmArgon2id, bcrypt.MinCost, testArgon2Params)
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmArgon2id, hasher.Algorithm())
+
+	_, err = NewHasher("md5", bcrypt.MinCost, testArgon2Params)
+	assert.ErrorIs(t, err, ErrUnknownAlgorithm)
+}
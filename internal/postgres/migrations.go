@@ -3,8 +3,10 @@ package postgres
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
 )
 
 func RunMigrations(dsn, migrations string) error {
@@ -22,3 +24,36 @@ func RunMigrations(dsn, migrations string) error {
 
 	return nil
 }
+
+// Возвращает версию последней миграции в директории migrations.
+// Если директория не содержит миграций, возвращает 0.
+func LatestMigration(migrations string) (uint, error) {
+	driver, err := source.Open(fmt.Sprintf("file://%s", migrations))
+	if err != nil {
+		return 0, err
+	}
+
+	defer driver.Close()
+
+	version, err := driver.First()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	for {
+		next, err := driver.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		version = next
+	}
+}
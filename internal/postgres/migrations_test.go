@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+const (
+	// Переменная окружения с адресом тестовой БД. Без неё тесты миграций пропускаются.
+	// Тесты изменяют схему и данные БД, поэтому БД должна использоваться только для тестов.
+	testDSNEnv     = "GOPHERMART_TEST_DATABASE_URI"
+	testMigrations = "../../sql/migrations"
+)
+
+// Проверяет, что миграция откатывается на БД с данными и после отката применяется повторно.
+func TestMigrationRollback(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	migration, err := migrate.New(fmt.Sprintf("file://%s", testMigrations), dsn)
+	require.NoError(t, err)
+
+	defer migration.Close()
+
+	tests := []struct {
+		name    string
+		version uint
+		// Заполняет БД данными, которые затрагивает откат миграции.
+		// $1 - логин пользователя, $2 - префикс номеров заказов.
+		seed string
+		// Баланс пользователя после отката миграции.
+		balance float64
+	}{
+		{
+			name:    "Balance adjustments",
+			version: 11,
+			seed: `
+				WITH u AS (
+					INSERT INTO users(login, password, salt) VALUES ($1, '', '')
+					RETURNING id
+				), b AS (
+					INSERT INTO user_balance(user_id, balance) SELECT id, 80 FROM u
+				)
+				INSERT INTO user_balance_log(user_id, processed, operation, order_num, sum)
+				SELECT u.id, now(), op.operation::balance_operation, $2::TEXT || op.suffix, op.sum
+				FROM u, (
+					VALUES ('refill', '1', 100), ('withdrawal', '2', 30), ('adjustment', '3', 10)
+				) AS op(operation, suffix, sum)
+			`,
+			balance: 70,
+		},
+		{
+			name:    "Withdrawal reversals",
+			version: 12,
+			seed: `
+				WITH u AS (
+					INSERT INTO users(login, password, salt) VALUES ($1, '', '')
+					RETURNING id
+				), b AS (
+					INSERT INTO user_balance(user_id, balance) SELECT id, 100 FROM u
+				), w AS (
+					INSERT INTO user_balance_log(user_id, processed, operation, order_num, sum)
+					SELECT id, now(), 'withdrawal', $2::TEXT || '1', 30 FROM u
+					RETURNING id, user_id, order_num
+				)
+				INSERT INTO user_balance_log(user_id, processed, operation, order_num, sum, reversal_of)
+				SELECT user_id, now(), 'reversal', order_num, 30, id FROM w
+			`,
+			balance: 70,
+		},
+	}
+
+	for _, test := range tests {
+		login := fmt.Sprintf("migration-%d", time.Now().UnixNano())
+
+		err = migrateTo(migration, test.version)
+		require.NoError(t, err, test.name)
+
+		_, err = db.Exec(test.seed, login, login)
+		require.NoError(t, err, test.name)
+
+		err = migrateTo(migration, test.version-1)
+		require.NoError(t, err, test.name)
+
+		var balance float64
+
+		err = db.QueryRow(
+			`SELECT balance FROM user_balance WHERE user_id = (SELECT id FROM users WHERE login = $1)`,
+			login,
+		).Scan(&balance)
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.balance, balance, test.name)
+
+		err = migrateTo(migration, test.version)
+		require.NoError(t, err, test.name)
+	}
+
+	err = migration.Down()
+	if !errors.Is(err, migrate.ErrNoChange) {
+		require.NoError(t, err)
+	}
+}
+
+// Применяет или откатывает миграции до версии version.
+func migrateTo(migration *migrate.Migrate, version uint) error {
+	err := migration.Migrate(version)
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+
+	return err
+}
@@ -3,10 +3,15 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 )
 
+var ErrMigrationsNotApplied = errors.New("database migrations are not applied")
+
 type Postgres struct {
 	*sql.DB
+	version uint // Версия последней миграции, которая должна быть применена к БД
 }
 
 func NewPostgres(ctx context.Context, dsn, migrations string) (*Postgres, error) {
@@ -27,7 +32,47 @@ func NewPostgres(ctx context.Context, dsn, migrations string) (*Postgres, error)
 		return nil, err
 	}
 
+	version, err := LatestMigration(migrations)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Postgres{
-		DB: db,
+		DB:      db,
+		version: version,
 	}, nil
 }
+
+// Проверяет, что к БД применены все миграции, известные сервису,
+// и последняя миграция не завершилась ошибкой.
+func (pg *Postgres) CheckMigrations(ctx context.Context) error {
+	query := `
+		SELECT version, dirty
+		FROM schema_migrations
+		LIMIT 1
+	`
+
+	var (
+		version uint
+		dirty   bool
+	)
+
+	err := pg.QueryRowContext(ctx, query).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: no migrations applied", ErrMigrationsNotApplied)
+		}
+
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("%w: migration %d is dirty", ErrMigrationsNotApplied, version)
+	}
+
+	if version < pg.version {
+		return fmt.Errorf("%w: applied version %d, expected %d", ErrMigrationsNotApplied, version, pg.version)
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package utils
+
+import "time"
+
+// Возвращает задержку перед очередной попыткой после attempts неудачных попыток:
+// задержка начинается с interval и удваивается с каждой попыткой, но не превышает maxDelay.
+// Если maxDelay меньше interval, задержка всегда равна interval.
+func Backoff(interval, maxDelay time.Duration, attempts uint) time.Duration {
+	if maxDelay < interval {
+		maxDelay = interval
+	}
+
+	delay := interval
+
+	for i := uint(1); i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return delay
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		maxDelay time.Duration
+		attempts uint
+		expected time.Duration
+	}{
+		{
+			name:     "First attempt",
+			interval: time.Second,
+			maxDelay: 10 * time.Second,
+			attempts: 1,
+			expected: time.Second,
+		},
+		{
+			name:     "Delay doubles",
+			interval: time.Second,
+			maxDelay: 10 * time.Second,
+			attempts: 4,
+			expected: 8 * time.Second,
+		},
+		{
+			name:     "Delay limited by maxDelay",
+			interval: time.Second,
+			maxDelay: 10 * time.Second,
+			attempts: 100,
+			expected: 10 * time.Second,
+		},
+		{
+			name:     "Zero maxDelay",
+			interval: time.Second,
+			maxDelay: 0,
+			attempts: 3,
+			expected: time.Second,
+		},
+		{
+			name:     "maxDelay less than interval",
+			interval: 3 * time.Second,
+			maxDelay: time.Second,
+			attempts: 3,
+			expected: 3 * time.Second,
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, Backoff(test.interval, test.maxDelay, test.attempts), test.name)
+	}
+}
@@ -13,46 +13,84 @@ var (
 	ErrTokenIsInvalid          = errors.New("token is invalid")
 )
 
+// Утверждения токена. Идентификатор токена передаётся в RegisteredClaims.ID (jti)
+// и используется для его отзыва. Role - роль владельца токена.
 type Claims struct {
 	jwt.RegisteredClaims
+	Role    string `json:"role,omitempty"`
 	Payload interface{}
 }
 
-func BuildJSWTString(secret []byte, lifetime time.Duration, payload interface{}) (string, error) {
+// Ключ подписи токенов.
+// Для HMAC ключи подписи и проверки совпадают, для асимметричных алгоритмов
+// VerifyKey содержит открытый ключ, соответствующий закрытому ключу SignKey.
+type JWTKey struct {
+	ID        string // Идентификатор ключа, передаваемый в заголовке kid
+	Method    jwt.SigningMethod
+	SignKey   interface{}
+	VerifyKey interface{}
+}
+
+// Возвращает ключ проверки подписи по идентификатору kid из заголовка токена.
+type JWTKeyFunc func(kid string) (JWTKey, error)
+
+func BuildJSWTString(
+	key JWTKey, tokenID, role string, lifetime time.Duration, payload interface{},
+) (string, error) {
+	now := time.Now()
+
 	token := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
+		key.Method,
 		Claims{
 			RegisteredClaims: jwt.RegisteredClaims{
-				ExpiresAt: jwt.NewNumericDate(time.Now().Add(lifetime)),
+				ID:        tokenID,
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(lifetime)),
 			},
+			Role:    role,
 			Payload: payload,
 		},
 	)
 
-	return token.SignedString(secret)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+
+	return token.SignedString(key.SignKey)
 }
 
-func ParseTokenString(dst interface{}, tokenString string, secret []byte) error {
+// Проверяет подпись и срок действия токена, считывает его полезную нагрузку в dst
+// и возвращает утверждения токена.
+// Ключ проверки выбирается функцией keyFunc по заголовку kid; алгоритм подписи токена
+// должен совпадать с алгоритмом выбранного ключа.
+func ParseTokenString(dst interface{}, tokenString string, keyFunc JWTKeyFunc) (*Claims, error) {
 	claims := &Claims{
 		Payload: dst,
 	}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims,
 		func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			kid, _ := t.Header["kid"].(string)
+
+			key, err := keyFunc(kid)
+			if err != nil {
+				return nil, err
+			}
+
+			if t.Method.Alg() != key.Method.Alg() {
 				return nil, fmt.Errorf("%w: %v", ErrUnexpectedSigningMethod, t.Header["alg"])
 			}
 
-			return secret, nil
+			return key.VerifyKey, nil
 		},
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if !token.Valid {
-		return ErrTokenIsInvalid
+		return nil, ErrTokenIsInvalid
 	}
 
-	return nil
+	return claims, nil
 }
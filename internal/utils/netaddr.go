@@ -0,0 +1,20 @@
+package utils
+
+import "net"
+
+// Проверяет, что ip - публичный адрес: не локальный, не частный, не link-local,
+// не групповой и не неопределённый. Запросы по таким адресам не должны уходить
+// во внутреннюю сеть сервиса.
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 0 {
+		return false
+	}
+
+	return !ip.IsUnspecified() &&
+		!ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast()
+}
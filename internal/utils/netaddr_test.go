@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip       string
+		expected bool
+	}{
+		{ip: "93.184.216.34", expected: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", expected: true},
+		{ip: "127.0.0.1", expected: false},
+		{ip: "::1", expected: false},
+		{ip: "10.0.0.1", expected: false},
+		{ip: "172.16.0.1", expected: false},
+		{ip: "192.168.1.1", expected: false},
+		{ip: "fd00::1", expected: false},
+		{ip: "169.254.169.254", expected: false},
+		{ip: "fe80::1", expected: false},
+		{ip: "0.0.0.0", expected: false},
+		{ip: "0.1.2.3", expected: false},
+		{ip: "::", expected: false},
+		{ip: "224.0.0.1", expected: false},
+		{ip: "::ffff:127.0.0.1", expected: false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, IsPublicIP(net.ParseIP(test.ip)), test.ip)
+	}
+}
@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+--
+ALTER TABLE "user_balance" ALTER COLUMN balance TYPE DOUBLE PRECISION;
+--
+ALTER TABLE "user_balance_log" ALTER COLUMN sum TYPE DOUBLE PRECISION;
+--
+ALTER TABLE "orders" ALTER COLUMN accrual TYPE DOUBLE PRECISION;
+--
+COMMIT TRANSACTION;
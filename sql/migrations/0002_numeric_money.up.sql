@@ -0,0 +1,12 @@
+BEGIN TRANSACTION;
+--
+ALTER TABLE "user_balance"
+    ALTER COLUMN balance TYPE NUMERIC(20, 2) USING round(balance::NUMERIC, 2);
+--
+ALTER TABLE "user_balance_log"
+    ALTER COLUMN sum TYPE NUMERIC(20, 2) USING round(sum::NUMERIC, 2);
+--
+ALTER TABLE "orders"
+    ALTER COLUMN accrual TYPE NUMERIC(20, 2) USING round(accrual::NUMERIC, 2);
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,8 @@
+BEGIN TRANSACTION;
+--
+-- Удалённые повторные начисления не восстанавливаются.
+DROP INDEX IF EXISTS user_balance_log_refill_order_num_idx;
+
+DROP TABLE IF EXISTS "user_balance_log_refill_duplicates";
+--
+COMMIT TRANSACTION;
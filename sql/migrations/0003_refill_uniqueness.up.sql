@@ -0,0 +1,38 @@
+BEGIN TRANSACTION;
+--
+-- До введения ограничения по одному заказу могло быть выполнено несколько начислений.
+-- Остаётся самое раннее начисление по заказу, остальные удаляются из журнала, а начисленные
+-- по ним баллы списываются со счёта пользователя. Удалённые записи сохраняются
+-- в таблице user_balance_log_refill_duplicates для ручной проверки.
+-- Если БД осталась в состоянии dirty на версии 3 после неудачного запуска прежней версии
+-- миграции, перед запуском сервиса версию нужно сбросить командой `migrate force 2`.
+CREATE TABLE IF NOT EXISTS "user_balance_log_refill_duplicates" AS
+    SELECT l.id, l.user_id, l.processed, l.order_num, l.sum
+    FROM "user_balance_log" l
+    WHERE l.operation = 'refill' AND EXISTS (
+        SELECT 1
+        FROM "user_balance_log" f
+        WHERE f.operation = 'refill' AND f.order_num = l.order_num AND f.id < l.id
+    );
+--
+-- Если пользователь уже потратил лишние баллы, баланс уменьшается только до нуля,
+-- чтобы миграция не нарушала ограничение balance >= 0. Непокрытый остаток
+-- определяется по таблице user_balance_log_refill_duplicates и списывается вручную.
+UPDATE "user_balance" ub
+SET balance = greatest(ub.balance - d.sum, 0)
+FROM (
+    SELECT user_id, sum(sum) AS sum
+    FROM "user_balance_log_refill_duplicates"
+    GROUP BY user_id
+) d
+WHERE ub.user_id = d.user_id;
+
+DELETE FROM "user_balance_log" l
+USING "user_balance_log_refill_duplicates" d
+WHERE l.id = d.id;
+--
+DROP INDEX IF EXISTS user_balance_log_refill_order_num_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS user_balance_log_refill_order_num_idx
+    ON user_balance_log USING btree(order_num) WHERE operation = 'refill';
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+--
+ALTER TABLE "orders" DROP COLUMN IF EXISTS claimed_until;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS claimed_by;
+--
+COMMIT TRANSACTION;
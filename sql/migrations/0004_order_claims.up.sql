@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+--
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS claimed_by TEXT;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMP WITH TIME ZONE;
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+--
+DROP INDEX IF EXISTS orders_next_poll_at_idx;
+--
+ALTER TABLE "orders" DROP COLUMN IF EXISTS next_poll_at;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS last_error;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS attempts;
+--
+COMMIT TRANSACTION;
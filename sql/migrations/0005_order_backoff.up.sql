@@ -0,0 +1,10 @@
+BEGIN TRANSACTION;
+--
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS last_error TEXT;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS next_poll_at TIMESTAMP WITH TIME ZONE;
+--
+CREATE INDEX IF NOT EXISTS orders_next_poll_at_idx ON orders USING btree(next_poll_at)
+    WHERE status = 'NEW' OR status = 'PROCESSING';
+--
+COMMIT TRANSACTION;
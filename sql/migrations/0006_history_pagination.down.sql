@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS user_balance_log_user_id_processed_idx;
+DROP INDEX IF EXISTS orders_user_id_uploaded_idx;
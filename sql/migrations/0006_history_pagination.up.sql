@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS orders_user_id_uploaded_idx ON orders USING btree(user_id, uploaded, id);
+CREATE INDEX IF NOT EXISTS user_balance_log_user_id_processed_idx ON user_balance_log USING btree(user_id, processed, id);
@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+--
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,29 @@
+BEGIN TRANSACTION;
+--
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id BIGINT GENERATED ALWAYS AS IDENTITY,
+    token_hash TEXT NOT NULL UNIQUE,
+    user_id BIGINT NOT NULL,
+    family UUID NOT NULL,
+    access_token_id UUID NOT NULL UNIQUE,
+    access_expires TIMESTAMP WITH TIME ZONE NOT NULL,
+    issued TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires TIMESTAMP WITH TIME ZONE NOT NULL,
+    used TIMESTAMP WITH TIME ZONE,
+    revoked TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY(id),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_idx ON refresh_tokens USING btree(family);
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_id_expires_idx ON refresh_tokens USING btree(user_id, expires);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    token_id UUID NOT NULL,
+    expires TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY(token_id)
+);
+
+CREATE INDEX IF NOT EXISTS revoked_tokens_expires_idx ON revoked_tokens USING btree(expires);
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+--
+DROP TABLE IF EXISTS login_attempts;
+--
+COMMIT TRANSACTION;
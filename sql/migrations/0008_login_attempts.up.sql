@@ -0,0 +1,13 @@
+BEGIN TRANSACTION;
+--
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    last_failure TIMESTAMP WITH TIME ZONE NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY(key)
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_last_failure_idx ON login_attempts USING btree(last_failure);
+--
+COMMIT TRANSACTION;
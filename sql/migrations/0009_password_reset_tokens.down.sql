@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+--
+DROP TABLE IF EXISTS password_reset_tokens;
+--
+COMMIT TRANSACTION;
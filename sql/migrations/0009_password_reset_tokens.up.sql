@@ -0,0 +1,16 @@
+BEGIN TRANSACTION;
+--
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id BIGINT GENERATED ALWAYS AS IDENTITY,
+    token_hash TEXT NOT NULL UNIQUE,
+    user_id BIGINT NOT NULL,
+    expires TIMESTAMP WITH TIME ZONE NOT NULL,
+    used TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY(id),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS password_reset_tokens_user_id_expires_idx
+    ON password_reset_tokens USING btree(user_id, expires);
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+--
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user'
+        CHECK (role IN ('user', 'admin'));
+--
+COMMIT TRANSACTION;
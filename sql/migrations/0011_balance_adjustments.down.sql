@@ -0,0 +1,41 @@
+BEGIN TRANSACTION;
+--
+-- Корректировки отменяются, чтобы баланс пользователей соответствовал оставшимся записям журнала.
+-- Если после отмены баланс пользователя станет отрицательным, откат миграции завершится ошибкой.
+UPDATE "user_balance" ub
+SET balance = ub.balance - a.sum
+FROM (
+    SELECT user_id, sum(sum) AS sum
+    FROM "user_balance_log"
+    WHERE operation = 'adjustment'
+    GROUP BY user_id
+) a
+WHERE ub.user_id = a.user_id;
+
+DELETE FROM "user_balance_log" WHERE operation = 'adjustment';
+--
+ALTER TABLE "user_balance_log"
+    DROP COLUMN IF EXISTS admin_id,
+    DROP COLUMN IF EXISTS reason,
+    DROP COLUMN IF EXISTS reference;
+--
+-- Значение перечисления нельзя удалить, поэтому тип пересоздаётся без него.
+ALTER TYPE "balance_operation" RENAME TO "__balance_operation";
+
+CREATE TYPE "balance_operation" AS ENUM ('withdrawal', 'refill');
+
+-- Частичные индексы с условием на operation зависят от старого типа и пересоздаются после смены типа столбца.
+DROP INDEX IF EXISTS user_balance_log_refill_order_num_idx;
+DROP INDEX IF EXISTS user_balance_log_user_id_idx;
+
+ALTER TABLE "user_balance_log"
+    ALTER COLUMN operation TYPE "balance_operation" USING operation::TEXT::"balance_operation";
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_balance_log_refill_order_num_idx
+    ON user_balance_log USING btree(order_num) WHERE operation = 'refill';
+CREATE INDEX IF NOT EXISTS user_balance_log_user_id_idx
+    ON user_balance_log USING btree(user_id) WHERE operation = 'withdrawal';
+
+DROP TYPE "__balance_operation";
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,10 @@
+BEGIN TRANSACTION;
+--
+ALTER TYPE "balance_operation" ADD VALUE IF NOT EXISTS 'adjustment';
+--
+ALTER TABLE "user_balance_log"
+    ADD COLUMN IF NOT EXISTS admin_id BIGINT REFERENCES users(id),
+    ADD COLUMN IF NOT EXISTS reason TEXT,
+    ADD COLUMN IF NOT EXISTS reference TEXT;
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,14 @@
+BEGIN TRANSACTION;
+--
+ALTER TYPE "balance_operation" ADD VALUE IF NOT EXISTS 'reversal';
+--
+ALTER TABLE "user_balance_log"
+    ADD COLUMN IF NOT EXISTS reversal_of BIGINT REFERENCES user_balance_log(id);
+
+-- Каждое списание может быть отменено только один раз
+CREATE UNIQUE INDEX IF NOT EXISTS user_balance_log_reversal_of_idx ON user_balance_log USING btree(reversal_of);
+
+CREATE INDEX IF NOT EXISTS user_balance_log_withdrawal_order_num_idx
+    ON user_balance_log USING btree(order_num) WHERE operation = 'withdrawal';
+--
+COMMIT TRANSACTION;
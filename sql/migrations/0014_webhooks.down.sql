@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+--
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,39 @@
+BEGIN TRANSACTION;
+--
+CREATE TABLE IF NOT EXISTS webhooks (
+    id BIGINT GENERATED ALWAYS AS IDENTITY,
+    user_id BIGINT NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    created TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY(id),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_user_id_idx ON webhooks USING btree(user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGINT GENERATED ALWAYS AS IDENTITY,
+    webhook_id BIGINT NOT NULL,
+    event_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    last_error TEXT,
+    created TIMESTAMP WITH TIME ZONE NOT NULL,
+    next_attempt_at TIMESTAMP WITH TIME ZONE,
+    delivered TIMESTAMP WITH TIME ZONE,
+    claimed_by TEXT,
+    claimed_until TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY(id),
+    FOREIGN KEY(webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_status_next_attempt_at_idx
+    ON webhook_deliveries USING btree(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_created_idx
+    ON webhook_deliveries USING btree(webhook_id, created, id);
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+--
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS locked_until;
+--
+COMMIT TRANSACTION;
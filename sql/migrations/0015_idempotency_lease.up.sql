@@ -0,0 +1,10 @@
+BEGIN TRANSACTION;
+--
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE;
+
+-- Ключи незавершённых запросов, сохранённые до появления захвата, могут быть захвачены повторно сразу.
+UPDATE idempotency_keys SET locked_until = created WHERE locked_until IS NULL;
+
+ALTER TABLE idempotency_keys ALTER COLUMN locked_until SET NOT NULL;
+--
+COMMIT TRANSACTION;